@@ -1,44 +1,93 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"training_session/config"
 	"training_session/db"
-	"training_session/pkg/controllers"
-	"training_session/pkg/routes"
-
-	"github.com/gin-gonic/gin"
+	"training_session/pkg/app"
+	"training_session/pkg/logging"
+	"training_session/pkg/repository/mongodb"
 )
 
+// fatal logs the error and exits
+func fatal(msg string, err error) { // Log an error and exit
+	slog.Error(msg, slog.Any("error", err)) // Log the error message
+	os.Exit(1)                              // Exit with a failure status
+}
+
 func main() {
+	// Running "config" prints the effective configuration, without secrets, and "migrate" applies the
+	// pending database migrations, instead of serving
+	args := os.Args[1:]                                                 // Get the command-line arguments
+	command := ""                                                       // Define the command
+	if len(args) > 0 && (args[0] == "config" || args[0] == "migrate") { // Check if a command was given
+		command, args = args[0], args[1:] // The remaining arguments are configuration flags
+	}
+
 	// Load configuration
-	cfg := config.LoadConfig() // Load the configuration
+	cfg, err := config.Load(args) // Load the configuration
+	if err != nil {               // Check if there is an error
+		fatal("Failed to load configuration", err) // Log the error message
+	}
 
-	// Connect to MongoDB
-	database, err := db.Connect(cfg) // Connect to MongoDB
-	if err != nil {                  // Check if there is an error
-		log.Fatalf("Failed to connect to MongoDB: %v", err) // Log the error message
+	// Log structured, scrubbed records; the standard log package is redirected to the same logger
+	logger, err := logging.New(os.Stdout, cfg.Log) // Create the logger
+	if err != nil {                                // Check if there is an error
+		fatal("Failed to create logger", err) // Log the error message
 	}
+	slog.SetDefault(logger) // Use the logger everywhere
 
-	// Log message indicating that the database has been initialized successfully
-	log.Println("Database initialized successfully") // Add this log message
+	if command == "config" { // Check if the configuration must be printed
+		dump, err := cfg.Dump() // Render the redacted configuration
+		if err != nil {         // Check if there is an error
+			fatal("Failed to render configuration", err) // Log the error message
+		}
+		fmt.Print(string(dump)) // Print the configuration
+		return                  // Do not start the server
+	}
 
-	// Initialize controllers with the database connection
-	controllers.InitializeSession(database)            // Initialize the controllers
-	controllers.InitializeUser(database)               // Initialize the controllers
-	controllers.InitializeInvitation(database)         // Initialize the invitation controller
-	controllers.InitializeNotification(database)       // Initialize the notification controller
-	controllers.InitializeFeedbackController(database) // Initialize the feedback controller
-	controllers.InitializePitchBooking(database)       // Initialize the pitch booking controller
+	if command == "migrate" { // Check if the database must be migrated
+		if err := migrate(cfg, logger); err != nil { // Apply the pending migrations
+			fatal("Failed to migrate the database", err) // Log the error message
+		}
+		return // Do not start the server
+	}
 
-	// Set up routes and start the server
-	r := gin.Default()    // Create a new Gin router
-	routes.SetupRoutes(r) // Set up the routes
+	// Build the application, connecting to MongoDB
+	application, err := app.New(cfg, app.WithLogger(logger)) // Create the application
+	if err != nil {                                          // Check if there is an error
+		fatal("Failed to initialize application", err) // Log the error message
+	}
+
+	// Run the server until SIGINT or SIGTERM, then shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM) // Cancel the context on the shutdown signals
+	defer stop()                                                                             // Stop listening for the signals
+
+	if err := application.Run(ctx); err != nil { // Run the server
+		fatal("Server stopped with errors", err) // Log any errors
+	}
+	slog.Info("Server stopped") // Log the clean shutdown
+}
+
+// migrate connects to MongoDB and applies the pending migrations, waiting for the instances doing the same
+func migrate(cfg *config.Config, logger *slog.Logger) error { // Migrate the database
+	database, err := db.Connect(cfg) // Connect to MongoDB
+	if err != nil {                  // Check if there is an error
+		return fmt.Errorf("failed to connect to MongoDB: %w", err) // Return the error
+	}
+	defer database.Client().Disconnect(context.Background()) // Disconnect once migrated
 
-	// Run the server
-	log.Printf("Starting server on :%d", cfg.ServerPort)              // Log the server port
-	if err := r.Run(fmt.Sprintf(":%d", cfg.ServerPort)); err != nil { // Run the server
-		log.Fatal(err) // Log any errors
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Migrations.Timeout)          // Bound the migrations
+	defer cancel()                                                                            // Release the context
+	applied, err := mongodb.NewMigrator(database, cfg.Migrations.LockTimeout, logger).Up(ctx) // Apply the pending migrations
+	if err != nil {                                                                           // Check if there is an error
+		return err // Return the error
 	}
+	logger.Info("Database migrated", slog.Int("applied", applied)) // Log a success message
+	return nil                                                     // Return nil
 }
@@ -1,59 +1,383 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
-
-	"github.com/joho/godotenv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
+// Config holds the configuration of the service.
+//
+// Every field is tagged with its key in the configuration file; the environment variable and the
+// command-line flag of a field are derived from that key (see Load).
 type Config struct {
-	ServerPort   int    // Server port number
-	MongoURI     string // MongoDB URI
-	DatabaseName string // Database name
-	JwtSecretKey string // JWT secret key
+	ServerPort   int    `config:"server_port"`                  // Server port number
+	MongoURI     string `config:"mongo_uri" secret:"true"`      // MongoDB URI, may embed credentials
+	DatabaseName string `config:"database_name"`                // Database name
+	JwtSecretKey string `config:"jwt_secret_key" secret:"true"` // JWT secret key
+
+	FeedbackBlocklist []string `config:"feedback_blocklist"` // Words masked in feedback and coach replies
+	TrainingTypes     []string `config:"training_types"`     // Training types a session can be of
+
+	Server    ServerConfig    `config:"server"`     // HTTP server timeouts
+	Mongo     MongoConfig     `config:"mongo"`      // MongoDB client settings
+	Auth      AuthConfig      `config:"auth"`       // Token lifetimes and cookie settings
+	CORS      CORSConfig      `config:"cors"`       // Cross-origin resource sharing
+	RateLimit RateLimitConfig `config:"rate_limit"` // Request rate limiting
+	SMTP      SMTPConfig      `config:"smtp"`       // Outgoing email
+	Tracing   TracingConfig   `config:"tracing"`    // OpenTelemetry tracing
+	Log       LogConfig       `config:"log"`        // Structured logging
+
+	Idempotency IdempotencyConfig `config:"idempotency"` // Replay of the mutating requests sent with an Idempotency-Key
+	Migrations  MigrationsConfig  `config:"migrations"`  // Migrations of the MongoDB indexes, validators and data
+	Events      EventsConfig      `config:"events"`      // Publication of the domain events
+	Webhooks    WebhooksConfig    `config:"webhooks"`    // Delivery of the domain events to the partner webhooks
+	Deletion    DeletionConfig    `config:"deletion"`    // Retention of the soft deleted documents
+}
+
+// ServerConfig holds the timeouts of the HTTP server
+type ServerConfig struct {
+	ReadTimeout       time.Duration `config:"read_timeout"`        // Maximum duration for reading a whole request
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout"` // Maximum duration for reading the request headers
+	WriteTimeout      time.Duration `config:"write_timeout"`       // Maximum duration before timing out the writes of a response
+	IdleTimeout       time.Duration `config:"idle_timeout"`        // Maximum duration to keep an idle keep-alive connection open
+	ShutdownTimeout   time.Duration `config:"shutdown_timeout"`    // Maximum duration to drain in-flight requests on shutdown
+	ReadinessTimeout  time.Duration `config:"readiness_timeout"`   // Maximum duration of each readiness check of /readyz
+}
+
+// MongoConfig holds the settings of the MongoDB client
+type MongoConfig struct {
+	ConnectTimeout time.Duration `config:"connect_timeout"` // Maximum duration to connect to and ping the server
+}
+
+// AuthConfig holds the lifetimes of the issued tokens and the settings of the auth cookie
+type AuthConfig struct {
+	TokenTTL     time.Duration `config:"token_ttl"`     // Lifetime of the JWT issued on login
+	CookieTTL    time.Duration `config:"cookie_ttl"`    // Lifetime of the auth_token cookie
+	CookieDomain string        `config:"cookie_domain"` // Domain of the auth_token cookie, the request host when empty
+	CookieSecure bool          `config:"cookie_secure"` // Only send the auth_token cookie over HTTPS
+}
+
+// CORSConfig holds the cross-origin resource sharing policy, CORS is disabled when no origin is allowed
+type CORSConfig struct {
+	AllowedOrigins   []string      `config:"allowed_origins"`   // Origins allowed to call the API, "*" for any
+	AllowedMethods   []string      `config:"allowed_methods"`   // Methods allowed in cross-origin requests
+	AllowedHeaders   []string      `config:"allowed_headers"`   // Headers allowed in cross-origin requests
+	AllowCredentials bool          `config:"allow_credentials"` // Allow cookies in cross-origin requests
+	MaxAge           time.Duration `config:"max_age"`           // How long browsers may cache a preflight response
+}
+
+// RateLimitConfig holds the per client rate limit
+type RateLimitConfig struct {
+	Enabled           bool    `config:"enabled"`             // Enable rate limiting
+	RequestsPerSecond float64 `config:"requests_per_second"` // Sustained number of requests a client may send per second
+	Burst             int     `config:"burst"`               // Number of requests a client may send at once
+}
+
+// SMTPConfig holds the settings of the mail server, emails are not sent when the host is empty
+type SMTPConfig struct {
+	Host     string        `config:"host"`                   // Mail server host
+	Port     int           `config:"port"`                   // Mail server port
+	Username string        `config:"username"`               // Mail server user
+	Password string        `config:"password" secret:"true"` // Mail server password
+	From     string        `config:"from"`                   // Sender address of the emails
+	Timeout  time.Duration `config:"timeout"`                // Maximum duration to deliver an email
+}
+
+// TracingConfig holds the OpenTelemetry trace exporter, tracing is disabled when the exporter is "none"
+type TracingConfig struct {
+	Exporter    string  `config:"exporter"`     // Span exporter: "none", "stdout" or "otlp"
+	Endpoint    string  `config:"endpoint"`     // Host and port of the OTLP/HTTP collector, e.g. localhost:4318
+	Insecure    bool    `config:"insecure"`     // Send the spans to the collector over plain HTTP
+	SampleRatio float64 `config:"sample_ratio"` // Fraction of the traces started here that are recorded
+	ServiceName string  `config:"service_name"` // Service name attached to the spans
+}
+
+// LogConfig holds the level and format of the logs
+type LogConfig struct {
+	Level  string `config:"level"`  // Minimum level logged: "debug", "info", "warn" or "error"
+	Format string `config:"format"` // Output format: "json" or "text"
+}
+
+// IdempotencyConfig holds how long the responses of the requests sent with an Idempotency-Key are replayed
+type IdempotencyConfig struct {
+	TTL         time.Duration `config:"ttl"`          // How long a key and its response are kept
+	LockTimeout time.Duration `config:"lock_timeout"` // How long a request that never finished holds its key
+}
+
+// MigrationsConfig holds when the database migrations run and how the runners exclude each other
+type MigrationsConfig struct {
+	OnStartup   bool          `config:"on_startup"`   // Apply the pending migrations when the server starts, not only with the migrate command
+	Timeout     time.Duration `config:"timeout"`      // Maximum duration to wait for the lock and apply the pending migrations
+	LockTimeout time.Duration `config:"lock_timeout"` // How long a runner that stopped without releasing the lock holds it
+}
+
+// EventsConfig holds the broker the domain events are published to and how the outbox relay polls
+type EventsConfig struct {
+	Broker       string        `config:"broker"`        // Broker the events are published to: "bus", "nats" or "kafka"
+	PollInterval time.Duration `config:"poll_interval"` // How often the relay looks for events to publish
+	BatchSize    int           `config:"batch_size"`    // Maximum number of events published per poll
+	LeaseTimeout time.Duration `config:"lease_timeout"` // How long a relay that stopped keeps the right to publish
+	NATS         NATSConfig    `config:"nats"`          // NATS broker
+	Kafka        KafkaConfig   `config:"kafka"`         // Kafka broker
+}
+
+// NATSConfig holds the NATS server the events are published to with JetStream
+type NATSConfig struct {
+	URL           string `config:"url" secret:"true"` // NATS server URL, may embed credentials
+	SubjectPrefix string `config:"subject_prefix"`    // Events are published on <subject_prefix>.<type>
+}
+
+// KafkaConfig holds the Kafka cluster the events are published to
+type KafkaConfig struct {
+	Brokers []string `config:"brokers"` // Addresses of the brokers, host:port
+	Topic   string   `config:"topic"`   // Topic of the events, keyed by aggregate ID
 }
 
-func LoadConfig() *Config { // LoadConfig function to load the configuration
-	if err := godotenv.Load(); err != nil { // Load the .env file
-		log.Fatal("Error loading .env file") // Log an error message if the .env file cannot be loaded
+// WebhooksConfig holds how the domain events are delivered to the webhooks and retried
+type WebhooksConfig struct {
+	PollInterval time.Duration `config:"poll_interval"` // How often the dispatcher looks for deliveries to attempt
+	BatchSize    int           `config:"batch_size"`    // Maximum number of deliveries attempted per poll
+	Timeout      time.Duration `config:"timeout"`       // Maximum duration of a request to a webhook
+	MaxAttempts  int           `config:"max_attempts"`  // Attempts before a delivery is moved to the dead letters
+	Backoff      time.Duration `config:"backoff"`       // Delay before the first retry, doubled after every failure
+	MaxBackoff   time.Duration `config:"max_backoff"`   // Longest delay between two attempts
+
+	AllowPrivateNetworks bool `config:"allow_private_networks"` // Accept and deliver to loopback, private and internal addresses, for local development only
+}
+
+// DeletionConfig holds how long the soft deleted sessions, users, invitations and pitch bookings can be restored
+type DeletionConfig struct {
+	Retention     time.Duration `config:"retention"`      // How long a soft deleted document is kept before it is purged
+	PurgeInterval time.Duration `config:"purge_interval"` // How often the expired documents are purged
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config { // Build the default configuration
+	return &Config{
+		ServerPort:   8080,   // Listen on port 8080
+		DatabaseName: "test", // Use the "test" database
+
+		TrainingTypes: []string{"individual", "group", "private"}, // Offer individual, group and private sessions
+
+		Server: ServerConfig{
+			ReadTimeout:       15 * time.Second, // Set the read timeout
+			ReadHeaderTimeout: 5 * time.Second,  // Set the read header timeout
+			WriteTimeout:      15 * time.Second, // Set the write timeout
+			IdleTimeout:       60 * time.Second, // Set the idle timeout
+			ShutdownTimeout:   30 * time.Second, // Set the shutdown timeout
+			ReadinessTimeout:  2 * time.Second,  // Set the readiness check timeout
+		},
+		Mongo: MongoConfig{
+			ConnectTimeout: 10 * time.Second, // Set the connect timeout
+		},
+		Auth: AuthConfig{
+			TokenTTL:  72 * time.Hour, // Tokens are valid for 72 hours
+			CookieTTL: 2 * time.Hour,  // The cookie expires after 2 hours
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},                              // Set the allowed methods
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-None-Match"}, // Set the allowed headers
+			MaxAge:         12 * time.Hour,                                                                            // Cache preflight responses for 12 hours
+		},
+		RateLimit: RateLimitConfig{
+			RequestsPerSecond: 10, // Allow 10 requests per second
+			Burst:             20, // Allow bursts of 20 requests
+		},
+		SMTP: SMTPConfig{
+			Port:    587,              // Use the submission port
+			Timeout: 10 * time.Second, // Set the delivery timeout
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",             // Do not export spans
+			SampleRatio: 1,                  // Record every trace
+			ServiceName: "training-session", // Name the service
+		},
+		Log: LogConfig{
+			Level:  "info", // Log informational messages and above
+			Format: "json", // Log JSON lines
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:         24 * time.Hour, // Replay the responses for a day
+			LockTimeout: time.Minute,    // Free the keys of the requests that never finished after a minute
+		},
+		Migrations: MigrationsConfig{
+			OnStartup:   true,             // Migrate the database when the server starts
+			Timeout:     5 * time.Minute,  // Give up after 5 minutes
+			LockTimeout: 10 * time.Minute, // Take over the lock of a runner silent for 10 minutes
+		},
+		Events: EventsConfig{
+			Broker:       "bus",            // Publish the events to the in-process bus
+			PollInterval: time.Second,      // Look for events every second
+			BatchSize:    100,              // Publish up to 100 events per poll
+			LeaseTimeout: 30 * time.Second, // Take over the lease of a relay silent for 30 seconds
+			NATS: NATSConfig{
+				SubjectPrefix: "training_session.events", // Publish on training_session.events.<type>
+			},
+			Kafka: KafkaConfig{
+				Topic: "training_session.events", // Publish on the training_session.events topic
+			},
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval: time.Second,      // Look for deliveries every second
+			BatchSize:    50,               // Attempt up to 50 deliveries per poll
+			Timeout:      10 * time.Second, // Give up on a receiver silent for 10 seconds
+			MaxAttempts:  8,                // Retry for about an hour with the default backoff
+			Backoff:      30 * time.Second, // Retry after 30 seconds, then 1, 2, 4... minutes
+			MaxBackoff:   time.Hour,        // Retry at least every hour
+		},
+		Deletion: DeletionConfig{
+			Retention:     30 * 24 * time.Hour, // Keep the deleted documents restorable for 30 days
+			PurgeInterval: time.Hour,           // Purge the expired documents every hour
+		},
 	}
+}
 
-	mongoURI, exists := os.LookupEnv("MONGO_URI") // Get the MongoDB URI from the environment
-	if !exists {                                  // Check if the MONGO_URI environment variable is set
-		log.Fatal("MONGO_URI environment variable is required") // Log an error message if the MONGO_URI is not set
+// Validate checks the configuration and reports every problem at once
+func (cfg *Config) Validate() error { // Validate the configuration
+	var errs []error                                        // Define a slice to hold the problems found
+	fail := func(key, format string, args ...interface{}) { // Record a problem
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...))) // Add the problem
 	}
 
-	serverPortStr := os.Getenv("SERVER_PORT") // Get the server port from the environment
-	if serverPortStr == "" {                  // Check if the SERVER_PORT environment variable is not set
-		serverPortStr = "8080" // Set the default server port to 8080
+	if cfg.ServerPort < 1 || cfg.ServerPort > 65535 { // Check if the port is out of range
+		fail("server_port", "must be between 1 and 65535, got %d", cfg.ServerPort) // Add a problem
+	}
+	if cfg.MongoURI == "" { // Check if the MongoDB URI is missing
+		fail("mongo_uri", "is required") // Add a problem
+	} else if !strings.HasPrefix(cfg.MongoURI, "mongodb://") && !strings.HasPrefix(cfg.MongoURI, "mongodb+srv://") { // Check the scheme
+		fail("mongo_uri", "must start with mongodb:// or mongodb+srv://") // Add a problem
+	}
+	if cfg.DatabaseName == "" { // Check if the database name is missing
+		fail("database_name", "is required") // Add a problem
+	}
+	if cfg.JwtSecretKey == "" { // Check if the JWT secret key is missing
+		fail("jwt_secret_key", "is required") // Add a problem
 	}
 
-	serverPort, err := strconv.Atoi(serverPortStr) // Convert the server port to an integer
-	if err != nil {                                // Check if there is an error
-		log.Fatalf("Invalid SERVER_PORT value: %v", err) // Log an error message if the SERVER_PORT value is invalid
+	if len(cfg.TrainingTypes) == 0 { // Check if no training type is offered
+		fail("training_types", "at least one training type is required") // Add a problem
 	}
 
-	databaseName := os.Getenv("DATABASE_NAME") // Get the database name from the environment
-	if databaseName == "" {                    // Check if the DATABASE_NAME environment variable is not set
-		databaseName = "test" // Set the default database name to "test"
+	durations := map[string]time.Duration{ // Durations that must be positive
+		"server.read_timeout":        cfg.Server.ReadTimeout,       // Read timeout
+		"server.read_header_timeout": cfg.Server.ReadHeaderTimeout, // Read header timeout
+		"server.write_timeout":       cfg.Server.WriteTimeout,      // Write timeout
+		"server.idle_timeout":        cfg.Server.IdleTimeout,       // Idle timeout
+		"server.shutdown_timeout":    cfg.Server.ShutdownTimeout,   // Shutdown timeout
+		"server.readiness_timeout":   cfg.Server.ReadinessTimeout,  // Readiness check timeout
+		"mongo.connect_timeout":      cfg.Mongo.ConnectTimeout,     // Connect timeout
+		"auth.token_ttl":             cfg.Auth.TokenTTL,            // Token lifetime
+		"auth.cookie_ttl":            cfg.Auth.CookieTTL,           // Cookie lifetime
+		"idempotency.ttl":            cfg.Idempotency.TTL,          // Idempotency key lifetime
+		"idempotency.lock_timeout":   cfg.Idempotency.LockTimeout,  // Idempotency key lock timeout
+		"migrations.timeout":         cfg.Migrations.Timeout,       // Migration timeout
+		"migrations.lock_timeout":    cfg.Migrations.LockTimeout,   // Migration lock timeout
+		"events.poll_interval":       cfg.Events.PollInterval,      // Outbox poll interval
+		"events.lease_timeout":       cfg.Events.LeaseTimeout,      // Relay lease timeout
+		"webhooks.poll_interval":     cfg.Webhooks.PollInterval,    // Delivery poll interval
+		"webhooks.timeout":           cfg.Webhooks.Timeout,         // Webhook request timeout
+		"webhooks.backoff":           cfg.Webhooks.Backoff,         // First retry delay
+		"webhooks.max_backoff":       cfg.Webhooks.MaxBackoff,      // Longest retry delay
+		"deletion.retention":         cfg.Deletion.Retention,       // Soft deletion retention
+		"deletion.purge_interval":    cfg.Deletion.PurgeInterval,   // Purge interval
+	}
+	for _, key := range sortedKeys(durations) { // Iterate over the durations in a stable order
+		if durations[key] <= 0 { // Check if the duration is not positive
+			fail(key, "must be positive, got %s", durations[key]) // Add a problem
+		}
 	}
 
-	jwtSecretKey := os.Getenv("JWT_SECRET_KEY") // Get the JWT secret key from the environment
-	if jwtSecretKey == "" {                     // Check if the JWT_SECRET_KEY environment variable is not set
-		log.Fatal("JWT_SECRET_KEY environment variable is required") // Log an error message if the JWT_SECRET_KEY is not set
+	if cfg.CORS.MaxAge < 0 { // Check if the preflight cache duration is negative
+		fail("cors.max_age", "must not be negative, got %s", cfg.CORS.MaxAge) // Add a problem
+	}
+	for _, origin := range cfg.CORS.AllowedOrigins { // Iterate over the allowed origins
+		if origin == "*" && cfg.CORS.AllowCredentials { // Check if credentials are allowed for any origin
+			fail("cors.allow_credentials", "cannot be combined with the \"*\" origin") // Add a problem
+		}
 	}
 
-	log.Println("Mongo URI:", mongoURI)          // Log the MongoDB URI
-	log.Println("Database Name:", databaseName)  // Log the database name
-	log.Println("Server Port:", serverPort)      // Log the server port
-	log.Println("JWT Secret Key:", jwtSecretKey) // Log the JWT secret key
+	if cfg.RateLimit.Enabled { // Check the rate limit only when it is used
+		if cfg.RateLimit.RequestsPerSecond <= 0 { // Check if the rate is not positive
+			fail("rate_limit.requests_per_second", "must be positive, got %g", cfg.RateLimit.RequestsPerSecond) // Add a problem
+		}
+		if cfg.RateLimit.Burst < 1 { // Check if the burst is too small
+			fail("rate_limit.burst", "must be at least 1, got %d", cfg.RateLimit.Burst) // Add a problem
+		}
+	}
 
-	return &Config{ // Return the configuration
-		ServerPort:   serverPort,   // Set the server port
-		MongoURI:     mongoURI,     // Set the MongoDB URI
-		DatabaseName: databaseName, // Set the database name
-		JwtSecretKey: jwtSecretKey, // Set the JWT secret key
+	if cfg.SMTP.Host != "" { // Check the mail server only when emails are sent
+		if cfg.SMTP.Port < 1 || cfg.SMTP.Port > 65535 { // Check if the port is out of range
+			fail("smtp.port", "must be between 1 and 65535, got %d", cfg.SMTP.Port) // Add a problem
+		}
+		if cfg.SMTP.From == "" { // Check if the sender is missing
+			fail("smtp.from", "is required when smtp.host is set") // Add a problem
+		}
+		if cfg.SMTP.Timeout <= 0 { // Check if the timeout is not positive
+			fail("smtp.timeout", "must be positive, got %s", cfg.SMTP.Timeout) // Add a problem
+		}
 	}
+
+	switch cfg.Tracing.Exporter { // Check the span exporter
+	case "none", "stdout": // Nothing else to check
+	case "otlp": // The collector must be known
+		if cfg.Tracing.Endpoint == "" { // Check if the collector is missing
+			fail("tracing.endpoint", "is required when tracing.exporter is otlp") // Add a problem
+		}
+	default: // Unknown exporter
+		fail("tracing.exporter", "must be none, stdout or otlp, got %q", cfg.Tracing.Exporter) // Add a problem
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 { // Check if the ratio is out of range
+		fail("tracing.sample_ratio", "must be between 0 and 1, got %g", cfg.Tracing.SampleRatio) // Add a problem
+	}
+	if cfg.Tracing.ServiceName == "" { // Check if the service name is missing
+		fail("tracing.service_name", "is required") // Add a problem
+	}
+
+	switch strings.ToLower(cfg.Log.Level) { // Check the log level
+	case "debug", "info", "warn", "error": // Known level
+	default: // Unknown level
+		fail("log.level", "must be debug, info, warn or error, got %q", cfg.Log.Level) // Add a problem
+	}
+	switch strings.ToLower(cfg.Log.Format) { // Check the log format
+	case "json", "text": // Known format
+	default: // Unknown format
+		fail("log.format", "must be json or text, got %q", cfg.Log.Format) // Add a problem
+	}
+
+	switch cfg.Events.Broker { // Check the event broker
+	case "bus": // Nothing else to check
+	case "nats": // The server must be known
+		if cfg.Events.NATS.URL == "" { // Check if the server is missing
+			fail("events.nats.url", "is required when events.broker is nats") // Add a problem
+		}
+		if cfg.Events.NATS.SubjectPrefix == "" { // Check if the subject prefix is missing
+			fail("events.nats.subject_prefix", "is required when events.broker is nats") // Add a problem
+		}
+	case "kafka": // The cluster must be known
+		if len(cfg.Events.Kafka.Brokers) == 0 { // Check if the brokers are missing
+			fail("events.kafka.brokers", "is required when events.broker is kafka") // Add a problem
+		}
+		if cfg.Events.Kafka.Topic == "" { // Check if the topic is missing
+			fail("events.kafka.topic", "is required when events.broker is kafka") // Add a problem
+		}
+	default: // Unknown broker
+		fail("events.broker", "must be bus, nats or kafka, got %q", cfg.Events.Broker) // Add a problem
+	}
+	if cfg.Events.BatchSize < 1 { // Check if the batch is too small
+		fail("events.batch_size", "must be at least 1, got %d", cfg.Events.BatchSize) // Add a problem
+	}
+
+	if cfg.Webhooks.BatchSize < 1 { // Check if the batch is too small
+		fail("webhooks.batch_size", "must be at least 1, got %d", cfg.Webhooks.BatchSize) // Add a problem
+	}
+	if cfg.Webhooks.MaxAttempts < 1 { // Check if a delivery is never attempted
+		fail("webhooks.max_attempts", "must be at least 1, got %d", cfg.Webhooks.MaxAttempts) // Add a problem
+	}
+	if cfg.Webhooks.MaxBackoff < cfg.Webhooks.Backoff { // Check if the delays are inverted
+		fail("webhooks.max_backoff", "must not be shorter than webhooks.backoff (%s), got %s", cfg.Webhooks.Backoff, cfg.Webhooks.MaxBackoff) // Add a problem
+	}
+
+	return errors.Join(errs...) // Return every problem found
 }
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of secret fields in dumps
+const redacted = "[REDACTED]"
+
+// field is a settable leaf of the configuration
+type field struct {
+	key    string        // Dotted key of the field, e.g. "server.read_timeout"
+	value  reflect.Value // Value of the field
+	secret bool          // Whether the value must be redacted
+}
+
+// env returns the environment variable of the field, e.g. SERVER_READ_TIMEOUT
+func (f field) env() string { // Derive the environment variable name
+	return strings.ToUpper(strings.ReplaceAll(f.key, ".", "_")) // Upper case the key, with underscores
+}
+
+// flag returns the command-line flag of the field, e.g. server-read-timeout
+func (f field) flag() string { // Derive the flag name
+	return strings.NewReplacer(".", "-", "_", "-").Replace(f.key) // Use dashes only
+}
+
+// set parses a raw value into the field
+func (f field) set(raw string) error { // Set the field from a string
+	v := f.value // Get the value of the field
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) { // Check if the field is a duration
+		d, err := time.ParseDuration(strings.TrimSpace(raw)) // Parse the duration
+		if err != nil {                                      // Check if there is an error
+			return fmt.Errorf("%s: invalid duration %q", f.key, raw) // Return the error
+		}
+		v.SetInt(int64(d)) // Set the duration
+		return nil         // Return nil
+	}
+
+	switch v.Kind() { // Parse according to the type of the field
+	case reflect.String: // Plain strings
+		v.SetString(raw) // Set the string
+	case reflect.Bool: // Booleans
+		b, err := strconv.ParseBool(strings.TrimSpace(raw)) // Parse the boolean
+		if err != nil {                                     // Check if there is an error
+			return fmt.Errorf("%s: invalid boolean %q", f.key, raw) // Return the error
+		}
+		v.SetBool(b) // Set the boolean
+	case reflect.Int: // Integers
+		i, err := strconv.Atoi(strings.TrimSpace(raw)) // Parse the integer
+		if err != nil {                                // Check if there is an error
+			return fmt.Errorf("%s: invalid integer %q", f.key, raw) // Return the error
+		}
+		v.SetInt(int64(i)) // Set the integer
+	case reflect.Float64: // Decimal numbers
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64) // Parse the number
+		if err != nil {                                          // Check if there is an error
+			return fmt.Errorf("%s: invalid number %q", f.key, raw) // Return the error
+		}
+		v.SetFloat(n) // Set the number
+	case reflect.Slice: // Comma separated lists of strings
+		items := []string{}                            // Define a slice to hold the items
+		for _, item := range strings.Split(raw, ",") { // Iterate over the comma separated items
+			if item = strings.TrimSpace(item); item != "" { // Skip the empty items
+				items = append(items, item) // Add the item
+			}
+		}
+		v.Set(reflect.ValueOf(items)) // Set the list
+	default: // Unsupported type, a programming error
+		return fmt.Errorf("%s: unsupported type %s", f.key, v.Type()) // Return the error
+	}
+	return nil // Return nil
+}
+
+// fields lists the leaves of a configuration struct
+func fields(v reflect.Value, prefix string) []field { // Walk the configuration struct
+	var out []field                     // Define a slice to hold the fields
+	t := v.Type()                       // Get the type of the struct
+	for i := 0; i < t.NumField(); i++ { // Iterate over the struct fields
+		sf := t.Field(i)            // Get the struct field
+		key := sf.Tag.Get("config") // Get the key of the field
+		if key == "" {              // Check if the field is not configurable
+			continue // Skip the field
+		}
+		key = prefix + key // Prefix the key with the section
+
+		if sf.Type.Kind() == reflect.Struct { // Check if the field is a section
+			out = append(out, fields(v.Field(i), key+".")...) // Add the fields of the section
+			continue                                          // Go to the next field
+		}
+		out = append(out, field{key: key, value: v.Field(i), secret: sf.Tag.Get("secret") == "true"}) // Add the field
+	}
+	return out // Return the fields
+}
+
+// flagValue records a command-line flag so it can be applied after the file and the environment
+type flagValue struct {
+	field   field           // Field set by the flag
+	pending *[]func() error // Flags to apply, in the order they were given
+	isBool  bool            // Whether the flag can be given without a value
+}
+
+func (fv *flagValue) String() string   { return "" }        // Flags have no printed default, see Default
+func (fv *flagValue) IsBoolFlag() bool { return fv.isBool } // Boolean flags need no value
+
+// Set records the value of the flag
+func (fv *flagValue) Set(raw string) error { // Record the flag
+	f := fv.field                                                         // Get the field of the flag
+	*fv.pending = append(*fv.pending, func() error { return f.set(raw) }) // Apply the flag later
+	return nil                                                            // Return nil
+}
+
+// Load builds the configuration from, in increasing order of precedence: the defaults, the
+// optional YAML or TOML file given with -config or CONFIG_FILE, the environment (and the .env
+// file) and the command-line arguments.
+//
+// The key "server.read_timeout" of the file is read from the SERVER_READ_TIMEOUT environment
+// variable and the -server-read-timeout flag. Any variable can instead be read from a file by
+// setting <VARIABLE>_FILE to its path, as done by Docker and Kubernetes secret mounts.
+//
+// Every problem found, from unparsable values to failed validation, is reported at once.
+func Load(args []string) (*Config, error) { // Load the configuration
+	cfg := Default()                               // Start from the defaults
+	all := fields(reflect.ValueOf(cfg).Elem(), "") // List the configurable fields
+
+	var pending []func() error                                                            // Define a slice to hold the flags to apply
+	flags := flag.NewFlagSet("training_session", flag.ContinueOnError)                    // Define the command-line flags
+	configFile := flags.String("config", "", "path of a YAML or TOML configuration file") // Define the configuration file flag
+	for _, f := range all {                                                               // Iterate over the fields
+		isBool := f.value.Kind() == reflect.Bool                                            // Check if the field is a boolean
+		flags.Var(&flagValue{field: f, pending: &pending, isBool: isBool}, f.flag(), f.key) // Define the flag of the field
+	}
+	if err := flags.Parse(args); err != nil { // Parse the command-line arguments
+		return nil, err // Return the error
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) { // Load the .env file, it is optional
+		return nil, fmt.Errorf("error loading .env file: %w", err) // Return an error if the .env file cannot be read
+	}
+
+	var errs []error    // Define a slice to hold the problems found
+	path := *configFile // Get the configuration file from the flag
+	if path == "" {     // Check if the flag was not given
+		path = os.Getenv("CONFIG_FILE") // Get the configuration file from the environment
+	}
+	if path != "" { // Check if a configuration file was given
+		errs = append(errs, loadFile(path, all)...) // Apply the configuration file
+	}
+
+	for _, f := range all { // Iterate over the fields
+		raw, ok, err := lookupEnv(f.env()) // Read the environment variable of the field
+		if err != nil {                    // Check if there is an error
+			errs = append(errs, err) // Add the problem
+			continue                 // Go to the next field
+		}
+		if ok { // Check if the variable is set
+			if err := f.set(raw); err != nil { // Set the field
+				errs = append(errs, err) // Add the problem
+			}
+		}
+	}
+
+	for _, apply := range pending { // Iterate over the flags
+		if err := apply(); err != nil { // Set the field of the flag
+			errs = append(errs, err) // Add the problem
+		}
+	}
+
+	if err := cfg.Validate(); err != nil { // Validate the configuration
+		errs = append(errs, err) // Add the problems
+	}
+	if len(errs) > 0 { // Check if there is any problem
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...)) // Return every problem
+	}
+
+	return cfg, nil // Return the configuration
+}
+
+// lookupEnv reads an environment variable, or the file named by <name>_FILE
+func lookupEnv(name string) (string, bool, error) { // Read an environment variable
+	if path, ok := os.LookupEnv(name + "_FILE"); ok && path != "" { // Check if the value is stored in a file
+		content, err := os.ReadFile(path) // Read the file
+		if err != nil {                   // Check if there is an error
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err) // Return the error
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil // Return the content without the trailing newline
+	}
+
+	raw, ok := os.LookupEnv(name) // Read the variable
+	return raw, ok, nil           // Return the value
+}
+
+// loadFile applies a YAML or TOML configuration file, chosen by its extension
+func loadFile(path string, all []field) []error { // Apply a configuration file
+	content, err := os.ReadFile(path) // Read the file
+	if err != nil {                   // Check if there is an error
+		return []error{fmt.Errorf("config file: %w", err)} // Return the error
+	}
+
+	values := map[string]interface{}{}           // Define a map to hold the decoded values
+	switch strings.ToLower(filepath.Ext(path)) { // Decode according to the extension
+	case ".yaml", ".yml": // YAML file
+		err = yaml.Unmarshal(content, &values) // Decode the YAML
+	case ".toml": // TOML file
+		err = toml.Unmarshal(content, &values) // Decode the TOML
+	default: // Unknown format
+		return []error{fmt.Errorf("config file: unsupported format %q, use .yaml, .yml or .toml", filepath.Ext(path))} // Return the error
+	}
+	if err != nil { // Check if there is a decoding error
+		return []error{fmt.Errorf("config file: %w", err)} // Return the error
+	}
+
+	byKey := map[string]field{} // Index the fields by key
+	for _, f := range all {     // Iterate over the fields
+		byKey[f.key] = f // Add the field to the index
+	}
+
+	flat := map[string]string{} // Define a map to hold the flattened values
+	flatten("", values, flat)   // Flatten the sections into dotted keys
+
+	var errs []error                       // Define a slice to hold the problems found
+	for _, key := range sortedKeys(flat) { // Iterate over the values in a stable order
+		f, ok := byKey[key] // Find the field of the key
+		if !ok {            // Check if the key is unknown
+			errs = append(errs, fmt.Errorf("config file: unknown key %q", key)) // Add the problem
+			continue                                                            // Go to the next key
+		}
+		if err := f.set(flat[key]); err != nil { // Set the field
+			errs = append(errs, err) // Add the problem
+		}
+	}
+	return errs // Return the problems found
+}
+
+// flatten turns nested sections into dotted keys, lists are joined with commas
+func flatten(prefix string, values map[string]interface{}, out map[string]string) { // Flatten decoded values
+	for key, value := range values { // Iterate over the values
+		switch v := value.(type) { // Convert according to the type of the value
+		case map[string]interface{}: // Nested section
+			flatten(prefix+key+".", v, out) // Flatten the section
+		case []interface{}: // List
+			items := make([]string, len(v)) // Define a slice to hold the items
+			for i, item := range v {        // Iterate over the items
+				items[i] = fmt.Sprint(item) // Convert the item
+			}
+			out[prefix+key] = strings.Join(items, ",") // Join the items
+		default: // Scalar
+			out[prefix+key] = fmt.Sprint(v) // Convert the value
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map in alphabetical order
+func sortedKeys[V any](m map[string]V) []string { // Sort the keys of a map
+	keys := make([]string, 0, len(m)) // Define a slice to hold the keys
+	for key := range m {              // Iterate over the map
+		keys = append(keys, key) // Add the key
+	}
+	sort.Strings(keys) // Sort the keys
+	return keys        // Return the keys
+}
+
+// Redacted returns the configuration as nested sections, with the secrets replaced, safe to log or serve
+func (cfg *Config) Redacted() map[string]interface{} { // Dump the configuration without secrets
+	out := map[string]interface{}{}                             // Define the dump
+	for _, f := range fields(reflect.ValueOf(cfg).Elem(), "") { // Iterate over the fields
+		var value interface{} = f.value.Interface() // Get the value of the field
+		if d, ok := value.(time.Duration); ok {     // Check if the value is a duration
+			value = d.String() // Print durations the way they are configured
+		}
+		if f.secret && !f.value.IsZero() { // Check if the value is a secret
+			value = redacted // Hide the secret
+		}
+
+		section := out                              // Start from the top of the dump
+		parts := strings.Split(f.key, ".")          // Split the key into sections
+		for _, part := range parts[:len(parts)-1] { // Iterate over the sections
+			next, ok := section[part].(map[string]interface{}) // Find the section
+			if !ok {                                           // Check if the section does not exist yet
+				next = map[string]interface{}{} // Create the section
+				section[part] = next            // Add the section
+			}
+			section = next // Go down into the section
+		}
+		section[parts[len(parts)-1]] = value // Set the value
+	}
+	return out // Return the dump
+}
+
+// Dump renders the redacted configuration as YAML
+func (cfg *Config) Dump() ([]byte, error) { // Render the configuration
+	return yaml.Marshal(cfg.Redacted()) // Encode the redacted configuration
+}
@@ -2,32 +2,28 @@ package db
 
 import (
 	"context"
-	"log"
-	"time"
+	"log/slog"
 	"training_session/config"
 
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-var (
-	Client   *mongo.Client   // Global MongoDB client variable
-	Database *mongo.Database // Global MongoDB database variable
-)
-
-// Connect initializes the MongoDB connection using the provided configuration.
-func Connect(cfg *config.Config) (*mongo.Database, error) {
+// Connect initializes the MongoDB connection using the provided configuration, extra client options
+// such as command monitors are applied after the URI.
+func Connect(cfg *config.Config, opts ...*options.ClientOptions) (*mongo.Database, error) {
 	// Create a context with a timeout for the MongoDB connection.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // Create a context with a timeout
-	defer cancel()                                                           // Defer the cancel function to release resources after the function returns.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Mongo.ConnectTimeout) // Create a context with a timeout
+	defer cancel()                                                                     // Defer the cancel function to release resources after the function returns.
 
 	// Set the client options using the MongoDB URI from the configuration.
 	clientOptions := options.Client().ApplyURI(cfg.MongoURI)
 
 	// Connect to MongoDB using the client options.
-	client, err := mongo.Connect(ctx, clientOptions) // Connect to MongoDB
-	if err != nil {                                  // Check if there is an error
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{clientOptions}, opts...)...) // Connect to MongoDB
+	if err != nil {                                                                                // Check if there is an error
 		return nil, err // Return the error
 	}
 
@@ -37,11 +33,35 @@ func Connect(cfg *config.Config) (*mongo.Database, error) {
 		return nil, err // Return the error
 	}
 
-	// Set the global client and database variables.
-	Client = client                              // Set the global client variable
-	Database = client.Database(cfg.DatabaseName) // Set the global database variable
-
 	// Log a success message and return the connected database.
-	log.Println("Connected to MongoDB!") // Log a success message
-	return Database, nil                 // Return the connected database
+	slog.Info("Connected to MongoDB")             // Log a success message
+	return client.Database(cfg.DatabaseName), nil // Return the connected database
+}
+
+// CombineMonitors returns a command monitor forwarding every event to each of the given monitors,
+// since the driver accepts a single one
+func CombineMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) { // Forward the started events
+			for _, m := range monitors { // Iterate over the monitors
+				if m.Started != nil { // Check if the monitor watches this event
+					m.Started(ctx, e) // Forward the event
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) { // Forward the succeeded events
+			for _, m := range monitors { // Iterate over the monitors
+				if m.Succeeded != nil { // Check if the monitor watches this event
+					m.Succeeded(ctx, e) // Forward the event
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) { // Forward the failed events
+			for _, m := range monitors { // Iterate over the monitors
+				if m.Failed != nil { // Check if the monitor watches this event
+					m.Failed(ctx, e) // Forward the event
+				}
+			}
+		},
+	}
 }
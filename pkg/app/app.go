@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"training_session/config"
+	"training_session/db"
+	"training_session/pkg/audit"
+	"training_session/pkg/controllers"
+	"training_session/pkg/dto"
+	"training_session/pkg/events"
+	"training_session/pkg/health"
+	"training_session/pkg/metrics"
+	"training_session/pkg/middleware"
+	"training_session/pkg/notify"
+	"training_session/pkg/repository"
+	"training_session/pkg/repository/mongodb"
+	"training_session/pkg/routes"
+	"training_session/pkg/tracing"
+	"training_session/pkg/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// App owns the dependencies of the server and passes them explicitly to the components that need them
+type App struct {
+	Config     *config.Config           // Application configuration
+	Database   *mongo.Database          // MongoDB database, nil when the repositories are overridden
+	Repos      *repository.Repositories // Repositories of every aggregate
+	Notifier   notify.Notifier          // Notifier delivering notifications to users
+	Broker     events.Broker            // Broker the domain events of the outbox are published to
+	Webhooks   *webhooks.Dispatcher     // Dispatcher delivering the domain events to the webhooks
+	Controller *controllers.Controller  // HTTP handlers
+	Router     *gin.Engine              // Router serving the API
+	Migrator   *mongodb.Migrator        // Migrator of the database, nil when the repositories are overridden
+	Probes     *health.Probes           // Liveness, readiness and version probes
+	Metrics    *metrics.Metrics         // Prometheus metrics
+	Logger     *slog.Logger             // Structured logger
+
+	ownsDatabase   bool                     // Whether the database was connected by the application and must be disconnected
+	ownsBroker     bool                     // Whether the broker was created by the application and must be closed
+	tracerProvider *sdktrace.TracerProvider // Tracer provider to flush on shutdown, nil when tracing is disabled
+	workers        sync.WaitGroup           // Background workers still running
+	workerCtx      context.Context          // Context of the background workers, cancelled on shutdown
+	stopWorkers    context.CancelFunc       // Cancel the context of the background workers
+	workersMu      sync.Mutex               // Mutex guarding the pending, started and stopped workers
+	pendingWorkers []func()                 // Start the background workers added before Run
+	workersStarted bool                     // Whether Run started the background workers
+	failedWorkers  []string                 // Background workers that returned before shutdown
+	migrated       atomic.Bool              // Whether every migration was found applied, they cannot become pending again
+}
+
+// Option overrides a dependency of the application, typically in tests
+type Option func(*App)
+
+// WithDatabase uses an already connected database instead of connecting with the configuration
+func WithDatabase(database *mongo.Database) Option { // Override the database
+	return func(app *App) { app.Database = database } // Set the database
+}
+
+// WithRepositories uses the given repositories, no database connection is made
+func WithRepositories(repos *repository.Repositories) Option { // Override the repositories
+	return func(app *App) { app.Repos = repos } // Set the repositories
+}
+
+// WithNotifier uses the given notifier instead of storing notifications in the repositories
+func WithNotifier(notifier notify.Notifier) Option { // Override the notifier
+	return func(app *App) { app.Notifier = notifier } // Set the notifier
+}
+
+// WithBroker publishes the domain events to the given broker instead of the configured one, e.g. an events.Bus in tests
+func WithBroker(broker events.Broker) Option { // Override the broker
+	return func(app *App) { app.Broker = broker } // Set the broker
+}
+
+// WithLogger logs with the given logger instead of the default one
+func WithLogger(logger *slog.Logger) Option { // Override the logger
+	return func(app *App) { app.Logger = logger } // Set the logger
+}
+
+// WithRouter registers the routes on the given router instead of a default one
+func WithRouter(router *gin.Engine) Option { // Override the router
+	return func(app *App) { app.Router = router } // Set the router
+}
+
+// New builds the application from the configuration, creating every dependency that was not overridden.
+// The background workers only start with Run; when the application cannot be built, what was created is
+// released.
+func New(cfg *config.Config, opts ...Option) (_ *App, err error) { // Create the application
+	app := &App{Config: cfg, Metrics: metrics.New(), Logger: slog.Default()}  // Define the application
+	app.workerCtx, app.stopWorkers = context.WithCancel(context.Background()) // Define the context of the background workers
+	for _, opt := range opts {                                                // Iterate over the options
+		opt(app) // Apply the option
+	}
+	defer func() { // Release the dependencies when the application cannot be built
+		if err == nil { // Check if the application was built
+			return // Keep the dependencies
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout) // Bound the release
+		defer cancel()                                                                       // Release the timer
+		if closeErr := app.Close(ctx); closeErr != nil {                                     // Disconnect the database and close the broker
+			err = errors.Join(err, closeErr) // Report the failure
+		}
+	}()
+
+	tracerProvider, err := tracing.Setup(context.Background(), cfg.Tracing) // Set up the trace exporter
+	if err != nil {                                                         // Check if there is an error
+		return nil, fmt.Errorf("failed to set up tracing: %w", err) // Return the error
+	}
+	app.tracerProvider = tracerProvider // Flush the spans on shutdown
+
+	if app.Repos == nil { // Check if the repositories must be created
+		if app.Database == nil { // Check if the database must be connected
+			monitor := db.CombineMonitors(app.Metrics.CommandMonitor(), otelmongo.NewMonitor()) // Time and trace the commands
+			database, err := db.Connect(cfg, options.Client().SetMonitor(monitor))              // Connect to MongoDB
+			if err != nil {                                                                     // Check if there is an error
+				return nil, fmt.Errorf("failed to connect to MongoDB: %w", err) // Return the error
+			}
+			app.Database = database // Set the database
+			app.ownsDatabase = true // Disconnect the database on shutdown
+
+			app.Logger.Info("Database initialized successfully") // Log a success message
+		}
+		app.Repos = mongodb.NewRepositories(app.Database) // Create the MongoDB repositories
+
+		app.Migrator = mongodb.NewMigrator(app.Database, cfg.Migrations.LockTimeout, app.Logger) // Create the migrator
+		if cfg.Migrations.OnStartup {                                                            // Check if the database must be migrated now
+			if err := app.migrate(); err != nil { // Apply the pending migrations
+				return nil, err // Return the error
+			}
+		}
+	}
+
+	if app.Notifier == nil { // Check if the notifier must be created
+		app.Notifier = notify.NewStoreNotifier(app.Repos.Notifications, app.Metrics) // Store notifications in the repositories
+		if cfg.SMTP.Host != "" {                                                     // Check if emails must be sent
+			app.Notifier = notify.NewEmailNotifier(app.Notifier, cfg.SMTP, app.Repos.Users, app.Metrics) // Email a copy of the notifications
+		}
+	}
+
+	if app.Broker == nil { // Check if the broker must be created
+		broker, err := events.NewBroker(cfg.Events) // Create the configured broker
+		if err != nil {                             // Check if there is an error
+			return nil, fmt.Errorf("failed to create the event broker: %w", err) // Return the error
+		}
+		app.Broker = broker   // Set the broker
+		app.ownsBroker = true // Close the broker on shutdown
+	}
+	app.Webhooks = webhooks.NewDispatcher(app.Repos, cfg.Webhooks, app.Metrics, app.Logger)                                              // Create the webhook dispatcher
+	auditLog := audit.NewLog(app.Repos.Audit, app.Repos.Outbox)                                                                          // Create the audit log, recording its entries in the outbox
+	consumers := events.Fanout(events.Public(app.Broker), events.Public(app.Webhooks), notify.NewOutboxNotifier(app.Notifier), auditLog) // Publish the public events, queue the webhook deliveries, deliver the notifications and append the audit entries
+	relay := events.NewRelay(app.Repos.Outbox, consumers, cfg.Events, app.Metrics, app.Logger)                                           // Create the outbox relay
+	app.Schedule("outbox relay", cfg.Events.PollInterval, relay.PublishPending)                                                          // Publish the domain events in the background once running
+	app.Schedule("webhook deliveries", cfg.Webhooks.PollInterval, app.Webhooks.DeliverDue)                                               // Deliver the domain events to the webhooks in the background once running
+	app.Schedule("deletion purge", cfg.Deletion.PurgeInterval, app.purgeDeleted)                                                         // Remove the expired soft deleted documents in the background once running
+
+	if err := dto.RegisterValidators(cfg.TrainingTypes, cfg.Webhooks.AllowPrivateNetworks); err != nil { // Register the custom rules of the request bodies
+		return nil, fmt.Errorf("failed to register the request validators: %w", err) // Return the error
+	}
+	app.Controller = controllers.New(cfg, app.Repos, app.Metrics) // Create the controller
+
+	app.Probes = health.New(cfg.Server.ReadinessTimeout) // Create the probes
+	if app.Database != nil {                             // Check if the database must be reachable
+		app.Probes.AddCheck("mongo", app.pingDatabase) // Ping MongoDB on readiness
+	}
+	if app.Migrator != nil { // Check if the database must be migrated
+		app.Probes.AddCheck("migrations", app.checkMigrations) // Wait for the migrations on readiness
+	}
+	app.Probes.AddCheck("workers", app.checkWorkers) // Check the background workers on readiness
+
+	if app.Router == nil { // Check if the router must be created
+		app.Router = gin.New() // Create a new Gin router
+	}
+	app.Router.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(isNotProbe)))                        // Trace the requests, except the probes
+	app.Router.Use(middleware.RequestID())                                                                             // Tag the requests and their logs with an ID
+	app.Router.Use(middleware.AccessLog(app.Logger, routes.ProbePaths))                                                // Log the requests, except the probes
+	app.Router.Use(app.Metrics.Middleware())                                                                           // Record the count and latency of the requests
+	app.Router.Use(middleware.Idempotency(app.Repos.Idempotency, cfg.Idempotency, app.Logger, routes.CredentialPaths)) // Replay the responses of the retried requests, except the ones carrying credentials
+	app.Router.Use(middleware.Audit(auditLog, app.Repos.Transactions, app.Logger))                                     // Record the state-changing requests in the audit log, except the replays
+	app.Router.Use(middleware.Recovery(app.Logger))                                                                    // Recover from panics
+	app.Router.Use(middleware.Problems(app.Logger))                                                                    // Render the errors of the handlers as problem responses
+	app.Router.NoRoute(middleware.RouteNotFound(app.Logger))                                                           // Answer unknown routes with a problem response
+	routes.SetupProbeRoutes(app.Router, app.Probes, app.Metrics.Handler())                                             // Set up the probe routes before the CORS and rate limit middlewares
+	if len(cfg.CORS.AllowedOrigins) > 0 {                                                                              // Check if cross-origin requests are allowed
+		app.Router.Use(middleware.CORS(cfg.CORS)) // Answer cross-origin requests
+	}
+	if cfg.RateLimit.Enabled { // Check if the requests must be rate limited
+		app.Router.Use(middleware.RateLimit(cfg.RateLimit)) // Limit the request rate of each client
+	}
+	routes.SetupRoutes(app.Router, app.Controller, middleware.AuthMiddleware(cfg.JwtSecretKey), middleware.OptionalAuth(cfg.JwtSecretKey)) // Set up the routes
+
+	return app, nil // Return the application
+}
+
+// isNotProbe reports whether the request is not a probe, probes are not traced
+func isNotProbe(r *http.Request) bool { // Filter out the probes
+	return !slices.Contains(routes.ProbePaths, r.URL.Path) // Check the path
+}
+
+// pingDatabase: Readiness check reporting whether MongoDB answers
+func (app *App) pingDatabase(ctx context.Context) error { // Ping MongoDB
+	return app.Database.Client().Ping(ctx, readpref.Primary()) // Ping the primary
+}
+
+// purgeDeleted removes the sessions, users, invitations and pitch bookings soft deleted for longer than the
+// retention, they can no longer be restored
+func (app *App) purgeDeleted(ctx context.Context) error { // Purge the expired soft deleted documents
+	before := time.Now().Add(-app.Config.Deletion.Retention) // Get the end of the retention
+	deleters := []struct {
+		kind    string                 // Kind of the documents
+		deleter repository.SoftDeleter // Repository of the documents
+	}{
+		{"sessions", app.Repos.Sessions},       // Training sessions
+		{"users", app.Repos.Users},             // Users
+		{"invitations", app.Repos.Invitations}, // Session invitations
+		{"pitch bookings", app.Repos.Pitches},  // Pitch bookings
+	}
+
+	var errs []error             // Define a slice to hold the errors
+	for _, d := range deleters { // Iterate over the repositories
+		purged, err := d.deleter.Purge(ctx, before) // Remove the expired documents
+		if err != nil {                             // Check if there is an error
+			errs = append(errs, fmt.Errorf("failed to purge the deleted %s: %w", d.kind, err)) // Add the error
+			continue                                                                           // Purge the other kinds
+		}
+		if purged > 0 { // Check if documents were removed
+			app.Logger.InfoContext(ctx, "Purged deleted documents", slog.String("kind", d.kind), slog.Int64("count", purged)) // Log the purge
+		}
+	}
+	return errors.Join(errs...) // Return the errors met
+}
+
+// migrate applies the pending migrations of the database, waiting for the other instances doing the same
+func (app *App) migrate() error { // Migrate the database
+	ctx, cancel := context.WithTimeout(context.Background(), app.Config.Migrations.Timeout) // Bound the migrations
+	defer cancel()                                                                          // Release the context
+	applied, err := app.Migrator.Up(ctx)                                                    // Apply the pending migrations
+	if err != nil {                                                                         // Check if there is an error
+		return fmt.Errorf("failed to migrate the database after %d migrations: %w", applied, err) // Return the error
+	}
+	app.Logger.Info("Database migrated", slog.Int("applied", applied)) // Log a success message
+	return nil                                                         // Return nil
+}
+
+// checkMigrations: Readiness check reporting the migrations not applied yet, when they are left to the migrate command
+func (app *App) checkMigrations(ctx context.Context) error { // Check the migrations
+	if app.migrated.Load() { // Check if the migrations were already found applied
+		return nil // The database is migrated
+	}
+	pending, err := app.Migrator.Pending(ctx) // List the pending migrations
+	if err != nil {                           // Check if there is an error
+		return err // Return the error
+	}
+	if len(pending) > 0 { // Check if migrations are pending
+		versions := make([]string, len(pending)) // Define a slice to hold the versions
+		for i, migration := range pending {      // Iterate over the pending migrations
+			versions[i] = fmt.Sprint(migration.Version) // Add the version
+		}
+		return fmt.Errorf("pending migrations: %s", strings.Join(versions, ", ")) // Report the pending migrations
+	}
+	app.migrated.Store(true) // Skip the query from now on
+	return nil               // The database is migrated
+}
+
+// checkWorkers: Readiness check reporting the background workers that stopped before shutdown
+func (app *App) checkWorkers(ctx context.Context) error { // Check the background workers
+	app.workersMu.Lock()            // Lock the stopped workers
+	defer app.workersMu.Unlock()    // Unlock the stopped workers
+	if len(app.failedWorkers) > 0 { // Check if a worker stopped
+		return fmt.Errorf("background workers stopped: %s", strings.Join(app.failedWorkers, ", ")) // Report the stopped workers
+	}
+	return nil // Every worker is running
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Go adds a background worker, started by Run or right away once Run started the others. The worker
+// must return once its context is cancelled; Run waits for every worker to return, within the shutdown
+// timeout, before disconnecting the database.
+func (app *App) Go(name string, worker func(ctx context.Context)) { // Add a background worker
+	start := func() { // Start the worker
+		app.workers.Add(1)             // Track the worker
+		go app.runWorker(name, worker) // Run the worker in the background
+	}
+
+	app.workersMu.Lock()         // Lock the workers
+	defer app.workersMu.Unlock() // Unlock the workers
+	if app.workersStarted {      // Check if Run already started the workers
+		start() // Start the worker now
+		return  // Return from the function
+	}
+	app.pendingWorkers = append(app.pendingWorkers, start) // Start the worker with the others
+}
+
+// startWorkers starts the background workers added so far, the ones added later start right away
+func (app *App) startWorkers() { // Start the background workers
+	app.workersMu.Lock()                       // Lock the workers
+	defer app.workersMu.Unlock()               // Unlock the workers
+	app.workersStarted = true                  // Start the next workers right away
+	for _, start := range app.pendingWorkers { // Iterate over the pending workers
+		start() // Start the worker
+	}
+	app.pendingWorkers = nil // Forget the started workers
+}
+
+// runWorker runs a background worker, reporting it as not ready if it returns before shutdown
+func (app *App) runWorker(name string, worker func(ctx context.Context)) { // Run a background worker
+	defer app.workers.Done()                                                  // Untrack the worker when it returns
+	worker(app.workerCtx)                                                     // Run the worker
+	app.Logger.Info("Background worker stopped", slog.String("worker", name)) // Log the end of the worker
+	if app.workerCtx.Err() == nil {                                           // Check if the worker returned before shutdown
+		app.workersMu.Lock()                                // Lock the stopped workers
+		app.failedWorkers = append(app.failedWorkers, name) // Report the worker as not ready
+		app.workersMu.Unlock()                              // Unlock the stopped workers
+	}
+}
+
+// Schedule runs a job every interval as a background worker until shutdown. Each run gets its own
+// span, and a failed run is logged and retried at the next tick.
+func (app *App) Schedule(name string, interval time.Duration, job func(ctx context.Context) error) { // Schedule a job
+	tracer := otel.Tracer("training_session/pkg/app") // Get the tracer of the jobs
+	app.Go(name, func(ctx context.Context) {          // Run the job in a background worker
+		ticker := time.NewTicker(interval) // Tick every interval
+		defer ticker.Stop()                // Stop the ticker when the worker returns
+		for {                              // Run the job until shutdown
+			select { // Wait for the next tick or the shutdown
+			case <-ctx.Done(): // The shutdown was requested
+				return // Stop the worker
+			case <-ticker.C: // The job is due
+			}
+
+			runCtx, span := tracer.Start(ctx, "job "+name)         // Trace the run
+			span.SetAttributes(attribute.String("job.name", name)) // Name the job
+			if err := job(runCtx); err != nil {                    // Run the job
+				span.RecordError(err)                                                                           // Record the error
+				span.SetStatus(codes.Error, err.Error())                                                        // Mark the run as failed
+				app.Logger.ErrorContext(runCtx, "Job failed", slog.String("job", name), slog.Any("error", err)) // Log the error
+			}
+			span.End() // End the run
+		}
+	})
+}
+
+// Run starts the background workers and serves the API on the configured port with the configured
+// timeouts until the context is cancelled, then shuts down gracefully: the listener is closed, in-flight
+// requests and background workers are drained within the shutdown timeout and the database is disconnected.
+func (app *App) Run(ctx context.Context) error { // Run the server
+	app.startWorkers() // Start the background workers
+
+	server := &http.Server{ // Define the HTTP server
+		Addr:              fmt.Sprintf(":%d", app.Config.ServerPort), // Set the address
+		Handler:           app.Router,                                // Serve the routes
+		ReadTimeout:       app.Config.Server.ReadTimeout,             // Set the read timeout
+		ReadHeaderTimeout: app.Config.Server.ReadHeaderTimeout,       // Set the read header timeout
+		WriteTimeout:      app.Config.Server.WriteTimeout,            // Set the write timeout
+		IdleTimeout:       app.Config.Server.IdleTimeout,             // Set the idle timeout
+	}
+
+	serveErr := make(chan error, 1) // Define a channel to receive the error of the server
+	go func() {                     // Serve in the background so the context can be watched
+		app.Logger.Info("Starting server", slog.Int("port", app.Config.ServerPort)) // Log the server port
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {  // Serve until the server is shut down
+			serveErr <- err // Report the failure of the server
+		}
+		close(serveErr) // The server stopped
+	}()
+
+	var errs []error // Define a slice to hold the errors met while stopping
+	select {         // Wait for the server to fail or for the shutdown signal
+	case err := <-serveErr: // The server failed to start or stopped unexpectedly
+		errs = append(errs, err) // Report the failure
+	case <-ctx.Done(): // The shutdown was requested
+		app.Logger.Info("Shutting down server") // Log the shutdown
+	}
+	app.Probes.SetDraining() // Report the server as not ready while it drains
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.Config.Server.ShutdownTimeout) // Bound the shutdown
+	defer cancel()                                                                                      // Release the timer
+
+	if err := server.Shutdown(shutdownCtx); err != nil { // Stop accepting requests and drain the in-flight ones
+		errs = append(errs, fmt.Errorf("failed to drain in-flight requests: %w", err)) // Report the failure
+	}
+	if err := app.Close(shutdownCtx); err != nil { // Stop the workers and release the dependencies
+		errs = append(errs, err) // Report the failure
+	}
+
+	return errors.Join(errs...) // Return the errors met
+}
+
+// Close stops the background workers, waiting for them until the context is done, then disconnects
+// the database if the application connected it. Close also releases an application that never ran.
+func (app *App) Close(ctx context.Context) error { // Release the resources of the application
+	var errs []error // Define a slice to hold the errors met
+
+	app.stopWorkers()              // Ask the background workers to return
+	drained := make(chan struct{}) // Define a channel closed once every worker returned
+	go func() {                    // Wait for the workers in the background so the deadline can be enforced
+		app.workers.Wait() // Wait for the workers
+		close(drained)     // Report that the workers returned
+	}()
+	select { // Wait for the workers or the deadline
+	case <-drained: // Every worker returned
+	case <-ctx.Done(): // The deadline expired
+		errs = append(errs, fmt.Errorf("background workers did not stop in time: %w", ctx.Err())) // Report the failure
+	}
+
+	if app.ownsBroker { // Check if the broker must be closed
+		if err := app.Broker.Close(); err != nil { // Flush the publications and close the connection
+			errs = append(errs, fmt.Errorf("failed to close the event broker: %w", err)) // Report the failure
+		}
+	}
+
+	if app.ownsDatabase { // Check if the database must be disconnected
+		if err := app.Database.Client().Disconnect(ctx); err != nil { // Disconnect from MongoDB
+			errs = append(errs, fmt.Errorf("failed to disconnect from MongoDB: %w", err)) // Report the failure
+		} else {
+			app.Logger.Info("Disconnected from MongoDB") // Log the disconnection
+		}
+	}
+
+	if app.tracerProvider != nil { // Check if spans must be flushed
+		if err := app.tracerProvider.Shutdown(ctx); err != nil { // Export the remaining spans
+			errs = append(errs, fmt.Errorf("failed to flush the spans: %w", err)) // Report the failure
+		}
+	}
+
+	return errors.Join(errs...) // Return the errors met
+}
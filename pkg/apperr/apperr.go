@@ -0,0 +1,126 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error, it decides the HTTP status of the response
+type Kind int
+
+const (
+	KindInternal             Kind = iota // Unexpected failure, its details are never shown to the client
+	KindValidation                       // The request is malformed or breaks a validation rule
+	KindUnauthorized                     // The client is not authenticated
+	KindForbidden                        // The client may not perform the action
+	KindNotFound                         // The resource does not exist
+	KindConflict                         // The request conflicts with the current state of the resource
+	KindUnprocessable                    // The request is well formed but cannot be processed, e.g. a reused idempotency key
+	KindPreconditionFailed               // The resource changed since the client read it
+	KindPreconditionRequired             // The request must be conditional, e.g. an update without If-Match
+	KindUnsupportedMediaType             // The body is not in a format the route accepts
+	KindRateLimited                      // The client sent too many requests
+)
+
+// FieldError describes why a field of the request is invalid
+type FieldError struct {
+	Field   string `json:"field"`   // Name of the field, as sent by the client
+	Message string `json:"message"` // Why the field is invalid
+}
+
+// Error is a domain error: its kind, code and message are shown to the client, the wrapped cause is only logged
+type Error struct {
+	Kind    Kind         // Kind of the error
+	Code    string       // Stable machine-readable code, e.g. "session_not_found"
+	Message string       // Human-readable explanation
+	Fields  []FieldError // Invalid fields of a validation error
+	Err     error        // Underlying cause, never shown to the client
+}
+
+// Error returns the code, message and cause of the error
+func (e *Error) Error() string { // Describe the error
+	if e.Err != nil { // Check if the error has a cause
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err) // Return the error with its cause
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message) // Return the error
+}
+
+// Unwrap returns the cause of the error
+func (e *Error) Unwrap() error { // Get the cause
+	return e.Err // Return the cause
+}
+
+// Wrap records the cause of the error and returns it
+func (e *Error) Wrap(err error) *Error { // Attach a cause
+	e.Err = err // Set the cause
+	return e    // Return the error
+}
+
+// Field builds the description of an invalid field
+func Field(field, message string) FieldError { // Describe an invalid field
+	return FieldError{Field: field, Message: message} // Return the field error
+}
+
+// Validation reports a malformed request or a broken validation rule, with the invalid fields if any
+func Validation(code, message string, fields ...FieldError) *Error { // Create a validation error
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields} // Return the error
+}
+
+// Unauthorized reports a request that is not authenticated
+func Unauthorized(code, message string) *Error { // Create an unauthorized error
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message} // Return the error
+}
+
+// Forbidden reports an action the client may not perform, with the fields they may not write if any
+func Forbidden(code, message string, fields ...FieldError) *Error { // Create a forbidden error
+	return &Error{Kind: KindForbidden, Code: code, Message: message, Fields: fields} // Return the error
+}
+
+// NotFound reports a missing resource
+func NotFound(code, message string) *Error { // Create a not found error
+	return &Error{Kind: KindNotFound, Code: code, Message: message} // Return the error
+}
+
+// Conflict reports a request conflicting with the current state of a resource
+func Conflict(code, message string) *Error { // Create a conflict error
+	return &Error{Kind: KindConflict, Code: code, Message: message} // Return the error
+}
+
+// Unprocessable reports a well formed request that cannot be processed
+func Unprocessable(code, message string) *Error { // Create an unprocessable error
+	return &Error{Kind: KindUnprocessable, Code: code, Message: message} // Return the error
+}
+
+// PreconditionFailed reports a conditional request whose precondition does not hold, e.g. a stale ETag
+func PreconditionFailed(code, message string) *Error { // Create a precondition failed error
+	return &Error{Kind: KindPreconditionFailed, Code: code, Message: message} // Return the error
+}
+
+// PreconditionRequired reports a request that must be conditional but is not
+func PreconditionRequired(code, message string) *Error { // Create a precondition required error
+	return &Error{Kind: KindPreconditionRequired, Code: code, Message: message} // Return the error
+}
+
+// UnsupportedMediaType reports a body in a format the route does not accept
+func UnsupportedMediaType(code, message string) *Error { // Create an unsupported media type error
+	return &Error{Kind: KindUnsupportedMediaType, Code: code, Message: message} // Return the error
+}
+
+// RateLimited reports a client that sent too many requests
+func RateLimited(code, message string) *Error { // Create a rate limit error
+	return &Error{Kind: KindRateLimited, Code: code, Message: message} // Return the error
+}
+
+// Internal reports an unexpected failure, the cause is logged but never shown to the client
+func Internal(err error) *Error { // Create an internal error
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "An internal error occurred", Err: err} // Return the error
+}
+
+// As returns the domain error found in the chain of err, or nil when there is none
+func As(err error) *Error { // Find the domain error
+	var e *Error            // Define the domain error
+	if errors.As(err, &e) { // Check if the chain holds a domain error
+		return e // Return the domain error
+	}
+	return nil // No domain error
+}
@@ -0,0 +1,249 @@
+// Package audit keeps the audit log of the state-changing requests as a hash chain: every entry holds the
+// hash of the previous one, so an entry altered or removed in the database is found by Verify. Entries are
+// recorded in the outbox with the changes of their request and appended to the chain when the relay publishes them.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ContextKey is the key of the request context value the handlers describe their change with, a Change
+const ContextKey = "audit"
+
+// maxAppendAttempts bounds the retries of an append racing with other instances for the same position
+const maxAppendAttempts = 10
+
+// verifyBatchSize is the number of entries read at once when verifying the chain
+const verifyBatchSize = 500
+
+// Change describes the document changed by a request and its state before and after the change. The states are
+// compared field by field as JSON, so the fields hidden from JSON, like the passwords, are never recorded. The
+// personal fields are recorded by name only: the chain cannot be edited, an erasure could not remove their values.
+type Change struct {
+	TargetType string      // Kind of the document (e.g. "sessions", "users")
+	TargetID   string      // Document changed
+	Before     interface{} // State before the request, nil when the document was created
+	After      interface{} // State after the request, nil when it was removed
+	Personal   []string    // JSON names of the fields holding personal data
+}
+
+// Diff returns the fields that differ between the states of the change, the personal ones without their values
+func (c Change) Diff() (map[string]models.FieldChange, error) { // Compare the states of the change
+	changes, err := Diff(c.Before, c.After) // Compare the states
+	for _, name := range c.Personal {       // Iterate over the personal fields
+		if _, ok := changes[name]; ok { // Check if the field changed
+			changes[name] = models.FieldChange{Redacted: true} // Keep the name only
+		}
+	}
+	return changes, err // Return the changed fields
+}
+
+// Verification is the result of a check of the chain
+type Verification struct {
+	Valid    bool   `json:"valid"`               // Whether every entry is intact and linked to the previous one
+	Entries  int64  `json:"entries"`             // Number of entries checked
+	BrokenAt int64  `json:"broken_at,omitempty"` // Sequence of the first entry failing the check
+	Reason   string `json:"reason,omitempty"`    // Why the entry fails the check
+}
+
+// chainID is the aggregate of the AuditRecorded events: every entry belongs to the one chain, so the relay
+// publishes them in the order they were recorded and holds the later ones back while one fails
+var chainID = primitive.NilObjectID
+
+// Log records the entries of the audit log, appends them to its chain and verifies the chain. As a broker of the
+// outbox relay it appends the entries of the AuditRecorded events.
+type Log struct {
+	entries repository.AuditRepository  // Entries of the log
+	outbox  repository.OutboxRepository // Outbox the entries are recorded in
+}
+
+// NewLog creates a log storing its entries in the repository and recording them in the outbox
+func NewLog(entries repository.AuditRepository, outbox repository.OutboxRepository) *Log { // Create a log
+	return &Log{entries: entries, outbox: outbox} // Return the log
+}
+
+// Record writes an entry to the outbox. Called in the transaction of the request it describes, the entry is
+// appended to the chain if and only if the changes of the request are committed.
+func (l *Log) Record(ctx context.Context, entry models.AuditEntry) error { // Record an entry
+	data, err := json.Marshal(entry) // Encode the entry
+	if err != nil {                  // Check if there is an error
+		return fmt.Errorf("failed to encode the audit entry: %w", err) // Return the error
+	}
+	event := models.Event{
+		ID:            primitive.NewObjectID(),   // Generate a new ObjectID for the event, the ID of the entry
+		Type:          models.EventAuditRecorded, // Set the type of the event
+		AggregateType: models.AggregateAudit,     // Set the type of the aggregate
+		AggregateID:   chainID,                   // Append the entries in order
+		Data:          data,                      // Set the entry
+		OccurredAt:    entry.OccurredAt,          // Set the occurred_at timestamp
+	}
+	if err := l.outbox.Insert(ctx, &event); err != nil { // Append the event to the outbox
+		return fmt.Errorf("failed to record the audit entry: %w", err) // Return the error
+	}
+	return nil // Return nil
+}
+
+// Publish appends the entry of an AuditRecorded event to the chain, ignoring the other events. An entry already
+// appended by an earlier publication of the event is not appended again.
+func (l *Log) Publish(ctx context.Context, event models.Event) error { // Append a recorded entry
+	if event.Type != models.EventAuditRecorded { // Check if the event records no entry
+		return nil // Nothing to append
+	}
+	var entry models.AuditEntry                                // Define the entry
+	if err := json.Unmarshal(event.Data, &entry); err != nil { // Decode the entry
+		return fmt.Errorf("invalid audit entry in event %s: %w", event.ID.Hex(), err) // Return the error
+	}
+	entry.ID = event.ID            // Identify the entry by its event
+	_, err := l.Append(ctx, entry) // Append the entry
+	return err                     // Return the error
+}
+
+// Close releases nothing, the log has no connection of its own
+func (l *Log) Close() error { // Close the log
+	return nil // Return nil
+}
+
+// Append links an entry to the last one and stores it, retrying when another entry took the position first. An
+// entry whose ID is already in the chain is returned as stored.
+func (l *Log) Append(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) { // Append an entry
+	entry.OccurredAt = entry.OccurredAt.UTC().Truncate(time.Millisecond) // Keep the precision of the database, the hash must survive a round trip
+	if len(entry.Changes) == 0 {                                         // Check if no field changed
+		entry.Changes = nil // Store no changes
+	}
+
+	for attempt := 1; ; attempt++ { // Try until the entry takes a free position
+		last, err := l.entries.Last(ctx)            // Find the end of the chain
+		if errors.Is(err, repository.ErrNotFound) { // Check if the log is empty
+			last = models.AuditEntry{} // Start the chain
+		} else if err != nil { // Check if there is an error
+			return entry, fmt.Errorf("failed to find the last audit entry: %w", err) // Return the error
+		}
+
+		entry.Sequence = last.Sequence + 1 // Take the next position
+		entry.PrevHash = last.Hash         // Link to the last entry
+		entry.Hash = Hash(entry)           // Seal the entry
+
+		err = l.entries.Append(ctx, &entry)                               // Store the entry
+		if errors.Is(err, repository.ErrConflict) && !entry.ID.IsZero() { // Check if the ID may be taken by the entry itself
+			if stored, err := l.entries.FindByID(ctx, entry.ID); err == nil { // Find the entry appended earlier
+				return stored, nil // Return the entry as stored
+			}
+		}
+		if !errors.Is(err, repository.ErrConflict) || attempt == maxAppendAttempts { // Check if the position was free or the retries are over
+			return entry, err // Return the entry
+		}
+	}
+}
+
+// Verify checks every entry of the chain in order: the sequences follow each other, each entry links to the
+// hash of the previous one and its own hash matches its content
+func (l *Log) Verify(ctx context.Context) (Verification, error) { // Verify the chain
+	var result Verification        // Define the result
+	var previous models.AuditEntry // Define the last entry checked, none at the start
+	for {                          // Read the chain batch by batch
+		entries, err := l.entries.FindRange(ctx, previous.Sequence, verifyBatchSize) // Find the next entries
+		if err != nil {                                                              // Check if there is an error
+			return result, fmt.Errorf("failed to read the audit log: %w", err) // Return the error
+		}
+		for _, entry := range entries { // Iterate over the entries
+			result.Entries++ // Count the entry
+			switch {         // Check the entry
+			case entry.Sequence != previous.Sequence+1: // A position is missing
+				return broken(result, previous.Sequence+1, "entry is missing"), nil // Return the broken chain
+			case entry.PrevHash != previous.Hash: // The link does not match
+				return broken(result, entry.Sequence, "previous hash does not match"), nil // Return the broken chain
+			case entry.Hash != Hash(entry): // The content was changed
+				return broken(result, entry.Sequence, "hash does not match the content"), nil // Return the broken chain
+			}
+			previous = entry // Move to the entry
+		}
+		if len(entries) < verifyBatchSize { // Check if the end of the chain is reached
+			result.Valid = true // Every entry is intact
+			return result, nil  // Return the result
+		}
+	}
+}
+
+// broken returns the result of a check failing at the given sequence
+func broken(result Verification, sequence int64, reason string) Verification { // Describe a broken chain
+	result.BrokenAt, result.Reason = sequence, reason // Set where and why the chain breaks
+	return result                                     // Return the result
+}
+
+// Hash returns the hex SHA-256 of the content of an entry and of the hash of the previous entry
+func Hash(entry models.AuditEntry) string { // Hash an entry
+	actorID := ""             // Define the actor, empty when anonymous
+	if entry.ActorID != nil { // Check if the actor is known
+		actorID = entry.ActorID.Hex() // Set the actor
+	}
+	content, err := json.Marshal(struct { // Encode the content in a fixed order
+		Sequence   int64                         `json:"sequence"`
+		ActorID    string                        `json:"actor_id"`
+		Action     string                        `json:"action"`
+		TargetType string                        `json:"target_type"`
+		TargetID   string                        `json:"target_id"`
+		Changes    map[string]models.FieldChange `json:"changes"`
+		Status     int                           `json:"status"`
+		IP         string                        `json:"ip"`
+		RequestID  string                        `json:"request_id"`
+		OccurredAt int64                         `json:"occurred_at"`
+		PrevHash   string                        `json:"prev_hash"`
+	}{entry.Sequence, actorID, entry.Action, entry.TargetType, entry.TargetID, entry.Changes, entry.Status, entry.IP, entry.RequestID, entry.OccurredAt.UnixMilli(), entry.PrevHash})
+	if err != nil { // Check if there is an error
+		panic(fmt.Sprintf("audit: cannot encode entry: %v", err)) // Entries are always encodable
+	}
+	sum := sha256.Sum256(content)     // Hash the content
+	return hex.EncodeToString(sum[:]) // Return the hash
+}
+
+// Diff returns the top-level JSON fields that differ between two states of a document, either of them nil
+func Diff(before, after interface{}) (map[string]models.FieldChange, error) { // Compare two states
+	beforeFields, err := fields(before) // Split the state before
+	if err != nil {                     // Check if there is an error
+		return nil, err // Return the error
+	}
+	afterFields, err := fields(after) // Split the state after
+	if err != nil {                   // Check if there is an error
+		return nil, err // Return the error
+	}
+
+	changes := map[string]models.FieldChange{} // Define the changed fields
+	for name, value := range beforeFields {    // Iterate over the fields before
+		if !bytes.Equal(value, afterFields[name]) { // Check if the field changed or was removed
+			changes[name] = models.FieldChange{Before: value, After: afterFields[name]} // Record the change
+		}
+	}
+	for name, value := range afterFields { // Iterate over the fields after
+		if _, ok := beforeFields[name]; !ok { // Check if the field was added
+			changes[name] = models.FieldChange{After: value} // Record the change
+		}
+	}
+	return changes, nil // Return the changed fields
+}
+
+// fields splits the JSON object of a state into its compacted fields, none for nil
+func fields(state interface{}) (map[string]json.RawMessage, error) { // Split a state
+	result := map[string]json.RawMessage{} // Define the fields
+	if state == nil {                      // Check if there is no state
+		return result, nil // Return no fields
+	}
+	data, err := json.Marshal(state) // Encode the state
+	if err != nil {                  // Check if there is an error
+		return nil, fmt.Errorf("failed to encode the audited state: %w", err) // Return the error
+	}
+	if err := json.Unmarshal(data, &result); err != nil { // Split the fields
+		return nil, fmt.Errorf("audited state is not an object: %w", err) // Return the error
+	}
+	return result, nil // Return the fields
+}
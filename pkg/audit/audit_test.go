@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository/memory"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// appendEntries appends n entries to the log and returns them as stored
+func appendEntries(t *testing.T, log *Log, n int) []models.AuditEntry {
+	t.Helper()
+	entries := make([]models.AuditEntry, n)
+	for i := range entries {
+		entry, err := log.Append(context.Background(), models.AuditEntry{Action: "POST /sessions/create", TargetType: "sessions", Status: http.StatusCreated, IP: "192.0.2.1", OccurredAt: time.Now()})
+		if err != nil {
+			t.Fatalf("append entry %d: %v", i+1, err)
+		}
+		entries[i] = entry
+	}
+	return entries
+}
+
+func TestAppendChainsEntries(t *testing.T) {
+	repos := memory.NewRepositories()
+	log := NewLog(repos.Audit, repos.Outbox)
+	entries := appendEntries(t, log, 3)
+
+	previous := ""
+	for i, entry := range entries {
+		if entry.Sequence != int64(i+1) {
+			t.Errorf("entry %d has sequence %d", i+1, entry.Sequence)
+		}
+		if entry.PrevHash != previous {
+			t.Errorf("entry %d links to %q, want %q", i+1, entry.PrevHash, previous)
+		}
+		if entry.Hash == "" || entry.Hash != Hash(entry) {
+			t.Errorf("entry %d has hash %q, want %q", i+1, entry.Hash, Hash(entry))
+		}
+		previous = entry.Hash
+	}
+
+	result, err := log.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !result.Valid || result.Entries != 3 {
+		t.Errorf("Verify() = %+v, want 3 valid entries", result)
+	}
+}
+
+func TestHashCoversTheContent(t *testing.T) {
+	actorID := primitive.NewObjectID()
+	entry := models.AuditEntry{Sequence: 1, ActorID: &actorID, Action: "DELETE /users/delete/:userId", TargetType: "users", Status: http.StatusOK, OccurredAt: time.UnixMilli(1700000000000)}
+	tests := []struct {
+		name   string
+		change func(e *models.AuditEntry)
+	}{
+		{name: "sequence", change: func(e *models.AuditEntry) { e.Sequence = 2 }},
+		{name: "actor", change: func(e *models.AuditEntry) { e.ActorID = nil }},
+		{name: "action", change: func(e *models.AuditEntry) { e.Action = "PUT /users/update/:userId" }},
+		{name: "status", change: func(e *models.AuditEntry) { e.Status = http.StatusForbidden }},
+		{name: "changes", change: func(e *models.AuditEntry) {
+			e.Changes = map[string]models.FieldChange{"role": {Redacted: true}}
+		}},
+		{name: "time", change: func(e *models.AuditEntry) { e.OccurredAt = e.OccurredAt.Add(time.Millisecond) }},
+		{name: "previous hash", change: func(e *models.AuditEntry) { e.PrevHash = "00" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := entry
+			tt.change(&changed)
+			if Hash(changed) == Hash(entry) {
+				t.Errorf("changing the %s keeps the hash", tt.name)
+			}
+		})
+	}
+}
+
+// tamperedRepository serves a stored chain the test edits, as an attacker with access to the database could
+type tamperedRepository struct {
+	*memory.AuditRepository                     // Repository the chain was appended to
+	entries                 []models.AuditEntry // Chain served by FindRange
+}
+
+// FindRange serves the edited chain
+func (r *tamperedRepository) FindRange(ctx context.Context, after int64, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	for _, entry := range r.entries {
+		if entry.Sequence > after && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func TestVerifyFindsTampering(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(entries []models.AuditEntry) []models.AuditEntry
+		brokenAt int64
+		reason   string
+	}{
+		{name: "intact chain", tamper: func(entries []models.AuditEntry) []models.AuditEntry { return entries }},
+		{
+			name: "changed content",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				entries[1].Status = http.StatusForbidden
+				return entries
+			},
+			brokenAt: 2, reason: "hash does not match the content",
+		},
+		{
+			name: "removed entry",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				return append(entries[:1], entries[2:]...)
+			},
+			brokenAt: 2, reason: "entry is missing",
+		},
+		{
+			name: "resealed entry",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				entries[1].Status = http.StatusForbidden
+				entries[1].Hash = Hash(entries[1])
+				return entries
+			},
+			brokenAt: 3, reason: "previous hash does not match",
+		},
+		{
+			name: "rewritten first entry",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				entries[0].PrevHash = "forged"
+				entries[0].Hash = Hash(entries[0])
+				return entries
+			},
+			brokenAt: 1, reason: "previous hash does not match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := memory.NewRepositories()
+			entries := appendEntries(t, NewLog(repos.Audit, repos.Outbox), 3)
+			repo := &tamperedRepository{AuditRepository: repos.Audit.(*memory.AuditRepository), entries: tt.tamper(entries)}
+
+			result, err := NewLog(repo, repos.Outbox).Verify(context.Background())
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if result.Valid != (tt.brokenAt == 0) || result.BrokenAt != tt.brokenAt || result.Reason != tt.reason {
+				t.Errorf("Verify() = %+v, want broken at %d (%q)", result, tt.brokenAt, tt.reason)
+			}
+		})
+	}
+}
+
+func TestRecordedEntryIsAppendedOnce(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.NewRepositories()
+	log := NewLog(repos.Audit, repos.Outbox)
+	actorID := primitive.NewObjectID()
+	recorded := models.AuditEntry{ActorID: &actorID, Action: "POST /sessions/create", TargetType: "sessions", TargetID: "abc", Status: http.StatusCreated, IP: "192.0.2.1", RequestID: "req-1", OccurredAt: time.Now()}
+	if err := log.Record(ctx, recorded); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if entries, _ := repos.Audit.FindRange(ctx, 0, 10); len(entries) != 0 {
+		t.Fatalf("recorded entry is in the chain before it is published: %+v", entries)
+	}
+
+	events, err := repos.Outbox.FindPending(ctx, 10)
+	if err != nil || len(events) != 1 || events[0].Type != models.EventAuditRecorded {
+		t.Fatalf("outbox holds %+v (%v), want one AuditRecorded event", events, err)
+	}
+	for i := 0; i < 2; i++ { // The relay may publish an event twice
+		if err := log.Publish(ctx, events[0]); err != nil {
+			t.Fatalf("publish %d: %v", i+1, err)
+		}
+	}
+
+	entries, err := repos.Audit.FindRange(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("find entries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("chain holds %d entries, want 1", len(entries))
+	}
+	got := entries[0]
+	if got.ID != events[0].ID || got.Sequence != 1 || *got.ActorID != actorID || got.Action != recorded.Action || got.Status != recorded.Status || got.RequestID != recorded.RequestID {
+		t.Errorf("appended %+v, want the recorded entry %+v", got, recorded)
+	}
+	if result, err := log.Verify(ctx); err != nil || !result.Valid {
+		t.Errorf("Verify() = %+v, %v; want a valid chain", result, err)
+	}
+}
+
+func TestChangeDiffRedactsPersonalFields(t *testing.T) {
+	before := map[string]any{"name": "Ann", "cin": "AB123", "role": "user"}
+	after := map[string]any{"name": "Anna", "cin": "CD456", "role": "coach"}
+	changes, err := Change{Before: before, After: after, Personal: []string{"name", "cin", "email"}}.Diff()
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("Diff() = %v, want 3 fields", changes)
+	}
+	for _, name := range []string{"name", "cin"} {
+		if change := changes[name]; !change.Redacted || change.Before != nil || change.After != nil {
+			t.Errorf("%s = %+v, want redacted without values", name, change)
+		}
+	}
+	if change := changes["role"]; change.Redacted || string(change.Before) != `"user"` || string(change.After) != `"coach"` {
+		t.Errorf("role = %+v, want its values", change)
+	}
+	if _, ok := changes["email"]; ok {
+		t.Error("unchanged personal field email is listed")
+	}
+}
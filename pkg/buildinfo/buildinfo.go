@@ -0,0 +1,51 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with:
+//
+//	go build -ldflags "-X training_session/pkg/buildinfo.Version=v1.2.0 -X training_session/pkg/buildinfo.Commit=$(git rev-parse HEAD) -X training_session/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// When they are not set, the VCS information recorded by the Go toolchain is used instead.
+var (
+	Version   = "dev" // Release version
+	Commit    = ""    // Git commit the binary was built from
+	BuildTime = ""    // Time the binary was built, RFC 3339
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`              // Release version
+	Commit    string `json:"commit,omitempty"`     // Git commit the binary was built from
+	Modified  bool   `json:"modified,omitempty"`   // Whether the working tree had uncommitted changes
+	BuildTime string `json:"build_time,omitempty"` // Time the binary was built, or of the commit when unknown
+	GoVersion string `json:"go_version"`           // Go version the binary was built with
+}
+
+// Get returns the build information of the running binary
+func Get() Info { // Get the build information
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()} // Start from the linker flags
+
+	build, ok := debug.ReadBuildInfo() // Read the information recorded by the toolchain
+	if !ok {                           // Check if the information is unavailable
+		return info // Return the linker flags only
+	}
+	for _, setting := range build.Settings { // Iterate over the build settings
+		switch setting.Key { // Fill the blanks left by the linker flags
+		case "vcs.revision": // Commit
+			if info.Commit == "" { // Check if the commit was not set
+				info.Commit = setting.Value // Use the recorded commit
+			}
+		case "vcs.time": // Commit time
+			if info.BuildTime == "" { // Check if the build time was not set
+				info.BuildTime = setting.Value // Use the commit time
+			}
+		case "vcs.modified": // Uncommitted changes
+			info.Modified = setting.Value == "true" // Record the uncommitted changes
+		}
+	}
+	return info // Return the build information
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"training_session/pkg/audit"
+	"training_session/pkg/dto"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultAuditLimit is the number of audit entries listed when the query sets no limit
+const defaultAuditLimit = 100
+
+// personalFields lists the fields holding personal data of each kind of audited document, recorded by name only
+var personalFields = map[string][]string{
+	"users":            {"name", "email", "avatar"}, // Profile of the user, the CIN and password are never serialized
+	"erasure_requests": {"reason", "review_note"},   // Free texts about the user
+}
+
+// describeChange tells the audit middleware which document the request changed and its state before and after,
+// nil when it was created or removed
+func describeChange(c *gin.Context, targetType string, targetID primitive.ObjectID, before, after interface{}) { // Describe the change of a request
+	change := audit.Change{TargetType: targetType, TargetID: targetID.Hex(), Before: before, After: after, Personal: personalFields[targetType]} // Describe the change
+	c.Set(audit.ContextKey, change)                                                                                                              // Store the change for the audit middleware
+}
+
+// auditFilter converts a validated audit query into a repository filter
+func auditFilter(query dto.AuditQuery) repository.AuditFilter { // Build the audit filter
+	filter := repository.AuditFilter{Action: query.Action, TargetType: query.TargetType, TargetID: query.TargetID, Before: query.Before, Limit: query.Limit} // Copy the plain filters
+	if actorID, err := primitive.ObjectIDFromHex(query.ActorID); err == nil {                                                                                // Check if the actor is filtered
+		filter.ActorID = &actorID // Match the actor
+	}
+	filter.From, _ = time.Parse(time.RFC3339, query.From) // Match the start of the period, zero when omitted
+	filter.To, _ = time.Parse(time.RFC3339, query.To)     // Match the end of the period, zero when omitted
+	return filter                                         // Return the filter
+}
+
+// GetAuditLog: Allows admins to list the audit log, newest first, filtered by actor, action, target and period
+func (ctrl *Controller) GetAuditLog(c *gin.Context) { // Get the audit log
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	var query dto.AuditQuery                     // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	if query.Limit == 0 { // Check if no limit was set
+		query.Limit = defaultAuditLimit // Use the default limit
+	}
+
+	entries, err := ctrl.repos.Audit.Find(c.Request.Context(), auditFilter(query)) // Find the entries
+	if err != nil {                                                                // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve the audit log: %w", err)) // Return an error response
+		return                                                           // Return from the function
+	}
+
+	c.JSON(http.StatusOK, entries) // Return the entries
+}
+
+// ExportAuditLog: Allows admins to download the audit log matching the filters as NDJSON or CSV, in sequence order
+// with the hashes, so the chain can be checked outside of the service
+func (ctrl *Controller) ExportAuditLog(c *gin.Context) { // Export the audit log
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	var query dto.AuditQuery                     // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	var export dto.AuditExportQuery               // Define the export variable
+	if err := bindQuery(c, &export); err != nil { // Bind the format to the export variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	entries, err := ctrl.repos.Audit.Find(c.Request.Context(), auditFilter(query)) // Find the entries
+	if err != nil {                                                                // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve the audit log: %w", err)) // Return an error response
+		return                                                           // Return from the function
+	}
+	slices.Reverse(entries) // Put the entries in sequence order
+
+	filename := "audit-log-" + time.Now().UTC().Format("20060102T150405Z") // Name the file after the export time
+	if export.Format == "csv" {                                            // Check if CSV was asked for
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`) // Download the file
+		c.Header("Content-Type", "text/csv; charset=utf-8")                        // Send CSV
+		if err := writeAuditCSV(c, entries); err != nil {                          // Write the entries
+			c.Error(fmt.Errorf("failed to export the audit log: %w", err)) // Return an error response
+		}
+		return // Return from the function
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.ndjson"`) // Download the file
+	c.Header("Content-Type", "application/x-ndjson")                              // Send one JSON entry per line
+	encoder := json.NewEncoder(c.Writer)                                          // Encode the entries to the response
+	for _, entry := range entries {                                               // Iterate over the entries
+		if err := encoder.Encode(entry); err != nil { // Write the entry on its own line
+			c.Error(fmt.Errorf("failed to export the audit log: %w", err)) // Return an error response
+			return                                                         // Return from the function
+		}
+	}
+}
+
+// writeAuditCSV writes the audit entries as CSV, the changes as a JSON object
+func writeAuditCSV(c *gin.Context, entries []models.AuditEntry) error { // Write the entries as CSV
+	writer := csv.NewWriter(c.Writer)                                                                                                                             // Write CSV to the response
+	header := []string{"sequence", "occurred_at", "actor_id", "action", "target_type", "target_id", "status", "ip", "request_id", "changes", "prev_hash", "hash"} // Define the columns
+	if err := writer.Write(header); err != nil {                                                                                                                  // Write the header
+		return err // Return the error
+	}
+	for _, entry := range entries { // Iterate over the entries
+		actorID := ""             // Define the actor, empty when anonymous
+		if entry.ActorID != nil { // Check if the actor is known
+			actorID = entry.ActorID.Hex() // Set the actor
+		}
+		changes := ""               // Define the changes, empty when not described
+		if len(entry.Changes) > 0 { // Check if the changes were described
+			data, err := json.Marshal(entry.Changes) // Encode the changes
+			if err != nil {                          // Check if there is an error
+				return err // Return the error
+			}
+			changes = string(data) // Set the changes
+		}
+		record := []string{strconv.FormatInt(entry.Sequence, 10), entry.OccurredAt.UTC().Format(time.RFC3339Nano), actorID, entry.Action, entry.TargetType, entry.TargetID, strconv.Itoa(entry.Status), entry.IP, entry.RequestID, changes, entry.PrevHash, entry.Hash} // Define the row
+		if err := writer.Write(record); err != nil {                                                                                                                                                                                                                    // Write the row
+			return err // Return the error
+		}
+	}
+	writer.Flush()        // Send the buffered rows
+	return writer.Error() // Return the error of the writer
+}
+
+// VerifyAuditLog: Allows admins to check that no entry of the audit log was altered or removed, up to its last entry
+func (ctrl *Controller) VerifyAuditLog(c *gin.Context) { // Verify the audit log
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	result, err := audit.NewLog(ctrl.repos.Audit, ctrl.repos.Outbox).Verify(c.Request.Context()) // Walk the chain
+	if err != nil {                                                                              // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	c.JSON(http.StatusOK, result) // Return the result, with the first broken entry when the chain is broken
+}
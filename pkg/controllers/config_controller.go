@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetConfig: Allows admins to inspect the effective configuration, with the secrets redacted
+func (ctrl *Controller) GetConfig(c *gin.Context) { // Get the configuration
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	c.JSON(http.StatusOK, ctrl.cfg.Redacted()) // Return the redacted configuration
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"training_session/config"
+	"training_session/pkg/apperr"
+	"training_session/pkg/metrics"
+	"training_session/pkg/moderation"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Controller holds the dependencies shared by the HTTP handlers
+type Controller struct {
+	cfg            *config.Config           // Application configuration
+	repos          *repository.Repositories // Repositories of every aggregate
+	feedbackFilter *moderation.Filter       // Filter masking blocklisted words in feedback
+	metrics        *metrics.Metrics         // Domain event counters
+}
+
+// New creates a controller with the given configuration, repositories and metrics. Notifications are queued in the
+// outbox and delivered by the notifier of the relay.
+func New(cfg *config.Config, repos *repository.Repositories, metrics *metrics.Metrics) *Controller { // Create a controller
+	return &Controller{
+		cfg:            cfg,                                         // Set the configuration
+		repos:          repos,                                       // Set the repositories
+		feedbackFilter: moderation.NewFilter(cfg.FeedbackBlocklist), // Create the blocklist filter
+		metrics:        metrics,                                     // Set the metrics
+	}
+}
+
+// bindJSON decodes the JSON body of the request into obj, reporting a malformed body or fields
+// breaking their binding rules as a validation error listing the invalid fields
+func bindJSON(c *gin.Context, obj interface{}) error { // Decode the request body
+	return bindingError(c.ShouldBindJSON(obj), "invalid_body", "The request body is invalid") // Bind the JSON to the object
+}
+
+// bindQuery decodes the query string of the request into obj, reporting the parameters breaking their binding rules
+func bindQuery(c *gin.Context, obj interface{}) error { // Decode the query string
+	return bindingError(c.ShouldBindQuery(obj), "invalid_query", "The query string is invalid") // Bind the query string to the object
+}
+
+// bindingError converts a binding error into a validation error listing the invalid fields, nil stays nil
+func bindingError(err error, code, message string) error { // Describe a binding error
+	if err == nil { // Check if the input is valid
+		return nil // Return nil
+	}
+
+	var fields []apperr.FieldError                // Define a slice to hold the invalid fields
+	var validationErrs validator.ValidationErrors // Define the binding rule failures
+	var typeErr *json.UnmarshalTypeError          // Define the type mismatch
+	switch {                                      // Describe the invalid fields
+	case errors.As(err, &validationErrs): // Fields breaking their binding rules
+		for _, fieldErr := range validationErrs { // Iterate over the failures
+			fields = append(fields, apperr.Field(fieldPath(fieldErr), ruleMessage(fieldErr))) // Add the field
+		}
+	case errors.As(err, &typeErr): // Field of the wrong type
+		fields = append(fields, apperr.Field(typeErr.Field, "must be a "+typeErr.Type.String())) // Add the field
+	}
+	return apperr.Validation(code, message, fields...).Wrap(err) // Return the error
+}
+
+// fieldPath returns the path of the invalid field without the name of the request struct, e.g. "coach_assists[0]"
+func fieldPath(fieldErr validator.FieldError) string { // Get the path of a field
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".") // Drop the name of the struct
+	if !found {                                              // Check if the field has no parent
+		return fieldErr.Field() // Return the field name
+	}
+	return path // Return the path
+}
+
+// ruleMessage describes the binding rule the field breaks
+func ruleMessage(fieldErr validator.FieldError) string { // Describe a rule
+	switch fieldErr.Tag() { // Describe the rule
+	case "required": // Missing value
+		return "is required" // Return the message
+	case "email": // Email address
+		return "must be a valid email address" // Return the message
+	case "objectid": // Reference to a document
+		return "must be a valid ID" // Return the message
+	case "min", "gte": // Lower bound
+		if unit := lengthUnit(fieldErr.Kind()); unit != "" { // Check if the length is bounded
+			return "must contain at least " + fieldErr.Param() + " " + unit // Return the message
+		}
+		return "must be at least " + fieldErr.Param() // Return the message
+	case "max", "lte": // Upper bound
+		if unit := lengthUnit(fieldErr.Kind()); unit != "" { // Check if the length is bounded
+			return "must contain at most " + fieldErr.Param() + " " + unit // Return the message
+		}
+		return "must be at most " + fieldErr.Param() // Return the message
+	case "gt": // Strict lower bound
+		return "must be greater than " + fieldErr.Param() // Return the message
+	case "after_start": // End of a session
+		return "must be after start_time" // Return the message
+	case "ne": // Forbidden value
+		return "must not be " + fieldErr.Param() // Return the message
+	case "url": // Link
+		return "must be a valid URL" // Return the message
+	case "alphanum": // Letters and digits
+		return "must only contain letters and digits" // Return the message
+	case "datetime": // Formatted date
+		return "must be an RFC 3339 date" // Return the message
+	case "webhook_url": // Webhook endpoint
+		return "must be an http or https URL to a public host" // Return the message
+	case "role", "session_status", "training_type", "event_type", "delivery_status", "erasure_status": // Enumerations
+		return "is not a known value" // Return the message
+	case "oneof": // Listed values
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ") // Return the message
+	case "schedule": // Duration of a session
+		return "must match the time between start_time and end_time" // Return the message
+	}
+	return "failed the " + fieldErr.Tag() + " rule" // Return the generic message
+}
+
+// etag formats the version of a document as its entity tag
+func etag(version int64) string { // Format an entity tag
+	return `"` + strconv.FormatInt(version, 10) + `"` // Quote the version
+}
+
+// setETag tags the response with the version of the document it serves
+func setETag(c *gin.Context, version int64) { // Set the ETag header
+	c.Header("ETag", etag(version)) // Set the entity tag
+}
+
+// viewETag formats the version of a document served in several views as the entity tag of the view, e.g. "3-self",
+// so a cache never serves the body of one view to a caller who sees another
+func viewETag(version int64, view string) string { // Format the entity tag of a view
+	return `"` + strconv.FormatInt(version, 10) + "-" + view + `"` // Quote the version and the view
+}
+
+// setViewETag tags the response with the version and the view of the document it serves, and tells caches the
+// view depends on the credentials of the caller
+func setViewETag(c *gin.Context, version int64, view string) { // Set the ETag and Vary headers
+	c.Header("ETag", viewETag(version, view)) // Set the entity tag
+	c.Header("Vary", "Authorization, Cookie") // The view depends on the caller
+}
+
+// notModified tags the response with the version of the document and answers 304 Not Modified when the
+// If-None-Match header already lists that version, in which case the handler must not write a body
+func notModified(c *gin.Context, version int64) bool { // Handle a conditional read
+	setETag(c, version)                       // Set the entity tag
+	return notModifiedSince(c, etag(version)) // Compare the tags
+}
+
+// notModifiedView is notModified for a document served in several views, tagged with the view served
+func notModifiedView(c *gin.Context, version int64, view string) bool { // Handle a conditional read of a view
+	setViewETag(c, version, view)                       // Set the entity tag
+	return notModifiedSince(c, viewETag(version, view)) // Compare the tags
+}
+
+// notModifiedSince answers 304 Not Modified when the If-None-Match header lists the tag
+func notModifiedSince(c *gin.Context, tag string) bool { // Compare the tag the client holds
+	if !matchesETag(c.GetHeader("If-None-Match"), tag, true) { // Check if the client holds another version
+		return false // Serve the document
+	}
+	c.AbortWithStatus(http.StatusNotModified) // Tell the client its copy is current
+	return true                               // Skip the body
+}
+
+// checkIfMatch requires the update to carry the ETag of the version the client read in If-Match, so a client
+// never overwrites a change it has not seen. The tag of any view of that version matches.
+func checkIfMatch(c *gin.Context, version int64) error { // Check a conditional write
+	header := c.GetHeader("If-Match") // Get the precondition
+	if header == "" {                 // Check if the update is unconditional
+		return apperr.PreconditionRequired("if_match_required", "Send the ETag of the resource in the If-Match header") // Return a precondition required error
+	}
+	if !matchesETag(header, etag(version), false) { // Check if the client read another version
+		return apperr.PreconditionFailed("version_mismatch", "The resource was modified since it was read") // Return a precondition failed error
+	}
+	return nil // The client read the current version
+}
+
+// versionError reports a conditional write that lost the race against another write as a precondition failure
+func versionError(err error) error { // Describe a failed conditional write
+	if errors.Is(err, repository.ErrVersionMismatch) { // Check if the document changed in the meantime
+		return apperr.PreconditionFailed("version_mismatch", "The resource was modified since it was read").Wrap(err) // Return a precondition failed error
+	}
+	return err // Return the error
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header lists the tag or is "*"; weak tags only
+// match with the weak comparison of If-None-Match, and the view of a tag is only compared by If-None-Match
+func matchesETag(header, tag string, weak bool) bool { // Compare entity tags
+	for _, candidate := range strings.Split(header, ",") { // Iterate over the listed tags
+		candidate = strings.TrimSpace(candidate) // Drop the spaces
+		if weak {                                // Check if weak tags match
+			candidate = strings.TrimPrefix(candidate, "W/") // Drop the weak indicator
+		} else if version, _, found := strings.Cut(candidate, "-"); found { // Check if the tag names a view
+			candidate = version + `"` // Keep the version
+		}
+		if candidate == "*" || candidate == tag { // Check if the tag matches
+			return true // The tag is listed
+		}
+	}
+	return false // The tag is not listed
+}
+
+// lengthUnit returns what the length of a value of the kind counts, empty when the rule bounds the value itself
+func lengthUnit(kind reflect.Kind) string { // Get the unit of a length
+	switch kind { // Check the kind
+	case reflect.String: // Strings are bounded by their length
+		return "characters" // Return the unit
+	case reflect.Slice, reflect.Map: // Collections are bounded by their size
+		return "elements" // Return the unit
+	}
+	return "" // Numbers are bounded by their value
+}
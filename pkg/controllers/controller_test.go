@@ -0,0 +1,161 @@
+package controllers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"training_session/config"
+	"training_session/pkg/app"
+	"training_session/pkg/events"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+	"training_session/pkg/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// server serves the API over the in-memory repositories
+type server struct {
+	t     *testing.T
+	app   *app.App                 // Application under test
+	repos *repository.Repositories // Repositories behind the handlers
+}
+
+// newServer creates the application with the in-memory repositories and an in-process broker, with the
+// configuration changed by configure
+func newServer(t *testing.T, configure ...func(cfg *config.Config)) *server {
+	t.Helper()
+	cfg := config.Default()
+	cfg.JwtSecretKey = "test-secret"
+	for _, change := range configure {
+		change(cfg)
+	}
+	repos := memory.NewRepositories()
+	a, err := app.New(cfg, app.WithRepositories(repos), app.WithRouter(gin.New()), app.WithBroker(events.NewBus()))
+	if err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := a.Close(context.Background()); err != nil {
+			t.Errorf("close app: %v", err)
+		}
+	})
+	return &server{t: t, app: a, repos: repos}
+}
+
+// response is an answer of the API
+type response struct {
+	status int            // Status code
+	header http.Header    // Headers
+	body   map[string]any // Decoded JSON body, nil when the body is not an object
+}
+
+// code returns the code of a problem response
+func (r response) code() string {
+	code, _ := r.body["code"].(string)
+	return code
+}
+
+// do sends a request with the body encoded as JSON and the headers, authenticated with the token when not empty
+func (s *server) do(method, path, token string, body any, headers ...http.Header) response {
+	s.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			s.t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	for _, header := range headers {
+		for name, values := range header {
+			req.Header[name] = values
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.AddCookie(&http.Cookie{Name: "auth_token", Value: token})
+	}
+	rec := httptest.NewRecorder()
+	s.app.Router.ServeHTTP(rec, req)
+
+	res := response{status: rec.Code, header: rec.Header()}
+	_ = json.Unmarshal(rec.Body.Bytes(), &res.body)
+	return res
+}
+
+// account is a registered and logged in user
+type account struct {
+	id    string // ID of the user
+	token string // Token of the user
+}
+
+// signUp registers a user with the role and logs them in
+func (s *server) signUp(email, role string) account {
+	s.t.Helper()
+	res := s.do(http.MethodPost, "/users/register", "", map[string]any{"name": "Test", "email": email, "password": "password123", "role": role})
+	if res.status != http.StatusCreated {
+		s.t.Fatalf("register %s: status %d, body %v", email, res.status, res.body)
+	}
+	res = s.do(http.MethodPost, "/users/login", "", map[string]any{"email": email, "password": "password123"})
+	if res.status != http.StatusOK {
+		s.t.Fatalf("login %s: status %d, body %v", email, res.status, res.body)
+	}
+	user, _ := res.body["user"].(map[string]any)
+	id, _ := user["id"].(string)
+	token, _ := res.body["token"].(string)
+	return account{id: id, token: token}
+}
+
+// signUpAdmin registers a user and appoints them admin, which only an admin can do through the API
+func (s *server) signUpAdmin(email string) account {
+	s.t.Helper()
+	admin := s.signUp(email, "user")
+	id, _ := primitive.ObjectIDFromHex(admin.id)
+	user, err := s.repos.Users.FindByID(context.Background(), id)
+	if err != nil {
+		s.t.Fatalf("find admin: %v", err)
+	}
+	user.Role = models.RoleAdmin
+	if err := s.repos.Users.Replace(context.Background(), &user); err != nil {
+		s.t.Fatalf("appoint admin: %v", err)
+	}
+	return admin
+}
+
+// sessionBody returns a valid session request starting tomorrow, with the fields overridden
+func sessionBody(overrides map[string]any) map[string]any {
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	body := map[string]any{
+		"title":         "Morning drills",
+		"start_time":    start,
+		"end_time":      start.Add(90 * time.Minute),
+		"location":      "Pitch 1",
+		"training_type": "group",
+	}
+	for field, value := range overrides {
+		body[field] = value
+	}
+	return body
+}
+
+// createSession creates a session as the coach and returns its ID
+func (s *server) createSession(coach account, overrides map[string]any) string {
+	s.t.Helper()
+	res := s.do(http.MethodPost, "/sessions/create", coach.token, sessionBody(overrides))
+	if res.status != http.StatusCreated {
+		s.t.Fatalf("create session: status %d, body %v", res.status, res.body)
+	}
+	session, _ := res.body["session"].(map[string]any)
+	id, _ := session["id"].(string)
+	return id
+}
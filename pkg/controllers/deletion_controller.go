@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newDeletion describes a deletion made now by the caller, if known, for the reason of the query string or
+// the given default reason
+func (ctrl *Controller) newDeletion(c *gin.Context, defaultReason string) (models.Deletion, error) { // Describe a deletion
+	var query dto.DeleteQuery                    // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		return models.Deletion{}, err // Return the error
+	}
+	if query.Reason == "" { // Check if no reason was given
+		query.Reason = defaultReason // Use the default reason
+	}
+
+	now := time.Now()                                                  // Get the current time
+	deletion := models.Deletion{DeletedAt: &now, Reason: query.Reason} // Define the deletion
+	if caller := ctrl.viewer(c); caller != nil {                       // Check if the caller is known
+		deletion.DeletedBy = &caller.ID // Record who deleted the document
+	}
+	return deletion, nil // Return the deletion
+}
+
+// successorCoach returns the live coach or business owner with the given ID, who takes over the sessions of a
+// deleted user, or nil when no ID is given
+func (ctrl *Controller) successorCoach(ctx context.Context, userID primitive.ObjectID, reassignTo string) (*primitive.ObjectID, error) { // Find the successor of a coach
+	if reassignTo == "" { // Check if no successor is given
+		return nil, nil // Keep the sessions
+	}
+	successorID, _ := primitive.ObjectIDFromHex(reassignTo) // Convert the validated ID to an ObjectID
+	if successorID == userID {                              // Check if the successor is the deleted user
+		return nil, apperr.Validation("invalid_successor", "Invalid coach to reassign the sessions to", apperr.Field("reassign_to", "must be another user")) // Return a bad request error
+	}
+
+	successor, err := ctrl.repos.Users.FindByID(ctx, successorID) // Find the successor
+	if errors.Is(err, repository.ErrNotFound) {                   // Check if the successor was not found
+		return nil, apperr.Validation("invalid_successor", "Invalid coach to reassign the sessions to", apperr.Field("reassign_to", "must be an existing user")) // Return a bad request error
+	}
+	if err != nil { // Check if there is an error
+		return nil, err // Return the error
+	}
+	if successor.Role != models.RoleCoach && successor.Role != models.RoleBusinessOwner { // Check if the successor cannot run sessions
+		return nil, apperr.Validation("invalid_successor", "Invalid coach to reassign the sessions to", apperr.Field("reassign_to", "must be a coach or business owner")) // Return a bad request error
+	}
+	return &successor.ID, nil // Return the successor
+}
+
+// handOverSessions gives the sessions a user coaches that have not ended at the given time to the successor and
+// returns their count; with no successor, such sessions are a conflict as they would lose their coach
+func (ctrl *Controller) handOverSessions(ctx context.Context, userID primitive.ObjectID, successor *primitive.ObjectID, at time.Time) (int64, error) { // Hand over the sessions of a coach
+	sessions, err := ctrl.repos.Sessions.FindByUser(ctx, userID) // Find the sessions of the user
+	if err != nil {                                              // Check if there is an error
+		return 0, err // Return the error
+	}
+	remaining := 0                     // Define the number of sessions coached by the user that have not ended
+	for _, session := range sessions { // Iterate over the sessions
+		if session.Coach == userID && session.EndTime.After(at) { // Check if the user still coaches the session
+			remaining++ // Count the session
+		}
+	}
+	if remaining == 0 { // Check if there is nothing to hand over
+		return 0, nil // Return nil
+	}
+	if successor == nil { // Check if the sessions would lose their coach
+		return 0, apperr.Conflict("coach_has_sessions", fmt.Sprintf("The user still coaches %d session(s) that have not ended, give them to another coach with reassign_to", remaining)) // Return a conflict error
+	}
+	return ctrl.repos.Sessions.ReassignCoach(ctx, userID, *successor, at) // Give the sessions to the successor
+}
+
+// deleteUser soft deletes a user and cleans up the references to them: their invitations and pitch bookings are
+// soft deleted along with them, so restoring the user restores them, they leave the sessions they attend or assist,
+// their notifications are removed, their feedback, flags and replies are kept without them and their webhooks are
+// disabled. The sessions they coach that have not ended are given to the successor, who also becomes the owner of
+// the ones they created; without one the user is kept and a conflict is returned. Past sessions keep their coach
+// and owner as history, as do the sessions they own but others coach: the coach still manages them. Their feedback
+// forms are kept for the sessions they own and the feedback given through them, and their check-ins as attendance
+// history. When any clean-up other than the invitations and pitch bookings changed a document, the deletion of the
+// user is final: restoring them would leave them without their sessions, notifications, feedback and webhooks, so
+// they are never restored.
+func (ctrl *Controller) deleteUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion, successor *primitive.ObjectID) (dto.UserDeletionReport, error) { // Delete a user and the references to them
+	var report dto.UserDeletionReport // Define the report of the changed documents
+	var err error                     // Define the error
+
+	_, err = ctrl.repos.Users.FindByID(ctx, userID) // Check that the user exists before cleaning up
+	if errors.Is(err, repository.ErrNotFound) {     // Check if the user was not found
+		return report, apperr.NotFound("user_not_found", "User not found") // Return a not found error
+	}
+	if err != nil { // Check if there is an error
+		return report, err // Return the error
+	}
+
+	if report.SessionsReassigned, err = ctrl.handOverSessions(ctx, userID, successor, *deletion.DeletedAt); err != nil { // Give the remaining sessions to the successor
+		return report, err // Return the error
+	}
+	if report.SessionsLeft, err = ctrl.repos.Sessions.RemoveUser(ctx, userID); err != nil { // Remove the user from the participants and assistants
+		return report, err // Return the error
+	}
+	if report.InvitationsDeleted, err = ctrl.repos.Invitations.SoftDeleteByUser(ctx, userID, deletion); err != nil { // Soft delete the invitations
+		return report, err // Return the error
+	}
+	if report.PitchBookingsDeleted, err = ctrl.repos.Pitches.SoftDeleteByUser(ctx, userID, deletion); err != nil { // Soft delete the pitch bookings
+		return report, err // Return the error
+	}
+	if report.NotificationsDeleted, err = ctrl.repos.Notifications.DeleteByUser(ctx, userID); err != nil { // Remove the notifications
+		return report, err // Return the error
+	}
+	if report.FeedbackAnonymized, err = ctrl.repos.Feedback.AnonymizeAuthor(ctx, userID); err != nil { // Detach the feedback from its author
+		return report, err // Return the error
+	}
+	if report.FlagsAnonymized, err = ctrl.repos.Feedback.AnonymizeFlags(ctx, userID); err != nil { // Detach the flags from the user who raised them
+		return report, err // Return the error
+	}
+	if report.RepliesAnonymized, err = ctrl.repos.Feedback.AnonymizeReplies(ctx, userID); err != nil { // Detach the replies from their coach
+		return report, err // Return the error
+	}
+	if report.WebhooksDisabled, err = ctrl.repos.Webhooks.DisableByOwner(ctx, userID); err != nil { // Disable the webhooks
+		return report, err // Return the error
+	}
+
+	deletion.Final = report.Irreversible()                   // Keep the user deleted if the clean-ups cannot be undone
+	err = ctrl.repos.Users.SoftDelete(ctx, userID, deletion) // Soft delete the user
+	if errors.Is(err, repository.ErrNotFound) {              // Check if the user was deleted in the meantime
+		return report, apperr.NotFound("user_not_found", "User not found") // Return a not found error
+	}
+	return report, err // Return the report
+}
+
+// softDeleters maps the kinds of the admin deletion routes to their repositories
+func (ctrl *Controller) softDeleters() map[string]repository.SoftDeleter { // Get the soft deleting repositories
+	return map[string]repository.SoftDeleter{
+		"sessions":    ctrl.repos.Sessions,    // Training sessions
+		"users":       ctrl.repos.Users,       // Users
+		"invitations": ctrl.repos.Invitations, // Session invitations
+		"pitches":     ctrl.repos.Pitches,     // Pitch bookings
+	}
+}
+
+// GetDeleted: Allows admins to list the soft deleted sessions, users, invitations or pitch bookings, last deleted
+// first, until they are purged
+func (ctrl *Controller) GetDeleted(c *gin.Context) { // Get the soft deleted documents
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	var deleted interface{}    // Define the deleted documents
+	var err error              // Define the error
+	ctx := c.Request.Context() // Get the context of the request
+	switch c.Param("kind") {   // Check the kind of the documents
+	case "sessions": // Training sessions
+		deleted, err = ctrl.repos.Sessions.FindDeleted(ctx) // Find the deleted sessions
+	case "users": // Users, shown with their admin view
+		var users []models.User                        // Define the deleted users
+		users, err = ctrl.repos.Users.FindDeleted(ctx) // Find the deleted users
+		views := make([]dto.AdminUser, len(users))     // Define a slice to hold the views
+		for i, user := range users {                   // Iterate over the users
+			views[i] = dto.NewAdminUser(user) // Build the admin view
+		}
+		deleted = views // Return the views
+	case "invitations": // Session invitations
+		deleted, err = ctrl.repos.Invitations.FindDeleted(ctx) // Find the deleted invitations
+	case "pitches": // Pitch bookings
+		deleted, err = ctrl.repos.Pitches.FindDeleted(ctx) // Find the deleted pitch bookings
+	default: // Unknown kind
+		c.Error(apperr.NotFound("unknown_kind", "Unknown kind of deleted documents")) // Return a not found response
+		return                                                                        // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve deleted %s: %w", c.Param("kind"), err)) // Return an error response
+		return                                                                         // Return from the function
+	}
+
+	c.JSON(http.StatusOK, deleted) // Return the deleted documents
+}
+
+// RestoreDeleted: Allows admins to restore a soft deleted session, user, invitation or pitch booking; restoring
+// a cancelled session also restores the invitations deleted with it, and restoring a user their invitations and
+// pitch bookings. Users whose deletion is final, erased or cleaned up beyond what a restore undoes, stay deleted.
+func (ctrl *Controller) RestoreDeleted(c *gin.Context) { // Restore a soft deleted document
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	kind := c.Param("kind")                  // Get the kind of the document
+	deleter, ok := ctrl.softDeleters()[kind] // Get the repository of the kind
+	if !ok {                                 // Check if the kind is unknown
+		c.Error(apperr.NotFound("unknown_kind", "Unknown kind of deleted documents")) // Return a not found response
+		return                                                                        // Return from the function
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(c.Param("id")) // Convert the ID to an ObjectID
+	if err != nil {                                           // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_id", "Invalid ID", apperr.Field("id", "must be a valid ID"))) // Return a bad request response
+		return                                                                                           // Return from the function
+	}
+
+	// Restore the document, and the documents deleted with a session or a user, in one transaction
+	var undone models.Deletion                                                                           // Define the undone deletion
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the restoration in a transaction
+		restored, err := deleter.Restore(ctx, objectID) // Restore the document
+		undone = restored                               // Keep the undone deletion
+		if err != nil {                                 // Check if there is an error
+			return err // Return the error
+		}
+		switch kind { // Check the kind of the document
+		case "sessions": // Session, cancelled with its invitations
+			return ctrl.repos.Invitations.RestoreBySession(ctx, objectID, *restored.DeletedAt) // Restore the invitations deleted with the session
+		case "users": // User, deleted with their invitations and pitch bookings
+			if err := ctrl.repos.Invitations.RestoreByUser(ctx, objectID, *restored.DeletedAt); err != nil { // Restore the invitations deleted with the user
+				return err // Return the error
+			}
+			return ctrl.repos.Pitches.RestoreByUser(ctx, objectID, *restored.DeletedAt) // Restore the pitch bookings deleted with the user
+		}
+		return nil // Nothing else to restore
+	})
+	if errors.Is(err, repository.ErrNotFound) { // Check if the document is missing or not deleted
+		c.Error(apperr.NotFound("deleted_document_not_found", "Deleted document not found")) // Return a not found response
+		return                                                                               // Return from the function
+	}
+	if errors.Is(err, repository.ErrFinalDeletion) { // Check if the deletion cannot be undone
+		c.Error(apperr.Conflict("deletion_final", "The deletion changed documents a restore cannot bring back, the document stays deleted")) // Return a conflict response
+		return                                                                                                                               // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to restore %s %s: %w", kind, objectID.Hex(), err)) // Return an error response
+		return                                                                        // Return from the function
+	}
+
+	describeChange(c, kind, objectID, undone, models.Deletion{})                              // Record the changed fields in the audit log
+	c.JSON(http.StatusOK, gin.H{"message": "Document restored successfully", "id": objectID}) // Return a success response
+}
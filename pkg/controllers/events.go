@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordEvent writes a domain event to the outbox. Called in the transaction of the change it describes, the
+// event is published by the relay if and only if the change is committed.
+func (ctrl *Controller) recordEvent(ctx context.Context, eventType, aggregateType string, aggregateID primitive.ObjectID, data interface{}) error { // Record a domain event
+	payload, err := json.Marshal(data) // Encode the data of the event
+	if err != nil {                    // Check if there is an error
+		return fmt.Errorf("failed to encode the %s event: %w", eventType, err) // Return the error
+	}
+
+	event := models.Event{ // Define the event
+		ID:            primitive.NewObjectID(), // Generate a new ObjectID for the event
+		Type:          eventType,               // Set the type of the event
+		AggregateType: aggregateType,           // Set the type of the aggregate
+		AggregateID:   aggregateID,             // Set the aggregate
+		Data:          payload,                 // Set the data
+		OccurredAt:    time.Now(),              // Set the occurred_at timestamp
+	}
+	if err := ctrl.repos.Outbox.Insert(ctx, &event); err != nil { // Append the event to the outbox
+		return fmt.Errorf("failed to record the %s event: %w", eventType, err) // Return the error
+	}
+	return nil // Return nil
+}
+
+// queueNotifications writes a NotificationRequested event of the aggregate for each notification to the outbox. Called
+// in the transaction of the change they announce, the notifications are delivered if and only if the change is committed.
+func (ctrl *Controller) queueNotifications(ctx context.Context, aggregateType string, aggregateID primitive.ObjectID, notifications []models.Notification) error { // Queue notifications
+	for _, notification := range notifications { // Iterate over the notifications
+		if err := ctrl.recordEvent(ctx, models.EventNotificationRequested, aggregateType, aggregateID, notification); err != nil { // Queue the notification
+			return err // Return the error
+		}
+	}
+	return nil // Return nil
+}
+
+// replaceSession replaces a session still at its version, recording a SessionRescheduled event in the same
+// transaction when its start or end time changed from the previous state, enrolling the waitlisted users a
+// raised capacity makes room for and queueing the notifications
+func (ctrl *Controller) replaceSession(ctx context.Context, session *models.Session, previous models.Session, notifications ...models.Notification) error { // Replace a session
+	var replaced models.Session                                                           // Define the replaced session
+	var promoted int                                                                      // Define the number of waitlisted users enrolled
+	err := ctrl.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error { // Run the replacement in a transaction
+		replaced = *session                                                 // Start from the session at its version, the transaction may be retried
+		if err := ctrl.repos.Sessions.Replace(ctx, &replaced); err != nil { // Replace the session
+			return err // Return the error
+		}
+		if !replaced.StartTime.Equal(previous.StartTime) || !replaced.EndTime.Equal(previous.EndTime) { // Check if the schedule changed
+			err := ctrl.recordEvent(ctx, models.EventSessionRescheduled, models.AggregateSession, replaced.ID, map[string]interface{}{ // Announce the new schedule
+				"session_id":          replaced.ID,        // Rescheduled session
+				"previous_start_time": previous.StartTime, // Start time before the change
+				"previous_end_time":   previous.EndTime,   // End time before the change
+				"start_time":          replaced.StartTime, // New start time
+				"end_time":            replaced.EndTime,   // New end time
+			})
+			if err != nil { // Check if there is an error
+				return err // Return the error
+			}
+		}
+		var err error                                                          // Define the error
+		if promoted, err = ctrl.fillFromWaitlist(ctx, &replaced); err != nil { // Enroll the waiting users
+			return err // Return the error
+		}
+		return ctrl.queueNotifications(ctx, models.AggregateSession, replaced.ID, notifications) // Queue the notifications with the change
+	})
+	if err == nil { // Check if the session was replaced
+		*session = replaced                                                       // Report the new version
+		ctrl.metrics.Enrollments.WithLabelValues("enroll").Add(float64(promoted)) // Count the waitlisted users enrolled
+	}
+	return err // Return the error
+}
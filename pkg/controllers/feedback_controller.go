@@ -2,135 +2,311 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
 	"training_session/pkg/models"
+	"training_session/pkg/repository"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var feedbackCollection *mongo.Collection
-
-func InitializeFeedbackController(database *mongo.Database) { // Initialize the feedback controller
-	feedbackCollection = database.Collection("feedbacks") // Set the feedback collection
-}
-
 // SubmitFeedback: Manages the submission of feedback for sessions and coaches
-func SubmitFeedback(c *gin.Context) { // Submit feedback for sessions and coaches
-	var feedback models.Feedback                  // Define a feedback variable
-	if err := c.BindJSON(&feedback); err != nil { // Bind the JSON to the feedback struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"}) // Return a bad request response
-		return                                                         // Return from the function
+func (ctrl *Controller) SubmitFeedback(c *gin.Context) { // Submit feedback for sessions and coaches
+	var request dto.SubmitFeedbackRequest         // Define a feedback request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the feedback request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	feedback := request.ToModel() // Get the submitted feedback
+
+	form, err := ctrl.findFeedbackForm(c.Request.Context(), feedback) // Find the form the feedback must follow
+	if err != nil {                                                   // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the given form was not found
+			c.Error(apperr.Validation("feedback_form_not_found", "Feedback form not found", apperr.Field("form_id", "must reference an existing feedback form"))) // Return a bad request response
+		} else {
+			c.Error(fmt.Errorf("failed to retrieve feedback form: %w", err)) // Return an error response
+		}
+		return // Return from the function
+	}
+	if form != nil { // Check if a form applies to the feedback
+		if problems := validateFeedbackAnswers(*form, feedback.Answers); len(problems) > 0 { // Validate the answers against the form
+			c.Error(apperr.Validation("invalid_feedback_answers", "Invalid feedback answers", problems...)) // Return a bad request response
+			return                                                                                          // Return from the function
+		}
+		feedback.FormID = form.ID // Record the form the answers were validated against
 	}
 
+	var filtered bool                                                                                     // Define whether blocklisted words were found
+	feedback.Content, feedback.Answers, filtered = ctrl.cleanFeedback(feedback.Content, feedback.Answers) // Mask the blocklisted words of the texts
+	feedback.Status = models.FeedbackStatusApproved                                                       // Publish the feedback by default
+	if filtered {                                                                                         // Check if the texts contained blocklisted words
+		feedback.Status = models.FeedbackStatusPending // Send the feedback to the moderation queue
+	}
 	feedback.ID = primitive.NewObjectID() // Generate a new ObjectID for the feedback
 	feedback.CreatedAt = time.Now()       // Set the created_at timestamp
 	feedback.UpdatedAt = time.Now()       // Set the updated_at timestamp
 
-	_, err := feedbackCollection.InsertOne(context.TODO(), feedback) // Insert the feedback
-	if err != nil {                                                  // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit feedback"}) // Return an error response
-		return                                                                              // Return from the function
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Insert the feedback, its event and its moderation entry together
+		if err := ctrl.repos.Feedback.Insert(ctx, &feedback); err != nil { // Insert the feedback
+			return err // Return the error
+		}
+		if filtered { // Check if the texts were filtered
+			if err := ctrl.recordModerationAction(ctx, feedback.ID, primitive.NilObjectID, "filtered", "blocklisted words masked"); err != nil { // Audit the automatic filtering
+				return err // Return the error
+			}
+		}
+		return ctrl.recordEvent(ctx, models.EventFeedbackSubmitted, models.AggregateFeedback, feedback.ID, feedback) // Announce the feedback
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to submit feedback: %w", err)) // Return an error response
+		return                                                    // Return from the function
 	}
 
+	setETag(c, feedback.Version)         // Tag the response with the version of the feedback
 	c.JSON(http.StatusCreated, feedback) // Return the created feedback
 }
 
-// ViewFeedback: Allows users to view feedback they have submitted
-func ViewFeedback(c *gin.Context) { // View feedback submitted by a user
+// ViewFeedback: Allows users to view feedback they have submitted. The author and admins see every feedback with its
+// status and flags, anyone else only sees the approved feedback the author signed, as shown publicly.
+func (ctrl *Controller) ViewFeedback(c *gin.Context) { // View feedback submitted by a user
 	userID := c.Param("userId") // Get user ID from the URL
 
 	objectUserID, err := primitive.ObjectIDFromHex(userID) // Convert user ID to ObjectID
 	if err != nil {                                        // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return an error response
-		return                                                           // Return from the function
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return an error response
+		return                                                                                                         // Return from the function
+	}
+
+	filter := repository.FeedbackFilter{UserID: objectUserID}                                  // Find feedbacks by user ID
+	viewer := ctrl.viewer(c)                                                                   // Get the caller
+	private := viewer != nil && (viewer.ID == objectUserID || viewer.Role == models.RoleAdmin) // Check if the caller may see every feedback of the user
+	if !private {                                                                              // Check if the caller only gets the public view
+		filter.Status = models.FeedbackStatusApproved // Only approved feedback is public
 	}
 
 	// Find feedbacks submitted by this user
-	var feedbacks []models.Feedback                                                         // Define a feedbacks variable
-	cursor, err := feedbackCollection.Find(context.TODO(), bson.M{"user_id": objectUserID}) // Find feedbacks by user ID
-	if err != nil {                                                                         // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve feedback"}) // Return an error response
-		return                                                                                // Return from the function
-	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-
-	for cursor.Next(context.TODO()) { // Iterate over the cursor
-		var feedback models.Feedback                     // Define a feedback variable
-		if err := cursor.Decode(&feedback); err != nil { // Decode the feedback
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding feedback"}) // Return an error response
-			return                                                                            // Return from the function
+	feedbacks, err := ctrl.repos.Feedback.Find(c.Request.Context(), filter) // Find the feedbacks
+	if err != nil {                                                         // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	if !private { // Check if the caller only gets the public view
+		signed := []models.Feedback{}        // Define a slice to hold the feedback the author signed
+		for _, feedback := range feedbacks { // Iterate over the feedback
+			if !feedback.Anonymous { // Check if the author is shown publicly, listing anonymous feedback here would reveal them
+				signed = append(signed, publicFeedback(feedback)) // Keep the public view of the feedback
+			}
 		}
-		feedbacks = append(feedbacks, feedback) // Append the feedback to the feedbacks slice
+		feedbacks = signed // Return the public feedback only
 	}
 
 	c.JSON(http.StatusOK, feedbacks) // Return the feedbacks
 }
 
-// EditFeedback: Handles editing of previously submitted feedback
-func EditFeedback(c *gin.Context) { // Edit previously submitted feedback
+// EditFeedback: Handles editing of previously submitted feedback, with the same permissions as PatchFeedback
+func (ctrl *Controller) EditFeedback(c *gin.Context) { // Edit previously submitted feedback
 	feedbackID := c.Param("feedbackId")         // Get feedback ID from the URL
-	var updatedFeedback models.Feedback // Define an updated feedback variable
+	var updatedFeedback dto.EditFeedbackRequest // Define a feedback edit request variable
 
-	if err := c.BindJSON(&updatedFeedback); err != nil { // Bind the JSON to the updated feedback struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"}) // Return a bad request response
-		return                                                         // Return from the function
+	if err := bindJSON(c, &updatedFeedback); err != nil { // Bind the JSON to the feedback edit request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
 	objectFeedbackID, err := primitive.ObjectIDFromHex(feedbackID) // Convert feedback ID to ObjectID
 	if err != nil {                                                // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feedback ID"}) // Return an error response
-		return                                                               // Return from the function
+		c.Error(apperr.Validation("invalid_feedback_id", "Invalid feedback ID", apperr.Field("feedbackId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                     // Return from the function
 	}
 
-	updatedFeedback.UpdatedAt = time.Now() // Update the updated_at timestamp
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	feedback, err := ctrl.repos.Feedback.FindByID(c.Request.Context(), objectFeedbackID) // Find the feedback to check the version the client read
+	if err != nil {                                                                      // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the feedback was not found
+			c.Error(apperr.NotFound("feedback_not_found", "Feedback not found")) // Return a not found response
+			return                                                               // Return from the function
+		}
+		c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+	fields := dto.ChangedFields(dto.NewEditFeedbackRequest(feedback), updatedFeedback)                                   // List the fields the request changes
+	if err := checkPatchFields(&dto.EditFeedbackRequest{}, fields, feedbackWritableFields(feedback, user)); err != nil { // Check that the user may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, feedback.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
 
-	// Find and update feedback
-	filter := bson.M{"_id": objectFeedbackID} // Define the filter
-	update := bson.M{"$set": bson.M{          // Define the update
-		"content":    updatedFeedback.Content,   // Define the update
-		"rating":     updatedFeedback.Rating,    // Update the content and rating
-		"updated_at": updatedFeedback.UpdatedAt, // Update the updated_at timestamp
-	}}
+	content, answers, filtered := ctrl.cleanFeedback(updatedFeedback.Content, feedback.Answers) // Mask the blocklisted words of the texts
 
-	result, err := feedbackCollection.UpdateOne(context.TODO(), filter, update) // Update the feedback
-	if err != nil {                                                             // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit feedback"}) // Return an error response
-		return                                                                            // Return from the function
+	edit := repository.FeedbackEdit{ // Define the edit
+		Version:   feedback.Version,          // Only edit the version the client read
+		Content:   content,                   // Update the content
+		Rating:    updatedFeedback.Rating,    // Update the rating
+		Anonymous: updatedFeedback.Anonymous, // Update the anonymous display choice
+		Answers:   answers,                   // Keep the answers, masked with the current blocklist
+	}
+	if filtered && feedback.Status != models.FeedbackStatusHidden { // Check if the texts contained blocklisted words, hidden feedback stays hidden
+		edit.Status = models.FeedbackStatusPending // Send the feedback back to the moderation queue
 	}
 
-	if result.MatchedCount == 0 { // Check if the feedback was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feedback not found"}) // Return a not found response
-		return                                                            // Return from the function
+	err = ctrl.editFeedback(c.Request.Context(), objectFeedbackID, edit, filtered) // Update the feedback
+	if err != nil {                                                                // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the feedback was not found
+			c.Error(apperr.NotFound("feedback_not_found", "Feedback not found")) // Return a not found response
+			return                                                               // Return from the function
+		}
+		c.Error(versionError(fmt.Errorf("failed to edit feedback: %w", err))) // Return an error response
+		return                                                                // Return from the function
 	}
 
+	setETag(c, feedback.Version+1)                                           // Tag the response with the new version of the feedback
 	c.JSON(http.StatusOK, gin.H{"message": "Feedback updated successfully"}) // Return a success response
 }
 
+// feedbackWritableFields returns the fields of the feedback the user may change: only its author may edit it,
+// moderators act on it through the moderation routes
+func feedbackWritableFields(feedback models.Feedback, user models.User) []string { // List the writable fields
+	if feedback.UserID == user.ID { // Check if the user wrote the feedback
+		return dto.Fields(dto.EditFeedbackRequest{}) // Every field
+	}
+	return nil // Nothing
+}
+
+// PatchFeedback: Applies a JSON merge patch to a feedback, only its author may change it
+func (ctrl *Controller) PatchFeedback(c *gin.Context) { // Patch a feedback
+	objectFeedbackID, err := primitive.ObjectIDFromHex(c.Param("feedbackId")) // Convert feedback ID to ObjectID
+	if err != nil {                                                           // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_feedback_id", "Invalid feedback ID", apperr.Field("feedbackId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                     // Return from the function
+	}
+
+	patch, fields, err := readMergePatch(c) // Read the patch
+	if err != nil {                         // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	feedback, err := ctrl.repos.Feedback.FindByID(c.Request.Context(), objectFeedbackID) // Find the feedback to patch
+	if err != nil {                                                                      // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the feedback was not found
+			c.Error(apperr.NotFound("feedback_not_found", "Feedback not found")) // Return a not found response
+			return                                                               // Return from the function
+		}
+		c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	request := dto.NewEditFeedbackRequest(feedback)                                                    // Describe the current state of the feedback
+	if err := checkPatchFields(&request, fields, feedbackWritableFields(feedback, user)); err != nil { // Check that the user may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, feedback.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
+	if err := applyMergePatch(&request, patch); err != nil { // Patch and validate the feedback
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	content, answers, filtered := ctrl.cleanFeedback(request.Content, feedback.Answers) // Mask the blocklisted words of the texts
+	edit := repository.FeedbackEdit{                                                    // Define the edit
+		Version:   feedback.Version,  // Only edit the version the client read
+		Content:   content,           // Set the content
+		Rating:    request.Rating,    // Set the rating
+		Anonymous: request.Anonymous, // Set the anonymous display choice
+		Answers:   answers,           // Keep the answers, masked with the current blocklist
+	}
+	if filtered && feedback.Status != models.FeedbackStatusHidden { // Check if the texts contained blocklisted words, hidden feedback stays hidden
+		edit.Status = models.FeedbackStatusPending // Send the feedback back to the moderation queue
+	}
+
+	err = ctrl.editFeedback(c.Request.Context(), objectFeedbackID, edit, filtered) // Update the feedback if nobody changed it in the meantime
+	if err != nil {                                                                // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the feedback was not found
+			c.Error(apperr.NotFound("feedback_not_found", "Feedback not found")) // Return a not found response
+			return                                                               // Return from the function
+		}
+		c.Error(versionError(fmt.Errorf("failed to patch feedback: %w", err))) // Return an error response
+		return                                                                 // Return from the function
+	}
+
+	patched, err := ctrl.repos.Feedback.FindByID(c.Request.Context(), objectFeedbackID) // Find the patched feedback
+	if err != nil {                                                                     // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	setETag(c, patched.Version)    // Tag the response with the new version of the feedback
+	c.JSON(http.StatusOK, patched) // Return the patched feedback
+}
+
+// editFeedback stores the edit of a feedback and, when its texts were filtered, the moderation entry of the
+// automatic filtering in one transaction
+func (ctrl *Controller) editFeedback(ctx context.Context, id primitive.ObjectID, edit repository.FeedbackEdit, filtered bool) error { // Edit a feedback
+	return ctrl.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error { // Run the edit in a transaction
+		if err := ctrl.repos.Feedback.Edit(ctx, id, edit); err != nil { // Update the feedback
+			return err // Return the error
+		}
+		if !filtered { // Check if the texts were not filtered
+			return nil // Nothing to audit
+		}
+		return ctrl.recordModerationAction(ctx, id, primitive.NilObjectID, "filtered", "blocklisted words masked") // Audit the automatic filtering
+	})
+}
+
+// cleanFeedback masks the blocklisted words of the content and of the free text answers of a feedback and reports
+// whether any was found. The answers are copied, the given slice is left unchanged.
+func (ctrl *Controller) cleanFeedback(content string, answers []models.FeedbackAnswer) (string, []models.FeedbackAnswer, bool) { // Clean the texts of a feedback
+	content, filtered := ctrl.feedbackFilter.Clean(content)     // Mask the blocklisted words of the content
+	cleaned := append([]models.FeedbackAnswer(nil), answers...) // Copy the answers
+	for i := range cleaned {                                    // Iterate over the answers
+		text, found := ctrl.feedbackFilter.Clean(cleaned[i].Text) // Mask the blocklisted words of the answer
+		cleaned[i].Text = text                                    // Keep the cleaned answer
+		filtered = filtered || found                              // Remember that a word was found
+	}
+	return content, cleaned, filtered // Return the cleaned texts
+}
+
 // DeleteFeedback: Manages deletion of feedback if necessary
-func DeleteFeedback(c *gin.Context) { // Delete feedback
+func (ctrl *Controller) DeleteFeedback(c *gin.Context) { // Delete feedback
 	feedbackID := c.Param("feedbackId") // Get feedback ID from the URL
 
 	objectFeedbackID, err := primitive.ObjectIDFromHex(feedbackID) // Convert feedback ID to ObjectID
 	if err != nil {                                                // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid feedback ID"}) // Return an error response
-		return                                                               // Return from the function
+		c.Error(apperr.Validation("invalid_feedback_id", "Invalid feedback ID", apperr.Field("feedbackId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                     // Return from the function
 	}
 
-	// Find and delete feedback
-	filter := bson.M{"_id": objectFeedbackID}                           // Define the filter
-	result, err := feedbackCollection.DeleteOne(context.TODO(), filter) // Delete the feedback
-	if err != nil {                                                     // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete feedback"}) // Return an error response
-		return                                                                              // Return from the function
-	}
-
-	if result.DeletedCount == 0 { // Check if the feedback was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feedback not found"}) // Return a not found response
-		return                                                            // Return from the function
+	err = ctrl.repos.Feedback.Delete(c.Request.Context(), objectFeedbackID) // Delete the feedback
+	if err != nil {                                                         // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the feedback was not found
+			c.Error(apperr.NotFound("feedback_not_found", "Feedback not found")) // Return a not found response
+			return                                                               // Return from the function
+		}
+		c.Error(fmt.Errorf("failed to delete feedback: %w", err)) // Return an error response
+		return                                                    // Return from the function
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Feedback deleted successfully"}) // Return a success response
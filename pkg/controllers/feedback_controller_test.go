@@ -0,0 +1,47 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"training_session/config"
+)
+
+func TestSubmitFeedback(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		answer  string // Free text answer to the form
+		status  string // Moderation status of the stored feedback
+		masked  string // Stored content
+		text    string // Stored answer
+	}{
+		{name: "clean feedback", content: "Great drills", answer: "More stretching", status: "approved", masked: "Great drills", text: "More stretching"},
+		{name: "blocklisted content", content: "Dumb drills", answer: "More stretching", status: "pending", masked: "**** drills", text: "More stretching"},
+		{name: "blocklisted answer", content: "Great drills", answer: "Less dumb warmups", status: "pending", masked: "Great drills", text: "Less **** warmups"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, func(cfg *config.Config) { cfg.FeedbackBlocklist = []string{"dumb"} })
+			owner := s.signUp("owner@example.com", "business owner")
+			ann := s.signUp("ann@example.com", "user")
+			id := s.createSession(owner, nil)
+			form := map[string]any{"training_type": "group", "criteria": []map[string]any{{"key": "improve", "label": "What should improve?", "type": "text"}}}
+			if res := s.do(http.MethodPost, "/feedback/forms", owner.token, form); res.status != http.StatusCreated {
+				t.Fatalf("create form: status %d, body %v", res.status, res.body)
+			}
+
+			res := s.do(http.MethodPost, "/feedback", ann.token, map[string]any{
+				"session_id": id, "coach_id": owner.id, "user_id": ann.id, "content": tt.content, "rating": 4,
+				"answers": []map[string]any{{"criterion": "improve", "text": tt.answer}},
+			})
+			if res.status != http.StatusCreated {
+				t.Fatalf("status %d, body %v", res.status, res.body)
+			}
+			answers, _ := res.body["answers"].([]any)
+			answer, _ := answers[0].(map[string]any)
+			if res.body["status"] != tt.status || res.body["content"] != tt.masked || answer["text"] != tt.text {
+				t.Errorf("stored %v, want status %q, content %q and answer %q", res.body, tt.status, tt.masked, tt.text)
+			}
+		})
+	}
+}
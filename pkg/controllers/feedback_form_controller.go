@@ -0,0 +1,455 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// canManageFeedbackForms checks whether the user is allowed to define feedback forms
+func canManageFeedbackForms(user models.User) bool { // Check the role of the user
+	return user.Role == models.RoleBusinessOwner || user.Role == models.RoleAdmin // Only business owners and admins manage forms
+}
+
+// feedbackFormExists is the error of a second form of an owner for a training type
+func feedbackFormExists() error { // Describe a duplicate form
+	return apperr.Conflict("feedback_form_exists", "A feedback form already exists for this training type, update it instead") // Return a conflict error
+}
+
+// ownedFeedbackForm loads the feedback form of the URL and responds with an error unless the user may change it:
+// business owners change their own forms, admins change every form
+func (ctrl *Controller) ownedFeedbackForm(c *gin.Context, user models.User) (models.FeedbackForm, bool) { // Get a form the user may change
+	objectFormID, err := primitive.ObjectIDFromHex(c.Param("formId")) // Convert form ID to ObjectID
+	if err != nil {                                                   // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_form_id", "Invalid form ID", apperr.Field("formId", "must be a valid ID"))) // Return an error response
+		return models.FeedbackForm{}, false                                                                            // Return from the function
+	}
+
+	form, err := ctrl.repos.FeedbackForms.FindByID(c.Request.Context(), objectFormID) // Find the form by ID
+	if errors.Is(err, repository.ErrNotFound) {                                       // Check if the form was not found
+		c.Error(apperr.NotFound("feedback_form_not_found", "Feedback form not found")) // Return a not found response
+		return form, false                                                             // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve feedback form: %w", err)) // Return an error response
+		return form, false                                               // Return from the function
+	}
+	if form.OwnerID != user.ID && user.Role != models.RoleAdmin { // Check if the form belongs to another business owner
+		c.Error(apperr.Forbidden("forbidden", "You can only manage your own feedback forms")) // Return a forbidden response
+		return form, false                                                                    // Return from the function
+	}
+	return form, true // Return the form
+}
+
+// validateFeedbackForm checks that a feedback form definition is consistent
+func validateFeedbackForm(form models.FeedbackForm) []apperr.FieldError { // Validate a feedback form
+	var problems []apperr.FieldError // Define a slice to hold the problems found
+
+	seen := map[string]bool{}                 // Keep track of the criterion keys already defined
+	for i, criterion := range form.Criteria { // Iterate over the criteria
+		if criterion.Key == "" { // Check if the key is missing
+			problems = append(problems, apperr.Field(fmt.Sprintf("criteria[%d].key", i), "is required")) // Add a problem
+			continue                                                                                     // Skip the other checks
+		}
+		if seen[criterion.Key] { // Check if the key is duplicated
+			problems = append(problems, apperr.Field(fmt.Sprintf("criteria[%d].key", i), fmt.Sprintf("duplicate key %q", criterion.Key))) // Add a problem
+		}
+		seen[criterion.Key] = true // Remember the key
+
+		switch criterion.Type { // Check the question type
+		case models.QuestionTypeScale: // Scale questions need a valid range
+			if criterion.Max <= criterion.Min { // Check if the range is empty
+				problems = append(problems, apperr.Field(fmt.Sprintf("criteria[%d].max", i), "must be greater than min")) // Add a problem
+			}
+		case models.QuestionTypeChoice: // Choice questions need options
+			if len(criterion.Options) == 0 { // Check if there are no options
+				problems = append(problems, apperr.Field(fmt.Sprintf("criteria[%d].options", i), "are required for choice questions")) // Add a problem
+			}
+		case models.QuestionTypeText: // Free text questions need nothing else
+		default: // Unknown question type
+			problems = append(problems, apperr.Field(fmt.Sprintf("criteria[%d].type", i), fmt.Sprintf("unknown type %q", criterion.Type))) // Add a problem
+		}
+	}
+
+	return problems // Return the problems found
+}
+
+// validateFeedbackAnswers checks the answers of a feedback submission against its form
+func validateFeedbackAnswers(form models.FeedbackForm, answers []models.FeedbackAnswer) []apperr.FieldError { // Validate answers against a form
+	var problems []apperr.FieldError // Define a slice to hold the problems found
+
+	criteria := map[string]models.FeedbackCriterion{} // Index the criteria by key
+	for _, criterion := range form.Criteria {         // Iterate over the criteria
+		criteria[criterion.Key] = criterion // Add the criterion to the index
+	}
+
+	answered := map[string]bool{}    // Keep track of the answered criteria
+	for _, answer := range answers { // Iterate over the answers
+		criterion, ok := criteria[answer.Criterion] // Find the criterion of the answer
+		if !ok {                                    // Check if the criterion does not exist
+			problems = append(problems, apperr.Field("answers."+answer.Criterion, "unknown criterion")) // Add a problem
+			continue                                                                                    // Skip the other checks
+		}
+		if answered[answer.Criterion] { // Check if the criterion was answered twice
+			problems = append(problems, apperr.Field("answers."+answer.Criterion, "answered more than once")) // Add a problem
+			continue                                                                                          // Skip the other checks
+		}
+		answered[answer.Criterion] = true // Remember the answer
+
+		switch criterion.Type { // Check the answer according to the question type
+		case models.QuestionTypeScale: // Scale answers need a score within the range
+			if answer.Score == nil { // Check if the score is missing
+				problems = append(problems, apperr.Field("answers."+answer.Criterion, "score is required")) // Add a problem
+			} else if *answer.Score < criterion.Min || *answer.Score > criterion.Max { // Check if the score is out of range
+				problems = append(problems, apperr.Field("answers."+answer.Criterion, fmt.Sprintf("score must be between %d and %d", criterion.Min, criterion.Max))) // Add a problem
+			}
+		case models.QuestionTypeChoice: // Choice answers need one of the options
+			valid := false                             // Assume the choice is not valid
+			for _, option := range criterion.Options { // Iterate over the options
+				if option == answer.Choice { // Check if the choice matches the option
+					valid = true // The choice is valid
+					break        // Stop searching
+				}
+			}
+			if !valid { // Check if the choice is not one of the options
+				problems = append(problems, apperr.Field("answers."+answer.Criterion, fmt.Sprintf("choice must be one of %v", criterion.Options))) // Add a problem
+			}
+		case models.QuestionTypeText: // Text answers need some text
+			if answer.Text == "" { // Check if the text is empty
+				problems = append(problems, apperr.Field("answers."+answer.Criterion, "text is required")) // Add a problem
+			}
+		}
+	}
+
+	for _, criterion := range form.Criteria { // Iterate over the criteria
+		if criterion.Required && !answered[criterion.Key] { // Check if a required criterion was not answered
+			problems = append(problems, apperr.Field("answers."+criterion.Key, "answer is required")) // Add a problem
+		}
+	}
+
+	return problems // Return the problems found
+}
+
+// findFeedbackForm finds the form a feedback must be validated against, either by ID or as the form the owner of the session
+// defined for its training type. A form given by ID must belong to the same owner and training type, so no lax form of another
+// owner can be picked to skip criteria.
+func (ctrl *Controller) findFeedbackForm(ctx context.Context, feedback models.Feedback) (*models.FeedbackForm, error) { // Find the form of a feedback
+	session, err := ctrl.repos.Sessions.FindByID(ctx, feedback.SessionID) // Find the session of the feedback
+	if errors.Is(err, repository.ErrNotFound) {                           // Check if the session was not found
+		return nil, apperr.Validation("session_not_found", "Session not found", apperr.Field("session_id", "must reference an existing session")) // Return a validation error
+	} else if err != nil { // Check if there is another error
+		return nil, err // Return the error
+	}
+
+	if !feedback.FormID.IsZero() { // Check if the form was given explicitly
+		form, err := ctrl.repos.FeedbackForms.FindByID(ctx, feedback.FormID) // Find the form by ID
+		if err != nil {                                                      // Check if there is an error
+			return nil, err // Return the error
+		}
+		if form.OwnerID != session.OwnerID || form.TrainingType != session.TrainingType { // Check if the form is meant for another owner or training type
+			return nil, apperr.Validation("feedback_form_mismatch", "The feedback form does not apply to the session", apperr.Field("form_id", "must be a form of the owner and training type of the session")) // Return a validation error
+		}
+		return &form, nil // Return the form
+	}
+
+	form, err := ctrl.repos.FeedbackForms.FindByOwner(ctx, session.OwnerID, session.TrainingType) // Find the form the owner of the session defined for its training type
+	if errors.Is(err, repository.ErrNotFound) {                                                   // Check if the owner defined no form for the training type
+		return nil, nil // No form applies
+	} else if err != nil { // Check if there is another error
+		return nil, err // Return the error
+	}
+
+	return &form, nil // Return the form
+}
+
+// CreateFeedbackForm: Allows business owners to define a feedback form for a training type
+func (ctrl *Controller) CreateFeedbackForm(c *gin.Context) { // Create a feedback form
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+	if !canManageFeedbackForms(user) { // Check if the user can manage forms
+		c.Error(apperr.Forbidden("forbidden", "You do not have the required permissions to manage feedback forms")) // Return a forbidden response
+		return                                                                                                      // Return from the function
+	}
+
+	var request dto.FeedbackFormRequest           // Define a form request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the form request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	form := request.ToModel()                                      // Get the form to create
+	if problems := validateFeedbackForm(form); len(problems) > 0 { // Validate the form
+		c.Error(apperr.Validation("invalid_feedback_form", "Invalid feedback form", problems...)) // Return a bad request response
+		return                                                                                    // Return from the function
+	}
+
+	form.ID = primitive.NewObjectID() // Generate a new ObjectID for the form
+	form.OwnerID = user.ID            // Set the owner of the form
+	form.CreatedAt = time.Now()       // Set the created_at timestamp
+	form.UpdatedAt = time.Now()       // Set the updated_at timestamp
+
+	err = ctrl.repos.FeedbackForms.Insert(c.Request.Context(), &form) // Insert the form
+	if errors.Is(err, repository.ErrConflict) {                       // Check if the user already has a form for the training type
+		c.Error(feedbackFormExists()) // Return a conflict response
+		return                        // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to create feedback form: %w", err)) // Return an error response
+		return                                                         // Return from the function
+	}
+
+	setETag(c, form.Version)         // Tag the response with the version of the form
+	c.JSON(http.StatusCreated, form) // Return the created form
+}
+
+// GetFeedbackForms: Lists the feedback forms, optionally filtered by training type
+func (ctrl *Controller) GetFeedbackForms(c *gin.Context) { // Get all feedback forms
+	trainingType := c.Query("training_type") // Get the optional training type filter
+
+	forms, err := ctrl.repos.FeedbackForms.FindAll(c.Request.Context(), trainingType) // Find the forms
+	if err != nil {                                                                   // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve feedback forms: %w", err)) // Return an error response
+		return                                                            // Return from the function
+	}
+
+	c.JSON(http.StatusOK, forms) // Return the forms
+}
+
+// GetFeedbackFormByID: Retrieves a feedback form so clients can render it
+func (ctrl *Controller) GetFeedbackFormByID(c *gin.Context) { // Get a feedback form by ID
+	objectFormID, err := primitive.ObjectIDFromHex(c.Param("formId")) // Convert form ID to ObjectID
+	if err != nil {                                                   // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_form_id", "Invalid form ID", apperr.Field("formId", "must be a valid ID"))) // Return an error response
+		return                                                                                                         // Return from the function
+	}
+
+	form, err := ctrl.repos.FeedbackForms.FindByID(c.Request.Context(), objectFormID) // Find the form by ID
+	if err != nil {                                                                   // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the form was not found
+			c.Error(apperr.NotFound("feedback_form_not_found", "Feedback form not found")) // Return a not found response
+		} else {
+			c.Error(fmt.Errorf("failed to retrieve feedback form: %w", err)) // Return an error response
+		}
+		return // Return from the function
+	}
+
+	if notModified(c, form.Version) { // Check if the client holds the current version
+		return // Return from the function
+	}
+	c.JSON(http.StatusOK, form) // Return the form
+}
+
+// UpdateFeedbackForm: Allows business owners to change the criteria of a feedback form
+func (ctrl *Controller) UpdateFeedbackForm(c *gin.Context) { // Update a feedback form
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+	if !canManageFeedbackForms(user) { // Check if the user can manage forms
+		c.Error(apperr.Forbidden("forbidden", "You do not have the required permissions to manage feedback forms")) // Return a forbidden response
+		return                                                                                                      // Return from the function
+	}
+
+	current, ok := ctrl.ownedFeedbackForm(c, user) // Get the form if the user may change it
+	if !ok {                                       // Check if the user may not change the form
+		return // Return from the function
+	}
+	if err := checkIfMatch(c, current.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
+
+	var request dto.FeedbackFormRequest           // Define a form request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the form request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	form := request.ToModel()                                      // Get the updated form
+	if problems := validateFeedbackForm(form); len(problems) > 0 { // Validate the form
+		c.Error(apperr.Validation("invalid_feedback_form", "Invalid feedback form", problems...)) // Return a bad request response
+		return                                                                                    // Return from the function
+	}
+
+	form.ID = current.ID           // Set the form ID
+	form.OwnerID = current.OwnerID // Keep the owner of the form
+	form.Version = current.Version // Only update the version the client read
+	form.UpdatedAt = time.Now()    // Set the updated_at timestamp
+
+	err = ctrl.repos.FeedbackForms.Update(c.Request.Context(), form) // Update the form
+	if err != nil {                                                  // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the form was not found
+			c.Error(apperr.NotFound("feedback_form_not_found", "Feedback form not found")) // Return a not found response
+			return                                                                         // Return from the function
+		}
+		if errors.Is(err, repository.ErrConflict) { // Check if the owner already has a form for the training type
+			c.Error(feedbackFormExists()) // Return a conflict response
+			return                        // Return from the function
+		}
+		c.Error(versionError(fmt.Errorf("failed to update feedback form: %w", err))) // Return an error response
+		return                                                                       // Return from the function
+	}
+
+	setETag(c, current.Version+1)                                                 // Tag the response with the new version of the form
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback form updated successfully"}) // Return a success response
+}
+
+// DeleteFeedbackForm: Removes a feedback form
+func (ctrl *Controller) DeleteFeedbackForm(c *gin.Context) { // Delete a feedback form
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+	if !canManageFeedbackForms(user) { // Check if the user can manage forms
+		c.Error(apperr.Forbidden("forbidden", "You do not have the required permissions to manage feedback forms")) // Return a forbidden response
+		return                                                                                                      // Return from the function
+	}
+
+	form, ok := ctrl.ownedFeedbackForm(c, user) // Get the form if the user may change it
+	if !ok {                                    // Check if the user may not change the form
+		return // Return from the function
+	}
+
+	err = ctrl.repos.FeedbackForms.Delete(c.Request.Context(), form.ID) // Delete the form
+	if err != nil {                                                     // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the form was not found
+			c.Error(apperr.NotFound("feedback_form_not_found", "Feedback form not found")) // Return a not found response
+			return                                                                         // Return from the function
+		}
+		c.Error(fmt.Errorf("failed to delete feedback form: %w", err)) // Return an error response
+		return                                                         // Return from the function
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback form deleted successfully"}) // Return a success response
+}
+
+// buildFeedbackReport aggregates the overall rating and the answers of each criterion
+func buildFeedbackReport(feedbacks []models.Feedback, forms []models.FeedbackForm) models.FeedbackReport { // Build a feedback report
+	report := models.FeedbackReport{Feedbacks: len(feedbacks), Criteria: []models.CriterionReport{}} // Define the report
+
+	index := map[string]int{}    // Index of each criterion in the report
+	scores := map[string]int{}   // Sum of the scores of each criterion
+	for _, form := range forms { // Iterate over the forms
+		for _, criterion := range form.Criteria { // Iterate over the criteria
+			if _, ok := index[criterion.Key]; ok { // Check if the criterion is already in the report
+				continue // Skip the criterion
+			}
+			index[criterion.Key] = len(report.Criteria)                       // Remember the position of the criterion
+			report.Criteria = append(report.Criteria, models.CriterionReport{ // Add the criterion to the report
+				Criterion:    criterion.Key,    // Set the key
+				Label:        criterion.Label,  // Set the label
+				Type:         criterion.Type,   // Set the type
+				Distribution: map[string]int{}, // Initialize the distribution
+			})
+		}
+	}
+
+	ratings := 0                         // Sum of the overall ratings
+	for _, feedback := range feedbacks { // Iterate over the feedback
+		ratings += feedback.Rating // Add the rating
+
+		for _, answer := range feedback.Answers { // Iterate over the answers
+			i, ok := index[answer.Criterion] // Find the criterion in the report
+			if !ok {                         // Check if the criterion is not part of any form
+				continue // Skip the answer
+			}
+			entry := &report.Criteria[i] // Get the entry of the criterion
+			entry.Responses++            // Count the answer
+
+			switch { // Aggregate according to the answer
+			case answer.Score != nil: // Scale answer
+				score := *answer.Score                         // Get the score
+				if entry.Responses == 1 || score < entry.Min { // Check if this is the lowest score
+					entry.Min = score // Update the lowest score
+				}
+				if entry.Responses == 1 || score > entry.Max { // Check if this is the highest score
+					entry.Max = score // Update the highest score
+				}
+				scores[answer.Criterion] += score         // Add the score
+				entry.Distribution[strconv.Itoa(score)]++ // Count the score
+			case answer.Choice != "": // Choice answer
+				entry.Distribution[answer.Choice]++ // Count the option
+			}
+		}
+	}
+
+	if len(feedbacks) > 0 { // Check if there is any feedback
+		report.AverageRating = float64(ratings) / float64(len(feedbacks)) // Compute the average rating
+	}
+	for i := range report.Criteria { // Iterate over the criteria
+		entry := &report.Criteria[i]                                       // Get the entry of the criterion
+		if entry.Type == models.QuestionTypeScale && entry.Responses > 0 { // Check if the criterion has scores
+			entry.Average = float64(scores[entry.Criterion]) / float64(entry.Responses) // Compute the average score
+		}
+	}
+
+	return report // Return the report
+}
+
+// feedbackReport loads the feedback matching the filter and the forms they were submitted against, then builds the report
+func (ctrl *Controller) feedbackReport(c *gin.Context, filter repository.FeedbackFilter) { // Respond with a feedback report
+	filter.ExcludeStatus = models.FeedbackStatusHidden // Hidden feedback is never part of a report
+
+	feedbacks, err := ctrl.repos.Feedback.Find(c.Request.Context(), filter) // Find the feedback
+	if err != nil {                                                         // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	formIDs := []primitive.ObjectID{}     // Define a slice to hold the form IDs
+	seen := map[primitive.ObjectID]bool{} // Keep track of the form IDs already added
+	for _, feedback := range feedbacks {  // Iterate over the feedback
+		if !feedback.FormID.IsZero() && !seen[feedback.FormID] { // Check if the form was not added yet
+			seen[feedback.FormID] = true               // Remember the form
+			formIDs = append(formIDs, feedback.FormID) // Add the form ID
+		}
+	}
+
+	var forms []models.FeedbackForm // Define a forms variable
+	if len(formIDs) > 0 {           // Check if any feedback was submitted against a form
+		forms, err = ctrl.repos.FeedbackForms.FindByIDs(c.Request.Context(), formIDs) // Find the forms
+		if err != nil {                                                               // Check if there is an error
+			c.Error(fmt.Errorf("failed to retrieve feedback forms: %w", err)) // Return an error response
+			return                                                            // Return from the function
+		}
+	}
+
+	c.JSON(http.StatusOK, buildFeedbackReport(feedbacks, forms)) // Return the report
+}
+
+// GetCoachFeedbackReport: Breaks down the ratings received by a coach by criterion
+func (ctrl *Controller) GetCoachFeedbackReport(c *gin.Context) { // Get the feedback report of a coach
+	objectCoachID, err := primitive.ObjectIDFromHex(c.Param("coachId")) // Convert coach ID to ObjectID
+	if err != nil {                                                     // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_coach_id", "Invalid coach ID", apperr.Field("coachId", "must be a valid ID"))) // Return an error response
+		return                                                                                                            // Return from the function
+	}
+
+	ctrl.feedbackReport(c, repository.FeedbackFilter{CoachID: objectCoachID}) // Respond with the report of the coach
+}
+
+// GetSessionFeedbackReport: Breaks down the ratings received by a session by criterion
+func (ctrl *Controller) GetSessionFeedbackReport(c *gin.Context) { // Get the feedback report of a session
+	objectSessionID, err := primitive.ObjectIDFromHex(c.Param("sessionId")) // Convert session ID to ObjectID
+	if err != nil {                                                         // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                  // Return from the function
+	}
+
+	ctrl.feedbackReport(c, repository.FeedbackFilter{SessionID: objectSessionID}) // Respond with the report of the session
+}
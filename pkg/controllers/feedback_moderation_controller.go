@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordModerationAction appends an action to the moderation audit log. Called in the transaction of the action, so
+// the action and its entry are committed together.
+func (ctrl *Controller) recordModerationAction(ctx context.Context, feedbackID, actorID primitive.ObjectID, action, reason string) error { // Record a moderation action
+	entry := models.ModerationAction{ // Define the audit entry
+		ID:         primitive.NewObjectID(), // Generate a new ObjectID for the entry
+		FeedbackID: feedbackID,              // Set the feedback
+		ActorID:    actorID,                 // Set the actor
+		Action:     action,                  // Set the action
+		Reason:     reason,                  // Set the reason
+		CreatedAt:  time.Now(),              // Set the created_at timestamp
+	}
+
+	if err := ctrl.repos.ModerationLog.Insert(ctx, &entry); err != nil { // Insert the entry
+		return fmt.Errorf("failed to record the %s moderation action: %w", action, err) // Return the error
+	}
+	return nil // Return nil
+}
+
+// publicFeedback strips the fields that must not be shown publicly from a feedback
+func publicFeedback(feedback models.Feedback) models.Feedback { // Prepare a feedback for public display
+	if feedback.Anonymous { // Check if the author asked to stay anonymous
+		feedback.UserID = primitive.NilObjectID // Hide the author
+	}
+	feedback.Flags = nil // Hide the flags raised by other users
+	return feedback      // Return the public feedback
+}
+
+// findFeedback loads a feedback by the ID found in the URL, responding with an error if it cannot be found
+func (ctrl *Controller) findFeedback(c *gin.Context) (models.Feedback, bool) { // Find the feedback of the request
+	objectFeedbackID, err := primitive.ObjectIDFromHex(c.Param("feedbackId")) // Convert feedback ID to ObjectID
+	if err != nil {                                                           // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_feedback_id", "Invalid feedback ID", apperr.Field("feedbackId", "must be a valid ID"))) // Return an error response
+		return models.Feedback{}, false                                                                                            // Return from the function
+	}
+
+	feedback, err := ctrl.repos.Feedback.FindByID(c.Request.Context(), objectFeedbackID) // Find the feedback by ID
+	if err != nil {                                                                      // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the feedback was not found
+			c.Error(apperr.NotFound("feedback_not_found", "Feedback not found")) // Return a not found response
+		} else {
+			c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		}
+		return feedback, false // Return from the function
+	}
+
+	return feedback, true // Return the feedback
+}
+
+// GetCoachFeedback: Lists the approved feedback received by a coach, as shown publicly
+func (ctrl *Controller) GetCoachFeedback(c *gin.Context) { // Get the public feedback of a coach
+	objectCoachID, err := primitive.ObjectIDFromHex(c.Param("coachId")) // Convert coach ID to ObjectID
+	if err != nil {                                                     // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_coach_id", "Invalid coach ID", apperr.Field("coachId", "must be a valid ID"))) // Return an error response
+		return                                                                                                            // Return from the function
+	}
+
+	filter := repository.FeedbackFilter{CoachID: objectCoachID, Status: models.FeedbackStatusApproved} // Only approved feedback is public
+	feedbacks, err := ctrl.repos.Feedback.Find(c.Request.Context(), filter)                            // Find the feedback
+	if err != nil {                                                                                    // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	for i := range feedbacks { // Iterate over the feedback
+		feedbacks[i] = publicFeedback(feedbacks[i]) // Keep the public view of the feedback
+	}
+
+	c.JSON(http.StatusOK, feedbacks) // Return the feedbacks
+}
+
+// FlagFeedback: Allows users to report a feedback, sending it back to the moderation queue
+func (ctrl *Controller) FlagFeedback(c *gin.Context) { // Flag a feedback
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	var input dto.FlagFeedbackRequest           // Define the expected input
+	if err := bindJSON(c, &input); err != nil { // Bind the JSON to the input struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	feedback, ok := ctrl.findFeedback(c) // Find the feedback
+	if !ok {                             // Check if the feedback was not found
+		return // Return from the function
+	}
+
+	for _, flag := range feedback.Flags { // Iterate over the existing flags
+		if flag.UserID == user.ID { // Check if the user already flagged the feedback
+			c.Error(apperr.Conflict("already_flagged", "Feedback already flagged by this user")) // Return a conflict response
+			return                                                                               // Return from the function
+		}
+	}
+
+	flag := models.FeedbackFlag{UserID: user.ID, Reason: input.Reason, CreatedAt: time.Now()} // Define the flag
+	status := ""                                                                              // Keep the current status by default
+	if feedback.Status == models.FeedbackStatusApproved {                                     // Check if the feedback is currently public
+		status = models.FeedbackStatusPending // Send the feedback back to the moderation queue
+	}
+
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Flag the feedback and audit the flag together
+		if err := ctrl.repos.Feedback.AddFlag(ctx, feedback.ID, flag, status); err != nil { // Update the feedback
+			return err // Return the error
+		}
+		return ctrl.recordModerationAction(ctx, feedback.ID, user.ID, "flagged", input.Reason) // Audit the flag
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to flag feedback: %w", err)) // Return an error response
+		return                                                  // Return from the function
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback flagged successfully"}) // Return a success response
+}
+
+// GetModerationQueue: Lists the feedback waiting for a moderator, oldest first
+func (ctrl *Controller) GetModerationQueue(c *gin.Context) { // Get the moderation queue
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	filter := repository.FeedbackFilter{Status: models.FeedbackStatusPending} // Only pending feedback waits for a moderator
+	feedbacks, err := ctrl.repos.Feedback.Find(c.Request.Context(), filter)   // Find the pending feedback, oldest first
+	if err != nil {                                                           // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve moderation queue: %w", err)) // Return an error response
+		return                                                              // Return from the function
+	}
+
+	c.JSON(http.StatusOK, feedbacks) // Return the queue
+}
+
+// moderateFeedback sets the moderation status of a feedback on behalf of an admin
+func (ctrl *Controller) moderateFeedback(c *gin.Context, status, action string) { // Moderate a feedback
+	admin, ok := ctrl.requireAdmin(c) // Check that the user is an admin
+	if !ok {                          // Check if the user is not an admin
+		return // Return from the function
+	}
+
+	var input dto.ModerationRequest   // Define the optional input
+	if c.Request.ContentLength != 0 { // Check if a body was sent, it is optional
+		if err := bindJSON(c, &input); err != nil { // Bind the JSON to the input struct
+			c.Error(err) // Return a bad request response
+			return       // Return from the function
+		}
+	}
+
+	feedback, ok := ctrl.findFeedback(c) // Find the feedback
+	if !ok {                             // Check if the feedback was not found
+		return // Return from the function
+	}
+
+	err := ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Moderate the feedback and audit the decision together
+		if err := ctrl.repos.Feedback.SetStatus(ctx, feedback.ID, status); err != nil { // Update the feedback
+			return err // Return the error
+		}
+		return ctrl.recordModerationAction(ctx, feedback.ID, admin.ID, action, input.Reason) // Audit the decision
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to moderate feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback " + action + " successfully"}) // Return a success response
+}
+
+// ApproveFeedback: Allows admins to publish a feedback from the moderation queue
+func (ctrl *Controller) ApproveFeedback(c *gin.Context) { // Approve a feedback
+	ctrl.moderateFeedback(c, models.FeedbackStatusApproved, "approved") // Approve the feedback
+}
+
+// HideFeedback: Allows admins to hide a feedback from public views
+func (ctrl *Controller) HideFeedback(c *gin.Context) { // Hide a feedback
+	ctrl.moderateFeedback(c, models.FeedbackStatusHidden, "hidden") // Hide the feedback
+}
+
+// ReplyToFeedback: Allows the coach who received a feedback to post one public reply
+func (ctrl *Controller) ReplyToFeedback(c *gin.Context) { // Reply to a feedback
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	var input dto.ReplyRequest                  // Define the expected input
+	if err := bindJSON(c, &input); err != nil { // Bind the JSON to the input struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	feedback, ok := ctrl.findFeedback(c) // Find the feedback
+	if !ok {                             // Check if the feedback was not found
+		return // Return from the function
+	}
+
+	if feedback.CoachID != user.ID { // Check if the user is not the coach of the feedback
+		c.Error(apperr.Forbidden("not_feedback_coach", "Only the coach who received the feedback can reply")) // Return a forbidden response
+		return                                                                                                // Return from the function
+	}
+
+	content, filtered := ctrl.feedbackFilter.Clean(input.Content)                         // Mask the blocklisted words of the reply
+	reply := models.CoachReply{CoachID: user.ID, Content: content, CreatedAt: time.Now()} // Define the reply
+
+	reason := ""  // Define the reason of the audit entry
+	if filtered { // Check if the reply contained blocklisted words
+		reason = "blocklisted words masked" // Record that the reply was filtered
+	}
+
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Store the reply and audit it together
+		if err := ctrl.repos.Feedback.SetReply(ctx, feedback.ID, reply); err != nil { // Update the feedback
+			return err // Return the error
+		}
+		return ctrl.recordModerationAction(ctx, feedback.ID, user.ID, "replied", reason) // Audit the reply
+	})
+	if err != nil { // Check if there is an error
+		if errors.Is(err, repository.ErrConflict) { // Check if the feedback already has a reply
+			c.Error(apperr.Conflict("already_replied", "Feedback already has a reply")) // Return a conflict response
+			return                                                                      // Return from the function
+		}
+		c.Error(fmt.Errorf("failed to reply to feedback: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	c.JSON(http.StatusCreated, reply) // Return the reply
+}
+
+// GetModerationLog: Lists the moderation actions performed on a feedback
+func (ctrl *Controller) GetModerationLog(c *gin.Context) { // Get the moderation log of a feedback
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	objectFeedbackID, err := primitive.ObjectIDFromHex(c.Param("feedbackId")) // Convert feedback ID to ObjectID
+	if err != nil {                                                           // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_feedback_id", "Invalid feedback ID", apperr.Field("feedbackId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                     // Return from the function
+	}
+
+	actions, err := ctrl.repos.ModerationLog.FindByFeedback(c.Request.Context(), objectFeedbackID) // Find the actions, oldest first
+	if err != nil {                                                                                // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve moderation log: %w", err)) // Return an error response
+		return                                                            // Return from the function
+	}
+
+	c.JSON(http.StatusOK, actions) // Return the actions
+}
@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestModerateFeedback(t *testing.T) {
+	tests := []struct {
+		name   string
+		admin  bool // Whether an admin moderates, a member otherwise
+		body   any
+		status int
+		code   string
+	}{
+		{name: "without a body", admin: true, status: http.StatusOK},
+		{name: "with a reason", admin: true, body: map[string]any{"reason": "Offensive"}, status: http.StatusOK},
+		{name: "body that is not an object", admin: true, body: "hide", status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "reason too long", admin: true, body: map[string]any{"reason": strings.Repeat("a", 501)}, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "member", body: map[string]any{"reason": "Offensive"}, status: http.StatusForbidden, code: "forbidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			coach := s.signUp("coach@example.com", "coach")
+			ann := s.signUp("ann@example.com", "user")
+			caller := ann
+			if tt.admin {
+				caller = s.signUpAdmin("admin@example.com")
+			}
+			id := s.createSession(coach, nil)
+			res := s.do(http.MethodPost, "/feedback", ann.token, map[string]any{"session_id": id, "coach_id": coach.id, "user_id": ann.id, "content": "Great drills", "rating": 4})
+			if res.status != http.StatusCreated {
+				t.Fatalf("submit feedback: status %d, body %v", res.status, res.body)
+			}
+			feedbackID, _ := res.body["id"].(string)
+
+			res = s.do(http.MethodPost, "/feedback/"+feedbackID+"/hide", caller.token, tt.body)
+			if res.status != tt.status || (tt.code != "" && res.code() != tt.code) {
+				t.Fatalf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+
+			objectID, _ := primitive.ObjectIDFromHex(feedbackID)
+			feedback, err := s.repos.Feedback.FindByID(context.Background(), objectID)
+			if err != nil {
+				t.Fatalf("find feedback: %v", err)
+			}
+			actions, err := s.repos.ModerationLog.FindByFeedback(context.Background(), objectID)
+			if err != nil {
+				t.Fatalf("find moderation log: %v", err)
+			}
+			hidden := tt.status == http.StatusOK
+			if (feedback.Status == "hidden") != hidden || (len(actions) == 1) != hidden {
+				t.Errorf("status %q with %d moderation actions, want hidden and logged: %v", feedback.Status, len(actions), hidden)
+			}
+		})
+	}
+}
@@ -1,132 +1,104 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
 	"training_session/pkg/models"
+	"training_session/pkg/repository"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var invitationCollection *mongo.Collection // Define an invitationCollection variable
-
-func InitializeInvitation(database *mongo.Database) { // Initialize the controllers
-	invitationCollection = database.Collection("invitations") // Set the invitation collection
-}
-
-func SendInvitation(c *gin.Context) { // Send an invitation for a private training session
-	var invitation models.Invitation                // Define an invitation variable
-	if err := c.BindJSON(&invitation); err != nil { // Bind the JSON to the invitation struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Return a bad request response
-		return                                                     // Return from the function
+func (ctrl *Controller) SendInvitation(c *gin.Context) { // Send an invitation for a private training session
+	var request dto.InvitationRequest             // Define an invitation request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the invitation request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
+	invitation := request.ToModel()      // Get the pending invitation
+	if invitation.InvitationDate == "" { // Check if the invitation date was omitted
+		invitation.InvitationDate = time.Now().Format(time.RFC3339) // Date the invitation now
+	}
 	invitation.ID = primitive.NewObjectID() // Generate a new ObjectID for the invitation
 	invitation.CreatedAt = time.Now()       // Set the created_at timestamp
 	invitation.UpdatedAt = time.Now()       // Set the updated_at timestamp
 
-	_, err := invitationCollection.InsertOne(context.TODO(), invitation) // Insert the invitation
-	if err != nil {                                                      // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	err := ctrl.repos.Invitations.Insert(c.Request.Context(), &invitation) // Insert the invitation
+	if err != nil {                                                        // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
-	c.JSON(http.StatusCreated, invitation) // Return the created invitation
+	describeChange(c, "invitations", invitation.ID, nil, invitation) // Record the created fields in the audit log
+	c.JSON(http.StatusCreated, invitation)                           // Return the created invitation
 }
 
-func AcceptInvitation(c *gin.Context) { // Handle user acceptance of session invitations
+// setInvitationStatus changes the status of the invitation found in the URL
+func (ctrl *Controller) setInvitationStatus(c *gin.Context, status string) bool { // Change the status of an invitation
 	invitationID := c.Param("invitationId") // Get the invitation ID from the URL
 
 	// Convert invitationID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(invitationID) // Convert the invitation ID to an ObjectID
 	if err != nil {                                          // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"}) // Return a bad request response
-		return                                                                 // Return from the function
+		c.Error(apperr.Validation("invalid_invitation_id", "Invalid invitation ID", apperr.Field("invitationId", "must be a valid ID"))) // Return a bad request response
+		return false                                                                                                                     // Return from the function
 	}
 
-	// Update the invitation document to mark as accepted
-	filter := bson.M{"_id": objectID}                                             // Define the filter
-	update := bson.M{"$set": bson.M{"status": "accepted"}}                        // Define the update
-	result, err := invitationCollection.UpdateOne(context.TODO(), filter, update) // Update the invitation
+	// Update the invitation document with the new status
+	err = ctrl.repos.Invitations.SetStatus(c.Request.Context(), objectID, status) // Update the invitation
 	if err != nil {                                                               // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
-	}
-
-	if result.MatchedCount == 0 { // Check if the invitation was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"}) // Return a not found response
-		return                                                              // Return from the function
+		if errors.Is(err, repository.ErrNotFound) { // Check if the invitation was not found
+			c.Error(apperr.NotFound("invitation_not_found", "Invitation not found")) // Return a not found response
+			return false                                                             // Return from the function
+		}
+		c.Error(err) // Return an error response
+		return false // Return from the function
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted"}) // Return a success response
+	return true // The status was changed
 }
 
-func DeclineInvitation(c *gin.Context) { // Manage user decline of session invitations
-	invitationID := c.Param("invitationId") // Get the invitation ID from the URL
-
-	// Convert invitationID to ObjectID
-	objectID, err := primitive.ObjectIDFromHex(invitationID) // Convert the invitation ID to an ObjectID
-	if err != nil {                                          // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"}) // Return a bad request response
-		return                                                                 // Return from the function
-	}
-
-	// Update the invitation document to mark as declined
-	filter := bson.M{"_id": objectID}                                             // Define the filter
-	update := bson.M{"$set": bson.M{"status": "declined"}}                        // Define the update
-	result, err := invitationCollection.UpdateOne(context.TODO(), filter, update) // Update the invitation
-	if err != nil {                                                               // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+func (ctrl *Controller) AcceptInvitation(c *gin.Context) { // Handle user acceptance of session invitations
+	if ctrl.setInvitationStatus(c, models.InvitationStatusAccepted) { // Mark the invitation as accepted
+		c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted"}) // Return a success response
 	}
+}
 
-	if result.MatchedCount == 0 { // Check if the invitation was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"}) // Return a not found response
-		return                                                              // Return from the function
+func (ctrl *Controller) DeclineInvitation(c *gin.Context) { // Manage user decline of session invitations
+	if ctrl.setInvitationStatus(c, models.InvitationStatusDeclined) { // Mark the invitation as declined
+		c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"}) // Return a success response
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"}) // Return a success response
 }
 
-func GetInvitations(c *gin.Context) { // Get all invitations
-	var invitations []models.Invitation                                  // Define a slice to hold the invitations
-	cursor, err := invitationCollection.Find(context.TODO(), bson.D{{}}) // Find all invitations
-	if err != nil {                                                      // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
+func (ctrl *Controller) GetInvitations(c *gin.Context) { // Get all invitations
+	invitations, err := ctrl.repos.Invitations.FindAll(c.Request.Context()) // Find all invitations
+	if err != nil {                                                         // Check if there is an error
+		c.Error(err) // Return an error response
 		return
 	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-	for cursor.Next(context.TODO()) {  // Iterate over the cursor
-		var invitation models.Invitation                   // Define an invitation variable
-		if err := cursor.Decode(&invitation); err != nil { // Decode the invitation
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-			return
-		}
-		invitations = append(invitations, invitation) // Append the invitation to the slice
-	}
 	c.JSON(http.StatusOK, invitations) // Return a success response
 }
 
-func GetInvitationByID(c *gin.Context) { // Get an invitation by ID
+func (ctrl *Controller) GetInvitationByID(c *gin.Context) { // Get an invitation by ID
 	invitationID := c.Param("invitationId") // Get the invitation ID from the URL
 
 	objectID, err := primitive.ObjectIDFromHex(invitationID) // Convert ID to ObjectID
 	if err != nil {                                          // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"}) // Return a bad request response
+		c.Error(apperr.Validation("invalid_invitation_id", "Invalid invitation ID", apperr.Field("invitationId", "must be a valid ID"))) // Return a bad request response
 		return
 	}
 
-	var invitation models.Invitation                                                                // Define an invitation variable
-	err = invitationCollection.FindOne(context.TODO(), bson.M{"_id": objectID}).Decode(&invitation) // Find and decode the invitation
-	if err != nil {                                                                                 // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if no documents were found
-			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"}) // Return a not found response
+	invitation, err := ctrl.repos.Invitations.FindByID(c.Request.Context(), objectID) // Find the invitation
+	if err != nil {                                                                   // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if no documents were found
+			c.Error(apperr.NotFound("invitation_not_found", "Invitation not found")) // Return a not found response
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
+			c.Error(err) // Return an error response
 		}
 		return
 	}
@@ -134,25 +106,31 @@ func GetInvitationByID(c *gin.Context) { // Get an invitation by ID
 	c.JSON(http.StatusOK, invitation) // Return the invitation
 }
 
-func DeleteInvitation(c *gin.Context) { // Delete an invitation
+func (ctrl *Controller) DeleteInvitation(c *gin.Context) { // Delete an invitation
 	invitationID := c.Param("invitationId") // Get the invitation ID from the URL
 
 	objectID, err := primitive.ObjectIDFromHex(invitationID) // Convert ID to ObjectID
 	if err != nil {                                          // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"}) // Return a bad request response
+		c.Error(apperr.Validation("invalid_invitation_id", "Invalid invitation ID", apperr.Field("invitationId", "must be a valid ID"))) // Return a bad request response
 		return
 	}
 
-	result, err := invitationCollection.DeleteOne(context.TODO(), bson.M{"_id": objectID}) // Delete the invitation
-	if err != nil {                                                                        // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
+	deletion, err := ctrl.newDeletion(c, "Invitation deleted") // Describe the deletion
+	if err != nil {                                            // Check if there is an error
+		c.Error(err) // Return a bad request response
 		return
 	}
 
-	if result.DeletedCount == 0 { // Check if no document was deleted
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"}) // Return a not found response
+	err = ctrl.repos.Invitations.SoftDelete(c.Request.Context(), objectID, deletion) // Soft delete the invitation
+	if err != nil {                                                                  // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if no document was deleted
+			c.Error(apperr.NotFound("invitation_not_found", "Invitation not found")) // Return a not found response
+			return
+		}
+		c.Error(err) // Return an error response
 		return
 	}
 
+	describeChange(c, "invitations", objectID, models.Deletion{}, deletion)    // Record the changed fields in the audit log
 	c.JSON(http.StatusOK, gin.H{"message": "Invitation deleted successfully"}) // Return a success response
 }
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// readMergePatch reads the JSON merge patch sent as the body of a PATCH request and returns it with the
+// fields it changes. The body must be a JSON object sent as application/merge-patch+json or application/json.
+func readMergePatch(c *gin.Context) ([]byte, []string, error) { // Read a merge patch
+	if contentType := c.ContentType(); contentType != dto.MergePatchContentType && contentType != binding.MIMEJSON { // Check the media type
+		return nil, nil, apperr.UnsupportedMediaType("unsupported_media_type", "Send the patch as "+dto.MergePatchContentType) // Return an unsupported media type error
+	}
+
+	patch, err := io.ReadAll(c.Request.Body) // Read the patch
+	if err != nil {                          // Check if there is an error
+		return nil, nil, apperr.Validation("invalid_patch", "The patch could not be read").Wrap(err) // Return a bad request error
+	}
+
+	var changes map[string]json.RawMessage                                    // Define the changed fields
+	if err := json.Unmarshal(patch, &changes); err != nil || changes == nil { // Decode the patch, null is not an object either
+		return nil, nil, apperr.Validation("invalid_patch", "The patch must be a JSON object").Wrap(err) // Return a bad request error
+	}
+	fields := make([]string, 0, len(changes)) // Define a slice to hold the changed fields
+	for field := range changes {              // Iterate over the changes
+		fields = append(fields, field) // Add the field
+	}
+	slices.Sort(fields)       // Report the fields in a stable order
+	return patch, fields, nil // Return the patch and its fields
+}
+
+// checkPatchFields rejects the patches changing fields the resource does not have or exposes read-only (400)
+// and the fields the caller may not write (403); writable lists the fields the caller may write, none when
+// they may not change the resource at all
+func checkPatchFields(req interface{}, fields, writable []string) error { // Check the fields of a patch
+	if len(writable) == 0 { // Check if the caller may not change the resource at all
+		return apperr.Forbidden("forbidden", "You do not have the required permissions to change this resource") // Return a forbidden error
+	}
+	known := dto.Fields(req)                   // Get the fields of the resource a patch may set
+	var unknown, forbidden []apperr.FieldError // Define the rejected fields
+	for _, field := range fields {             // Iterate over the changed fields
+		switch { // Check the field
+		case !slices.Contains(known, field): // Field the patch may not set
+			unknown = append(unknown, apperr.Field(field, "is not a writable field")) // Add the field
+		case !slices.Contains(writable, field): // Field the caller may not write
+			forbidden = append(forbidden, apperr.Field(field, "may not be changed by you")) // Add the field
+		}
+	}
+	if len(unknown) > 0 { // Check if the patch sets unknown or read-only fields
+		return apperr.Validation("invalid_patch", "The patch changes fields that cannot be written", unknown...) // Return a bad request error
+	}
+	if len(forbidden) > 0 { // Check if the patch sets fields the caller may not write
+		return apperr.Forbidden("field_forbidden", "You may not change some of the fields of the patch", forbidden...) // Return a forbidden error
+	}
+	return nil // Every field may be written
+}
+
+// applyMergePatch merges the patch into req, the request describing the current state of the resource, and
+// checks the patched state with the binding rules of the request
+func applyMergePatch(req interface{}, patch []byte) error { // Patch a request
+	if err := dto.Patch(req, patch); err != nil { // Merge the patch
+		return bindingError(err, "invalid_patch", "The patch is invalid") // Return a bad request error
+	}
+	return bindingError(binding.Validator.ValidateStruct(req), "invalid_patch", "The patched resource is invalid") // Validate the patched state
+}
@@ -2,212 +2,264 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
 	"training_session/pkg/models"
+	"training_session/pkg/repository"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var (
-	pitchBookingCollection *mongo.Collection // Define a pitchBookingCollection variable
-)
-
-// InitializePitchBookingController initializes the pitch booking controller
-func InitializePitchBooking(database *mongo.Database) {
-	pitchBookingCollection = database.Collection("pitch_bookings") // Set the pitch booking collection
-}
-
 // GetPitchBookings retrieves all pitch bookings
-func GetPitchBookings(c *gin.Context) {
-	var pitchBookings []models.Pitch                                       // Define a pitchBookings variable
-	cursor, err := pitchBookingCollection.Find(context.TODO(), bson.D{{}}) // Find all pitch bookings
-	if err != nil {                                                        // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
-	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-	for cursor.Next(context.TODO()) {  // Iterate over the cursor
-		var pitchBooking models.Pitch                        // Define a pitchBooking variable
-		if err := cursor.Decode(&pitchBooking); err != nil { // Decode the pitch booking
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-			return                                                              // Return from the function
-		}
-		pitchBookings = append(pitchBookings, pitchBooking) // Append the pitch booking to the pitchBookings slice
+func (ctrl *Controller) GetPitchBookings(c *gin.Context) {
+	pitchBookings, err := ctrl.repos.Pitches.FindAll(c.Request.Context()) // Find all pitch bookings
+	if err != nil {                                                       // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 	c.JSON(http.StatusOK, pitchBookings) // Return a success response
 }
 
 // GetPitchBookingByID retrieves a pitch booking by ID
-func GetPitchBookingByID(c *gin.Context) {
+func (ctrl *Controller) GetPitchBookingByID(c *gin.Context) {
 	pitchBookingID := c.Param("pitchId") // Get the pitch booking ID from the URL
 
 	objectID, err := primitive.ObjectIDFromHex(pitchBookingID) // Convert ID to ObjectID
 	if err != nil {                                            // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pitch booking ID"}) // Return a bad request response
-		return                                                                    // Return from the function
+		c.Error(apperr.Validation("invalid_pitch_booking_id", "Invalid pitch booking ID", apperr.Field("pitchId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                            // Return from the function
 	}
 
-	var pitchBooking models.Pitch                                                                       // Define a pitchBooking variable
-	err = pitchBookingCollection.FindOne(context.TODO(), bson.M{"_id": objectID}).Decode(&pitchBooking) // Find and decode the pitch booking
-	if err != nil {                                                                                     // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if no documents were found
-			c.JSON(http.StatusNotFound, gin.H{"error": "Pitch booking not found"}) // Return a not found response
+	pitchBooking, err := ctrl.repos.Pitches.FindByID(c.Request.Context(), objectID) // Find the pitch booking
+	if err != nil {                                                                 // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if no documents were found
+			c.Error(apperr.NotFound("pitch_booking_not_found", "Pitch booking not found")) // Return a not found response
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
+			c.Error(err) // Return an error response
 		}
 		return
 	}
 
+	if notModified(c, pitchBooking.Version) { // Check if the client holds the current version
+		return // Return from the function
+	}
 	c.JSON(http.StatusOK, pitchBooking) // Return the pitch booking
 }
 
 // CreatePitchBooking creates a new pitch booking
-func BookPitch(c *gin.Context) {
-	var pitchBooking models.Pitch                     // Define a pitchBooking variable
-	if err := c.BindJSON(&pitchBooking); err != nil { // Bind the JSON to the pitch booking struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Return a bad request response
-		return                                                     // Return from the function
+func (ctrl *Controller) BookPitch(c *gin.Context) {
+	var request dto.PitchRequest                  // Define a pitch booking request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the pitch booking request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
+	var pitchBooking models.Pitch             // Define a pitchBooking variable
+	request.Apply(&pitchBooking)              // Copy the fields of the request
 	pitchBooking.ID = primitive.NewObjectID() // Generate a new ObjectID for the pitch booking
 	pitchBooking.CreatedAt = time.Now()       // Set the created_at timestamp
 	pitchBooking.UpdatedAt = time.Now()       // Set the updated_at timestamp
 
-	_, err := pitchBookingCollection.InsertOne(context.TODO(), pitchBooking) // Insert the pitch booking
-	if err != nil {                                                          // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pitch booking"}) // Return an error response
-		return                                                                                   // Return from the function
+	err := ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Insert the pitch booking and its event together
+		if err := ctrl.repos.Pitches.Insert(ctx, &pitchBooking); err != nil { // Insert the pitch booking
+			return err // Return the error
+		}
+		return ctrl.recordEvent(ctx, models.EventPitchBooked, models.AggregatePitch, pitchBooking.ID, pitchBooking) // Announce the booking
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to create pitch booking: %w", err)) // Return an error response
+		return                                                         // Return from the function
 	}
+	ctrl.metrics.PitchBookings.Inc() // Count the pitch booking
 
-	c.JSON(http.StatusCreated, pitchBooking) // Return the created pitch booking
+	describeChange(c, "pitches", pitchBooking.ID, nil, pitchBooking) // Record the created fields in the audit log
+	setETag(c, pitchBooking.Version)                                 // Tag the response with the version of the pitch booking
+	c.JSON(http.StatusCreated, pitchBooking)                         // Return the created pitch booking
 }
 
-// UpdatePitchBooking updates an existing pitch booking
-func UpdatePitchBooking(c *gin.Context) {
-	pitchBookingID := c.Param("pitchID") // Get the pitch booking ID from the URL
-	var updatedPitchBooking models.Pitch // Define an updated pitch booking variable
+// UpdatePitchBooking updates an existing pitch booking, with the same permissions as PatchPitchBooking
+func (ctrl *Controller) UpdatePitchBooking(c *gin.Context) {
+	pitchBookingID := c.Param("pitchId") // Get the pitch booking ID from the URL
+	var request dto.PitchRequest         // Define a pitch booking request variable
 
-	if err := c.BindJSON(&updatedPitchBooking); err != nil { // Bind the JSON to the updated pitch booking struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Return a bad request response
-		return                                                     // Return from the function
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the pitch booking request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
 	objectID, err := primitive.ObjectIDFromHex(pitchBookingID) // Convert ID to ObjectID
 	if err != nil {                                            // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pitch booking ID"}) // Return a bad request response
-		return                                                                    // Return from the function
+		c.Error(apperr.Validation("invalid_pitch_booking_id", "Invalid pitch booking ID", apperr.Field("pitchId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                            // Return from the function
+	}
+
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
 	}
 
+	updatedPitchBooking, err := ctrl.repos.Pitches.FindByID(c.Request.Context(), objectID) // Find the pitch booking to keep its server-managed fields
+	if err != nil {                                                                        // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the pitch booking was not found
+			c.Error(apperr.NotFound("pitch_booking_not_found", "Pitch booking not found")) // Return a not found response
+			return                                                                         // Return from the function
+		}
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	previous := updatedPitchBooking                                                                            // Keep the pitch booking before the update
+	request.Apply(&updatedPitchBooking)                                                                        // Copy the fields of the request
+	fields := dto.ChangedFields(dto.NewPitchRequest(previous), dto.NewPitchRequest(updatedPitchBooking))       // List the fields the request changes
+	if err := checkPatchFields(&dto.PitchRequest{}, fields, pitchWritableFields(previous, user)); err != nil { // Check that the user may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, previous.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
 	updatedPitchBooking.UpdatedAt = time.Now() // Set the updated_at timestamp
 
-	_, err = pitchBookingCollection.ReplaceOne(context.TODO(), bson.M{"_id": objectID}, updatedPitchBooking) // Update the pitch booking
-	if err != nil {                                                                                          // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pitch booking"}) // Return an error response
-		return                                                                                   // Return from the function
+	err = ctrl.repos.Pitches.Replace(c.Request.Context(), &updatedPitchBooking) // Update the pitch booking if nobody changed it in the meantime
+	if err != nil {                                                             // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the pitch booking was not found
+			c.Error(apperr.NotFound("pitch_booking_not_found", "Pitch booking not found")) // Return a not found response
+			return                                                                         // Return from the function
+		}
+		c.Error(versionError(fmt.Errorf("failed to update pitch booking: %w", err))) // Return an error response
+		return                                                                       // Return from the function
 	}
 
-	c.JSON(http.StatusOK, updatedPitchBooking) // Return the updated pitch booking
+	describeChange(c, "pitches", objectID, previous, updatedPitchBooking) // Record the changed fields in the audit log
+	setETag(c, updatedPitchBooking.Version)                               // Tag the response with the new version of the pitch booking
+	c.JSON(http.StatusOK, updatedPitchBooking)                            // Return the updated pitch booking
 }
 
-// DeletePitchBooking deletes an existing pitch booking
-func DeletePitchBooking(c *gin.Context) {
-	pitchBookingID := c.Param("pitchId") // Get the pitch booking ID from the URL
-
-	objectID, err := primitive.ObjectIDFromHex(pitchBookingID) // Convert ID to ObjectID
-	if err != nil {                                            // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pitch booking ID"}) // Return a bad request response
-		return                                                                    // Return from the function
+// pitchWritableFields returns the fields of the pitch booking the user may patch: the user who booked the pitch
+// may change the booking but not hand it over to someone else, admins may change every field
+func pitchWritableFields(pitch models.Pitch, user models.User) []string { // List the writable fields
+	switch { // Check the relation of the user to the pitch booking
+	case user.Role == models.RoleAdmin: // Admin
+		return dto.Fields(dto.PitchRequest{}) // Every field
+	case pitch.UserID == user.ID: // Owner of the booking
+		return []string{"session_id", "title", "description"} // Every field but the owner
 	}
+	return nil // Nothing
+}
 
-	_, err = pitchBookingCollection.DeleteOne(context.TODO(), bson.M{"_id": objectID}) // Delete the pitch booking
-	if err != nil {                                                                    // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pitch booking"}) // Return an error response
-		return                                                                                   // Return from the function
+// PatchPitchBooking applies a JSON merge patch to a pitch booking
+func (ctrl *Controller) PatchPitchBooking(c *gin.Context) {
+	objectID, err := primitive.ObjectIDFromHex(c.Param("pitchId")) // Convert ID to ObjectID
+	if err != nil {                                                // Check if there is an error
+		c.Error(apperr.Validation("invalid_pitch_booking_id", "Invalid pitch booking ID", apperr.Field("pitchId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                            // Return from the function
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Pitch booking deleted successfully"}) // Return a success response
-}
+	patch, fields, err := readMergePatch(c) // Read the patch
+	if err != nil {                         // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
 
-// GetPitchBookingsByPitchID retrieves all pitch bookings by pitch ID
-func GetPitchBookingsByPitchID(c *gin.Context) {
-	pitchID := c.Param("pitchId") // Get the pitch ID from the URL
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
 
-	objectPitchID, err := primitive.ObjectIDFromHex(pitchID) // Convert ID to ObjectID
-	if err != nil {                                          // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pitch ID"}) // Return a bad request response
-		return                                                            // Return from the function
+	pitchBooking, err := ctrl.repos.Pitches.FindByID(c.Request.Context(), objectID) // Find the pitch booking to patch
+	if err != nil {                                                                 // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the pitch booking was not found
+			c.Error(apperr.NotFound("pitch_booking_not_found", "Pitch booking not found")) // Return a not found response
+			return                                                                         // Return from the function
+		}
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
-	var pitchBookings []models.Pitch                                                             // Define a pitchBookings variable
-	cursor, err := pitchBookingCollection.Find(context.TODO(), bson.M{"pitchId": objectPitchID}) // Find pitch bookings by pitch ID
-	if err != nil {                                                                              // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	request := dto.NewPitchRequest(pitchBooking)                                                        // Describe the current state of the pitch booking
+	if err := checkPatchFields(&request, fields, pitchWritableFields(pitchBooking, user)); err != nil { // Check that the user may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, pitchBooking.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
 	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-	for cursor.Next(context.TODO()) {  // Iterate over the cursor
-		var pitchBooking models.Pitch                        // Define a pitchBooking variable
-		if err := cursor.Decode(&pitchBooking); err != nil { // Decode the pitch booking
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-			return                                                              // Return from the function
+	if err := applyMergePatch(&request, patch); err != nil { // Patch and validate the pitch booking
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	previous := pitchBooking            // Keep the pitch booking before the patch
+	request.Apply(&pitchBooking)        // Copy the patched fields
+	pitchBooking.UpdatedAt = time.Now() // Set the updated_at timestamp
+
+	err = ctrl.repos.Pitches.Replace(c.Request.Context(), &pitchBooking) // Replace the pitch booking if nobody changed it in the meantime
+	if err != nil {                                                      // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the pitch booking was not found
+			c.Error(apperr.NotFound("pitch_booking_not_found", "Pitch booking not found")) // Return a not found response
+			return                                                                         // Return from the function
 		}
-		pitchBookings = append(pitchBookings, pitchBooking) // Append the pitch booking to the pitchBookings slice
+		c.Error(versionError(fmt.Errorf("failed to patch pitch booking: %w", err))) // Return an error response
+		return                                                                      // Return from the function
 	}
 
-	c.JSON(http.StatusOK, pitchBookings) // Return a success response
+	describeChange(c, "pitches", objectID, previous, pitchBooking) // Record the changed fields in the audit log
+	setETag(c, pitchBooking.Version)                               // Tag the response with the new version of the pitch booking
+	c.JSON(http.StatusOK, pitchBooking)                            // Return the patched pitch booking
 }
 
-// GetPitchBookingsByUserID retrieves all pitch bookings by user ID
-func GetPitchBookingsByUserID(c *gin.Context) {
-	userID := c.Param("userId") // Get the user ID from the URL
+// DeletePitchBooking soft deletes an existing pitch booking, restorable by an admin until it is purged
+func (ctrl *Controller) DeletePitchBooking(c *gin.Context) {
+	pitchBookingID := c.Param("pitchId") // Get the pitch booking ID from the URL
 
-	objectUserID, err := primitive.ObjectIDFromHex(userID) // Convert ID to ObjectID
-	if err != nil {                                        // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return a bad request response
-		return                                                           // Return from the function
+	objectID, err := primitive.ObjectIDFromHex(pitchBookingID) // Convert ID to ObjectID
+	if err != nil {                                            // Check if there is an error
+		c.Error(apperr.Validation("invalid_pitch_booking_id", "Invalid pitch booking ID", apperr.Field("pitchId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                            // Return from the function
 	}
 
-	var pitchBookings []models.Pitch                                                           // Define a pitchBookings variable
-	cursor, err := pitchBookingCollection.Find(context.TODO(), bson.M{"userId": objectUserID}) // Find pitch bookings by user ID
-	if err != nil {                                                                            // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	deletion, err := ctrl.newDeletion(c, "Pitch booking deleted") // Describe the deletion
+	if err != nil {                                               // Check if there is an error
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-	for cursor.Next(context.TODO()) {  // Iterate over the cursor
-		var pitchBooking models.Pitch                        // Define a pitchBooking variable
-		if err := cursor.Decode(&pitchBooking); err != nil { // Decode the pitch booking
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-			return                                                              // Return from the function
+
+	err = ctrl.repos.Pitches.SoftDelete(c.Request.Context(), objectID, deletion) // Soft delete the pitch booking
+	if err != nil {                                                              // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the pitch booking was not found
+			c.Error(apperr.NotFound("pitch_booking_not_found", "Pitch booking not found")) // Return a not found response
+			return                                                                         // Return from the function
 		}
-		pitchBookings = append(pitchBookings, pitchBooking) // Append the pitch booking to the pitchBookings slice
+		c.Error(fmt.Errorf("failed to delete pitch booking: %w", err)) // Return an error response
+		return                                                         // Return from the function
 	}
 
-	c.JSON(http.StatusOK, pitchBookings) // Return a success response
+	describeChange(c, "pitches", objectID, models.Deletion{}, deletion)           // Record the changed fields in the audit log
+	c.JSON(http.StatusOK, gin.H{"message": "Pitch booking deleted successfully"}) // Return a success response
 }
 
-// GetPitchBookingsByDate retrieves all pitch bookings by date
-func GetPitchBookingsByDate(c *gin.Context) {
-	date := c.Param("date") // Get the date from the URL
-
-	var pitchBookings []models.Pitch                                                 // Define a pitchBookings variable
-	cursor, err := pitchBookingCollection.Find(context.TODO(), bson.M{"date": date}) // Find pitch bookings by date
-	if err != nil {                                                                  // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
-	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-	for cursor.Next(context.TODO()) {  // Iterate over the cursor
-		var pitchBooking models.Pitch                        // Define a pitchBooking variable
-		if err := cursor.Decode(&pitchBooking); err != nil { // Decode the pitch booking
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-			return                                                              // Return from the function
-		}
-		pitchBookings = append(pitchBookings, pitchBooking) // Append the pitch booking to the pitchBookings slice
+// GetPitchBookingsByUserID retrieves all pitch bookings by user ID
+func (ctrl *Controller) GetPitchBookingsByUserID(c *gin.Context) {
+	userID := c.Param("userId") // Get the user ID from the URL
+
+	objectUserID, err := primitive.ObjectIDFromHex(userID) // Convert ID to ObjectID
+	if err != nil {                                        // Check if there is an error
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
+	}
+
+	pitchBookings, err := ctrl.repos.Pitches.FindByUser(c.Request.Context(), objectUserID) // Find pitch bookings by user ID
+	if err != nil {                                                                        // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
 	c.JSON(http.StatusOK, pitchBookings) // Return a success response
@@ -0,0 +1,473 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// exportFile is a file of the personal data archive
+type exportFile struct {
+	name string // Name of the file in the archive
+	data []byte // Content of the file
+}
+
+// exportDataset encodes documents as a JSON file and a CSV file of the personal data archive
+func exportDataset[T any](name string, documents []T) ([]exportFile, error) { // Encode a dataset
+	data, err := json.MarshalIndent(documents, "", "  ") // Encode the documents as JSON
+	if err != nil {                                      // Check if there is an error
+		return nil, err // Return the error
+	}
+	table, err := documentsCSV(documents) // Encode the documents as CSV
+	if err != nil {                       // Check if there is an error
+		return nil, err // Return the error
+	}
+	return []exportFile{{name: name + ".json", data: data}, {name: name + ".csv", data: table}}, nil // Return the files
+}
+
+// documentsCSV encodes documents as CSV with one column per top-level JSON field, in the order the fields first
+// appear; strings are written as is, null as an empty cell and the other values as JSON
+func documentsCSV[T any](documents []T) ([]byte, error) { // Encode documents as CSV
+	var zero T                                                 // Start with the fields of an empty document
+	var columns []string                                       // Define the columns
+	known := map[string]bool{}                                 // Define the columns already added
+	rows := make([]map[string]json.RawMessage, 0)              // Define the fields of each document
+	for i, document := range append([]T{zero}, documents...) { // Iterate over the empty document and the documents
+		data, err := json.Marshal(document) // Encode the document
+		if err != nil {                     // Check if there is an error
+			return nil, err // Return the error
+		}
+		names, fields, err := jsonFields(data) // Split the document into its fields
+		if err != nil {                        // Check if there is an error
+			return nil, err // Return the error
+		}
+		for _, name := range names { // Iterate over the fields
+			if !known[name] { // Check if the field has no column yet
+				known[name] = true              // Remember the column
+				columns = append(columns, name) // Add the column
+			}
+		}
+		if i > 0 { // Check if this is a document, not the empty one
+			rows = append(rows, fields) // Keep the fields
+		}
+	}
+
+	var buffer bytes.Buffer                       // Define the CSV output
+	writer := csv.NewWriter(&buffer)              // Write CSV to the buffer
+	if err := writer.Write(columns); err != nil { // Write the header
+		return nil, err // Return the error
+	}
+	for _, fields := range rows { // Iterate over the documents
+		record := make([]string, len(columns)) // Define the row
+		for i, name := range columns {         // Iterate over the columns
+			record[i] = csvCell(fields[name]) // Set the cell
+		}
+		if err := writer.Write(record); err != nil { // Write the row
+			return nil, err // Return the error
+		}
+	}
+	writer.Flush()                        // Flush the rows
+	return buffer.Bytes(), writer.Error() // Return the CSV
+}
+
+// jsonFields splits a JSON object into its top-level fields, returning their names in order
+func jsonFields(data []byte) ([]string, map[string]json.RawMessage, error) { // Split a JSON object
+	decoder := json.NewDecoder(bytes.NewReader(data)) // Decode the object token by token
+	if _, err := decoder.Token(); err != nil {        // Read the opening brace
+		return nil, nil, err // Return the error
+	}
+	var names []string                     // Define the names of the fields
+	fields := map[string]json.RawMessage{} // Define the values of the fields
+	for decoder.More() {                   // Iterate over the fields
+		token, err := decoder.Token() // Read the name of the field
+		if err != nil {               // Check if there is an error
+			return nil, nil, err // Return the error
+		}
+		name, _ := token.(string)                      // Get the name
+		var value json.RawMessage                      // Define the value
+		if err := decoder.Decode(&value); err != nil { // Read the value
+			return nil, nil, err // Return the error
+		}
+		names = append(names, name) // Add the name
+		fields[name] = value        // Set the value
+	}
+	return names, fields, nil // Return the fields
+}
+
+// csvCell converts a JSON value into a CSV cell
+func csvCell(value json.RawMessage) string { // Convert a value
+	if len(value) == 0 || string(value) == "null" { // Check if the value is missing
+		return "" // Leave the cell empty
+	}
+	var text string                                      // Define the string value
+	if err := json.Unmarshal(value, &text); err == nil { // Check if the value is a string
+		return text // Write the string as is
+	}
+	return string(value) // Write the value as JSON
+}
+
+// ExportPersonalData: Allows users to download their personal data as a ZIP archive: their profile, the sessions
+// they coach or are enrolled in, their feedback, notifications, pitch bookings, invitations and erasure requests,
+// each as JSON and, but for the profile and the erasure requests, as CSV
+func (ctrl *Controller) ExportPersonalData(c *gin.Context) { // Export the personal data of the caller
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	files, err := ctrl.personalData(c.Request.Context(), user) // Collect the personal data
+	if err != nil {                                            // Check if there is an error
+		c.Error(fmt.Errorf("failed to export the personal data of user %s: %w", user.ID.Hex(), err)) // Return an error response
+		return                                                                                       // Return from the function
+	}
+
+	filename := "personal-data-" + time.Now().UTC().Format("20060102T150405Z") + ".zip" // Name the archive after the export time
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)              // Download the archive
+	c.Header("Content-Type", "application/zip")                                         // Send a ZIP archive
+	c.Status(http.StatusOK)                                                             // Write the status before the archive
+	archive := zip.NewWriter(c.Writer)                                                  // Write the archive to the response
+	for _, file := range files {                                                        // Iterate over the files
+		entry, err := archive.Create(file.name) // Add the file
+		if err == nil {                         // Check if the file was added
+			_, err = entry.Write(file.data) // Write the content
+		}
+		if err != nil { // Check if there is an error
+			c.Error(fmt.Errorf("failed to export the personal data of user %s: %w", user.ID.Hex(), err)) // Log the error, the response has started
+			return                                                                                       // Return from the function
+		}
+	}
+	if err := archive.Close(); err != nil { // Finish the archive
+		c.Error(fmt.Errorf("failed to export the personal data of user %s: %w", user.ID.Hex(), err)) // Log the error, the response has started
+	}
+}
+
+// personalData collects the files of the personal data archive of a user
+func (ctrl *Controller) personalData(ctx context.Context, user models.User) ([]exportFile, error) { // Collect the personal data
+	profile, err := json.MarshalIndent(dto.NewAdminUser(user), "", "  ") // Encode every field of the profile but the password
+	if err != nil {                                                      // Check if there is an error
+		return nil, err // Return the error
+	}
+	files := []exportFile{{name: "profile.json", data: profile}} // Start with the profile
+
+	sessions, err := ctrl.repos.Sessions.FindByUser(ctx, user.ID) // Find the sessions of the user
+	if err != nil {                                               // Check if there is an error
+		return nil, err // Return the error
+	}
+	checkIns, err := ctrl.repos.CheckIns.FindByUser(ctx, user.ID) // Find the check-ins of the user
+	if err != nil {                                               // Check if there is an error
+		return nil, err // Return the error
+	}
+	checkedIn := make(map[primitive.ObjectID]*time.Time, len(checkIns)) // Define the check-in time of each session
+	for i := range checkIns {                                           // Iterate over the check-ins
+		checkedIn[checkIns[i].SessionID] = &checkIns[i].CheckedInAt // Remember the check-in time
+	}
+	exported := make([]dto.ExportedSession, len(sessions)) // Define a slice to hold the exported sessions
+	for i, session := range sessions {                     // Iterate over the sessions
+		role := "participant"         // Enrolled in the session unless they coach it
+		if session.Coach == user.ID { // Check if the user coaches the session
+			role = "coach" // Coach of the session
+		}
+		exported[i] = dto.ExportedSession{ID: session.ID, Title: session.Title, TrainingType: session.TrainingType, Location: session.Location, StartTime: session.StartTime, EndTime: session.EndTime, Status: session.Status, Role: role, Attended: checkedIn[session.ID] != nil, CheckedInAt: checkedIn[session.ID]} // Describe the part the user took
+	}
+	feedback, err := ctrl.repos.Feedback.Find(ctx, repository.FeedbackFilter{UserID: user.ID}) // Find the feedback written by the user
+	if err != nil {                                                                            // Check if there is an error
+		return nil, err // Return the error
+	}
+	for i := range feedback { // Iterate over the feedback
+		feedback[i].Flags = nil // Leave out the flags, they name the users who raised them
+	}
+	notifications, err := ctrl.repos.Notifications.FindByUser(ctx, user.ID) // Find the notifications of the user
+	if err != nil {                                                         // Check if there is an error
+		return nil, err // Return the error
+	}
+	pitches, err := ctrl.repos.Pitches.FindByUser(ctx, user.ID) // Find the pitch bookings of the user
+	if err != nil {                                             // Check if there is an error
+		return nil, err // Return the error
+	}
+	invitations, err := ctrl.repos.Invitations.FindByUser(ctx, user.ID) // Find the invitations of the user
+	if err != nil {                                                     // Check if there is an error
+		return nil, err // Return the error
+	}
+
+	datasets := []func() ([]exportFile, error){ // Encode each dataset as JSON and CSV
+		func() ([]exportFile, error) { return exportDataset("sessions", exported) },
+		func() ([]exportFile, error) { return exportDataset("feedback", feedback) },
+		func() ([]exportFile, error) { return exportDataset("notifications", notifications) },
+		func() ([]exportFile, error) { return exportDataset("pitch_bookings", pitches) },
+		func() ([]exportFile, error) { return exportDataset("invitations", invitations) },
+	}
+	for _, dataset := range datasets { // Iterate over the datasets
+		encoded, err := dataset() // Encode the dataset
+		if err != nil {           // Check if there is an error
+			return nil, err // Return the error
+		}
+		files = append(files, encoded...) // Add the files
+	}
+
+	requests, err := ctrl.repos.Erasures.FindByUser(ctx, user.ID) // Find the erasure requests of the user
+	if err != nil {                                               // Check if there is an error
+		return nil, err // Return the error
+	}
+	data, err := json.MarshalIndent(requests, "", "  ") // Encode the erasure requests
+	if err != nil {                                     // Check if there is an error
+		return nil, err // Return the error
+	}
+	return append(files, exportFile{name: "erasure_requests.json", data: data}), nil // Return the files
+}
+
+// RequestErasure: Allows users to ask for their personal data to be erased; the request waits for an admin, and a
+// user has at most one pending request
+func (ctrl *Controller) RequestErasure(c *gin.Context) { // Request the erasure of the personal data of the caller
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	var body dto.ErasureRequestBody            // Define the body variable
+	if err := bindJSON(c, &body); err != nil { // Bind the JSON to the body variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	request := models.ErasureRequest{ // Define the request
+		ID:          primitive.NewObjectID(),     // Generate a new ObjectID for the request
+		UserID:      user.ID,                     // Set the user
+		Reason:      body.Reason,                 // Set the reason
+		Status:      models.ErasureStatusPending, // Wait for an admin
+		RequestedAt: time.Now(),                  // Set the request time
+	}
+	err = ctrl.repos.Erasures.Insert(c.Request.Context(), &request) // Insert the request
+	if errors.Is(err, repository.ErrConflict) {                     // Check if a request is already pending
+		c.Error(apperr.Conflict("erasure_request_pending", "An erasure request is already waiting for review")) // Return a conflict response
+		return                                                                                                  // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to request the erasure of user %s: %w", user.ID.Hex(), err)) // Return an error response
+		return                                                                                  // Return from the function
+	}
+
+	describeChange(c, "erasure_requests", request.ID, nil, request) // Record the created fields in the audit log
+	c.JSON(http.StatusCreated, request)                             // Return the created request
+}
+
+// GetMyErasureRequests: Allows users to follow their erasure requests, newest first
+func (ctrl *Controller) GetMyErasureRequests(c *gin.Context) { // Get the erasure requests of the caller
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	requests, err := ctrl.repos.Erasures.FindByUser(c.Request.Context(), user.ID) // Find the requests of the user
+	if err != nil {                                                               // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve erasure requests: %w", err)) // Return an error response
+		return                                                              // Return from the function
+	}
+
+	c.JSON(http.StatusOK, requests) // Return the requests
+}
+
+// GetErasureRequests: Allows admins to list the erasure requests with a status, the pending ones by default,
+// oldest first
+func (ctrl *Controller) GetErasureRequests(c *gin.Context) { // Get the erasure requests
+	if _, ok := ctrl.requireAdmin(c); !ok { // Check that the user is an admin
+		return // Return from the function
+	}
+
+	var query dto.ErasureQuery                   // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	if query.Status == "" { // Check if no status was given
+		query.Status = models.ErasureStatusPending // List the approval queue
+	}
+
+	requests, err := ctrl.repos.Erasures.Find(c.Request.Context(), query.Status) // Find the requests
+	if err != nil {                                                              // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve erasure requests: %w", err)) // Return an error response
+		return                                                              // Return from the function
+	}
+
+	c.JSON(http.StatusOK, requests) // Return the requests
+}
+
+// pendingErasure loads the erasure request of the URL and responds with an error if it is missing or was reviewed
+func (ctrl *Controller) pendingErasure(c *gin.Context) (models.ErasureRequest, bool) { // Get a pending erasure request
+	requestID, err := primitive.ObjectIDFromHex(c.Param("requestId")) // Convert the request ID to an ObjectID
+	if err != nil {                                                   // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_request_id", "Invalid request ID", apperr.Field("requestId", "must be a valid ID"))) // Return a bad request response
+		return models.ErasureRequest{}, false                                                                                   // Return from the function
+	}
+
+	request, err := ctrl.repos.Erasures.FindByID(c.Request.Context(), requestID) // Find the request
+	if errors.Is(err, repository.ErrNotFound) {                                  // Check if the request was not found
+		c.Error(apperr.NotFound("erasure_request_not_found", "Erasure request not found")) // Return a not found response
+		return request, false                                                              // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(err)          // Return an error response
+		return request, false // Return from the function
+	}
+	if request.Status != models.ErasureStatusPending { // Check if the request was already reviewed
+		c.Error(apperr.Conflict("erasure_request_reviewed", "The erasure request was already reviewed")) // Return a conflict response
+		return request, false                                                                            // Return from the function
+	}
+	return request, true // Return the request
+}
+
+// reviewErasure records the outcome of a pending erasure request, a request reviewed concurrently is a conflict
+func (ctrl *Controller) reviewErasure(ctx context.Context, request *models.ErasureRequest, status string, reviewer primitive.ObjectID, note string) error { // Review an erasure request
+	now := time.Now()                                // Get the review time
+	request.Status = status                          // Set the status
+	request.ReviewedBy = &reviewer                   // Set the reviewer
+	request.ReviewedAt = &now                        // Set the review time
+	request.ReviewNote = note                        // Set the note
+	err := ctrl.repos.Erasures.Review(ctx, *request) // Store the review
+	if errors.Is(err, repository.ErrNotFound) {      // Check if the request was reviewed in the meantime
+		return apperr.Conflict("erasure_request_reviewed", "The erasure request was already reviewed") // Return a conflict error
+	}
+	return err // Return the error
+}
+
+// ApproveErasure: Allows admins to approve an erasure request: the personal fields of the user are anonymized and
+// they are soft deleted, their notifications are removed, their feedback is detached from them and their webhooks
+// are disabled. Their enrollments, bookings and ratings are kept without personal data, so the statistics stay
+// right. The sessions of a coach that have not ended must be given to another coach with reassign_to.
+func (ctrl *Controller) ApproveErasure(c *gin.Context) { // Approve an erasure request
+	admin, ok := ctrl.requireAdmin(c) // Check that the user is an admin
+	if !ok {                          // Check if the user is not an admin
+		return // Return from the function
+	}
+	request, ok := ctrl.pendingErasure(c) // Get the request
+	if !ok {                              // Check if the request cannot be reviewed
+		return // Return from the function
+	}
+
+	var review dto.ErasureReview                 // Define the review variable
+	if err := bindJSON(c, &review); err != nil { // Bind the JSON to the review variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	successor, err := ctrl.successorCoach(c.Request.Context(), request.UserID, review.ReassignTo) // Find the coach taking over the sessions
+	if err != nil {                                                                               // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	// Erase the personal data and record the review in one transaction
+	previous := request                                                                                  // Keep the request before the review
+	var report dto.ErasureReport                                                                         // Define the report of the changed documents
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the erasure in a transaction
+		var err error                                                          // Define the error
+		report, err = ctrl.eraseUser(ctx, request.UserID, admin.ID, successor) // Erase the personal data
+		if err != nil {                                                        // Check if there is an error
+			return err // Return the error
+		}
+		return ctrl.reviewErasure(ctx, &request, models.ErasureStatusApproved, admin.ID, review.Note) // Approve the request
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to approve erasure request %s: %w", request.ID.Hex(), err)) // Return an error response
+		return                                                                                 // Return from the function
+	}
+
+	describeChange(c, "erasure_requests", request.ID, previous, request) // Record the changed fields in the audit log, never the erased data
+	c.JSON(http.StatusOK, gin.H{"request": request, "affected": report}) // Return the approved request and the changed documents
+}
+
+// eraseUser anonymizes the personal fields of a user and deletes them for good, then removes or detaches the
+// documents holding their personal data. A user soft deleted since the request was made is erased all the same
+func (ctrl *Controller) eraseUser(ctx context.Context, userID, adminID primitive.ObjectID, successor *primitive.ObjectID) (dto.ErasureReport, error) { // Erase the personal data of a user
+	var report dto.ErasureReport // Define the report of the changed documents
+
+	user, err := ctrl.repos.Users.FindByIDWithDeleted(ctx, userID) // Find the user, even soft deleted
+	if errors.Is(err, repository.ErrNotFound) {                    // Check if the user was not found
+		return report, apperr.NotFound("user_not_found", "User not found") // Return a not found error
+	}
+	if err != nil { // Check if there is an error
+		return report, err // Return the error
+	}
+
+	now := time.Now()                                                                                    // Get the current time
+	if report.SessionsReassigned, err = ctrl.handOverSessions(ctx, userID, successor, now); err != nil { // Give the remaining sessions to the successor
+		return report, err // Return the error
+	}
+
+	user.Name = models.ErasedUserName                                                                                  // Forget the name
+	user.Email = "erased-" + user.ID.Hex() + "@erased.invalid"                                                         // Free the email, keeping one no one can own
+	user.Avatar = ""                                                                                                   // Forget the profile picture
+	user.Cin = ""                                                                                                      // Forget the national ID
+	user.Password = ""                                                                                                 // Forget the password, no one can log in anymore
+	user.UpdatedAt = now                                                                                               // Set the updated_at timestamp
+	user.Deletion = models.Deletion{DeletedAt: &now, DeletedBy: &adminID, Reason: "Personal data erased", Final: true} // Delete the user, an erased user is never restored
+	if err := ctrl.repos.Users.Erase(ctx, &user); err != nil {                                                         // Store the anonymized user
+		return report, err // Return the error
+	}
+
+	if report.NotificationsDeleted, err = ctrl.repos.Notifications.DeleteByUser(ctx, userID); err != nil { // Remove the notifications
+		return report, err // Return the error
+	}
+	if report.FeedbackAnonymized, err = ctrl.repos.Feedback.AnonymizeAuthor(ctx, userID); err != nil { // Detach the feedback from its author
+		return report, err // Return the error
+	}
+	if report.WebhooksDisabled, err = ctrl.repos.Webhooks.DisableByOwner(ctx, userID); err != nil { // Disable the webhooks
+		return report, err // Return the error
+	}
+	return report, nil // Return the report
+}
+
+// RejectErasure: Allows admins to reject an erasure request, the user is notified with the note of the admin
+func (ctrl *Controller) RejectErasure(c *gin.Context) { // Reject an erasure request
+	admin, ok := ctrl.requireAdmin(c) // Check that the user is an admin
+	if !ok {                          // Check if the user is not an admin
+		return // Return from the function
+	}
+	request, ok := ctrl.pendingErasure(c) // Get the request
+	if !ok {                              // Check if the request cannot be reviewed
+		return // Return from the function
+	}
+
+	var review dto.ErasureReview                 // Define the review variable
+	if err := bindJSON(c, &review); err != nil { // Bind the JSON to the review variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	message := "Your request to erase your personal data was rejected." // Describe the outcome
+	if review.Note != "" {                                              // Check if the admin left a note
+		message += " " + review.Note // Add the note
+	}
+	notification := newNotification(request.UserID, "Erasure Request", message) // Notify the user
+
+	// Record the review and queue the notification in one transaction
+	previous := request                                                                                   // Keep the request before the review
+	err := ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the rejection in a transaction
+		request = previous                                                                                             // Start from the pending request, the transaction may be retried
+		if err := ctrl.reviewErasure(ctx, &request, models.ErasureStatusRejected, admin.ID, review.Note); err != nil { // Reject the request
+			return err // Return the error
+		}
+		return ctrl.queueNotifications(ctx, models.AggregateErasure, request.ID, []models.Notification{notification}) // Notify the user once the review is committed
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to reject erasure request %s: %w", request.ID.Hex(), err)) // Return an error response
+		return                                                                                // Return from the function
+	}
+
+	describeChange(c, "erasure_requests", request.ID, previous, request)        // Record the changed fields in the audit log
+	c.JSON(http.StatusOK, gin.H{"request": request, "notifications_queued": 1}) // Return the rejected request
+}
@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApproveErasure(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool // Whether an admin soft deletes the user while the request is pending
+	}{
+		{name: "live user"},
+		{name: "soft deleted user", deleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			admin := s.signUpAdmin("admin@example.com")
+			ann := s.signUp("ann@example.com", "user")
+			res := s.do(http.MethodPost, "/privacy/erasure", ann.token, map[string]any{})
+			if res.status != http.StatusCreated {
+				t.Fatalf("request erasure: status %d, body %v", res.status, res.body)
+			}
+			requestID, _ := res.body["id"].(string)
+			if tt.deleted {
+				if res := s.do(http.MethodDelete, "/users/delete/"+ann.id, admin.token, nil); res.status != http.StatusOK {
+					t.Fatalf("delete user: status %d, body %v", res.status, res.body)
+				}
+			}
+
+			res = s.do(http.MethodPost, "/admin/erasure-requests/"+requestID+"/approve", admin.token, map[string]any{})
+			if res.status != http.StatusOK {
+				t.Fatalf("status %d, body %v", res.status, res.body)
+			}
+			userID, _ := primitive.ObjectIDFromHex(ann.id)
+			user, err := s.repos.Users.FindByIDWithDeleted(context.Background(), userID)
+			if err != nil {
+				t.Fatalf("find user: %v", err)
+			}
+			if user.Email == "ann@example.com" || user.Password != "" || !user.IsDeleted() || !user.Final {
+				t.Errorf("user %+v, want erased and deleted for good", user)
+			}
+		})
+	}
+}
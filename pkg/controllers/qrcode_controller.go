@@ -2,34 +2,30 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
 	"training_session/pkg/models"
+	"training_session/pkg/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/skip2/go-qrcode"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-var ( // Define a variable for the session collection in QR code controller
-	qrSessionCollection *mongo.Collection // Use a different variable for the session collection in QR code controller
-)
-
-func InitializeQRCodeController(database *mongo.Database) { // Initialize the QR code controller
-	qrSessionCollection = database.Collection("sessions") // Set the session collection for QR code controller
-}
-
 // GenerateQRCode generates a QR code for session verification
-func GenerateQRCode(c *gin.Context) { // Generate a QR code for session verification
+func (ctrl *Controller) GenerateQRCode(c *gin.Context) { // Generate a QR code for session verification
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
 	// Convert sessionID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                       // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"}) // Return an error response
-		return                                                              // Return from the function to stop execution
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                  // Return from the function to stop execution
 	}
 
 	// Generate QR code content
@@ -38,19 +34,19 @@ func GenerateQRCode(c *gin.Context) { // Generate a QR code for session verifica
 	// Create QR code
 	code, err := qrcode.Encode(qrContent, qrcode.Medium, 256) // Generate the QR code
 	if err != nil {                                           // Check if there is an error generating the QR code
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"}) // Return an error response
-		return                                                                               // Return from the function to stop execution
+		c.Error(fmt.Errorf("failed to generate QR code: %w", err)) // Return an error response
+		return                                                     // Return from the function to stop execution
 	}
 
 	// Save QR code to session document
-	_, err = qrSessionCollection.UpdateOne( // Update the session document with the QR code
-		context.TODO(),                              // Use the context
-		bson.M{"_id": objectID},                     // Find the session by ID
-		bson.M{"$set": bson.M{"qrCode": qrContent}}, // Update the session with the QR code
-	)
-	if err != nil { // Check if there is an error updating the session
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update session with QR code"}) // Return an error response
-		return                                                                                          // Return from the function to stop execution
+	err = ctrl.repos.Sessions.SetQRCode(c.Request.Context(), objectID, qrContent) // Update the session document with the QR code
+	if err != nil {                                                               // Check if there is an error updating the session
+		if errors.Is(err, repository.ErrNotFound) { // Check if the session was not found
+			c.Error(apperr.NotFound("session_not_found", "Session not found")) // Return a not found response
+			return                                                             // Return from the function to stop execution
+		}
+		c.Error(fmt.Errorf("failed to update session with QR code: %w", err)) // Return an error response
+		return                                                                // Return from the function to stop execution
 	}
 
 	c.Header("Content-Type", "image/png") // Set the content type to image/png
@@ -58,34 +54,85 @@ func GenerateQRCode(c *gin.Context) { // Generate a QR code for session verifica
 	c.Writer.Write(code)                  // Write the QR code image to the response
 }
 
-// ValidateQRCode validates the QR code to ensure session integrity
-func ValidateQRCode(c *gin.Context) {
+// ValidateQRCode checks in the caller with the scanned QR code of a session they are enrolled in, storing the
+// check-in and its CheckInRecorded event in one transaction. Checking in again returns the stored check-in.
+func (ctrl *Controller) ValidateQRCode(c *gin.Context) {
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
 	// Convert sessionID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                       // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"}) // Return an error response
-		return                                                              // Return from the function to stop execution
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                  // Return from the function to stop execution
 	}
 
-	// Find the session in the database
-	var session models.Session                                                                // Define a session variable
-	err = sessionCollection.FindOne(context.TODO(), bson.M{"_id": objectID}).Decode(&session) // Find the session by ID
-	if err != nil {                                                                           // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the session was not found
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"}) // Return a not found response
-		} else { // If there is another error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		}
-		return // Return from the function to stop execution
+	var request dto.CheckInRequest                // Define a check-in request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the check-in request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function to stop execution
 	}
 
-	// Check if the session is valid (you can add more validation logic here)
-	if session.Status != "active" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Session is not active"})
-		return
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function to stop execution
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "QR code is valid"}) // Return a success response
+	// Check the session and store the check-in with its event in one transaction
+	var checkIn models.CheckIn                                                                           // Define the check-in
+	var recorded bool                                                                                    // Define whether the check-in is new
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the check-in in a transaction
+		session, err := ctrl.repos.Sessions.FindByID(ctx, objectID) // Find the session by ID
+		if errors.Is(err, repository.ErrNotFound) {                 // Check if the session was not found
+			return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
+		}
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
+		if session.Status != models.SessionStatusActive { // Check if the session is archived
+			return apperr.Conflict("session_not_active", "Session is not active") // Return a conflict error
+		}
+		if session.QRCode == "" || request.QRCode != session.QRCode { // Check if the scanned code is not the code of the session
+			return apperr.Validation("invalid_qr_code", "Invalid QR code", apperr.Field("qr_code", "must be the QR code of the session")) // Return a validation error
+		}
+		if !slices.Contains(session.Participants, user.ID) { // Check if the user is not enrolled
+			return apperr.Forbidden("not_enrolled", "Only the participants of the session can check in") // Return a forbidden error
+		}
+
+		checkIn, err = ctrl.repos.CheckIns.Find(ctx, objectID, user.ID) // Find an earlier check-in
+		if err == nil {                                                 // Check if the user already checked in
+			recorded = false // Nothing new to record, the transaction may be retried
+			return nil       // Return the stored check-in
+		}
+		if !errors.Is(err, repository.ErrNotFound) { // Check if there is an error
+			return err // Return the error
+		}
+
+		checkIn = models.CheckIn{ // Define the check-in
+			ID:          primitive.NewObjectID(), // Generate a new ObjectID for the check-in
+			SessionID:   objectID,                // Set the session
+			UserID:      user.ID,                 // Set the participant
+			CheckedInAt: time.Now(),              // Set the checked_in_at timestamp
+		}
+		if err := ctrl.repos.CheckIns.Insert(ctx, &checkIn); err != nil { // Store the check-in
+			return err // Return the error
+		}
+		recorded = true                                                                                                                                                                       // Remember the new check-in
+		return ctrl.recordEvent(ctx, models.EventCheckInRecorded, models.AggregateSession, objectID, gin.H{"session_id": objectID, "user_id": user.ID, "checked_in_at": checkIn.CheckedInAt}) // Announce the check-in
+	})
+	if errors.Is(err, repository.ErrConflict) { // Check if a concurrent request checked the user in first
+		checkIn, err = ctrl.repos.CheckIns.Find(c.Request.Context(), objectID, user.ID) // Find the stored check-in
+		recorded = false                                                                // Nothing new was recorded
+	}
+	if err != nil { // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function to stop execution
+	}
+
+	if !recorded { // Check if the user had already checked in
+		c.JSON(http.StatusOK, checkIn) // Return the stored check-in
+		return                         // Return from the function to stop execution
+	}
+	ctrl.metrics.CheckIns.Inc()         // Count the check-in
+	c.JSON(http.StatusCreated, checkIn) // Return the new check-in
 }
@@ -2,522 +2,655 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"slices"
 	"time"
-
-	"training_session/config"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
 	"training_session/pkg/models"
+	"training_session/pkg/repository"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-)
-
-var (
-	sessionCollection *mongo.Collection // Define a sessionCollection variable
 )
 
-func InitializeSession(db *mongo.Database) { // Initialize the controllers
-	sessionCollection = db.Collection("sessions") // Set the session collection
-}
-
-func init() {
-	cfg = config.LoadConfig()
-}
+func (ctrl *Controller) GetSessions(c *gin.Context) { // Get all sessions
+	var query dto.SessionQuery                   // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
 
-func GetSessions(c *gin.Context) { // Get all sessions
 	var sessions []models.Session // Define a sessions variable
-
-	cursor, err := sessionCollection.Find(context.TODO(), bson.D{{}}) // Find all sessions
-	if err != nil {                                                   // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-			"error": err.Error(), // Return the error message
-		})
-		return // Return from the function
+	var err error                 // Define an error variable
+	if query.Status != "" {       // Check if the sessions are filtered by status
+		sessions, err = ctrl.repos.Sessions.FindByStatus(c.Request.Context(), query.Status) // Find the sessions with the status
+	} else {
+		sessions, err = ctrl.repos.Sessions.FindAll(c.Request.Context()) // Find all sessions
 	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-
-	for cursor.Next(context.TODO()) { // Iterate over the cursor
-		var session models.Session                      // Define a session variable
-		if err := cursor.Decode(&session); err != nil { // Decode the session
-			c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-				"error": err.Error(), // Return the error message
-			})
-			return // Return from the function
-		}
-		sessions = append(sessions, session) // Append the session to the sessions slice
+	if err != nil { // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
 	c.JSON(http.StatusOK, sessions) // Return a success response
 }
 
-func GetActiveSessions(c *gin.Context) { // Get all active sessions
-	var sessions []models.Session // Define a sessions variable
-
-	cursor, err := sessionCollection.Find(context.TODO(), bson.M{"status": "active"}) // Find all active sessions
-	if err != nil {                                                                   // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-			"error": err.Error(), // Return the error message
-		})
-		return // Return from the function
-	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-
-	for cursor.Next(context.TODO()) { // Iterate over the cursor
-		var session models.Session                      // Define a session variable
-		if err := cursor.Decode(&session); err != nil { // Decode the session
-			c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-				"error": err.Error(), // Return the error message
-			})
-			return // Return from the function
-		}
-		sessions = append(sessions, session) // Append the session to the sessions slice
+func (ctrl *Controller) GetActiveSessions(c *gin.Context) { // Get all active sessions
+	sessions, err := ctrl.repos.Sessions.FindByStatus(c.Request.Context(), models.SessionStatusActive) // Find all active sessions
+	if err != nil {                                                                                    // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
 	c.JSON(http.StatusOK, sessions) // Return a success response
 }
 
-func GetSessionByID(c *gin.Context) { // Get a session by ID
+func (ctrl *Controller) GetSessionByID(c *gin.Context) { // Get a session by ID
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
 	// Convert the sessionID from string to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                       // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{ // Return a bad request response
-			"error": "Invalid session ID format", // Return an error message
-		})
-		return // Return from the function
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
 	}
 
-	var session models.Session                                                   // Define a session variable
-	result := sessionCollection.FindOne(context.TODO(), bson.M{"_id": objectID}) // Find the session by ID
-	if err := result.Err(); err != nil {                                         // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the session was not found
-			c.JSON(http.StatusNotFound, gin.H{ // Return a not found response
-				"error": "Session not found", // Return an error message
-			})
+	session, err := ctrl.repos.Sessions.FindByID(c.Request.Context(), objectID) // Find the session by ID
+	if err != nil {                                                             // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the session was not found
+			c.Error(apperr.NotFound("session_not_found", "Session not found")) // Return a not found response
 		} else { // Check if there is another error
-			c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-				"error": err.Error(), // Return the error message
-			})
+			c.Error(err) // Return an error response
 		}
 		return
 	}
 
-	if err := result.Decode(&session); err != nil { // Decode the session
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-			"error": err.Error(), // Return the error message
-		})
+	if notModified(c, session.Version) { // Check if the client holds the current version
 		return // Return from the function
 	}
-
 	c.JSON(http.StatusOK, session) // Return the session
 }
 
-func GetSessionsByUserID(c *gin.Context) { // Get all sessions created by a user
-	userID := c.Param("userId") // Get the user ID from the URL
-
-	// Convert the userID from string to ObjectID
-	objectID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
-	if err != nil {                                    // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{ // Return a bad request response
-			"error": "Invalid user ID format", // Return an error message
-		})
-		return // Return from the function
-	}
-
-	var sessions []models.Session // Define a sessions variable
-
-	cursor, err := sessionCollection.Find(context.TODO(), bson.M{"user_id": objectID}) // Find all sessions by user ID
-	if err != nil {                                                                    // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-			"error": err.Error(), // Return the error message
-		})
-		return // Return from the function
+func (ctrl *Controller) GetSessionsByUserID(c *gin.Context) { // Get all sessions coached or attended by a user
+	userID, err := primitive.ObjectIDFromHex(c.Param("userId")) // Convert the user ID to an ObjectID
+	if err != nil {                                             // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
 	}
-	defer cursor.Close(context.TODO()) // Close the cursor
 
-	for cursor.Next(context.TODO()) { // Iterate over the cursor
-		var session models.Session                      // Define a session variable
-		if err := cursor.Decode(&session); err != nil { // Decode the session
-			c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-				"error": err.Error(), // Return the error message
-			})
-			return // Return from the function
-		}
-		sessions = append(sessions, session) // Append the session to the sessions slice
+	sessions, err := ctrl.repos.Sessions.FindByUser(c.Request.Context(), userID) // Find all sessions by user ID
+	if err != nil {                                                              // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
 	c.JSON(http.StatusOK, sessions) // Return a success response
 }
 
-func CreateSession(c *gin.Context) { // Create a session
-	if sessionCollection == nil { // Check if the session collection is not initialized
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an internal server error response
-			"error": "MongoDB session collection is not initialized", // Return an error message
-		})
-		return // Return from the function
-	}
-	log.Printf("JWT Secret: %s", cfg.JwtSecretKey) // Log the JWT secret key for debugging
-
+func (ctrl *Controller) CreateSession(c *gin.Context) { // Create a session
 	tokenString, err := c.Cookie("auth_token") // Get the JWT token from the cookie
 	if err != nil {                            // Check if there is an error
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"}) // Return an unauthorized response
-		return                                                          // Return from the function
+		c.Error(apperr.Unauthorized("missing_token", "Missing token")) // Return an unauthorized response
+		return                                                         // Return from the function
 	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) { // Parse the JWT token
-		return []byte(cfg.JwtSecretKey), nil // Return the JWT secret key
+		return []byte(ctrl.cfg.JwtSecretKey), nil // Return the JWT secret key
 	})
 
 	if err != nil { // Check if there is an error parsing the token
-		log.Printf("Error parsing token: %v", err)                       // Log the error details
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"}) // Return an unauthorized response with an error message
-		return                                                           // Return from the function
+		slog.WarnContext(c.Request.Context(), "Invalid auth token", slog.Any("error", err)) // Log the error details
+		c.Error(apperr.Unauthorized("invalid_token", "Invalid token"))                      // Return an unauthorized response with an error message
+		return                                                                              // Return from the function
 	}
 
 	if !token.Valid { // Check if the token is invalid
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"}) // Return an unauthorized response with an error message
-		return                                                           // Return from the function
+		c.Error(apperr.Unauthorized("invalid_token", "Invalid token")) // Return an unauthorized response with an error message
+		return                                                         // Return from the function
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims) // Get the claims from the token
 	if !ok || !token.Valid {                   // Check if the claims are valid
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"}) // Return an unauthorized response with an error message
-		return                                                                  // Return from the function
+		c.Error(apperr.Unauthorized("invalid_token_claims", "Invalid token claims")) // Return an unauthorized response with an error message
+		return                                                                       // Return from the function
 	}
 
 	userIDHex, ok := claims["id"].(string) // Get the user ID from the claims
 	if !ok {                               // Check if the user ID is valid
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"}) // Return an unauthorized response with an error message
+		c.Error(apperr.Unauthorized("invalid_token_claims", "Invalid token claims")) // Return an unauthorized response with an error message
 		return
 	}
 
 	userID, err := primitive.ObjectIDFromHex(userIDHex) // Convert the user ID to an ObjectID
 	if err != nil {                                     // Check if there is an error converting the ID
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"}) // Return an unauthorized response with an error message
+		c.Error(apperr.Unauthorized("invalid_user_id", "Invalid user ID")) // Return an unauthorized response with an error message
 		return
 	}
 
-	log.Printf("User ID from token: %s", userID.Hex()) // Log the user ID from the token for debugging
+	slog.DebugContext(c.Request.Context(), "User ID from token", slog.String("user_id", userID.Hex())) // Log the user ID from the token for debugging
 
-	var user models.User                                                              // Define a user variable
-	err = userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user) // Find the user by ID from the token claims
-	if err != nil {                                                                   // Check if there is an error finding the user
-		log.Printf("Error retrieving user: %v", err)                      // Log error details
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"}) // Return an unauthorized response with an error message
+	user, err := ctrl.repos.Users.FindByID(c.Request.Context(), userID) // Find the user by ID from the token claims
+	if err != nil {                                                     // Check if there is an error finding the user
+		slog.WarnContext(c.Request.Context(), "Error retrieving user", slog.Any("error", err)) // Log error details
+		c.Error(apperr.Unauthorized("user_not_found", "User not found"))                       // Return an unauthorized response with an error message
 		return
 	}
 
-	log.Printf("Searching for user with ID: %s", userID.Hex()) // Log the user ID for debugging
-
-	if user.Role != "coach" && user.Role != "business owner" { // Check if the user is not a coach or business owner
-		c.JSON(http.StatusForbidden, gin.H{ // Return a forbidden response
-			"error": "You do not have the required permissions to create a session", // Return an error message
-		})
+	if user.Role != models.RoleCoach && user.Role != models.RoleBusinessOwner { // Check if the user is not a coach or business owner
+		c.Error(apperr.Forbidden("forbidden", "You do not have the required permissions to create a session")) // Return a forbidden response
 		return
 	}
 
-	var session models.Session                         // Define a session variable
-	if err := c.ShouldBindJSON(&session); err != nil { // Bind the JSON data to the session variable
-		c.JSON(http.StatusBadRequest, gin.H{ // Return a bad request response
-			"error": err.Error(), // Return the error message
-		})
-		return // Return from the function
+	var request dto.SessionRequest                // Define a session request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON data to the session request variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
-	session.ID = primitive.NewObjectID() // Generate a new ObjectID for the session
-	session.Status = "active"            // Set the status of the session to active
-	session.CreatedAt = time.Now()       // Set the created time
-	session.UpdatedAt = time.Now()       // Set the updated time
+	session := models.Session{OwnerID: user.ID, Coach: user.ID} // Define a session owned and coached by its creator unless another coach is given
+	request.Apply(&session)                                     // Copy the fields of the request
+	session.ID = primitive.NewObjectID()                        // Generate a new ObjectID for the session
+	session.Status = models.SessionStatusActive                 // Set the status of the session to active
+	session.CreatedAt = time.Now()                              // Set the created time
+	session.UpdatedAt = time.Now()                              // Set the updated time
 
-	_, err = sessionCollection.InsertOne(context.TODO(), session) // Insert the session
-	if err != nil {                                               // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-			"error": fmt.Sprintf("Failed to create session: %v", err), // Return the error message
-		})
-		return // Return from the function
-	}
+	notification := newNotification(user.ID, "Session Created", fmt.Sprintf("The session '%s' has been created.", session.Title)) // Notify the creator
 
-	notification := models.Notification{ // Define a notification variable with the required fields
-		ID:        primitive.NewObjectID(),                                          // Generate a new ObjectID for the notification
-		UserID:    user.ID,                                                          // Assuming session.CreatorID holds the ID of the user to notify
-		Type:      "Session Created",                                                // Set the notification type
-		Message:   fmt.Sprintf("The session '%s' has been created.", session.Title), // Message for the notification
-		CreatedAt: time.Now(),                                                       // Set the created_at timestamp
-		UpdatedAt: time.Now(),                                                       // Set the updated_at timestamp
-	}
-
-	err = SendSessionNotification(notification) // Send the session notification
-	if err != nil {                             // Check if there is an error sending the notification
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"}) // Return an error response
-		return                                                                                // Return from the function
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Insert the session, its event and its notification together
+		if err := ctrl.repos.Sessions.Insert(ctx, &session); err != nil { // Insert the session
+			return err // Return the error
+		}
+		if err := ctrl.recordEvent(ctx, models.EventSessionCreated, models.AggregateSession, session.ID, session); err != nil { // Announce the session
+			return err // Return the error
+		}
+		return ctrl.queueNotifications(ctx, models.AggregateSession, session.ID, []models.Notification{notification}) // Notify the creator once the session is committed
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to create session: %w", err)) // Return an error response
+		return                                                   // Return from the function
 	}
+	ctrl.metrics.SessionsCreated.Inc() // Count the created session
 
-	c.JSON(http.StatusCreated, gin.H{ // Return a created response
-		"message":           "Session created successfully", // Return a success message
-		"notification_sent": true,                           // Return a notification sent status
-		"session":           session,                        // Return the created session
+	describeChange(c, "sessions", session.ID, nil, session) // Record the created fields in the audit log
+	setETag(c, session.Version)                             // Tag the response with the version of the session
+	c.JSON(http.StatusCreated, gin.H{                       // Return a created response
+		"message":              "Session created successfully", // Return a success message
+		"notifications_queued": 1,                              // Return the number of notifications queued
+		"session":              session,                        // Return the created session
 	}) // Return the created session and success message
 }
 
-// UpdateSession: Updates a session and sends a notification to the creator
-func UpdateSession(c *gin.Context) { // Update a session
+// UpdateSession: Updates a session, with the same permissions as PatchSession, and sends a notification to the creator
+func (ctrl *Controller) UpdateSession(c *gin.Context) { // Update a session
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
-	var session models.Session // Define a session variable
-	var user models.User       // Define a user variable
+	var request dto.SessionRequest // Define a session request variable
 
-	if err := c.ShouldBindJSON(&session); err != nil { // Bind the JSON data to the session variable
-		c.JSON(http.StatusBadRequest, gin.H{ // Return a bad request response
-			"error": err.Error(), // Return the error message
-		}) // Return a bad request response
-		return // Return from the function
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON data to the session request variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
 	// Convert the sessionID to an ObjectID
 	objectID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                       // Check if there is an error
-		c.JSON(http.StatusBadRequest, gin.H{ // Return a bad request response
-			"error": "Invalid session ID format", // Return an error message
-		})
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
+	}
+
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	session, err := ctrl.repos.Sessions.FindByID(c.Request.Context(), objectID) // Find the session to keep its server-managed fields
+	if err != nil {                                                             // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the session was not found
+			c.Error(apperr.NotFound("session_not_found", "Session not found")) // Return a not found response
+		} else { // Check if there is another error
+			c.Error(err) // Return an error response
+		}
 		return // Return from the function
 	}
 
-	session.ID = objectID          // Set the session ID to the converted ObjectID
+	previous := session                                                                                            // Keep the schedule before the update
+	request.Apply(&session)                                                                                        // Copy the fields of the request
+	fields := dto.ChangedFields(dto.NewSessionRequest(previous), dto.NewSessionRequest(session))                   // List the fields the request changes
+	if err := checkPatchFields(&dto.SessionRequest{}, fields, sessionWritableFields(previous, user)); err != nil { // Check that the user may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, previous.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
 	session.UpdatedAt = time.Now() // Set the updated time
 
-	// Update the session
-	result, err := sessionCollection.ReplaceOne(context.TODO(), bson.M{"_id": objectID}, session) // Replace the session document with the updated session
-	if err != nil {                                                                               // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{ // Return an error response
-			"error": err.Error(), // Return the error message
-		})
+	// Update the session and queue the notification of the user with it
+	notification := newNotification(user.ID, "Session Updated", fmt.Sprintf("The session '%s' has been updated.", session.Title)) // Notify the user
+	err = ctrl.replaceSession(c.Request.Context(), &session, previous, notification)                                              // Replace the session document if nobody changed it in the meantime
+	if err != nil {                                                                                                               // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the session was not found
+			c.Error(apperr.NotFound("session_not_found", "Session not found")) // Return a not found response
+		} else { // Check if there is another error
+			c.Error(versionError(err)) // Return an error response
+		}
 		return // Return from the function
 	}
 
-	// Check if any document was modified
-	if result.ModifiedCount == 0 { // Check if the document was not modified
-		c.JSON(http.StatusNotFound, gin.H{ // Return a not found response
-			"error": "No document found with the given ID", // Return an error message
-		})
-		return
+	describeChange(c, "sessions", session.ID, previous, session) // Record the changed fields in the audit log
+	setETag(c, session.Version)                                  // Tag the response with the new version of the session
+	c.JSON(http.StatusOK, session)                               // Return the updated session
+}
+
+// sessionWritableFields returns the fields of the session the user may patch: the managers of the session may change
+// every field, the assistants of the coach only the practical details, anyone else nothing
+func sessionWritableFields(session models.Session, user models.User) []string { // List the writable fields
+	switch { // Check the relation of the user to the session
+	case canManageSession(session, user): // Owner, coach or admin
+		return dto.Fields(dto.SessionRequest{}) // Every field
+	case slices.Contains(session.CoachAssists, user.ID): // Assistant of the coach
+		return []string{"description", "location", "recurrence"} // Practical details
 	}
+	return nil // Nothing
+}
 
-	// Prepare notification data
-	notification := models.Notification{ // Define a notification variable with the required fields
-		ID:        primitive.NewObjectID(),                                          // Generate a new ObjectID for the notification
-		UserID:    user.ID,                                                          // Assuming session.CreatorID holds the ID of the user to notify
-		Type:      "Session Updated",                                                // Set the notification type
-		Message:   fmt.Sprintf("The session '%s' has been updated.", session.Title), // Message for the notification
-		CreatedAt: time.Now(),                                                       // Set the created_at timestamp
-		UpdatedAt: time.Now(),                                                       // Set the updated_at timestamp
+// PatchSession: Applies a JSON merge patch to a session
+func (ctrl *Controller) PatchSession(c *gin.Context) { // Patch a session
+	objectID, err := primitive.ObjectIDFromHex(c.Param("sessionId")) // Convert the session ID to an ObjectID
+	if err != nil {                                                  // Check if there is an error
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
 	}
 
-	// Send session notification
-	err = SendSessionNotification(notification) // Send the session notification
-	if err != nil {                             // Check if there is an error sending the notification
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"}) // Return an error response
-		return                                                                                // Return from the function
+	patch, fields, err := readMergePatch(c) // Read the patch
+	if err != nil {                         // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
-	c.JSON(http.StatusOK, session) // Return the updated session
-}
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	session, err := ctrl.repos.Sessions.FindByID(c.Request.Context(), objectID) // Find the session to patch
+	if err != nil {                                                             // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the session was not found
+			c.Error(apperr.NotFound("session_not_found", "Session not found")) // Return a not found response
+		} else { // Check if there is another error
+			c.Error(err) // Return an error response
+		}
+		return // Return from the function
+	}
+
+	request := dto.NewSessionRequest(session)                                                        // Describe the current state of the session
+	if err := checkPatchFields(&request, fields, sessionWritableFields(session, user)); err != nil { // Check that the user may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, session.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
+	if err := applyMergePatch(&request, patch); err != nil { // Patch and validate the session
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	previous := session            // Keep the schedule before the patch
+	request.Apply(&session)        // Copy the patched fields
+	session.UpdatedAt = time.Now() // Set the updated time
+
+	err = ctrl.replaceSession(c.Request.Context(), &session, previous) // Replace the session document if nobody changed it in the meantime
+	if err != nil {                                                    // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the session was not found
+			c.Error(apperr.NotFound("session_not_found", "Session not found")) // Return a not found response
+		} else { // Check if there is another error
+			c.Error(versionError(err)) // Return an error response
+		}
+		return // Return from the function
+	}
 
-// DeleteSession: Deletes a session from the database
-func DeleteSession(sessionID primitive.ObjectID) error { // Delete a session
-	_, err := sessionCollection.DeleteOne(context.TODO(), bson.M{"_id": sessionID}) // Delete the session
-	return err                                                                      // Return the error
+	describeChange(c, "sessions", session.ID, previous, session) // Record the changed fields in the audit log
+	setETag(c, session.Version)                                  // Tag the response with the new version of the session
+	c.JSON(http.StatusOK, session)                               // Return the patched session
 }
 
-func EnrollInSession(c *gin.Context) { // Enroll user in a session
+func (ctrl *Controller) EnrollInSession(c *gin.Context) { // Enroll user in a session
 	userID := c.Param("userId")       // Get the user ID from the URL
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
-	// Check if user exists
-	var user models.User
-	err := userCollection.FindOne(context.TODO(), bson.M{"_id": userID}).Decode(&user) // Find the user by ID
-	if err != nil {                                                                    // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the user was not found
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"}) // Return a not found response
-			return                                                        // Return from the function
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	// Convert userID and sessionID to ObjectID
+	objectUserID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
+	if err != nil {                                        // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
 	}
 
-	// Check if session exists
-	var session models.Session                                                                 // Define a session variable
-	err = sessionCollection.FindOne(context.TODO(), bson.M{"_id": sessionID}).Decode(&session) // Find the session by ID
-	if err != nil {                                                                            // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the session was not found
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"}) // Return a not found response
-			return                                                           // Return from the function
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	objectSessionID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
+	if err != nil {                                              // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
 	}
 
-	// Check if user is already enrolled
-	for _, participant := range session.Participants { // Iterate over the participants array
-		if participant == userID { // Check if the user is already enrolled
-			c.JSON(http.StatusBadRequest, gin.H{"error": "User already enrolled in the session"}) // Return a bad request response
-			return                                                                                // Return from the function
+	// Check the user and the session and enroll or waitlist the user in one transaction, so a concurrent enrollment cannot slip in between
+	var waitlisted bool                                                                                  // Define whether the user was put on the waitlist
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the enrollment in a transaction
+		// Check if user exists
+		_, err := ctrl.repos.Users.FindByID(ctx, objectUserID) // Find the user by ID
+		if errors.Is(err, repository.ErrNotFound) {            // Check if the user was not found
+			return apperr.NotFound("user_not_found", "User not found") // Return a not found error
+		}
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
+
+		// Check if session exists and is open
+		session, err := ctrl.repos.Sessions.FindByID(ctx, objectSessionID) // Find the session by ID
+		if errors.Is(err, repository.ErrNotFound) {                        // Check if the session was not found
+			return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
+		}
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
+		if !session.Open(time.Now()) { // Check if the session is archived or over
+			return apperr.Conflict("session_closed", "Session is not open for enrollment") // Return a conflict error
+		}
+
+		// Check if user is already enrolled or waiting
+		if slices.Contains(session.Participants, objectUserID) { // Check if the user is already enrolled
+			return apperr.Conflict("already_enrolled", "User already enrolled in the session") // Return a conflict error
+		}
+		if slices.Contains(session.Waitlist, objectUserID) { // Check if the user is already waiting
+			return apperr.Conflict("already_waitlisted", "User already on the waitlist of the session") // Return a conflict error
+		}
+
+		// Enroll the user while the session has room, the update checks the capacity again
+		waitlisted = session.Full() // Check if there is no place left, the transaction may be retried
+		if !waitlisted {            // Check if there is a place left
+			err = ctrl.repos.Sessions.AddParticipant(ctx, objectSessionID, objectUserID) // Add the user to the participants array field
+			if errors.Is(err, repository.ErrNotFound) {                                  // Check if the session was deleted in the meantime
+				return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
+			}
+			waitlisted = errors.Is(err, repository.ErrConflict) // Check if the last place was taken in the meantime
+			if err != nil && !waitlisted {                      // Check if there is an error
+				return err // Return the error
+			}
+		}
+
+		// Put the user on the waitlist of a full session
+		if waitlisted { // Check if the user has to wait
+			err = ctrl.repos.Sessions.AddToWaitlist(ctx, objectSessionID, objectUserID) // Add the user to the waitlist
+			if errors.Is(err, repository.ErrNotFound) {                                 // Check if the session was deleted in the meantime
+				return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
+			}
+			if err != nil { // Check if there is an error
+				return err // Return the error
+			}
+			notification := newNotification(objectUserID, "Session Waitlist", fmt.Sprintf("The session '%s' is full, you are on its waitlist.", session.Title)) // Define the notification
+			return ctrl.queueNotifications(ctx, models.AggregateSession, objectSessionID, []models.Notification{notification})                                  // Notify the user once the change is committed
 		}
-	}
 
-	// Enroll user in the session
-	_, err = sessionCollection.UpdateOne( // Update the session document to add the user to the participants array field
-		context.TODO(),           // Context for the operation to execute in the database server environment (required by the driver)
-		bson.M{"_id": sessionID}, // Filter to find the session by ID
-		bson.M{"$push": bson.M{"participants": userID}}, // Update operation to push the user ID to the participants array
-	)
+		return ctrl.announceEnrollment(ctx, session, objectUserID) // Announce the enrollment and notify the user
+	})
 	if err != nil { // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	if waitlisted { // Check if the user is waiting for a place
+		ctrl.metrics.Enrollments.WithLabelValues("waitlist").Inc()                                                       // Count the waitlisted user
+		c.JSON(http.StatusAccepted, gin.H{"message": "Session is full, user added to the waitlist", "waitlisted": true}) // Return an accepted response
+		return                                                                                                           // Return from the function
 	}
+	ctrl.metrics.Enrollments.WithLabelValues("enroll").Inc() // Count the enrollment
 
 	// Respond with success
-	c.JSON(http.StatusOK, gin.H{"message": "User enrolled in session successfully"}) // Return a success response
+	c.JSON(http.StatusOK, gin.H{"message": "User enrolled in session successfully", "waitlisted": false}) // Return a success response
 }
 
-func CancelEnrollment(c *gin.Context) { // Cancel user enrollment in a session
+// CancelEnrollment: Removes a user from the participants or the waitlist of a session, then enrolls the first
+// waitlisted users while the session has room
+func (ctrl *Controller) CancelEnrollment(c *gin.Context) { // Cancel user enrollment in a session
 	userID := c.Param("userId")       // Get the user ID from the URL
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
 	// Convert userID and sessionID to ObjectID
 	objectUserID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
 	if err != nil {                                        // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return a bad request response
-		return                                                           // Return from the function
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
 	}
 
 	objectSessionID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                              // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"}) // Return a bad request response
-		return                                                              // Return from the function
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
 	}
 
-	// Remove user from the session
-	filter := bson.M{"_id": objectSessionID}                                   // Define the filter to find the session by ID
-	update := bson.M{"$pull": bson.M{"participants": objectUserID}}            // Define the update operation to remove the user from the participants array
-	result, err := sessionCollection.UpdateOne(context.TODO(), filter, update) // Update the session
-	if err != nil {                                                            // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
-	}
+	// Remove user from the session and give the freed place to the waitlist in one transaction
+	var promoted int                                                                                     // Define the number of waitlisted users enrolled
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the cancellation in a transaction
+		err := ctrl.repos.Sessions.RemoveParticipant(ctx, objectSessionID, objectUserID) // Remove the user from the participants and the waitlist
+		if errors.Is(err, repository.ErrNotFound) {                                      // Check if the session was not found
+			return apperr.NotFound("session_or_user_not_found", "Session or user not found") // Return a not found error
+		}
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
 
-	if result.MatchedCount == 0 { // Check if the session was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session or user not found"}) // Return a not found response
-		return                                                                   // Return from the function
+		session, err := ctrl.repos.Sessions.FindByID(ctx, objectSessionID) // Find the session without the user
+		if err != nil {                                                    // Check if there is an error
+			return err // Return the error
+		}
+		promoted, err = ctrl.fillFromWaitlist(ctx, &session) // Enroll the waiting users
+		return err                                           // Return the error
+	})
+	if err != nil { // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
+	ctrl.metrics.Enrollments.WithLabelValues("cancel").Inc()                  // Count the cancelled enrollment
+	ctrl.metrics.Enrollments.WithLabelValues("enroll").Add(float64(promoted)) // Count the waitlisted users enrolled
 
 	c.JSON(http.StatusOK, gin.H{"message": "Enrollment canceled successfully"}) // Return a success response
 }
 
-// CancelSession: Cancels a session and sends a notification to the creator
-func CancelSession(c *gin.Context) { // Cancel a session
+// canManageSession reports whether the user may change, cancel and archive the session: its owner, its coach and the admins
+func canManageSession(session models.Session, user models.User) bool { // Check if the user manages the session
+	return user.Role == models.RoleAdmin || session.OwnerID == user.ID || session.Coach == user.ID // Admin, owner or coach
+}
+
+// CancelSession: Cancels a session and its invitations, soft deleting them until they are restored by an admin or
+// purged, and notifies the coach, the assistants, the participants and the waitlisted users
+func (ctrl *Controller) CancelSession(c *gin.Context) { // Cancel a session
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
 	// Convert sessionID to ObjectID
 	objectSessionID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                              // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"}) // Return a bad request response
-		return                                                              // Return from the function
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
 	} // Check if there is an error converting the ID
 
-	// Find the session to get details
-	var session models.Session
-	var user models.User                                                                             // Define a session variable
-	err = sessionCollection.FindOne(context.TODO(), bson.M{"_id": objectSessionID}).Decode(&session) // Find the session by ID
-	if err != nil {                                                                                  // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the session was not found
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"}) // Return a not found response
-		} else { // Check if there is another error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		} // Check if there is another error
-		return // Return from the function
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
 	}
 
-	// Prepare notification data
-	notification := models.Notification{ // Define a notification variable with the required fields
-		ID:        primitive.NewObjectID(),                                           // Generate a new ObjectID for the notification
-		UserID:    user.ID,                                                           // Assuming this holds the ID of the user to notify
-		Type:      "Session Cancellation",                                            // Set the type of notification
-		Message:   fmt.Sprintf("The session '%s' has been canceled.", session.Title), // Set the message
-		CreatedAt: time.Now(),                                                        // Set the created_at timestamp
-		UpdatedAt: time.Now(),                                                        // Set the updated_at timestamp
+	deletion, err := ctrl.newDeletion(c, "Session cancelled") // Describe the cancellation
+	if err != nil {                                           // Check if there is an error
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
 
-	// Send session notification
-	err = SendSessionNotification(notification) // Send the session notification
-	if err != nil {                             // Check if there is an error sending the notification
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"}) // Return an error response
-		return                                                                                // Return from the function
-	} // Send session notification
+	// Delete the session and its invitations in one transaction, with the same deletion so they are restored together
+	var notifications []models.Notification                                                              // Define the notifications of the cancellation
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the cancellation in a transaction
+		session, err := ctrl.repos.Sessions.FindByID(ctx, objectSessionID) // Find the session to get details
+		if errors.Is(err, repository.ErrNotFound) {                        // Check if the session was not found
+			return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
+		}
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
+		if !canManageSession(session, user) { // Check if the user may cancel the session
+			return apperr.Forbidden("forbidden", "You do not have the required permissions to cancel this session") // Return a forbidden error
+		}
+
+		if err := ctrl.repos.Sessions.SoftDelete(ctx, objectSessionID, deletion); err != nil { // Soft delete the session
+			return err // Return the error
+		}
+		if err := ctrl.repos.Invitations.SoftDeleteBySession(ctx, objectSessionID, deletion); err != nil { // Soft delete the invitations to the session
+			return err // Return the error
+		}
 
-	// Delete the session from the database
-	err = DeleteSession(objectSessionID) // Delete the session
-	if err != nil {                      // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+		// Notify the people taking part once the session is gone
+		notifications = sessionNotifications(session, "Session Cancellation", fmt.Sprintf("The session '%s' has been canceled.", session.Title)) // Define the notifications
+		return ctrl.queueNotifications(ctx, models.AggregateSession, objectSessionID, notifications)                                             // Queue the notifications with the cancellation
+	})
+	if err != nil { // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
+	ctrl.metrics.SessionsCancelled.Inc()                                        // Count the cancelled session
+	describeChange(c, "sessions", objectSessionID, models.Deletion{}, deletion) // Record the changed fields in the audit log
 
-	c.JSON(http.StatusOK, gin.H{"message": "Session canceled and notification sent successfully"}) // Return a success response
+	c.JSON(http.StatusOK, gin.H{"message": "Session canceled successfully", "notifications_queued": len(notifications)}) // Return a success response
 }
 
-// ArchiveSession: Archives a session and sends a notification to the creator
-func ArchiveSession(c *gin.Context) { // Archive a session
+// ArchiveSession: Archives a session and notifies the coach, the assistants, the participants and the waitlisted users
+func (ctrl *Controller) ArchiveSession(c *gin.Context) { // Archive a session
 	sessionID := c.Param("sessionId") // Get the session ID from the URL
 
 	// Convert sessionID to ObjectID
 	objectSessionID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 	if err != nil {                                              // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"}) // Return a bad request response
-		return                                                              // Return from the function
+		c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                                  // Return from the function
 	}
 
-	// Find the session to get details
-	var session models.Session
-	var user models.User                                                                             // Define a session variable
-	err = sessionCollection.FindOne(context.TODO(), bson.M{"_id": objectSessionID}).Decode(&session) // Find the session by ID
-	if err != nil {                                                                                  // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the session was not found
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"}) // Return a not found response
-		} else { // If there's another error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	// Archive the session and queue its notifications in one transaction
+	var notifications []models.Notification                                                              // Define the notifications of the archiving
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the archiving in a transaction
+		session, err := ctrl.repos.Sessions.FindByID(ctx, objectSessionID) // Find the session to get details
+		if errors.Is(err, repository.ErrNotFound) {                        // Check if the session was not found
+			return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
 		}
-		return // Return from the function
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
+		if !canManageSession(session, user) { // Check if the user may archive the session
+			return apperr.Forbidden("forbidden", "You do not have the required permissions to archive this session") // Return a forbidden error
+		}
+
+		err = ctrl.repos.Sessions.SetStatus(ctx, objectSessionID, models.SessionStatusArchived) // Set the status to "archived"
+		if errors.Is(err, repository.ErrNotFound) {                                             // Check if the session was deleted in the meantime
+			return apperr.NotFound("session_not_found", "Session not found") // Return a not found error
+		}
+		if err != nil { // Check if there is an error
+			return err // Return the error
+		}
+
+		// Notify the people taking part once the session is archived
+		notifications = sessionNotifications(session, "Session Archived", fmt.Sprintf("The session '%s' has been archived.", session.Title)) // Define the notifications
+		return ctrl.queueNotifications(ctx, models.AggregateSession, objectSessionID, notifications)                                         // Queue the notifications with the archiving
+	})
+	if err != nil { // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session archived successfully", "notifications_queued": len(notifications)}) // Return a success response
+}
+
+// sessionNotifications builds a notification for each person taking part in a session: the coach, the assistants,
+// the participants and the waitlisted users
+func sessionNotifications(session models.Session, kind, message string) []models.Notification { // Build the notifications of a session
+	recipients := append([]primitive.ObjectID{session.Coach}, session.CoachAssists...) // Start with the coach and the assistants
+	recipients = append(recipients, session.Participants...)                           // Add the participants
+	recipients = append(recipients, session.Waitlist...)                               // Add the waitlisted users
+
+	var notifications []models.Notification                    // Define a slice to hold the notifications
+	seen := make(map[primitive.ObjectID]bool, len(recipients)) // Define the notified users
+	for _, userID := range recipients {                        // Iterate over the recipients
+		if userID.IsZero() || seen[userID] { // Check if there is no one to notify or the user is already notified
+			continue // Skip the user
+		}
+		seen[userID] = true                                                           // Mark the user as notified
+		notifications = append(notifications, newNotification(userID, kind, message)) // Add the notification
+	}
+	return notifications // Return the notifications
+}
+
+// newNotification builds a notification to a user
+func newNotification(userID primitive.ObjectID, kind, message string) models.Notification { // Build a notification
+	return models.Notification{
+		ID:        primitive.NewObjectID(), // Generate a new ObjectID for the notification
+		UserID:    userID,                  // Set the user to notify
+		Type:      kind,                    // Set the type of notification
+		Message:   message,                 // Set the message
+		CreatedAt: time.Now(),              // Set the created_at timestamp
+		UpdatedAt: time.Now(),              // Set the updated_at timestamp
 	}
+}
 
-	// Prepare notification data
-	notification := models.Notification{ // Define a notification variable with the required fields
-		ID:        primitive.NewObjectID(),                                           // Generate a new ObjectID for the notification
-		UserID:    user.ID,                                                           // Assuming session.CreatorID holds the ID of the user to notify
-		Type:      "Session Archived",                                                // Set the notification type
-		Message:   fmt.Sprintf("The session '%s' has been archived.", session.Title), // Message for the notification
-		CreatedAt: time.Now(),                                                        // Set the created_at timestamp
-		UpdatedAt: time.Now(),                                                        // Set the updated_at timestamp
+// announceEnrollment records the ParticipantEnrolled event of a user just enrolled in a session and queues their
+// notification, in the transaction of the enrollment
+func (ctrl *Controller) announceEnrollment(ctx context.Context, session models.Session, userID primitive.ObjectID) error { // Announce an enrollment
+	if err := ctrl.recordEvent(ctx, models.EventParticipantEnrolled, models.AggregateSession, session.ID, gin.H{"session_id": session.ID, "user_id": userID}); err != nil { // Announce the enrollment
+		return err // Return the error
 	}
+	notification := newNotification(userID, "Session Enrollment", fmt.Sprintf("You are enrolled in the session '%s'.", session.Title)) // Define the notification
+	return ctrl.queueNotifications(ctx, models.AggregateSession, session.ID, []models.Notification{notification})                      // Notify the user once the change is committed
+}
 
-	// Send session notification
-	err = SendSessionNotification(notification) // Send the session notification
-	if err != nil {                             // Check if there is an error sending the notification
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"}) // Return an error response
-		return                                                                                // Return from the function
+// fillFromWaitlist enrolls the first waitlisted users of an open session while it has room, in the transaction of
+// the change that freed the places, and returns their number. The session is read again when anyone was enrolled,
+// so it holds its new version.
+func (ctrl *Controller) fillFromWaitlist(ctx context.Context, session *models.Session) (int, error) { // Enroll the waiting users
+	if !session.Open(time.Now()) { // Check if the session is archived or over
+		return 0, nil // Nobody is enrolled any more
 	}
 
-	// Update the session status to "archived"
-	update := bson.M{"$set": bson.M{"status": "archived"}}                                       // Set the status to "archived"
-	_, err = sessionCollection.UpdateOne(context.TODO(), bson.M{"_id": objectSessionID}, update) // Update the session
-	if err != nil {                                                                              // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	waiting := session.Waitlist               // Get the waiting users, first come first served
+	promoted := 0                             // Define the number of enrolled users
+	for len(waiting) > 0 && !session.Full() { // Iterate while someone waits for a free place
+		userID := waiting[0]  // Take the first waiting user
+		waiting = waiting[1:] // Remove them from the queue
+
+		if err := ctrl.repos.Sessions.PromoteWaitlisted(ctx, session.ID, userID); err != nil { // Move the user to the participants
+			return 0, err // Return the error
+		}
+		session.Participants = append(session.Participants, userID)            // Count the new participant
+		if err := ctrl.announceEnrollment(ctx, *session, userID); err != nil { // Announce the enrollment
+			return 0, err // Return the error
+		}
+		promoted++ // Count the enrolled user
+	}
+	if promoted == 0 { // Check if nobody was enrolled
+		return 0, nil // The session is unchanged
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Session archived and notification sent successfully"}) // Return a success response
+	updated, err := ctrl.repos.Sessions.FindByID(ctx, session.ID) // Read the session at its new version
+	if err != nil {                                               // Check if there is an error
+		return 0, err // Return the error
+	}
+	*session = updated   // Report the new state
+	return promoted, nil // Return the number of enrolled users
 }
@@ -0,0 +1,229 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   string // Role of the caller, anonymous when empty
+		body   map[string]any
+		status int
+		code   string
+		field  string // Field reported invalid
+	}{
+		{name: "coach", role: "coach", body: sessionBody(nil), status: http.StatusCreated},
+		{name: "business owner", role: "business owner", body: sessionBody(nil), status: http.StatusCreated},
+		{name: "anonymous", body: sessionBody(nil), status: http.StatusUnauthorized},
+		{name: "member", role: "user", body: sessionBody(nil), status: http.StatusForbidden, code: "forbidden"},
+		{name: "missing title", role: "coach", body: sessionBody(map[string]any{"title": ""}), status: http.StatusBadRequest, code: "invalid_body", field: "title"},
+		{name: "unknown training type", role: "coach", body: sessionBody(map[string]any{"training_type": "yoga"}), status: http.StatusBadRequest, code: "invalid_body", field: "training_type"},
+		{name: "ends before it starts", role: "coach", body: sessionBody(map[string]any{"end_time": time.Now()}), status: http.StatusBadRequest, code: "invalid_body", field: "end_time"},
+		{name: "negative capacity", role: "coach", body: sessionBody(map[string]any{"capacity": -1}), status: http.StatusBadRequest, code: "invalid_body", field: "capacity"},
+		{name: "invalid coach", role: "coach", body: sessionBody(map[string]any{"coach": "nobody"}), status: http.StatusBadRequest, code: "invalid_body", field: "coach"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			var caller account
+			if tt.role != "" {
+				caller = s.signUp("caller@example.com", tt.role)
+			}
+
+			res := s.do(http.MethodPost, "/sessions/create", caller.token, tt.body)
+			if res.status != tt.status || (tt.code != "" && res.code() != tt.code) {
+				t.Fatalf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+			if tt.field != "" && !hasFieldError(res, tt.field) {
+				t.Errorf("no error for %q in %v", tt.field, res.body["errors"])
+			}
+			if tt.status == http.StatusCreated {
+				session, _ := res.body["session"].(map[string]any)
+				if session["owner_id"] != caller.id || session["coach"] != caller.id || session["status"] != "active" {
+					t.Errorf("session %v is not active, owned and coached by %s", session, caller.id)
+				}
+				if res.body["notifications_queued"] != float64(1) {
+					t.Errorf("notifications_queued %v, want 1", res.body["notifications_queued"])
+				}
+			}
+		})
+	}
+}
+
+// hasFieldError reports whether a validation problem lists the field
+func hasFieldError(res response, field string) bool {
+	errs, _ := res.body["errors"].([]any)
+	return slices.ContainsFunc(errs, func(e any) bool {
+		fieldErr, _ := e.(map[string]any)
+		return fieldErr["field"] == field
+	})
+}
+
+func TestGetSessionByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID func(id string) string
+		status    int
+		code      string
+	}{
+		{name: "existing session", sessionID: func(id string) string { return id }, status: http.StatusOK},
+		{name: "invalid ID", sessionID: func(string) string { return "not-an-id" }, status: http.StatusBadRequest, code: "invalid_session_id"},
+		{name: "unknown session", sessionID: func(string) string { return primitive.NewObjectID().Hex() }, status: http.StatusNotFound, code: "session_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			coach := s.signUp("coach@example.com", "coach")
+			id := s.createSession(coach, nil)
+
+			res := s.do(http.MethodGet, "/sessions/"+tt.sessionID(id), coach.token, nil)
+			if res.status != tt.status || res.code() != tt.code {
+				t.Errorf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+		})
+	}
+}
+
+func TestEnrollInSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		capacity   int
+		before     []string // Members enrolled before the request
+		member     string   // Member enrolling
+		archived   bool     // Whether the session is archived first
+		sessionID  string   // Session enrolled in, the created one when empty
+		status     int
+		code       string
+		waitlisted bool
+	}{
+		{name: "open session", member: "ann", status: http.StatusOK},
+		{name: "last place", capacity: 2, before: []string{"bob"}, member: "ann", status: http.StatusOK},
+		{name: "full session", capacity: 1, before: []string{"bob"}, member: "ann", status: http.StatusAccepted, waitlisted: true},
+		{name: "already enrolled", before: []string{"ann"}, member: "ann", status: http.StatusConflict, code: "already_enrolled"},
+		{name: "already waitlisted", capacity: 1, before: []string{"bob", "ann"}, member: "ann", status: http.StatusConflict, code: "already_waitlisted"},
+		{name: "archived session", archived: true, member: "ann", status: http.StatusConflict, code: "session_closed"},
+		{name: "unknown session", sessionID: primitive.NewObjectID().Hex(), member: "ann", status: http.StatusNotFound, code: "session_not_found"},
+		{name: "invalid session ID", sessionID: "not-an-id", member: "ann", status: http.StatusBadRequest, code: "invalid_session_id"},
+		{name: "unknown user", member: primitive.NewObjectID().Hex(), status: http.StatusNotFound, code: "user_not_found"},
+		{name: "invalid user ID", member: "not-an-id", status: http.StatusBadRequest, code: "invalid_user_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			coach := s.signUp("coach@example.com", "coach")
+			members := map[string]account{"ann": s.signUp("ann@example.com", "user"), "bob": s.signUp("bob@example.com", "user")}
+			id := s.createSession(coach, map[string]any{"capacity": tt.capacity})
+			for _, name := range tt.before {
+				if res := s.do(http.MethodPost, "/sessions/"+id+"/user/"+members[name].id+"/enroll", members[name].token, nil); res.status >= 300 {
+					t.Fatalf("enroll %s: status %d, body %v", name, res.status, res.body)
+				}
+			}
+			if tt.archived {
+				if res := s.do(http.MethodPost, "/sessions/"+id+"/archive", coach.token, nil); res.status != http.StatusOK {
+					t.Fatalf("archive: status %d, body %v", res.status, res.body)
+				}
+			}
+			if tt.sessionID != "" {
+				id = tt.sessionID
+			}
+			userID := tt.member
+			if member, ok := members[tt.member]; ok {
+				userID = member.id
+			}
+
+			res := s.do(http.MethodPost, "/sessions/"+id+"/user/"+userID+"/enroll", coach.token, nil)
+			if res.status != tt.status || res.code() != tt.code {
+				t.Fatalf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+			if tt.code == "" && res.body["waitlisted"] != tt.waitlisted {
+				t.Errorf("waitlisted %v, want %v", res.body["waitlisted"], tt.waitlisted)
+			}
+		})
+	}
+}
+
+func TestCancelEnrollmentPromotesWaitlist(t *testing.T) {
+	s := newServer(t)
+	coach := s.signUp("coach@example.com", "coach")
+	ann := s.signUp("ann@example.com", "user")
+	bob := s.signUp("bob@example.com", "user")
+	id := s.createSession(coach, map[string]any{"capacity": 1})
+	for _, member := range []account{ann, bob} {
+		if res := s.do(http.MethodPost, "/sessions/"+id+"/user/"+member.id+"/enroll", member.token, nil); res.status >= 300 {
+			t.Fatalf("enroll: status %d, body %v", res.status, res.body)
+		}
+	}
+
+	res := s.do(http.MethodPost, "/sessions/"+id+"/user/"+ann.id+"/cancel-enrollment", ann.token, nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("cancel: status %d, body %v", res.status, res.body)
+	}
+
+	sessionID, _ := primitive.ObjectIDFromHex(id)
+	session, err := s.repos.Sessions.FindByID(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	bobID, _ := primitive.ObjectIDFromHex(bob.id)
+	if !slices.Equal(session.Participants, []primitive.ObjectID{bobID}) || len(session.Waitlist) != 0 {
+		t.Errorf("participants %v, waitlist %v; want bob enrolled from the waitlist", session.Participants, session.Waitlist)
+	}
+
+	res = s.do(http.MethodPost, "/sessions/"+primitive.NewObjectID().Hex()+"/user/"+ann.id+"/cancel-enrollment", ann.token, nil)
+	if res.status != http.StatusNotFound || res.code() != "session_or_user_not_found" {
+		t.Errorf("unknown session: status %d, code %q; want 404, session_or_user_not_found", res.status, res.code())
+	}
+}
+
+func TestCancelAndArchiveSession(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller string // Caller of the request, anonymous when empty
+		status int
+		code   string
+	}{
+		{name: "coach", caller: "coach", status: http.StatusOK},
+		{name: "owner", caller: "owner", status: http.StatusOK},
+		{name: "admin", caller: "admin", status: http.StatusOK},
+		{name: "another coach", caller: "other coach", status: http.StatusForbidden, code: "forbidden"},
+		{name: "participant", caller: "member", status: http.StatusForbidden, code: "forbidden"},
+		{name: "anonymous", status: http.StatusUnauthorized},
+	}
+	for _, action := range []string{"cancel", "archive"} {
+		for _, tt := range tests {
+			t.Run(action+" as "+tt.name, func(t *testing.T) {
+				s := newServer(t)
+				callers := map[string]account{
+					"owner":       s.signUp("owner@example.com", "business owner"),
+					"coach":       s.signUp("coach@example.com", "coach"),
+					"other coach": s.signUp("other@example.com", "coach"),
+					"member":      s.signUp("ann@example.com", "user"),
+					"admin":       s.signUpAdmin("admin@example.com"),
+				}
+				id := s.createSession(callers["owner"], map[string]any{"coach": callers["coach"].id})
+				member := callers["member"]
+				if res := s.do(http.MethodPost, "/sessions/"+id+"/user/"+member.id+"/enroll", member.token, nil); res.status != http.StatusOK {
+					t.Fatalf("enroll: status %d, body %v", res.status, res.body)
+				}
+
+				res := s.do(http.MethodPost, "/sessions/"+id+"/"+action, callers[tt.caller].token, nil)
+				if res.status != tt.status || (tt.code != "" && res.code() != tt.code) {
+					t.Fatalf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+				}
+				sessionID, _ := primitive.ObjectIDFromHex(id)
+				session, err := s.repos.Sessions.FindByID(context.Background(), sessionID)
+				changed := err != nil || session.Status == "archived"
+				if changed != (tt.status == http.StatusOK) {
+					t.Errorf("session changed: %v, want %v", changed, tt.status == http.StatusOK)
+				}
+			})
+		}
+	}
+}
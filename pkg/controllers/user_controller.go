@@ -2,65 +2,92 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
-	"training_session/config"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
 	"training_session/pkg/models"
+	"training_session/pkg/repository"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	userCollection *mongo.Collection // Define a userCollection variable
-	cfg            *config.Config    // Define a config variable
-)
+// currentUser loads the authenticated user whose ID was stored by the AuthMiddleware
+func (ctrl *Controller) currentUser(c *gin.Context) (models.User, error) { // Get the authenticated user
+	userIDHex := c.GetString("userID")                  // Get the user ID set by the AuthMiddleware
+	userID, err := primitive.ObjectIDFromHex(userIDHex) // Convert the user ID to an ObjectID
+	if err != nil {                                     // Check if there is an error converting the ID
+		return models.User{}, err // Return the error
+	}
 
-func InitializeUser(database *mongo.Database) { // Initialize the controllers
-	userCollection = database.Collection("users")       // Set the user collection
-	sessionCollection = database.Collection("sessions") // Set the session collection
+	return ctrl.repos.Users.FindByID(c.Request.Context(), userID) // Find the user by ID
 }
 
-func init() { // Initialize the configuration
-	cfg = config.LoadConfig() // Load the configuration
+// emailError maps the conflict of a user write to the email already used by another account
+func emailError(err error) error { // Describe a taken email
+	if errors.Is(err, repository.ErrConflict) { // Check if the email is taken
+		return apperr.Conflict("email_taken", "Another account already uses this email") // Return a conflict error
+	}
+	return err // Return the error
 }
 
-func GetUsers(c *gin.Context) { // Get all users
-	var users []models.User                                        // Define a users variable
-	cursor, err := userCollection.Find(context.TODO(), bson.D{{}}) // Find all users
-	if err != nil {                                                // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
-	}
-	defer cursor.Close(context.TODO()) // Close the cursor
-	for cursor.Next(context.TODO()) {  // Iterate over the cursor
-		var user models.User                         // Define a user variable
-		if err := cursor.Decode(&user); err != nil { // Decode the user
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-			return                                                              // Return from the function
-		}
-		users = append(users, user) // Append the user to the users slice
+// requireAdmin loads the authenticated user and responds with an error if they are not an admin
+func (ctrl *Controller) requireAdmin(c *gin.Context) (models.User, bool) { // Check that the user is an admin
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return user, false                                               // Return from the function
+	}
+	if user.Role != models.RoleAdmin { // Check if the user is not an admin
+		c.Error(apperr.Forbidden("forbidden", "You do not have the required permissions to perform this action")) // Return a forbidden response
+		return user, false                                                                                        // Return from the function
+	}
+	return user, true // Return the admin
+}
+
+// viewer returns the user calling the route, nil for anonymous callers
+func (ctrl *Controller) viewer(c *gin.Context) *models.User { // Get the caller
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if the caller is anonymous or unknown
+		return nil // Return no user
 	}
-	c.JSON(http.StatusOK, users) // Return a success response
+	return &user // Return the caller
 }
 
-func RegisterUser(c *gin.Context) { // Create a user
-	var user models.User                      // Define a user variable
-	if err := c.BindJSON(&user); err != nil { // Bind the JSON to the user struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Return a bad request response
-		return                                                     // Return from the function
+func (ctrl *Controller) GetUsers(c *gin.Context) { // Get all users
+	users, err := ctrl.repos.Users.FindAll(c.Request.Context()) // Find all users
+	if err != nil {                                             // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
+	viewer := ctrl.viewer(c)                 // Get the caller
+	views := make([]interface{}, len(users)) // Define a slice to hold the views of the users
+	for i, user := range users {             // Iterate over the users
+		views[i] = dto.UserView(user, viewer) // Add the view of the user the caller may see
+	}
+	c.JSON(http.StatusOK, views) // Return a success response
+}
+
+func (ctrl *Controller) RegisterUser(c *gin.Context) { // Create a user
+	var request dto.RegisterUserRequest           // Define a registration request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the registration request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	user := request.ToModel() // Get the user to register
+
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost) // Hash the password
 	if err != nil {                                                                               // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"}) // Return an error response
-		return                                                                            // Return from the function
+		c.Error(fmt.Errorf("failed to hash password: %w", err)) // Return an error response
+		return                                                  // Return from the function
 	}
 	user.Password = string(hashedPassword) // Store the hashed password
 
@@ -68,168 +95,325 @@ func RegisterUser(c *gin.Context) { // Create a user
 	user.CreatedAt = time.Now()       // Set the created_at timestamp
 	user.UpdatedAt = time.Now()       // Set the updated_at timestamp
 
-	_, err = userCollection.InsertOne(context.TODO(), user) // Insert the user
-	if err != nil {                                         // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	err = ctrl.repos.Users.Insert(c.Request.Context(), &user) // Insert the user
+	if err != nil {                                           // Check if there is an error
+		c.Error(emailError(err)) // Return an error response
+		return                   // Return from the function
 	}
-	c.JSON(http.StatusCreated, user) // Return the created user
+	describeChange(c, "users", user.ID, nil, user)    // Record the created fields in the audit log
+	setViewETag(c, user.Version, dto.UserViewSelf)    // Tag the response with the version of the user
+	c.JSON(http.StatusCreated, dto.NewSelfUser(user)) // Return the created user as seen by themselves
 }
 
-func LoginUser(c *gin.Context) { // Login a user
-	var user models.User // Define a user variable
-	if err := c.ShouldBindJSON(&user); err != nil { // Bind the JSON to the user struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"}) // Return an error response
+func (ctrl *Controller) LoginUser(c *gin.Context) { // Login a user
+	var request dto.LoginRequest                  // Define a login request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the login request struct
+		c.Error(err) // Return an error response
 		return
 	}
 
 	// Retrieve the user from the database
-	var foundUser models.User                                                                     // Define a user variable
-	err := userCollection.FindOne(context.TODO(), bson.M{"email": user.Email}).Decode(&foundUser) // Find the user by email
-	if err != nil {                                                                               // Check if there is an error
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"}) // Return an error response
-		return                                                            // Return from the function
+	foundUser, err := ctrl.repos.Users.FindByEmail(c.Request.Context(), dto.NormalizeEmail(request.Email)) // Find the user by email
+	if err != nil {                                                                                        // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an error response
+		return                                                           // Return from the function
 	}
 
 	// Check if the password is correct (use proper hashing in production)
-	err = bcrypt.CompareHashAndPassword([]byte(foundUser.Password), []byte(user.Password)) // Compare the hashed password
-	if err != nil {                                                                        // Check if there is an error
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"}) // Return an error response
-		return                                                                 // Return from the function
+	err = bcrypt.CompareHashAndPassword([]byte(foundUser.Password), []byte(request.Password)) // Compare the hashed password
+	if err != nil {                                                                           // Check if there is an error
+		c.Error(apperr.Unauthorized("invalid_credentials", "Invalid credentials")) // Return an error response
+		return                                                                     // Return from the function
 	}
 
 	// Create JWT token with the correct user ID
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{ // Create a new JWT token
-		"id":  foundUser.ID.Hex(),                    // Convert ObjectID to hex string
-		"exp": time.Now().Add(time.Hour * 72).Unix(), // Set the expiration time to 72 hours
+		"id":  foundUser.ID.Hex(),                            // Convert ObjectID to hex string
+		"exp": time.Now().Add(ctrl.cfg.Auth.TokenTTL).Unix(), // Set the expiration time to the configured token lifetime
 	})
 
 	// Sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(cfg.JwtSecretKey)) // Sign the token with the secret key
-	if err != nil {                                                  // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"}) // Return an error response
+	tokenString, err := token.SignedString([]byte(ctrl.cfg.JwtSecretKey)) // Sign the token with the secret key
+	if err != nil {                                                       // Check if there is an error
+		c.Error(fmt.Errorf("could not generate token: %w", err)) // Return an error response
 		return
 	}
 
-	c.SetCookie("auth_token", tokenString, int(time.Hour*2), "/", "", false, true) // Set the auth token cookie
-	c.JSON(http.StatusOK, gin.H{                                                   // Return the user and token
-		"user":  foundUser,   // Return the user
-		"token": tokenString, // Return the token
+	maxAge := int(ctrl.cfg.Auth.CookieTTL.Seconds())                                                                  // Get the cookie lifetime in seconds
+	c.SetCookie("auth_token", tokenString, maxAge, "/", ctrl.cfg.Auth.CookieDomain, ctrl.cfg.Auth.CookieSecure, true) // Set the auth token cookie
+
+	c.JSON(http.StatusOK, gin.H{ // Return the user and token
+		"user":  dto.NewSelfUser(foundUser), // Return the user as seen by themselves
+		"token": tokenString,                // Return the token
 	})
 }
 
-func LogoutUser(c *gin.Context) { // Logout a user
+func (ctrl *Controller) LogoutUser(c *gin.Context) { // Logout a user
 	// Clear the auth token cookie by setting it to an empty value and setting the expiration to -1
-	c.SetCookie("auth_token", "", -1, "/", "", false, true) // Clear the auth token cookie
+	c.SetCookie("auth_token", "", -1, "/", ctrl.cfg.Auth.CookieDomain, ctrl.cfg.Auth.CookieSecure, true) // Clear the auth token cookie
 
 	// Log the logout action for debugging and auditing purposes
 	userID, _ := c.Get("userID") // Retrieve the user ID from context (assuming it's set by authentication middleware)
 	if userID != nil {           // Check if the user ID is not nil
-		log.Printf("User %s logged out successfully", userID) // Log the user ID
+		slog.InfoContext(c.Request.Context(), "User logged out", slog.Any("user_id", userID)) // Log the user ID
 	} else {
-		log.Println("Anonymous user logged out") // Log a message for anonymous users
+		slog.InfoContext(c.Request.Context(), "Anonymous user logged out") // Log a message for anonymous users
 	}
 
 	// Return a success response with a message
 	c.JSON(http.StatusOK, gin.H{"message": "User logged out successfully"}) // Return a success response
 }
 
-func GetUserByID(c *gin.Context) { // Get a user by ID
+func (ctrl *Controller) GetUserByID(c *gin.Context) { // Get a user by ID
 	userID := c.Param("userId") // Get the user ID from the URL
 
 	// Convert userID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
 	if err != nil {                                    // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return a bad request response
-		return                                                           // Return from the function
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
 	}
 
 	// Find the user document
-	var user models.User                                                                // Define a user variable
-	err = userCollection.FindOne(context.TODO(), bson.M{"_id": objectID}).Decode(&user) // Find the user by ID
-	if err != nil {                                                                     // Check if there is an error
-		if err == mongo.ErrNoDocuments { // Check if the user was not found
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"}) // Return a not found response
-			return                                                        // Return from the function
+	user, err := ctrl.repos.Users.FindByID(c.Request.Context(), objectID) // Find the user by ID
+	if err != nil {                                                       // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the user was not found
+			c.Error(apperr.NotFound("user_not_found", "User not found")) // Return a not found response
+			return                                                       // Return from the function
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
-	c.JSON(http.StatusOK, user) // Return the user
+	viewer := ctrl.viewer(c)                                              // Get the caller
+	if notModifiedView(c, user.Version, dto.UserViewName(user, viewer)) { // Check if the client holds the current version of its view
+		return // Return from the function
+	}
+	c.JSON(http.StatusOK, dto.UserView(user, viewer)) // Return the user as the caller may see them
 }
 
-func UpdateUser(c *gin.Context) { // Update a user
-	userID := c.Param("userId") // Get the user ID from the URL
-	var user models.User        // Define a user variable
+// UpdateUser: Updates the fields given in the body, with the same permissions as PatchUser
+func (ctrl *Controller) UpdateUser(c *gin.Context) { // Update a user
+	userID := c.Param("userId")       // Get the user ID from the URL
+	var request dto.UpdateUserRequest // Define an update request variable
 
-	// Bind JSON to user struct
-	if err := c.BindJSON(&user); err != nil { // Bind the JSON to the user struct
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Return a bad request response
-		return                                                     // Return from the function
+	// Bind JSON to update request struct
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the update request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
 	}
+	user := request.ToModel() // Get the fields to update
 
 	// Convert userID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
 	if err != nil {                                    // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return a bad request response
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
+	}
+
+	caller, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                    // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
 		return                                                           // Return from the function
 	}
 
+	current, err := ctrl.repos.Users.FindByID(c.Request.Context(), objectID) // Find the user to check the version the client read
+	if err != nil {                                                          // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the user was not found
+			c.Error(apperr.NotFound("user_not_found", "User not found")) // Return a not found response
+			return                                                       // Return from the function
+		}
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	next := current                                                                              // Start from the current user
+	request.Apply(&next)                                                                         // Copy the fields of the request
+	fields := dto.ChangedFields(dto.NewPatchUserRequest(current), dto.NewPatchUserRequest(next)) // List the fields the request changes
+	if user.Password != "" {                                                                     // Check if the password is being changed
+		fields = append(fields, "password") // Add the password
+	}
+	if err := checkPatchFields(&dto.PatchUserRequest{}, fields, userWritableFields(current, caller)); err != nil { // Check that the caller may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, current.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
+
 	// Hash the password if it is being updated
 	if user.Password != "" { // Check if the password is not empty
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost) // Hash the password
 		if err != nil {                                                                               // Check if there is an error
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"}) // Return an error response
+			c.Error(fmt.Errorf("failed to hash password: %w", err)) // Return an error response
 			return
 		}
 		user.Password = string(hashedPassword) // Store the hashed password
 	}
 
 	// Update the user document
-	user.ID = objectID          // Set the user ID
-	user.UpdatedAt = time.Now() // Set the updated_at timestamp
+	user.ID = objectID             // Set the user ID
+	user.Version = current.Version // Only update the version the client read
+	user.UpdatedAt = time.Now()    // Set the updated_at timestamp
+
+	err = ctrl.repos.Users.Update(c.Request.Context(), user) // Update the user document with the new data
+	if err != nil {                                          // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the user was not found
+			c.Error(apperr.NotFound("user_not_found", "User not found")) // Return a not found response
+			return                                                       // Return from the function
+		}
+		c.Error(emailError(versionError(err))) // Return an error response
+		return                                 // Return from the function
+	}
 
-	filter := bson.M{"_id": objectID}                                       // Define the filter to find the user by ID
-	update := bson.M{"$set": user}                                          // Define the update operation with the new user data (set)
-	result, err := userCollection.UpdateOne(context.TODO(), filter, update) // Update the user document with the new data
-	if err != nil {                                                         // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	updated, err := ctrl.repos.Users.FindByID(c.Request.Context(), objectID) // Find the updated user
+	if err != nil {                                                          // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
-	if result.MatchedCount == 0 { // Check if the user was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"}) // Return a not found response
-		return                                                        // Return from the function
+	describeChange(c, "users", objectID, current, updated)              // Record the changed fields in the audit log
+	setViewETag(c, updated.Version, dto.UserViewName(updated, &caller)) // Tag the response with the new version of the user
+	c.JSON(http.StatusOK, dto.UserView(updated, &caller))               // Return the updated user as the caller may see them
+}
+
+// userWritableFields returns the fields of the user the caller may patch: users may change their own profile
+// and password, admins every field of any user, including the role and CIN
+func userWritableFields(user, caller models.User) []string { // List the writable fields
+	switch { // Check the relation of the caller to the user
+	case caller.Role == models.RoleAdmin: // Admin
+		return dto.Fields(dto.PatchUserRequest{}) // Every field
+	case caller.ID == user.ID: // The user themselves
+		return []string{"name", "email", "password", "avatar"} // Profile and password
+	}
+	return nil // Nothing
+}
+
+// PatchUser: Applies a JSON merge patch to a user
+func (ctrl *Controller) PatchUser(c *gin.Context) { // Patch a user
+	objectID, err := primitive.ObjectIDFromHex(c.Param("userId")) // Convert the user ID to an ObjectID
+	if err != nil {                                               // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
+	}
+
+	patch, fields, err := readMergePatch(c) // Read the patch
+	if err != nil {                         // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	caller, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                    // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return                                                           // Return from the function
+	}
+
+	user, err := ctrl.repos.Users.FindByID(c.Request.Context(), objectID) // Find the user to patch
+	if err != nil {                                                       // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the user was not found
+			c.Error(apperr.NotFound("user_not_found", "User not found")) // Return a not found response
+			return                                                       // Return from the function
+		}
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	request := dto.NewPatchUserRequest(user)                                                     // Describe the current state of the user
+	if err := checkPatchFields(&request, fields, userWritableFields(user, caller)); err != nil { // Check that the caller may change the fields
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+	if err := checkIfMatch(c, user.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
+	if err := applyMergePatch(&request, patch); err != nil { // Patch and validate the user
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	previous := user            // Keep the user before the patch
+	request.Apply(&user)        // Copy the patched fields
+	if request.Password != "" { // Check if the password is being changed
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost) // Hash the password
+		if err != nil {                                                                                  // Check if there is an error
+			c.Error(fmt.Errorf("failed to hash password: %w", err)) // Return an error response
+			return                                                  // Return from the function
+		}
+		user.Password = string(hashedPassword) // Store the hashed password
+	}
+	user.UpdatedAt = time.Now() // Set the updated_at timestamp
+
+	err = ctrl.repos.Users.Replace(c.Request.Context(), &user) // Replace the user document if nobody changed it in the meantime
+	if err != nil {                                            // Check if there is an error
+		if errors.Is(err, repository.ErrNotFound) { // Check if the user was not found
+			c.Error(apperr.NotFound("user_not_found", "User not found")) // Return a not found response
+			return                                                       // Return from the function
+		}
+		c.Error(emailError(versionError(err))) // Return an error response
+		return                                 // Return from the function
 	}
 
-	c.JSON(http.StatusOK, user) // Return the updated user
+	describeChange(c, "users", user.ID, previous, user)           // Record the changed fields in the audit log
+	setViewETag(c, user.Version, dto.UserViewName(user, &caller)) // Tag the response with the new version of the user
+	c.JSON(http.StatusOK, dto.UserView(user, &caller))            // Return the patched user as the caller may see them
 }
 
-func DeleteUser(c *gin.Context) { // Delete a user
+// DeleteUser: Allows admins and the user themselves to soft delete a user and clean up the references to them,
+// reporting the documents it changed; the sessions of a coach that have not ended must be given to another coach
+// with ?reassign_to=
+func (ctrl *Controller) DeleteUser(c *gin.Context) { // Delete a user
 	userID := c.Param("userId") // Get the user ID from the URL
 
 	// Convert userID to ObjectID
 	objectID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
 	if err != nil {                                    // Check if there is an error converting the ID
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return a bad request response
+		c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
+		return                                                                                                         // Return from the function
+	}
+
+	caller, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                    // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
 		return                                                           // Return from the function
 	}
+	if caller.ID != objectID && caller.Role != models.RoleAdmin { // Check if the caller may not delete the user
+		c.Error(apperr.Forbidden("forbidden", "You can only delete your own account")) // Return a forbidden response
+		return                                                                         // Return from the function
+	}
 
-	// Delete the user document
-	filter := bson.M{"_id": objectID}                               // Define the filter
-	result, err := userCollection.DeleteOne(context.TODO(), filter) // Delete the user
-	if err != nil {                                                 // Check if there is an error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}) // Return an error response
-		return                                                              // Return from the function
+	deletion, err := ctrl.newDeletion(c, "User deleted") // Describe the deletion
+	if err != nil {                                      // Check if there is an error
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	var query dto.DeleteUserQuery                // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+	successor, err := ctrl.successorCoach(c.Request.Context(), objectID, query.ReassignTo) // Find the coach taking over the sessions
+	if err != nil {                                                                        // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
 	}
 
-	if result.DeletedCount == 0 { // Check if the user was not found
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"}) // Return a not found response
-		return                                                        // Return from the function
+	// Soft delete the user, the user can no longer log in, and clean up the references to them in one transaction
+	var report dto.UserDeletionReport                                                                    // Define the report of the changed documents
+	err = ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the deletion in a transaction
+		var err error                                                     // Define the error
+		report, err = ctrl.deleteUser(ctx, objectID, deletion, successor) // Delete the user and the references to them
+		return err                                                        // Return the error
+	})
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to delete user %s: %w", objectID.Hex(), err)) // Return an error response
+		return                                                                   // Return from the function
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"}) // Return a success response
+	describeChange(c, "users", objectID, models.Deletion{}, deletion)                        // Record the changed fields in the audit log
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully", "affected": report}) // Return a success response and the changed documents
 }
 
 /*
@@ -240,13 +424,13 @@ func DeleteUser(c *gin.Context) { // Delete a user
 		// Convert userID and sessionID to ObjectID
 		objectUserID, err := primitive.ObjectIDFromHex(userID) // Convert the user ID to an ObjectID
 		if err != nil {                                        // Check if there is an error converting the ID
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}) // Return a bad request response
+			c.Error(apperr.Validation("invalid_user_id", "Invalid user ID", apperr.Field("userId", "must be a valid ID"))) // Return a bad request response
 			return                                                           // Return from the function
 		}
 
 		objectSessionID, err := primitive.ObjectIDFromHex(sessionID) // Convert the session ID to an ObjectID
 		if err != nil {                                              // Check if there is an error converting the ID
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"}) // Return a bad request response
+			c.Error(apperr.Validation("invalid_session_id", "Invalid session ID", apperr.Field("sessionId", "must be a valid ID"))) // Return a bad request response
 			return
 		}
 
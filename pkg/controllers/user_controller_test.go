@@ -0,0 +1,215 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRegisterUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+		code   string
+	}{
+		{name: "valid user", body: map[string]any{"name": "Ann", "email": "ann@example.com", "password": "password123"}, status: http.StatusCreated},
+		{name: "coach", body: map[string]any{"name": "Ann", "email": "coach@example.com", "password": "password123", "role": "coach"}, status: http.StatusCreated},
+		{name: "missing email", body: map[string]any{"name": "Ann", "password": "password123"}, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "short password", body: map[string]any{"name": "Ann", "email": "ann@example.com", "password": "short"}, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "admin role", body: map[string]any{"name": "Ann", "email": "ann@example.com", "password": "password123", "role": "admin"}, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "unknown role", body: map[string]any{"name": "Ann", "email": "ann@example.com", "password": "password123", "role": "captain"}, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "email taken", body: map[string]any{"name": "Ann", "email": "Taken@Example.com", "password": "password123"}, status: http.StatusConflict, code: "email_taken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			s.signUp("taken@example.com", "user")
+
+			res := s.do(http.MethodPost, "/users/register", "", tt.body)
+			if res.status != tt.status || res.code() != tt.code {
+				t.Errorf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+			if tt.status == http.StatusCreated && res.body["password"] != nil {
+				t.Errorf("response exposes the password: %v", res.body)
+			}
+		})
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+		code   string
+	}{
+		{name: "valid credentials", body: map[string]any{"email": "ann@example.com", "password": "password123"}, status: http.StatusOK},
+		{name: "email in another case", body: map[string]any{"email": "ANN@example.com", "password": "password123"}, status: http.StatusOK},
+		{name: "wrong password", body: map[string]any{"email": "ann@example.com", "password": "password124"}, status: http.StatusUnauthorized, code: "invalid_credentials"},
+		{name: "unknown email", body: map[string]any{"email": "bob@example.com", "password": "password123"}, status: http.StatusUnauthorized, code: "user_not_found"},
+		{name: "invalid email", body: map[string]any{"email": "ann", "password": "password123"}, status: http.StatusBadRequest, code: "invalid_body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			s.signUp("ann@example.com", "user")
+
+			res := s.do(http.MethodPost, "/users/login", "", tt.body)
+			if res.status != tt.status || res.code() != tt.code {
+				t.Errorf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+			if tt.status == http.StatusOK && res.body["token"] == "" {
+				t.Errorf("no token in %v", res.body)
+			}
+		})
+	}
+}
+
+func TestGetUserByID(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID func(ann account) string
+		status int
+		code   string
+	}{
+		{name: "existing user", userID: func(ann account) string { return ann.id }, status: http.StatusOK},
+		{name: "invalid ID", userID: func(account) string { return "not-an-id" }, status: http.StatusBadRequest, code: "invalid_user_id"},
+		{name: "unknown user", userID: func(account) string { return primitive.NewObjectID().Hex() }, status: http.StatusNotFound, code: "user_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			ann := s.signUp("ann@example.com", "user")
+
+			res := s.do(http.MethodGet, "/users/"+tt.userID(ann), "", nil)
+			if res.status != tt.status || res.code() != tt.code {
+				t.Errorf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+		})
+	}
+}
+
+func TestGetUserByIDTagsTheView(t *testing.T) {
+	s := newServer(t)
+	ann := s.signUp("ann@example.com", "user")
+	bob := s.signUp("bob@example.com", "user")
+	admin := s.signUpAdmin("admin@example.com")
+	tests := []struct {
+		name   string
+		caller account
+		etag   string
+	}{
+		{name: "anonymous", etag: `"1-public"`},
+		{name: "another user", caller: bob, etag: `"1-public"`},
+		{name: "the user themselves", caller: ann, etag: `"1-self"`},
+		{name: "an admin", caller: admin, etag: `"1-admin"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.do(http.MethodGet, "/users/"+ann.id, tt.caller.token, nil)
+			if res.status != http.StatusOK || res.header.Get("ETag") != tt.etag || res.header.Get("Vary") != "Authorization, Cookie" {
+				t.Fatalf("status %d, ETag %q, Vary %q; want 200, %s varying by credentials", res.status, res.header.Get("ETag"), res.header.Get("Vary"), tt.etag)
+			}
+			if res := s.do(http.MethodGet, "/users/"+ann.id, tt.caller.token, nil, http.Header{"If-None-Match": {tt.etag}}); res.status != http.StatusNotModified {
+				t.Errorf("If-None-Match with the tag of the view: status %d, want 304", res.status)
+			}
+			for _, other := range []string{`"1-public"`, `"1-self"`, `"1-admin"`} {
+				if other != tt.etag {
+					if res := s.do(http.MethodGet, "/users/"+ann.id, tt.caller.token, nil, http.Header{"If-None-Match": {other}}); res.status != http.StatusOK {
+						t.Errorf("If-None-Match with the tag of the %s view: status %d, want 200", other, res.status)
+					}
+				}
+			}
+		})
+	}
+
+	res := s.do(http.MethodPatch, "/users/"+ann.id, ann.token, map[string]any{"name": "Anna"}, http.Header{"If-Match": {`"1-public"`}})
+	if res.status != http.StatusOK || res.header.Get("ETag") != `"2-self"` {
+		t.Errorf("patch with the tag of another view: status %d, ETag %q; want 200, \"2-self\"", res.status, res.header.Get("ETag"))
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller func(ann, bob, admin account) account
+		status int
+		code   string
+	}{
+		{name: "the user themselves", caller: func(ann, _, _ account) account { return ann }, status: http.StatusOK},
+		{name: "an admin", caller: func(_, _, admin account) account { return admin }, status: http.StatusOK},
+		{name: "another user", caller: func(_, bob, _ account) account { return bob }, status: http.StatusForbidden, code: "forbidden"},
+		{name: "anonymous", caller: func(ann, bob, admin account) account { return account{} }, status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			ann := s.signUp("ann@example.com", "user")
+			bob := s.signUp("bob@example.com", "user")
+			admin := s.signUpAdmin("admin@example.com")
+
+			res := s.do(http.MethodDelete, "/users/delete/"+ann.id, tt.caller(ann, bob, admin).token, nil)
+			if res.status != tt.status || (tt.code != "" && res.code() != tt.code) {
+				t.Errorf("status %d, code %q; want %d, %q (body %v)", res.status, res.code(), tt.status, tt.code, res.body)
+			}
+		})
+	}
+}
+
+func TestDeleteUserDetachesTheirReferences(t *testing.T) {
+	s := newServer(t)
+	admin := s.signUpAdmin("admin@example.com")
+	coach := s.signUp("coach@example.com", "coach")
+	successor := s.signUp("successor@example.com", "coach")
+	ann := s.signUp("ann@example.com", "user")
+	bob := s.signUp("bob@example.com", "user")
+	sessionID := s.createSession(coach, nil)
+	res := s.do(http.MethodPost, "/feedback", ann.token, map[string]any{"session_id": sessionID, "coach_id": coach.id, "user_id": ann.id, "content": "Great drills", "rating": 4})
+	if res.status != http.StatusCreated {
+		t.Fatalf("submit feedback: status %d, body %v", res.status, res.body)
+	}
+	feedbackID, _ := res.body["id"].(string)
+	if res := s.do(http.MethodPost, "/feedback/"+feedbackID+"/flag", bob.token, map[string]any{"reason": "Spam"}); res.status != http.StatusOK {
+		t.Fatalf("flag feedback: status %d, body %v", res.status, res.body)
+	}
+	if res := s.do(http.MethodPost, "/feedback/"+feedbackID+"/reply", coach.token, map[string]any{"content": "Thanks"}); res.status != http.StatusCreated {
+		t.Fatalf("reply to feedback: status %d, body %v", res.status, res.body)
+	}
+
+	res = s.do(http.MethodDelete, "/users/delete/"+coach.id+"?reassign_to="+successor.id, admin.token, nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("delete coach: status %d, body %v", res.status, res.body)
+	}
+	affected, _ := res.body["affected"].(map[string]any)
+	if affected["sessions_reassigned"] != 1.0 || affected["replies_anonymized"] != 1.0 {
+		t.Errorf("affected %v, want a session reassigned and a reply anonymized", affected)
+	}
+	res = s.do(http.MethodDelete, "/users/delete/"+bob.id, admin.token, nil)
+	if res.status != http.StatusOK {
+		t.Fatalf("delete bob: status %d, body %v", res.status, res.body)
+	}
+	if affected, _ := res.body["affected"].(map[string]any); affected["flags_anonymized"] != 1.0 {
+		t.Errorf("affected %v, want a flag anonymized", affected)
+	}
+
+	ctx := context.Background()
+	objectSessionID, _ := primitive.ObjectIDFromHex(sessionID)
+	session, err := s.repos.Sessions.FindByID(ctx, objectSessionID)
+	if err != nil {
+		t.Fatalf("find session: %v", err)
+	}
+	if session.Coach.Hex() != successor.id || session.OwnerID.Hex() != successor.id {
+		t.Errorf("session coached by %s and owned by %s, want the successor %s", session.Coach.Hex(), session.OwnerID.Hex(), successor.id)
+	}
+	objectFeedbackID, _ := primitive.ObjectIDFromHex(feedbackID)
+	feedback, err := s.repos.Feedback.FindByID(ctx, objectFeedbackID)
+	if err != nil {
+		t.Fatalf("find feedback: %v", err)
+	}
+	if len(feedback.Flags) != 1 || !feedback.Flags[0].UserID.IsZero() || feedback.Reply == nil || !feedback.Reply.CoachID.IsZero() {
+		t.Errorf("flags %+v and reply %+v, want them kept without their users", feedback.Flags, feedback.Reply)
+	}
+}
@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"training_session/pkg/apperr"
+	"training_session/pkg/dto"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+	"training_session/pkg/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// requireWebhookManager loads the authenticated user and responds with an error if they cannot manage webhooks,
+// only business owners and admins register webhooks
+func (ctrl *Controller) requireWebhookManager(c *gin.Context) (models.User, bool) { // Check that the user manages webhooks
+	user, err := ctrl.currentUser(c) // Get the authenticated user
+	if err != nil {                  // Check if there is an error
+		c.Error(apperr.Unauthorized("user_not_found", "User not found")) // Return an unauthorized response
+		return user, false                                               // Return from the function
+	}
+	if user.Role != models.RoleBusinessOwner && user.Role != models.RoleAdmin { // Check if the user cannot manage webhooks
+		c.Error(apperr.Forbidden("forbidden", "You do not have the required permissions to manage webhooks")) // Return a forbidden response
+		return user, false                                                                                    // Return from the function
+	}
+	return user, true // Return the user
+}
+
+// ownedWebhook loads the webhook of the route and responds with an error if it does not exist or belongs to
+// another business owner; admins reach every webhook
+func (ctrl *Controller) ownedWebhook(c *gin.Context) (models.Webhook, bool) { // Get the webhook of the route
+	user, ok := ctrl.requireWebhookManager(c) // Check that the user manages webhooks
+	if !ok {                                  // Check if the user cannot manage webhooks
+		return models.Webhook{}, false // Return from the function
+	}
+
+	objectWebhookID, err := primitive.ObjectIDFromHex(c.Param("webhookId")) // Convert webhook ID to ObjectID
+	if err != nil {                                                         // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_webhook_id", "Invalid webhook ID", apperr.Field("webhookId", "must be a valid ID"))) // Return an error response
+		return models.Webhook{}, false                                                                                          // Return from the function
+	}
+
+	webhook, err := ctrl.repos.Webhooks.FindByID(c.Request.Context(), objectWebhookID)                                         // Find the webhook
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && webhook.OwnerID != user.ID && user.Role != models.RoleAdmin) { // Check if the webhook is missing or not visible to the user
+		c.Error(apperr.NotFound("webhook_not_found", "Webhook not found")) // Return a not found response
+		return models.Webhook{}, false                                     // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve webhook: %w", err)) // Return an error response
+		return models.Webhook{}, false                             // Return from the function
+	}
+	return webhook, true // Return the webhook
+}
+
+// CreateWebhook: Registers a webhook of the business owner; the signing secret is only returned in this response
+func (ctrl *Controller) CreateWebhook(c *gin.Context) { // Register a webhook
+	user, ok := ctrl.requireWebhookManager(c) // Check that the user manages webhooks
+	if !ok {                                  // Check if the user cannot manage webhooks
+		return // Return from the function
+	}
+
+	var request dto.WebhookRequest                // Define a webhook request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the webhook request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	secret, err := webhooks.NewSecret() // Generate the signing secret
+	if err != nil {                     // Check if there is an error
+		c.Error(err) // Return an error response
+		return       // Return from the function
+	}
+
+	var webhook models.Webhook           // Define a webhook variable
+	request.Apply(&webhook)              // Set the fields of the request
+	webhook.ID = primitive.NewObjectID() // Generate a new ObjectID for the webhook
+	webhook.OwnerID = user.ID            // Set the owner of the webhook
+	webhook.Secret = secret              // Set the signing secret
+	webhook.CreatedAt = time.Now()       // Set the created_at timestamp
+	webhook.UpdatedAt = time.Now()       // Set the updated_at timestamp
+
+	if err := ctrl.repos.Webhooks.Insert(c.Request.Context(), &webhook); err != nil { // Insert the webhook
+		c.Error(fmt.Errorf("failed to create webhook: %w", err)) // Return an error response
+		return                                                   // Return from the function
+	}
+
+	setETag(c, webhook.Version)                                             // Tag the response with the version of the webhook
+	c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": secret}) // Return the created webhook and its secret
+}
+
+// GetWebhooks: Lists the webhooks of the business owner
+func (ctrl *Controller) GetWebhooks(c *gin.Context) { // Get the webhooks
+	user, ok := ctrl.requireWebhookManager(c) // Check that the user manages webhooks
+	if !ok {                                  // Check if the user cannot manage webhooks
+		return // Return from the function
+	}
+
+	registered, err := ctrl.repos.Webhooks.FindByOwner(c.Request.Context(), user.ID) // Find the webhooks of the user
+	if err != nil {                                                                  // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve webhooks: %w", err)) // Return an error response
+		return                                                      // Return from the function
+	}
+
+	c.JSON(http.StatusOK, registered) // Return the webhooks
+}
+
+// GetWebhookByID: Retrieves a webhook of the business owner
+func (ctrl *Controller) GetWebhookByID(c *gin.Context) { // Get a webhook
+	webhook, ok := ctrl.ownedWebhook(c) // Get the webhook of the route
+	if !ok {                            // Check if the webhook cannot be reached
+		return // Return from the function
+	}
+
+	if notModified(c, webhook.Version) { // Check if the client holds the current version
+		return // Return from the function
+	}
+	c.JSON(http.StatusOK, webhook) // Return the webhook
+}
+
+// UpdateWebhook: Changes the URL, description, event types or state of a webhook; the deliveries already queued are kept
+func (ctrl *Controller) UpdateWebhook(c *gin.Context) { // Update a webhook
+	webhook, ok := ctrl.ownedWebhook(c) // Get the webhook of the route
+	if !ok {                            // Check if the webhook cannot be reached
+		return // Return from the function
+	}
+	if err := checkIfMatch(c, webhook.Version); err != nil { // Check that the client read the current version
+		c.Error(err) // Return a precondition response
+		return       // Return from the function
+	}
+
+	var request dto.WebhookRequest                // Define a webhook request variable
+	if err := bindJSON(c, &request); err != nil { // Bind the JSON to the webhook request struct
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	request.Apply(&webhook)        // Set the fields of the request
+	webhook.UpdatedAt = time.Now() // Set the updated_at timestamp
+
+	err := ctrl.repos.Webhooks.Update(c.Request.Context(), webhook) // Update the webhook
+	if errors.Is(err, repository.ErrNotFound) {                     // Check if the webhook was deleted meanwhile
+		c.Error(apperr.NotFound("webhook_not_found", "Webhook not found")) // Return a not found response
+		return                                                             // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(versionError(fmt.Errorf("failed to update webhook: %w", err))) // Return an error response
+		return                                                                 // Return from the function
+	}
+
+	webhook.Version++              // Report the new version
+	setETag(c, webhook.Version)    // Tag the response with the new version of the webhook
+	c.JSON(http.StatusOK, webhook) // Return the updated webhook
+}
+
+// DeleteWebhook: Removes a webhook with its deliveries
+func (ctrl *Controller) DeleteWebhook(c *gin.Context) { // Delete a webhook
+	webhook, ok := ctrl.ownedWebhook(c) // Get the webhook of the route
+	if !ok {                            // Check if the webhook cannot be reached
+		return // Return from the function
+	}
+
+	// Delete the webhook and its deliveries in one transaction, so no delivery is left to a missing webhook
+	err := ctrl.repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error { // Run the deletion in a transaction
+		if err := ctrl.repos.Webhooks.Delete(ctx, webhook.ID); err != nil { // Delete the webhook
+			return err // Return the error
+		}
+		return ctrl.repos.Deliveries.DeleteByWebhook(ctx, webhook.ID) // Delete the deliveries of the webhook
+	})
+	if errors.Is(err, repository.ErrNotFound) { // Check if the webhook was deleted meanwhile
+		c.Error(apperr.NotFound("webhook_not_found", "Webhook not found")) // Return a not found response
+		return                                                             // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to delete webhook: %w", err)) // Return an error response
+		return                                                   // Return from the function
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"}) // Return a success response
+}
+
+// GetWebhookDeliveries: Lists the deliveries of a webhook with the log of their attempts, newest first,
+// optionally filtered by status
+func (ctrl *Controller) GetWebhookDeliveries(c *gin.Context) { // Get the deliveries of a webhook
+	webhook, ok := ctrl.ownedWebhook(c) // Get the webhook of the route
+	if !ok {                            // Check if the webhook cannot be reached
+		return // Return from the function
+	}
+
+	var query dto.DeliveryQuery                  // Define the query variable
+	if err := bindQuery(c, &query); err != nil { // Bind the query string to the query variable
+		c.Error(err) // Return a bad request response
+		return       // Return from the function
+	}
+
+	ctrl.listDeliveries(c, webhook, query.Status) // Return the deliveries
+}
+
+// GetWebhookDeadLetters: Lists the deliveries of a webhook that ran out of attempts, newest first
+func (ctrl *Controller) GetWebhookDeadLetters(c *gin.Context) { // Get the dead letters of a webhook
+	webhook, ok := ctrl.ownedWebhook(c) // Get the webhook of the route
+	if !ok {                            // Check if the webhook cannot be reached
+		return // Return from the function
+	}
+
+	ctrl.listDeliveries(c, webhook, models.DeliveryStatusDead) // Return the dead deliveries
+}
+
+// listDeliveries responds with the deliveries of a webhook with the given status, every status when empty
+func (ctrl *Controller) listDeliveries(c *gin.Context, webhook models.Webhook, status string) { // List the deliveries of a webhook
+	deliveries, err := ctrl.repos.Deliveries.FindByWebhook(c.Request.Context(), webhook.ID, status) // Find the deliveries
+	if err != nil {                                                                                 // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve webhook deliveries: %w", err)) // Return an error response
+		return                                                                // Return from the function
+	}
+
+	c.JSON(http.StatusOK, deliveries) // Return the deliveries
+}
+
+// RedeliverWebhookDelivery: Queues a delivery again with a fresh set of attempts, typically a dead letter once
+// the receiver is fixed; it is attempted on the next poll of the dispatcher
+func (ctrl *Controller) RedeliverWebhookDelivery(c *gin.Context) { // Redeliver a webhook delivery
+	webhook, ok := ctrl.ownedWebhook(c) // Get the webhook of the route
+	if !ok {                            // Check if the webhook cannot be reached
+		return // Return from the function
+	}
+
+	objectDeliveryID, err := primitive.ObjectIDFromHex(c.Param("deliveryId")) // Convert delivery ID to ObjectID
+	if err != nil {                                                           // Check if there is an error converting the ID
+		c.Error(apperr.Validation("invalid_delivery_id", "Invalid delivery ID", apperr.Field("deliveryId", "must be a valid ID"))) // Return an error response
+		return                                                                                                                     // Return from the function
+	}
+
+	delivery, err := ctrl.repos.Deliveries.FindByID(c.Request.Context(), objectDeliveryID)          // Find the delivery
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && delivery.WebhookID != webhook.ID) { // Check if the delivery is missing or belongs to another webhook
+		c.Error(apperr.NotFound("delivery_not_found", "Delivery not found")) // Return a not found response
+		return                                                               // Return from the function
+	}
+	if err != nil { // Check if there is an error
+		c.Error(fmt.Errorf("failed to retrieve webhook delivery: %w", err)) // Return an error response
+		return                                                              // Return from the function
+	}
+	if delivery.Status == models.DeliveryStatusPending { // Check if the delivery is still being attempted
+		c.Error(apperr.Conflict("delivery_pending", "The delivery is still pending")) // Return a conflict response
+		return                                                                        // Return from the function
+	}
+
+	if err := ctrl.repos.Deliveries.Redeliver(c.Request.Context(), delivery.ID, time.Now()); err != nil { // Queue the delivery again
+		c.Error(fmt.Errorf("failed to redeliver webhook delivery: %w", err)) // Return an error response
+		return                                                               // Return from the function
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Delivery queued for redelivery", "delivery_id": delivery.ID}) // Return an accepted response
+}
@@ -0,0 +1,18 @@
+package dto
+
+// AuditQuery filters the audit log, every field left empty matching every entry
+type AuditQuery struct {
+	ActorID    string `form:"actor_id" json:"actor_id" binding:"omitempty,objectid"`                   // Entries of the user
+	Action     string `form:"action" json:"action" binding:"max=200"`                                  // Entries of the method and route (e.g. "DELETE /users/delete/:userId")
+	TargetType string `form:"target_type" json:"target_type" binding:"max=50"`                         // Entries changing a kind of document
+	TargetID   string `form:"target_id" json:"target_id" binding:"max=100"`                            // Entries changing the document
+	From       string `form:"from" json:"from" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // Entries that occurred at or after this time (RFC 3339)
+	To         string `form:"to" json:"to" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`     // Entries that occurred before this time (RFC 3339)
+	Before     int64  `form:"before" json:"before" binding:"min=0"`                                    // Entries with a lower sequence, the next page starts before the last sequence received
+	Limit      int    `form:"limit" json:"limit" binding:"omitempty,min=1,max=1000"`                   // Maximum number of entries, 100 when omitted and every entry when exporting
+}
+
+// AuditExportQuery picks the format of the exported audit log, the entries being filtered by an AuditQuery
+type AuditExportQuery struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=ndjson csv"` // "ndjson" (default) or "csv"
+}
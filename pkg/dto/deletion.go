@@ -0,0 +1,30 @@
+package dto
+
+// DeleteQuery describes why a session, user, invitation or pitch booking is deleted
+type DeleteQuery struct {
+	Reason string `form:"reason" json:"reason" binding:"max=500"` // Reason of the deletion, shown to the admins
+}
+
+// DeleteUserQuery picks the coach taking over the remaining sessions of a deleted coach
+type DeleteUserQuery struct {
+	ReassignTo string `form:"reassign_to" json:"reassign_to" binding:"omitempty,objectid"` // Coach or business owner receiving the sessions not ended yet
+}
+
+// UserDeletionReport counts the documents changed along with a deleted user
+type UserDeletionReport struct {
+	SessionsReassigned   int64 `json:"sessions_reassigned"`    // Sessions not ended yet given to the new coach
+	SessionsLeft         int64 `json:"sessions_left"`          // Sessions the user no longer attends or assists
+	InvitationsDeleted   int64 `json:"invitations_deleted"`    // Invitations of the user, soft deleted with them
+	PitchBookingsDeleted int64 `json:"pitch_bookings_deleted"` // Pitch bookings of the user, soft deleted with them
+	NotificationsDeleted int64 `json:"notifications_deleted"`  // Notifications of the user, removed for good
+	FeedbackAnonymized   int64 `json:"feedback_anonymized"`    // Feedback written by the user, kept without its author
+	FlagsAnonymized      int64 `json:"flags_anonymized"`       // Feedback flagged by the user, kept with flags that no longer name them
+	RepliesAnonymized    int64 `json:"replies_anonymized"`     // Replies posted by the user, kept without their coach
+	WebhooksDisabled     int64 `json:"webhooks_disabled"`      // Webhooks registered by the user, no longer delivered to
+}
+
+// Irreversible reports whether the deletion changed documents that restoring the user does not bring back,
+// everything but the invitations and pitch bookings
+func (r UserDeletionReport) Irreversible() bool { // Check the report
+	return r.SessionsReassigned > 0 || r.SessionsLeft > 0 || r.NotificationsDeleted > 0 || r.FeedbackAnonymized > 0 || r.FlagsAnonymized > 0 || r.RepliesAnonymized > 0 || r.WebhooksDisabled > 0 // Check the clean-ups that are not undone
+}
@@ -0,0 +1,71 @@
+package dto
+
+import (
+	"training_session/pkg/models"
+)
+
+// SubmitFeedbackRequest is the body of the feedback submission
+type SubmitFeedbackRequest struct {
+	SessionID string                  `json:"session_id" binding:"required,objectid"` // Session the feedback is about
+	CoachID   string                  `json:"coach_id" binding:"required,objectid"`   // Coach who receives the feedback
+	UserID    string                  `json:"user_id" binding:"required,objectid"`    // User who provides the feedback
+	Content   string                  `json:"content" binding:"max=2000"`             // Feedback content
+	Rating    int                     `json:"rating" binding:"required,min=1,max=5"`  // Overall rating between 1 and 5
+	FormID    string                  `json:"form_id" binding:"omitempty,objectid"`   // Feedback form the answers follow, the form of the training type when omitted
+	Answers   []models.FeedbackAnswer `json:"answers"`                                // Answers to the criteria of the form, checked against the form
+	Anonymous bool                    `json:"anonymous"`                              // Whether the author is hidden in public views
+}
+
+// EditFeedbackRequest is the body of the feedback edit
+type EditFeedbackRequest struct {
+	Content   string `json:"content" binding:"max=2000"`            // Feedback content
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"` // Overall rating between 1 and 5
+	Anonymous bool   `json:"anonymous"`                             // Whether the author is hidden in public views
+}
+
+// NewEditFeedbackRequest returns the request describing the current state of the feedback, the target of a merge patch
+func NewEditFeedbackRequest(feedback models.Feedback) EditFeedbackRequest { // Describe a feedback
+	return EditFeedbackRequest{Content: feedback.Content, Rating: feedback.Rating, Anonymous: feedback.Anonymous} // Return the request
+}
+
+// FlagFeedbackRequest is the body of a feedback report
+type FlagFeedbackRequest struct {
+	Reason string `json:"reason" binding:"max=500"` // Reason for the flag
+}
+
+// ModerationRequest is the optional body of a moderation decision
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"max=500"` // Reason for the decision
+}
+
+// ReplyRequest is the body of a coach reply
+type ReplyRequest struct {
+	Content string `json:"content" binding:"required,max=2000"` // Reply content
+}
+
+// FeedbackFormRequest is the body of the feedback form create and update requests, the consistency
+// of the criteria is checked by the handlers
+type FeedbackFormRequest struct {
+	TrainingType string                     `json:"training_type" binding:"required,training_type"` // Training type the form applies to
+	Title        string                     `json:"title" binding:"max=200"`                        // Title of the form
+	Criteria     []models.FeedbackCriterion `json:"criteria" binding:"required,min=1"`              // Criteria evaluated by the form
+}
+
+// ToModel returns the feedback described by the request, before moderation
+func (r SubmitFeedbackRequest) ToModel() models.Feedback { // Convert the request to a feedback
+	return models.Feedback{
+		SessionID: objectID(r.SessionID), // Set the session
+		CoachID:   objectID(r.CoachID),   // Set the coach
+		UserID:    objectID(r.UserID),    // Set the author
+		Content:   r.Content,             // Set the content
+		Rating:    r.Rating,              // Set the rating
+		FormID:    objectID(r.FormID),    // Set the form
+		Answers:   r.Answers,             // Set the answers
+		Anonymous: r.Anonymous,           // Set the anonymous display choice
+	}
+}
+
+// ToModel returns the feedback form described by the request
+func (r FeedbackFormRequest) ToModel() models.FeedbackForm { // Convert the request to a feedback form
+	return models.FeedbackForm{TrainingType: r.TrainingType, Title: r.Title, Criteria: r.Criteria} // Return the form
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"training_session/pkg/models"
+)
+
+// InvitationRequest is the body of the invitation request
+type InvitationRequest struct {
+	SessionID      string `json:"session_id" binding:"required,objectid"`                                 // ID of the private training session
+	UserID         string `json:"user_id" binding:"required,objectid"`                                    // ID of the invited user
+	InvitationDate string `json:"invitation_date" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"` // Date of the invitation (RFC 3339), now when omitted
+}
+
+// ToModel returns the pending invitation described by the request
+func (r InvitationRequest) ToModel() models.Invitation { // Convert the request to an invitation
+	return models.Invitation{
+		SessionID:      objectID(r.SessionID),          // Set the session
+		UserID:         objectID(r.UserID),             // Set the invited user
+		InvitationDate: r.InvitationDate,               // Set the invitation date
+		Status:         models.InvitationStatusPending, // Wait for an answer
+	}
+}
@@ -0,0 +1,16 @@
+package dto
+
+import (
+	"training_session/pkg/models"
+)
+
+// NotificationRequest is the body of the user notification request
+type NotificationRequest struct {
+	UserID  string `json:"user_id" binding:"required,objectid"` // ID of the notified user
+	Message string `json:"message" binding:"required,max=1000"` // Content of the notification
+}
+
+// ToModel returns the notification described by the request
+func (r NotificationRequest) ToModel() models.Notification { // Convert the request to a notification
+	return models.Notification{UserID: objectID(r.UserID), Message: r.Message} // Return the notification
+}
@@ -0,0 +1,91 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// MergePatchContentType is the media type of the RFC 7396 JSON merge patches accepted by the PATCH routes
+const MergePatchContentType = "application/merge-patch+json"
+
+// Patch applies an RFC 7396 JSON merge patch to req, a request body holding the current state of a resource:
+// the patch is merged into the JSON of req and the result is decoded back into req. Fields set to null are
+// reset to their zero value. The result is not validated, the caller checks it with the binding rules.
+func Patch(req interface{}, patch []byte) error { // Apply a merge patch
+	current, err := json.Marshal(req) // Encode the current state
+	if err != nil {                   // Check if there is an error
+		return err // Return the error
+	}
+	var target, changes interface{}                      // Define the decoded documents
+	if err := decodeJSON(current, &target); err != nil { // Decode the current state
+		return err // Return the error
+	}
+	if err := decodeJSON(patch, &changes); err != nil { // Decode the patch
+		return err // Return the error
+	}
+
+	merged, err := json.Marshal(mergePatch(target, changes)) // Encode the patched state
+	if err != nil {                                          // Check if there is an error
+		return err // Return the error
+	}
+	value := reflect.ValueOf(req).Elem()  // Get the request
+	value.Set(reflect.Zero(value.Type())) // Reset the request so the removed fields are zero
+	return decodeJSON(merged, req)        // Decode the patched state into the request
+}
+
+// Fields returns the JSON names of the fields of a request body, the only fields a patch may set
+func Fields(req interface{}) []string { // List the fields of a request
+	typ := reflect.TypeOf(req)         // Get the type of the request
+	if typ.Kind() == reflect.Pointer { // Check if the request is given by pointer
+		typ = typ.Elem() // Get the struct type
+	}
+	fields := make([]string, 0, typ.NumField()) // Define a slice to hold the names
+	for i := 0; i < typ.NumField(); i++ {       // Iterate over the fields
+		if name := jsonName(typ.Field(i)); name != "" { // Check if the field is serialized
+			fields = append(fields, name) // Add the name
+		}
+	}
+	return fields // Return the names
+}
+
+// ChangedFields returns the JSON names of the fields whose value differs between two states of the same request
+func ChangedFields(before, after interface{}) []string { // List the changed fields
+	old, updated := reflect.ValueOf(before), reflect.ValueOf(after) // Get the states
+	var fields []string                                             // Define a slice to hold the names
+	for i := 0; i < old.NumField(); i++ {                           // Iterate over the fields
+		name := jsonName(old.Type().Field(i))                                                         // Get the JSON name of the field
+		if name != "" && !reflect.DeepEqual(old.Field(i).Interface(), updated.Field(i).Interface()) { // Check if the field changed
+			fields = append(fields, name) // Add the name
+		}
+	}
+	return fields // Return the names
+}
+
+// mergePatch merges the patch into the target as described by RFC 7396: objects are merged key by key,
+// null removes a key and any other value replaces the target
+func mergePatch(target, patch interface{}) interface{} { // Merge a patch
+	changes, ok := patch.(map[string]interface{}) // Check if the patch is an object
+	if !ok {                                      // Check if the patch replaces the target
+		return patch // Return the patch
+	}
+	document, ok := target.(map[string]interface{}) // Check if the target is an object
+	if !ok {                                        // Check if the target cannot be merged
+		document = map[string]interface{}{} // Start from an empty object
+	}
+	for key, value := range changes { // Iterate over the changes
+		if value == nil { // Check if the key is removed
+			delete(document, key) // Remove the key
+			continue              // Go to the next change
+		}
+		document[key] = mergePatch(document[key], value) // Merge the value
+	}
+	return document // Return the merged object
+}
+
+// decodeJSON decodes JSON keeping the numbers as written, so large integers survive the round trip
+func decodeJSON(data []byte, v interface{}) error { // Decode JSON
+	decoder := json.NewDecoder(bytes.NewReader(data)) // Define the decoder
+	decoder.UseNumber()                               // Keep the numbers as written
+	return decoder.Decode(v)                          // Decode the JSON
+}
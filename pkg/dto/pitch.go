@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PitchRequest is the body of the pitch booking create and update requests
+type PitchRequest struct {
+	SessionID   string `json:"session_id" binding:"required,objectid"` // ID of the associated training session
+	UserID      string `json:"user_id" binding:"required,objectid"`    // ID of the user booking the pitch
+	Title       string `json:"title" binding:"required,max=200"`       // Title of the pitch
+	Description string `json:"description" binding:"max=2000"`         // Description of the pitch
+}
+
+// NewPitchRequest returns the request describing the current state of the pitch booking, the target of a merge patch
+func NewPitchRequest(pitch models.Pitch) PitchRequest { // Describe a pitch booking
+	return PitchRequest{SessionID: pitch.SessionID.Hex(), UserID: pitch.UserID.Hex(), Title: pitch.Title, Description: pitch.Description} // Return the request
+}
+
+// Apply copies the fields of the request to the pitch booking, leaving the server-managed fields untouched
+func (r PitchRequest) Apply(pitch *models.Pitch) { // Apply the request to a pitch booking
+	pitch.SessionID = objectID(r.SessionID) // Set the session
+	pitch.UserID = objectID(r.UserID)       // Set the user
+	pitch.Title = r.Title                   // Set the title
+	pitch.Description = r.Description       // Set the description
+}
+
+// objectID converts a hex ID already checked by the "objectid" rule, an empty ID gives the nil ObjectID
+func objectID(hex string) primitive.ObjectID { // Convert an ID
+	id, _ := primitive.ObjectIDFromHex(hex) // Convert the ID, the rule already rejected the invalid ones
+	return id                               // Return the ID
+}
+
+// objectIDs converts a list of hex IDs already checked by the "objectid" rule
+func objectIDs(hexes []string) []primitive.ObjectID { // Convert IDs
+	if hexes == nil { // Check if the list was omitted
+		return nil // Keep the list empty
+	}
+	ids := make([]primitive.ObjectID, len(hexes)) // Define a slice to hold the IDs
+	for i, hex := range hexes {                   // Iterate over the hex IDs
+		ids[i] = objectID(hex) // Convert the ID
+	}
+	return ids // Return the IDs
+}
+
+// hexID converts an ID back to hex, the nil ObjectID gives an empty ID
+func hexID(id primitive.ObjectID) string { // Convert an ID to hex
+	if id.IsZero() { // Check if the ID is not set
+		return "" // Return an empty ID
+	}
+	return id.Hex() // Return the hex ID
+}
+
+// hexIDs converts a list of IDs back to hex
+func hexIDs(ids []primitive.ObjectID) []string { // Convert IDs to hex
+	if ids == nil { // Check if the list is empty
+		return nil // Keep the list empty
+	}
+	hexes := make([]string, len(ids)) // Define a slice to hold the hex IDs
+	for i, id := range ids {          // Iterate over the IDs
+		hexes[i] = id.Hex() // Convert the ID
+	}
+	return hexes // Return the hex IDs
+}
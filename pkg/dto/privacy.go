@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErasureRequestBody is the body of a request to have the personal data of the caller erased
+type ErasureRequestBody struct {
+	Reason string `json:"reason" binding:"max=500"` // Reason of the request, shown to the admins
+}
+
+// ErasureReview is the body approving or rejecting an erasure request
+type ErasureReview struct {
+	Note       string `json:"note" binding:"max=500"`                   // Note shown to the user
+	ReassignTo string `json:"reassign_to" binding:"omitempty,objectid"` // Coach or business owner receiving the sessions not ended yet of an erased coach
+}
+
+// ErasureQuery filters the erasure requests listed to the admins
+type ErasureQuery struct {
+	Status string `form:"status" json:"status" binding:"omitempty,erasure_status"` // Status of the requests, "pending" when omitted
+}
+
+// ErasureReport counts the documents changed by an approved erasure request
+type ErasureReport struct {
+	SessionsReassigned   int64 `json:"sessions_reassigned"`   // Sessions not ended yet given to the new coach
+	NotificationsDeleted int64 `json:"notifications_deleted"` // Notifications of the user, removed for good
+	FeedbackAnonymized   int64 `json:"feedback_anonymized"`   // Feedback written by the user, kept without its author
+	WebhooksDisabled     int64 `json:"webhooks_disabled"`     // Webhooks registered by the user, no longer delivered to
+}
+
+// ExportedSession is a session in the personal data export, with the part the user took in it
+type ExportedSession struct {
+	ID           primitive.ObjectID `json:"id"`            // Unique identifier for the session
+	Title        string             `json:"title"`         // Title of the session
+	TrainingType string             `json:"training_type"` // Type of training
+	Location     string             `json:"location"`      // Location of the session
+	StartTime    time.Time          `json:"start_time"`    // Start time of the session
+	EndTime      time.Time          `json:"end_time"`      // End time of the session
+	Status       string             `json:"status"`        // Status of the session
+	Role         string             `json:"role"`          // Part of the user: "coach" or "participant"
+	Attended     bool               `json:"attended"`      // Whether the user checked in to the session with its QR code
+	CheckedInAt  *time.Time         `json:"checked_in_at"` // Timestamp when the user checked in, nil if they did not
+}
@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"time"
+	"training_session/pkg/models"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// SessionRequest is the body of the session create and update requests
+type SessionRequest struct {
+	Title        string    `json:"title" binding:"required,max=200"`                // Title of the session
+	Description  string    `json:"description" binding:"max=2000"`                  // Description of the session
+	StartTime    time.Time `json:"start_time" binding:"required"`                   // Start time of the session
+	EndTime      time.Time `json:"end_time" binding:"required"`                     // End time of the session, after the start time
+	Location     string    `json:"location" binding:"required,max=200"`             // Location of the session
+	TrainingType string    `json:"training_type" binding:"required,training_type"`  // Type of training
+	Duration     int       `json:"duration" binding:"omitempty,gt=0"`               // Duration in minutes, derived from the schedule when omitted
+	Recurrence   string    `json:"recurrence" binding:"max=100"`                    // Recurrence pattern of the session
+	Coach        string    `json:"coach" binding:"omitempty,objectid"`              // ID of the coach, the creator when omitted
+	CoachAssists []string  `json:"coach_assists" binding:"omitempty,dive,objectid"` // IDs of the assistants of the coach
+	Capacity     int       `json:"capacity" binding:"min=0"`                        // Maximum number of participants, 0 for no limit
+}
+
+// CheckInRequest is the body of the QR code check-in
+type CheckInRequest struct {
+	QRCode string `json:"qr_code" binding:"required,max=100"` // Content of the scanned QR code
+}
+
+// SessionQuery is the query string of the session listing
+type SessionQuery struct {
+	Status string `form:"status" json:"status" binding:"omitempty,session_status"` // Only list the sessions with this status
+}
+
+// NewSessionRequest returns the request describing the current state of the session, the target of a merge patch.
+// A duration matching the schedule is left out so it follows the start and end times when the patch moves them.
+func NewSessionRequest(session models.Session) SessionRequest { // Describe a session
+	duration := session.Duration                                          // Get the duration
+	if duration == scheduledMinutes(session.StartTime, session.EndTime) { // Check if the duration follows the schedule
+		duration = 0 // Derive the duration again
+	}
+	return SessionRequest{
+		Title:        session.Title,                // Set the title
+		Description:  session.Description,          // Set the description
+		StartTime:    session.StartTime,            // Set the start time
+		EndTime:      session.EndTime,              // Set the end time
+		Location:     session.Location,             // Set the location
+		TrainingType: session.TrainingType,         // Set the training type
+		Duration:     duration,                     // Set the duration
+		Recurrence:   session.Recurrence,           // Set the recurrence pattern
+		Coach:        hexID(session.Coach),         // Set the coach
+		CoachAssists: hexIDs(session.CoachAssists), // Set the assistants of the coach
+		Capacity:     session.Capacity,             // Set the capacity
+	}
+}
+
+// Apply copies the fields of the request to the session, leaving the server-managed fields untouched
+func (r SessionRequest) Apply(session *models.Session) { // Apply the request to a session
+	session.Title = r.Title               // Set the title
+	session.Description = r.Description   // Set the description
+	session.StartTime = r.StartTime       // Set the start time
+	session.EndTime = r.EndTime           // Set the end time
+	session.Location = r.Location         // Set the location
+	session.TrainingType = r.TrainingType // Set the training type
+	session.Duration = r.Duration         // Set the duration
+	if session.Duration == 0 {            // Check if the duration was omitted
+		session.Duration = scheduledMinutes(r.StartTime, r.EndTime) // Derive the duration from the schedule
+	}
+	session.Recurrence = r.Recurrence // Set the recurrence pattern
+	if r.Coach != "" {                // Check if the coach was given
+		session.Coach = objectID(r.Coach) // Set the coach
+	}
+	session.CoachAssists = objectIDs(r.CoachAssists) // Set the assistants of the coach
+	session.Capacity = r.Capacity                    // Set the capacity
+}
+
+// scheduledMinutes returns the number of minutes between the start and end times
+func scheduledMinutes(start, end time.Time) int { // Compute the scheduled duration
+	return int(end.Sub(start) / time.Minute) // Return the duration in minutes
+}
+
+// validateSessionRequest checks that the session ends after it starts and that a given duration matches the schedule
+func validateSessionRequest(sl validator.StructLevel) { // Validate a session request
+	r := sl.Current().Interface().(SessionRequest)  // Get the request
+	if r.StartTime.IsZero() || r.EndTime.IsZero() { // Check if there is nothing to compare
+		return // The required rule reports the missing times
+	}
+	if !r.EndTime.After(r.StartTime) { // Check if the session ends before it starts
+		sl.ReportError(r.EndTime, "end_time", "EndTime", "after_start", "") // Report the end time
+		return                                                              // The duration cannot be checked
+	}
+	if r.Duration == 0 { // Check if the duration was omitted
+		return // The duration is derived from the schedule
+	}
+	if r.Duration != scheduledMinutes(r.StartTime, r.EndTime) { // Check if the duration disagrees with the schedule
+		sl.ReportError(r.Duration, "duration", "Duration", "schedule", "") // Report the duration
+	}
+}
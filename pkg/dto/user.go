@@ -0,0 +1,171 @@
+package dto
+
+import (
+	"strings"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RegisterUserRequest is the body of the registration request
+type RegisterUserRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`          // Name of the user
+	Email    string `json:"email" binding:"required,email"`           // Email address of the user
+	Password string `json:"password" binding:"required,min=8,max=72"` // Password of the user, bcrypt only uses 72 bytes
+	Role     string `json:"role" binding:"omitempty,role,ne=admin"`   // Role of the user, "user" when omitted; admins cannot register themselves
+	Avatar   string `json:"avatar" binding:"omitempty,url,max=500"`   // URL of the profile picture of the user
+	Cin      string `json:"cin" binding:"omitempty,alphanum,max=20"`  // National ID or CIN of the user
+}
+
+// LoginRequest is the body of the login request
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"` // Email address of the user
+	Password string `json:"password" binding:"required"`    // Password of the user
+}
+
+// UpdateUserRequest is the body of the user update request, omitted fields are left unchanged
+type UpdateUserRequest struct {
+	Name     string `json:"name" binding:"max=100"`                    // Name of the user
+	Email    string `json:"email" binding:"omitempty,email"`           // Email address of the user
+	Password string `json:"password" binding:"omitempty,min=8,max=72"` // Password of the user
+	Role     string `json:"role" binding:"omitempty,role,ne=admin"`    // Role of the user, admins are only appointed with PATCH
+	Avatar   string `json:"avatar" binding:"omitempty,url,max=500"`    // URL of the profile picture of the user
+	Cin      string `json:"cin" binding:"omitempty,alphanum,max=20"`   // National ID or CIN of the user
+}
+
+// PatchUserRequest describes the state of a user a merge patch applies to. The password is write-only:
+// it is empty unless the patch sets it.
+type PatchUserRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`           // Name of the user
+	Email    string `json:"email" binding:"required,email"`            // Email address of the user
+	Password string `json:"password" binding:"omitempty,min=8,max=72"` // New password of the user
+	Role     string `json:"role" binding:"required,role"`              // Role of the user
+	Avatar   string `json:"avatar" binding:"omitempty,url,max=500"`    // URL of the profile picture of the user
+	Cin      string `json:"cin" binding:"omitempty,alphanum,max=20"`   // National ID or CIN of the user
+}
+
+// NewPatchUserRequest returns the request describing the current state of the user, the target of a merge patch
+func NewPatchUserRequest(user models.User) PatchUserRequest { // Describe a user
+	return PatchUserRequest{Name: user.Name, Email: user.Email, Role: user.Role, Avatar: user.Avatar, Cin: user.Cin} // Return the request
+}
+
+// Apply copies the fields of the request to the user, leaving the password to the caller who hashes it
+func (r PatchUserRequest) Apply(user *models.User) { // Apply the request to a user
+	user.Name = r.Name                   // Set the name
+	user.Email = NormalizeEmail(r.Email) // Set the email
+	user.Role = r.Role                   // Set the role
+	user.Avatar = r.Avatar               // Set the avatar
+	user.Cin = r.Cin                     // Set the CIN
+}
+
+// Apply copies the fields given in the request to the user, leaving the password to the caller who hashes it
+func (r UpdateUserRequest) Apply(user *models.User) { // Apply the request to a user
+	next := r.ToModel()  // Get the fields to update
+	if next.Name != "" { // Check if the name was given
+		user.Name = next.Name // Set the name
+	}
+	if next.Email != "" { // Check if the email was given
+		user.Email = next.Email // Set the email
+	}
+	if next.Role != "" { // Check if the role was given
+		user.Role = next.Role // Set the role
+	}
+	if next.Avatar != "" { // Check if the avatar was given
+		user.Avatar = next.Avatar // Set the avatar
+	}
+	if next.Cin != "" { // Check if the CIN was given
+		user.Cin = next.Cin // Set the CIN
+	}
+}
+
+// NormalizeEmail lowercases an email, the same address always belongs to the same account whatever its case
+func NormalizeEmail(email string) string { // Normalize an email
+	return strings.ToLower(strings.TrimSpace(email)) // Return the normalized email
+}
+
+// ToModel returns the user described by the request, with the password still in clear text
+func (r RegisterUserRequest) ToModel() models.User { // Convert the request to a user
+	role := r.Role  // Get the requested role
+	if role == "" { // Check if the role was omitted
+		role = models.RoleUser // Default to a plain user
+	}
+	return models.User{Name: r.Name, Email: NormalizeEmail(r.Email), Password: r.Password, Role: role, Avatar: r.Avatar, Cin: r.Cin} // Return the user
+}
+
+// ToModel returns the fields to update, with the password still in clear text
+func (r UpdateUserRequest) ToModel() models.User { // Convert the request to a user
+	return models.User{Name: r.Name, Email: NormalizeEmail(r.Email), Password: r.Password, Role: r.Role, Avatar: r.Avatar, Cin: r.Cin} // Return the user
+}
+
+// PublicUser is the view of a user shown to anyone
+type PublicUser struct {
+	ID     primitive.ObjectID `json:"id"`     // Unique identifier for the user
+	Name   string             `json:"name"`   // Name of the user
+	Role   string             `json:"role"`   // Role of the user
+	Avatar string             `json:"avatar"` // URL of the profile picture of the user
+}
+
+// SelfUser is the view of a user shown to the user themselves
+type SelfUser struct {
+	PublicUser
+	Email     string    `json:"email"`      // Email address of the user
+	CreatedAt time.Time `json:"created_at"` // Timestamp when the user was created
+	UpdatedAt time.Time `json:"updated_at"` // Timestamp when the user was last updated
+	Version   int64     `json:"version"`    // Version of the user, to send in If-Match when updating them
+}
+
+// AdminUser is the view of a user shown to admins
+type AdminUser struct {
+	SelfUser
+	Cin string `json:"cin"` // National ID or CIN of the user
+
+	models.Deletion // Soft deletion of the user, empty while they are live
+}
+
+// NewPublicUser returns the public profile of the user
+func NewPublicUser(user models.User) PublicUser { // Build the public view
+	return PublicUser{ID: user.ID, Name: user.Name, Role: user.Role, Avatar: user.Avatar} // Return the view
+}
+
+// NewSelfUser returns the view of the user shown to themselves
+func NewSelfUser(user models.User) SelfUser { // Build the self view
+	return SelfUser{PublicUser: NewPublicUser(user), Email: user.Email, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt, Version: user.Version} // Return the view
+}
+
+// NewAdminUser returns the view of the user shown to admins
+func NewAdminUser(user models.User) AdminUser { // Build the admin view
+	return AdminUser{SelfUser: NewSelfUser(user), Cin: user.Cin, Deletion: user.Deletion} // Return the view
+}
+
+// Views of a user, the name of the view is part of its entity tag
+const (
+	UserViewPublic = "public" // Public profile
+	UserViewSelf   = "self"   // View of the user themselves
+	UserViewAdmin  = "admin"  // View of the admins
+)
+
+// UserViewName returns the name of the view of the user the viewer may see
+func UserViewName(user models.User, viewer *models.User) string { // Pick the view of a user
+	switch { // Check the viewer
+	case viewer == nil: // Anonymous viewer
+		return UserViewPublic // Return the public view
+	case viewer.Role == models.RoleAdmin: // Admin
+		return UserViewAdmin // Return the admin view
+	case viewer.ID == user.ID: // The user themselves
+		return UserViewSelf // Return the self view
+	}
+	return UserViewPublic // Return the public view
+}
+
+// UserView returns the view of the user the viewer may see: admins see every field but the password,
+// users see their own email and timestamps, and everyone else, anonymous viewers included, the public profile
+func UserView(user models.User, viewer *models.User) interface{} { // Build the view of a user
+	switch UserViewName(user, viewer) { // Check the view
+	case UserViewAdmin: // Admin
+		return NewAdminUser(user) // Return the admin view
+	case UserViewSelf: // The user themselves
+		return NewSelfUser(user) // Return the self view
+	}
+	return NewPublicUser(user) // Return the public view
+}
@@ -0,0 +1,95 @@
+// Package dto defines the bodies accepted by the API. Each request only exposes the fields a client may
+// set and carries the binding rules checked by gin before the handler runs; the server-managed fields
+// (ID, status, QR code, timestamps) are set by the handlers on the models.
+package dto
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"training_session/pkg/models"
+	"training_session/pkg/webhooks"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// roles are the values accepted by the "role" rule
+var roles = []string{models.RoleUser, models.RoleCoach, models.RoleBusinessOwner, models.RoleAdmin}
+
+// sessionStatuses are the values accepted by the "session_status" rule
+var sessionStatuses = []string{models.SessionStatusActive, models.SessionStatusArchived}
+
+// deliveryStatuses are the values accepted by the "delivery_status" rule
+var deliveryStatuses = []string{models.DeliveryStatusPending, models.DeliveryStatusSucceeded, models.DeliveryStatusDead}
+
+// erasureStatuses are the values accepted by the "erasure_status" rule
+var erasureStatuses = []string{models.ErasureStatusPending, models.ErasureStatusApproved, models.ErasureStatusRejected}
+
+// RegisterValidators registers the custom rules of the requests in gin's validator:
+// "role", "session_status", "training_type" (one of trainingTypes), "event_type", "delivery_status",
+// "erasure_status", "webhook_url" (a public host unless allowPrivateWebhooks) and "objectid", and reports the
+// invalid fields by their JSON name
+func RegisterValidators(trainingTypes []string, allowPrivateWebhooks bool) error { // Register the custom rules
+	v, ok := binding.Validator.Engine().(*validator.Validate) // Get gin's validator
+	if !ok {                                                  // Check if gin uses another validator
+		return errors.New("gin does not use the go-playground validator") // Return an error
+	}
+
+	v.RegisterTagNameFunc(jsonName) // Name the fields by their JSON key
+
+	rules := map[string]validator.Func{ // Define the rules
+		"role":            oneOf(roles),                     // Role of a user
+		"session_status":  oneOf(sessionStatuses),           // Status of a session
+		"training_type":   oneOf(trainingTypes),             // Training type of a session or feedback form
+		"event_type":      oneOf(models.EventTypes),         // Type of a domain event
+		"delivery_status": oneOf(deliveryStatuses),          // Status of a webhook delivery
+		"erasure_status":  oneOf(erasureStatuses),           // Status of an erasure request
+		"webhook_url":     webhookURL(allowPrivateWebhooks), // Endpoint of a webhook
+		"objectid":        isObjectID,                       // Hex encoded ObjectID
+	}
+	for tag, rule := range rules { // Iterate over the rules
+		if err := v.RegisterValidation(tag, rule); err != nil { // Register the rule
+			return err // Return the error
+		}
+	}
+
+	v.RegisterStructValidation(validateSessionRequest, SessionRequest{}) // Check the schedule of the sessions
+	return nil                                                           // Return nil
+}
+
+// jsonName returns the JSON key of a struct field, the invalid fields are reported by that name
+func jsonName(field reflect.StructField) string { // Get the JSON key
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",") // Drop the options of the tag
+	if name == "-" {                                      // Check if the field is not serialized
+		return "" // Fall back to the field name
+	}
+	return name // Return the key
+}
+
+// oneOf returns a rule accepting the string fields equal to one of the values
+func oneOf(values []string) validator.Func { // Build an enumeration rule
+	return func(fl validator.FieldLevel) bool { // Return the rule
+		return slices.Contains(values, fl.Field().String()) // Check the value
+	}
+}
+
+// isObjectID accepts the string fields holding a hex encoded ObjectID
+func isObjectID(fl validator.FieldLevel) bool { // Check an ObjectID
+	return primitive.IsValidObjectID(fl.Field().String()) // Check the value
+}
+
+// webhookURL returns a rule accepting the string fields holding an absolute http or https URL without
+// credentials, whose host is public unless private hosts are allowed
+func webhookURL(allowPrivate bool) validator.Func { // Build the webhook URL rule
+	return func(fl validator.FieldLevel) bool { // Return the rule
+		u, err := url.Parse(fl.Field().String())                                                              // Parse the URL
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil { // Check the scheme, the host and the credentials
+			return false // Not a webhook URL
+		}
+		return allowPrivate || webhooks.PublicHost(u.Hostname()) // Check the host
+	}
+}
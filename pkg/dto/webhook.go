@@ -0,0 +1,24 @@
+package dto
+
+import "training_session/pkg/models"
+
+// WebhookRequest is the body of the webhook registration and update requests
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=2000,webhook_url"`  // Endpoint the events are posted to
+	Description string   `json:"description" binding:"max=200"`                // Description of the receiver
+	EventTypes  []string `json:"event_types" binding:"max=20,dive,event_type"` // Event types delivered, every type when empty
+	Active      *bool    `json:"active"`                                       // Whether new events are delivered, true when omitted
+}
+
+// Apply copies the fields of the request to the webhook, leaving the owner, secret and timestamps untouched
+func (r WebhookRequest) Apply(webhook *models.Webhook) { // Apply the request to a webhook
+	webhook.URL = r.URL                                      // Set the URL
+	webhook.Description = r.Description                      // Set the description
+	webhook.EventTypes = append([]string{}, r.EventTypes...) // Set the event types, an empty list when omitted
+	webhook.Active = r.Active == nil || *r.Active            // Set the state, active by default
+}
+
+// DeliveryQuery filters the deliveries of a webhook
+type DeliveryQuery struct {
+	Status string `form:"status" json:"status" binding:"omitempty,delivery_status"` // Only list the deliveries with this status
+}
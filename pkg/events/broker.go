@@ -0,0 +1,86 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"training_session/config"
+	"training_session/pkg/models"
+)
+
+// Broker publishes the domain events to their consumers. Publish returns once the broker accepted the event:
+// the relay only marks an event published then, so every event is delivered at least once.
+type Broker interface {
+	Publish(ctx context.Context, event models.Event) error // Publish an event
+	Close() error                                          // Flush the pending publications and release the connection
+}
+
+// NewBroker creates the broker selected by the configuration
+func NewBroker(cfg config.EventsConfig) (Broker, error) { // Create the broker
+	switch cfg.Broker { // Create the configured broker
+	case "bus": // Deliver the events in the process
+		return NewBus(), nil // Return the bus
+	case "nats": // Publish the events to NATS JetStream
+		return NewNATSBroker(cfg.NATS) // Return the NATS broker
+	case "kafka": // Publish the events to Kafka
+		return NewKafkaBroker(cfg.Kafka), nil // Return the Kafka broker
+	default: // Unknown broker
+		return nil, fmt.Errorf("unknown event broker %q", cfg.Broker) // Return the error
+	}
+}
+
+// Encode renders an event as the JSON message sent to the brokers: its ID, type, aggregate, data and occurrence time
+func Encode(event models.Event) ([]byte, error) { // Encode an event
+	return json.Marshal(event) // Marshal the public fields of the event
+}
+
+// public publishes the domain events other services may consume, leaving the internal events out
+type public struct {
+	Broker // Broker the events are published to
+}
+
+// Public returns a broker publishing the events whose type is one of models.EventTypes to the broker, and
+// accepting the other events without publishing them
+func Public(broker Broker) Broker { // Keep the internal events in
+	return public{broker} // Return the filtering broker
+}
+
+// Publish publishes the event if it is public
+func (p public) Publish(ctx context.Context, event models.Event) error { // Publish an event
+	if !slices.Contains(models.EventTypes, event.Type) { // Check if the event is internal
+		return nil // Accept the event
+	}
+	return p.Broker.Publish(ctx, event) // Publish the event
+}
+
+// fanout publishes the events to several brokers
+type fanout []Broker
+
+// Fanout returns a broker publishing every event to each of the brokers in turn, stopping at the first one that
+// fails; the event is then published again to all of them, so the brokers before it see the event twice
+func Fanout(brokers ...Broker) Broker { // Combine brokers
+	return fanout(brokers) // Return the combined broker
+}
+
+// Publish publishes the event to every broker
+func (f fanout) Publish(ctx context.Context, event models.Event) error { // Publish an event
+	for _, broker := range f { // Iterate over the brokers
+		if err := broker.Publish(ctx, event); err != nil { // Publish the event
+			return err // Return the error
+		}
+	}
+	return nil // Every broker accepted the event
+}
+
+// Close closes every broker
+func (f fanout) Close() error { // Close the brokers
+	var errs []error           // Define a slice to hold the errors met
+	for _, broker := range f { // Iterate over the brokers
+		if err := broker.Close(); err != nil { // Close the broker
+			errs = append(errs, err) // Report the failure
+		}
+	}
+	return errors.Join(errs...) // Return the errors met
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"training_session/pkg/models"
+)
+
+// Handler consumes an event; an error makes the relay publish the event again on its next poll
+type Handler func(ctx context.Context, event models.Event) error
+
+// Bus delivers the events to the handlers subscribed in the same process, useful for tests and for a single
+// instance. Handlers run in the order they subscribed; when one fails the event is published again, so the
+// handlers before it see the event twice and must be idempotent.
+type Bus struct {
+	mu       sync.RWMutex // Protects the handlers
+	handlers []Handler    // Subscribed handlers
+}
+
+// NewBus creates a bus without handlers
+func NewBus() *Bus { // Create a bus
+	return &Bus{} // Return the bus
+}
+
+// Subscribe adds a handler receiving every published event
+func (b *Bus) Subscribe(handler Handler) { // Subscribe to the events
+	b.mu.Lock()                              // Lock the handlers
+	defer b.mu.Unlock()                      // Unlock the handlers
+	b.handlers = append(b.handlers, handler) // Add the handler
+}
+
+// Publish delivers the event to every handler, stopping at the first one that fails
+func (b *Bus) Publish(ctx context.Context, event models.Event) error { // Deliver an event
+	b.mu.RLock()                                      // Lock the handlers for reading
+	handlers := append([]Handler(nil), b.handlers...) // Copy the handlers so they can subscribe while delivering
+	b.mu.RUnlock()                                    // Unlock the handlers
+
+	for i, handler := range handlers { // Iterate over the handlers
+		if err := handler(ctx, event); err != nil { // Deliver the event
+			return fmt.Errorf("handler %d failed: %w", i, err) // Return the error
+		}
+	}
+	return nil // Every handler consumed the event
+}
+
+// Close does nothing, the bus holds no connection
+func (b *Bus) Close() error { // Close the bus
+	return nil // Return nil
+}
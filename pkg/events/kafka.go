@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"time"
+	"training_session/config"
+	"training_session/pkg/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBroker publishes the events to a Kafka topic, keyed by aggregate ID so the events of an aggregate land
+// on the same partition, in order. Every in-sync replica must acknowledge an event before it is accepted.
+type KafkaBroker struct {
+	writer *kafka.Writer // Writer of the topic
+}
+
+// NewKafkaBroker creates the writer of the topic, connections are opened on the first publication
+func NewKafkaBroker(cfg config.KafkaConfig) *KafkaBroker { // Create the Kafka broker
+	return &KafkaBroker{writer: &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...), // Set the brokers
+		Topic:        cfg.Topic,                 // Set the topic
+		Balancer:     &kafka.Hash{},             // Partition by key
+		RequiredAcks: kafka.RequireAll,          // Wait for the in-sync replicas
+		BatchTimeout: 10 * time.Millisecond,     // Send each event without waiting for a full batch
+	}} // Return the broker
+}
+
+// Publish publishes an event and waits for the brokers to acknowledge it
+func (b *KafkaBroker) Publish(ctx context.Context, event models.Event) error { // Publish an event
+	data, err := Encode(event) // Encode the event
+	if err != nil {            // Check if there is an error
+		return err // Return the error
+	}
+
+	return b.writer.WriteMessages(ctx, kafka.Message{ // Write the event
+		Key:   []byte(event.AggregateID.Hex()), // Keep the events of an aggregate on one partition
+		Value: data,                            // Set the payload
+		Headers: []kafka.Header{
+			{Key: "id", Value: []byte(event.ID.Hex())}, // Let the consumers drop the republished events
+			{Key: "type", Value: []byte(event.Type)},   // Let the consumers filter by type
+		},
+	})
+}
+
+// Close flushes the pending writes and closes the connections
+func (b *KafkaBroker) Close() error { // Close the broker
+	return b.writer.Close() // Close the writer
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"training_session/config"
+	"training_session/pkg/models"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSBroker publishes the events to NATS JetStream on <subject_prefix>.<type>. JetStream acknowledges the
+// events once stored and drops the duplicates of an event ID within the duplicate window of the stream; a
+// stream must capture the <subject_prefix>.> subjects.
+type NATSBroker struct {
+	conn   *nats.Conn            // Connection to the NATS server
+	stream nats.JetStreamContext // JetStream publisher of the connection
+	prefix string                // Prefix of the subjects
+}
+
+// NewNATSBroker connects to the NATS server
+func NewNATSBroker(cfg config.NATSConfig) (*NATSBroker, error) { // Create the NATS broker
+	conn, err := nats.Connect(cfg.URL, nats.Name("training-session")) // Connect to the server
+	if err != nil {                                                   // Check if there is an error
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err) // Return the error
+	}
+	stream, err := conn.JetStream() // Get the JetStream publisher
+	if err != nil {                 // Check if there is an error
+		conn.Close()                                               // Close the connection
+		return nil, fmt.Errorf("failed to use JetStream: %w", err) // Return the error
+	}
+	return &NATSBroker{conn: conn, stream: stream, prefix: cfg.SubjectPrefix}, nil // Return the broker
+}
+
+// Publish publishes an event and waits for JetStream to store it
+func (b *NATSBroker) Publish(ctx context.Context, event models.Event) error { // Publish an event
+	data, err := Encode(event) // Encode the event
+	if err != nil {            // Check if there is an error
+		return err // Return the error
+	}
+
+	msg := nats.NewMsg(b.prefix + "." + event.Type)         // Publish on the subject of the type
+	msg.Data = data                                         // Set the payload
+	msg.Header.Set(nats.MsgIdHdr, event.ID.Hex())           // Let JetStream drop the republished events
+	msg.Header.Set("Aggregate-Id", event.AggregateID.Hex()) // Let the consumers order the events of an aggregate
+	_, err = b.stream.PublishMsg(msg, nats.Context(ctx))    // Publish and wait for the acknowledgement
+	return err                                              // Return the error
+}
+
+// Close drains the connection
+func (b *NATSBroker) Close() error { // Close the broker
+	return b.conn.Drain() // Drain the connection
+}
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"training_session/config"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Observer is told the outcome of every publication, err is nil when the broker accepted the event
+type Observer interface {
+	ObservePublication(eventType string, err error) // Record a publication of an event of the type
+}
+
+// Relay publishes the events of the outbox to a broker. Only the relay holding the outbox lease publishes,
+// so the events of an aggregate are published in the order they occurred even with several instances. An
+// event is marked published once the broker accepted it: a relay stopping in between publishes it again,
+// consumers deduplicate with the event ID.
+type Relay struct {
+	outbox   repository.OutboxRepository // Outbox the events are read from
+	broker   Broker                      // Broker the events are published to
+	cfg      config.EventsConfig         // Batch size and lease timeout
+	owner    string                      // Owner of the lease, unique to this relay
+	observer Observer                    // Observer of the publications
+	logger   *slog.Logger                // Logger of the failed publications
+}
+
+// NewRelay creates a relay publishing the events of the outbox to the broker
+func NewRelay(outbox repository.OutboxRepository, broker Broker, cfg config.EventsConfig, observer Observer, logger *slog.Logger) *Relay { // Create a relay
+	host, _ := os.Hostname() // Get the host name, to find the relay holding the lease
+	return &Relay{
+		outbox:   outbox,                                     // Set the outbox
+		broker:   broker,                                     // Set the broker
+		cfg:      cfg,                                        // Set the configuration
+		owner:    host + "/" + primitive.NewObjectID().Hex(), // Identify the relay
+		observer: observer,                                   // Set the observer
+		logger:   logger,                                     // Set the logger
+	}
+}
+
+// PublishPending publishes a batch of pending events, oldest first. When an event fails, the later events of
+// its aggregate are left for the next poll so they are never published before it.
+func (r *Relay) PublishPending(ctx context.Context) error { // Publish the pending events
+	held, err := r.lease(ctx) // Take or renew the lease
+	if err != nil || !held {  // Check if this relay must not publish
+		return err // Return the error, nil when another relay holds the lease
+	}
+	renewAt := time.Now().Add(r.cfg.LeaseTimeout / 2) // Renew the lease halfway through
+
+	events, err := r.outbox.FindPending(ctx, r.cfg.BatchSize) // Find the pending events
+	if err != nil {                                           // Check if there is an error
+		return fmt.Errorf("failed to find the pending events: %w", err) // Return the error
+	}
+
+	blocked := make(map[primitive.ObjectID]bool) // Define the aggregates with an event that failed
+	failed := 0                                  // Define the number of failed publications
+	for _, event := range events {               // Iterate over the events
+		if blocked[event.AggregateID] { // Check if an earlier event of the aggregate failed
+			continue // Keep the order of the aggregate
+		}
+		if time.Now().After(renewAt) { // Check if the lease must be renewed
+			if held, err := r.lease(ctx); err != nil || !held { // Renew the lease
+				return err // Stop, another relay took the lease over
+			}
+			renewAt = time.Now().Add(r.cfg.LeaseTimeout / 2) // Renew the lease halfway through again
+		}
+
+		err := r.broker.Publish(ctx, event)            // Publish the event
+		r.observer.ObservePublication(event.Type, err) // Record the publication
+		if err != nil {                                // Check if the broker rejected the event
+			blocked[event.AggregateID] = true                                                                                                                      // Hold back the later events of the aggregate
+			failed++                                                                                                                                               // Count the failure
+			r.logger.WarnContext(ctx, "Failed to publish event", slog.String("event_id", event.ID.Hex()), slog.String("type", event.Type), slog.Any("error", err)) // Log the error message
+			if err := r.outbox.MarkFailed(ctx, event.ID, err.Error()); err != nil {                                                                                // Record the failure
+				return fmt.Errorf("failed to record the failed publication of event %s: %w", event.ID.Hex(), err) // Return the error
+			}
+			continue // Go to the next event
+		}
+		if err := r.outbox.MarkPublished(ctx, event.ID, time.Now()); err != nil { // Record the publication
+			return fmt.Errorf("failed to mark event %s published: %w", event.ID.Hex(), err) // Stop, the event will be published again
+		}
+	}
+
+	if failed > 0 { // Check if events failed
+		return fmt.Errorf("failed to publish %d of %d events", failed, len(events)) // Report the failures
+	}
+	return nil // Every event was published
+}
+
+// lease takes or renews the outbox lease, reporting false while another relay holds it
+func (r *Relay) lease(ctx context.Context) (bool, error) { // Hold the lease
+	now := time.Now()                                                     // Get the current time
+	err := r.outbox.Lease(ctx, r.owner, now, now.Add(r.cfg.LeaseTimeout)) // Take the lease
+	if errors.Is(err, repository.ErrConflict) {                           // Check if another relay holds the lease
+		return false, nil // Leave the events to the other relay
+	}
+	if err != nil { // Check if there is an error
+		return false, fmt.Errorf("failed to take the outbox lease: %w", err) // Return the error
+	}
+	return true, nil // The lease is held
+}
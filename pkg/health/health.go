@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"training_session/pkg/buildinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check reports whether a dependency is ready, it must return once the context is done
+type Check func(ctx context.Context) error
+
+// namedCheck is a readiness check with the name it is reported under
+type namedCheck struct {
+	name  string // Name of the check
+	check Check  // Check to run
+}
+
+// Probes serves the liveness, readiness and version endpoints
+type Probes struct {
+	mu       sync.RWMutex  // Mutex guarding the checks
+	checks   []namedCheck  // Readiness checks, in registration order
+	timeout  time.Duration // Maximum duration of each check
+	draining atomic.Bool   // Whether the server is shutting down
+}
+
+// New creates the probes, each readiness check is given the timeout
+func New(timeout time.Duration) *Probes { // Create the probes
+	return &Probes{timeout: timeout} // Return the probes
+}
+
+// AddCheck registers a readiness check
+func (p *Probes) AddCheck(name string, check Check) { // Register a readiness check
+	p.mu.Lock()                                                       // Lock the checks
+	defer p.mu.Unlock()                                               // Unlock the checks
+	p.checks = append(p.checks, namedCheck{name: name, check: check}) // Add the check
+}
+
+// SetDraining marks the server as shutting down, so it stops being ready while it drains requests
+func (p *Probes) SetDraining() { // Mark the server as shutting down
+	p.draining.Store(true) // Record the shutdown
+}
+
+// Healthz: Reports that the process is alive
+func (p *Probes) Healthz(c *gin.Context) { // Report liveness
+	c.JSON(http.StatusOK, gin.H{"status": "ok"}) // Return a success response
+}
+
+// Readyz: Reports whether the server can take traffic, running every readiness check
+func (p *Probes) Readyz(c *gin.Context) { // Report readiness
+	p.mu.RLock()                                     // Lock the checks
+	checks := append([]namedCheck(nil), p.checks...) // Copy the checks
+	p.mu.RUnlock()                                   // Unlock the checks
+
+	ready := !p.draining.Load()    // The server is not ready while it shuts down
+	results := map[string]string{} // Define a map to hold the result of each check
+	if !ready {                    // Check if the server is shutting down
+		results["shutdown"] = "server is shutting down" // Report the shutdown
+	}
+
+	for _, nc := range checks { // Iterate over the checks
+		ctx, cancel := context.WithTimeout(c.Request.Context(), p.timeout) // Bound the check
+		err := nc.check(ctx)                                               // Run the check
+		cancel()                                                           // Release the timer
+		if err != nil {                                                    // Check if the check failed
+			ready = false                  // The server is not ready
+			results[nc.name] = err.Error() // Report the failure
+		} else {
+			results[nc.name] = "ok" // Report the success
+		}
+	}
+
+	if !ready { // Check if the server is not ready
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unready", "checks": results}) // Return a service unavailable response
+		return                                                                               // Return from the function
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": results}) // Return a success response
+}
+
+// Version: Reports the build information of the running binary
+func (p *Probes) Version(c *gin.Context) { // Report the version
+	c.JSON(http.StatusOK, buildinfo.Get()) // Return the build information
+}
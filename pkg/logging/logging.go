@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"training_session/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the request ID, added to every record logged with it
+func WithRequestID(ctx context.Context, requestID string) context.Context { // Attach a request ID
+	return context.WithValue(ctx, requestIDKey{}, requestID) // Store the request ID
+}
+
+// RequestID returns the request ID carried by the context, empty when there is none
+func RequestID(ctx context.Context) string { // Get the request ID
+	requestID, _ := ctx.Value(requestIDKey{}).(string) // Read the request ID
+	return requestID                                   // Return the request ID
+}
+
+// New creates a logger writing to w with the configured level and format. Records are scrubbed of
+// secrets and personal data, and carry the request and trace IDs of their context.
+func New(w io.Writer, cfg config.LogConfig) (*slog.Logger, error) { // Create a logger
+	var level slog.Level                                           // Define the minimum level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil { // Parse the level
+		return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err) // Return the error
+	}
+
+	opts := &slog.HandlerOptions{Level: level} // Set the minimum level
+	var handler slog.Handler                   // Define the output handler
+	switch strings.ToLower(cfg.Format) {       // Create the configured format
+	case "json": // One JSON object per line
+		handler = slog.NewJSONHandler(w, opts) // Write JSON
+	case "text": // key=value pairs
+		handler = slog.NewTextHandler(w, opts) // Write text
+	default: // Unknown format
+		return nil, fmt.Errorf("invalid log format %q", cfg.Format) // Return the error
+	}
+
+	return slog.New(&contextHandler{next: NewScrubHandler(handler)}), nil // Return the logger
+}
+
+// contextHandler adds the request and trace IDs of the context to the records
+type contextHandler struct {
+	next slog.Handler // Handler the records are passed to
+}
+
+// Enabled reports whether the next handler takes records of the level
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool { // Check the level
+	return h.next.Enabled(ctx, level) // Ask the next handler
+}
+
+// Handle adds the IDs found in the context and passes the record on
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error { // Handle a record
+	if requestID := RequestID(ctx); requestID != "" { // Check if the record belongs to a request
+		r.AddAttrs(slog.String("request_id", requestID)) // Add the request ID
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() { // Check if the record belongs to a trace
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String())) // Add the trace IDs
+	}
+	return h.next.Handle(ctx, r) // Pass the record on
+}
+
+// WithAttrs returns a handler adding the attributes to every record
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler { // Add attributes
+	return &contextHandler{next: h.next.WithAttrs(attrs)} // Return the handler
+}
+
+// WithGroup returns a handler nesting the next attributes in the group
+func (h *contextHandler) WithGroup(name string) slog.Handler { // Open a group
+	return &contextHandler{next: h.next.WithGroup(name)} // Return the handler
+}
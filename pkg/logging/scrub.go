@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const redacted = "[REDACTED]" // Replacement of the scrubbed values
+
+// sensitiveKeys are the attribute key fragments whose values are never logged
+var sensitiveKeys = []string{"token", "password", "secret", "authorization", "cookie", "jwt"}
+
+// sensitiveWords are the attribute key words whose values are never logged, too short to match inside other words
+var sensitiveWords = []string{"cin"}
+
+// sensitiveValues match secrets and personal data embedded in free text
+var sensitiveValues = []*regexp.Regexp{
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),       // JSON Web Tokens
+	regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`),              // Authorization header values
+	regexp.MustCompile(`(?i)\b(password|passwd|pwd|secret|token)\b\s*[=:]\s*\S+`), // key=value secrets
+	regexp.MustCompile(`\b[A-Z]{1,2}[0-9]{5,6}\b`),                                // CIN numbers
+}
+
+// ScrubHandler removes secrets and personal data from the records before they reach the next handler:
+// attributes with a sensitive key are replaced and sensitive patterns are masked in messages and strings
+type ScrubHandler struct {
+	next slog.Handler // Handler the scrubbed records are passed to
+}
+
+// NewScrubHandler creates a handler scrubbing the records passed to next
+func NewScrubHandler(next slog.Handler) *ScrubHandler { // Create a scrub handler
+	return &ScrubHandler{next: next} // Return the handler
+}
+
+// Enabled reports whether the next handler takes records of the level
+func (h *ScrubHandler) Enabled(ctx context.Context, level slog.Level) bool { // Check the level
+	return h.next.Enabled(ctx, level) // Ask the next handler
+}
+
+// Handle scrubs the message and attributes of the record and passes it on
+func (h *ScrubHandler) Handle(ctx context.Context, r slog.Record) error { // Handle a record
+	scrubbed := slog.NewRecord(r.Time, r.Level, ScrubString(r.Message), r.PC) // Copy the record with a scrubbed message
+	r.Attrs(func(attr slog.Attr) bool {                                       // Iterate over the attributes
+		scrubbed.AddAttrs(scrubAttr(attr)) // Add the scrubbed attribute
+		return true                        // Continue with the next attribute
+	})
+	return h.next.Handle(ctx, scrubbed) // Pass the record on
+}
+
+// WithAttrs returns a handler adding the scrubbed attributes to every record
+func (h *ScrubHandler) WithAttrs(attrs []slog.Attr) slog.Handler { // Add attributes
+	scrubbed := make([]slog.Attr, len(attrs)) // Define a slice to hold the scrubbed attributes
+	for i, attr := range attrs {              // Iterate over the attributes
+		scrubbed[i] = scrubAttr(attr) // Scrub the attribute
+	}
+	return &ScrubHandler{next: h.next.WithAttrs(scrubbed)} // Return the handler
+}
+
+// WithGroup returns a handler nesting the next attributes in the group
+func (h *ScrubHandler) WithGroup(name string) slog.Handler { // Open a group
+	return &ScrubHandler{next: h.next.WithGroup(name)} // Return the handler
+}
+
+// ScrubString masks the secrets and personal data found in the text
+func ScrubString(text string) string { // Scrub a text
+	for _, pattern := range sensitiveValues { // Iterate over the patterns
+		text = pattern.ReplaceAllString(text, redacted) // Mask the matches
+	}
+	return text // Return the scrubbed text
+}
+
+// scrubAttr replaces the value of a sensitive attribute and scrubs the strings and groups of the others
+func scrubAttr(attr slog.Attr) slog.Attr { // Scrub an attribute
+	if isSensitiveKey(attr.Key) { // Check if the value must never be logged
+		return slog.String(attr.Key, redacted) // Replace the value
+	}
+
+	value := attr.Value.Resolve() // Resolve the LogValuer values
+	switch value.Kind() {         // Scrub according to the kind of value
+	case slog.KindString: // Mask the patterns in the string
+		return slog.String(attr.Key, ScrubString(value.String())) // Return the scrubbed string
+	case slog.KindGroup: // Scrub every attribute of the group
+		group := value.Group()                    // Get the attributes of the group
+		scrubbed := make([]slog.Attr, len(group)) // Define a slice to hold the scrubbed attributes
+		for i, member := range group {            // Iterate over the attributes
+			scrubbed[i] = scrubAttr(member) // Scrub the attribute
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(scrubbed...)} // Return the scrubbed group
+	case slog.KindAny: // Errors, structs and other values are logged through their text so it can be scrubbed
+		if err, ok := value.Any().(error); ok { // Check if the value is an error
+			return slog.String(attr.Key, ScrubString(err.Error())) // Return the scrubbed error
+		}
+		return slog.String(attr.Key, ScrubString(fmt.Sprintf("%+v", value.Any()))) // Return the scrubbed text
+	}
+	return slog.Attr{Key: attr.Key, Value: value} // Return the attribute unchanged
+}
+
+// isSensitiveKey reports whether the key names a secret or personal data
+func isSensitiveKey(key string) bool { // Check a key
+	key = strings.ToLower(key)               // Compare case-insensitively
+	for _, fragment := range sensitiveKeys { // Iterate over the sensitive fragments
+		if strings.Contains(key, fragment) { // Check if the key contains the fragment
+			return true // The key is sensitive
+		}
+	}
+	words := strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) // Split the key into words
+	for _, word := range words {                                                                    // Iterate over the words
+		if slices.Contains(sensitiveWords, word) { // Check if the word is sensitive
+			return true // The key is sensitive
+		}
+	}
+	return false // The key is not sensitive
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const namespace = "training" // Prefix of every metric name
+
+// Metrics holds the Prometheus collectors of the service, registered on their own registry
+type Metrics struct {
+	registry *prometheus.Registry // Registry served by the metrics endpoint
+
+	httpRequests *prometheus.CounterVec   // HTTP requests by method, route and status
+	httpDuration *prometheus.HistogramVec // HTTP request latency by method, route and status
+	mongoCommand *prometheus.HistogramVec // MongoDB command latency by command and outcome
+
+	SessionsCreated   prometheus.Counter     // Sessions created
+	SessionsCancelled prometheus.Counter     // Sessions cancelled
+	Enrollments       *prometheus.CounterVec // Enrollment changes by action, "enroll", "waitlist" or "cancel"
+	CheckIns          prometheus.Counter     // Participations verified with a session QR code
+	Notifications     *prometheus.CounterVec // Notification deliveries by channel and outcome, "delivered" or "failed"
+	PitchBookings     prometheus.Counter     // Pitches booked
+	Events            *prometheus.CounterVec // Domain event publications by type and outcome, "published" or "failed"
+	WebhookDeliveries *prometheus.CounterVec // Webhook delivery attempts by event type and outcome, "delivered", "retrying" or "dead"
+}
+
+// New creates the collectors and registers them, with the Go runtime and process collectors
+func New() *Metrics { // Create the metrics
+	m := &Metrics{
+		registry: prometheus.NewRegistry(), // Create the registry
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "http", Name: "requests_total",
+			Help: "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}), // Count the HTTP requests
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+			Help: "HTTP request latency by method, route and status.", Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}), // Time the HTTP requests
+		mongoCommand: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "mongo", Name: "command_duration_seconds",
+			Help:    "MongoDB command latency by command and outcome.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"command", "outcome"}), // Time the MongoDB commands
+		SessionsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "sessions_created_total", Help: "Sessions created.",
+		}), // Count the created sessions
+		SessionsCancelled: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "sessions_cancelled_total", Help: "Sessions cancelled.",
+		}), // Count the cancelled sessions
+		Enrollments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "enrollments_total", Help: "Enrollment changes by action.",
+		}, []string{"action"}), // Count the enrollment changes
+		CheckIns: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "checkins_total", Help: "Participations verified with a session QR code.",
+		}), // Count the check-ins
+		Notifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "notifications_total", Help: "Notification deliveries by channel and outcome.",
+		}, []string{"channel", "outcome"}), // Count the notification deliveries
+		PitchBookings: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Name: "pitch_bookings_total", Help: "Pitches booked.",
+		}), // Count the pitch bookings
+		Events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "events_total", Help: "Domain event publications by type and outcome.",
+		}, []string{"type", "outcome"}), // Count the event publications
+		WebhookDeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "webhook_deliveries_total", Help: "Webhook delivery attempts by event type and outcome.",
+		}, []string{"type", "outcome"}), // Count the webhook delivery attempts
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),                                       // Expose the Go runtime metrics
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}), // Expose the process metrics
+		m.httpRequests, m.httpDuration, m.mongoCommand, // Expose the HTTP and MongoDB metrics
+		m.SessionsCreated, m.SessionsCancelled, m.Enrollments, m.CheckIns, m.Notifications, m.PitchBookings, m.Events, m.WebhookDeliveries, // Expose the domain metrics
+	)
+	return m // Return the metrics
+}
+
+// Handler serves the registered metrics in the Prometheus text format
+func (m *Metrics) Handler() gin.HandlerFunc { // Create the metrics handler
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})) // Serve the registry
+}
+
+// Middleware records the count and latency of the requests. Requests are labelled with their route
+// pattern rather than their path, so IDs do not create a series per resource.
+func (m *Metrics) Middleware() gin.HandlerFunc { // Create the metrics middleware
+	return func(c *gin.Context) {
+		start := time.Now() // Record the start of the request
+		c.Next()            // Handle the request
+
+		route := c.FullPath() // Get the route pattern
+		if route == "" {      // Check if no route matched
+			route = "unmatched" // Group the unknown paths together
+		}
+		status := strconv.Itoa(c.Writer.Status())                                                            // Get the response status
+		m.httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()                                // Count the request
+		m.httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds()) // Time the request
+	}
+}
+
+// CommandMonitor returns a MongoDB command monitor timing every command sent by the driver
+func (m *Metrics) CommandMonitor() *event.CommandMonitor { // Create the command monitor
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) { // Record a successful command
+			m.mongoCommand.WithLabelValues(e.CommandName, "success").Observe(e.Duration.Seconds()) // Time the command
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) { // Record a failed command
+			m.mongoCommand.WithLabelValues(e.CommandName, "failure").Observe(e.Duration.Seconds()) // Time the command
+		},
+	}
+}
+
+// ObserveDelivery counts a notification delivery on the channel, failed when err is not nil
+func (m *Metrics) ObserveDelivery(channel string, err error) { // Count a notification delivery
+	outcome := "delivered" // The delivery succeeded by default
+	if err != nil {        // Check if the delivery failed
+		outcome = "failed" // The delivery failed
+	}
+	m.Notifications.WithLabelValues(channel, outcome).Inc() // Count the delivery
+}
+
+// ObservePublication counts a publication of a domain event of the type, failed when err is not nil
+func (m *Metrics) ObservePublication(eventType string, err error) { // Count an event publication
+	outcome := "published" // The publication succeeded by default
+	if err != nil {        // Check if the publication failed
+		outcome = "failed" // The publication failed
+	}
+	m.Events.WithLabelValues(eventType, outcome).Inc() // Count the publication
+}
+
+// ObserveWebhookDelivery counts an attempt to deliver an event of the type to a webhook
+func (m *Metrics) ObserveWebhookDelivery(eventType, outcome string) { // Count a webhook delivery attempt
+	m.WebhookDeliveries.WithLabelValues(eventType, outcome).Inc() // Count the attempt
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs one line per request, except for the given paths. The query string is left out
+// since it may carry tokens; server errors are logged at the error level and client errors at warn.
+func AccessLog(logger *slog.Logger, skipPaths []string) gin.HandlerFunc { // Create the access log middleware
+	return func(c *gin.Context) {
+		start := time.Now() // Record the start of the request
+		c.Next()            // Handle the request
+
+		if slices.Contains(skipPaths, c.Request.URL.Path) { // Check if the request is not logged
+			return // Skip the request
+		}
+
+		status := c.Writer.Status() // Get the response status
+		level := slog.LevelInfo     // Log successful requests at the info level
+		switch {                    // Raise the level of the failed requests
+		case status >= 500: // Server error
+			level = slog.LevelError // Log at the error level
+		case status >= 400: // Client error
+			level = slog.LevelWarn // Log at the warn level
+		}
+
+		logger.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),                               // Method of the request
+			slog.String("path", c.Request.URL.Path),                               // Path of the request
+			slog.String("route", c.FullPath()),                                    // Route pattern of the request
+			slog.Int("status", status),                                            // Response status
+			slog.Duration("latency", time.Since(start)),                           // Time taken to handle the request
+			slog.Int("size", c.Writer.Size()),                                     // Size of the response body
+			slog.String("client_ip", c.ClientIP()),                                // Address of the client
+			slog.String("user_agent", c.Request.UserAgent()),                      // User agent of the client
+			slog.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()), // Errors recorded by the handlers
+		) // Log the request
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"training_session/pkg/audit"
+	"training_session/pkg/logging"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// auditedMethods are the methods whose requests are recorded in the audit log
+var auditedMethods = map[string]bool{http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true}
+
+// errHandled fails a transaction retried after its handlers ran: they cannot run twice, the request fails instead
+var errHandled = errors.New("the request was already handled in a failed transaction")
+
+// Audit records every state-changing request of a known route in the audit log once it is answered, whatever its
+// outcome: the caller identified by the auth middlewares, the route, the status, the client address and the
+// request ID. The target defaults to the first segment and parameter of the route; handlers describe the document
+// they changed and its fields before and after with an audit.Change. The handlers run in one transaction, which
+// the transactions they start join, and the entry is recorded in it: the changes of a request are committed with
+// its entry or not at all. The response is held back until then: when the transaction fails, the changes are
+// rolled back, the entry is recorded on its own and a success of the handlers is answered with a 500 instead.
+func Audit(log *audit.Log, transactions repository.Transactor, logger *slog.Logger) gin.HandlerFunc { // Create the audit middleware
+	return func(c *gin.Context) {
+		if !auditedMethods[c.Request.Method] || c.FullPath() == "" { // Check if the request changes nothing or matched no route
+			c.Next() // Handle the request
+			return   // Skip the request
+		}
+
+		ctx := c.Request.Context()                                                   // Get the context of the request
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}   // Hold the response back
+		c.Writer = writer                                                            // Write the response through the buffer
+		var entry models.AuditEntry                                                  // Define the entry of the request
+		handled := false                                                             // Define whether the handlers ran
+		err := transactions.WithTransaction(ctx, func(txCtx context.Context) error { // Run the handlers and record the entry in one transaction
+			if handled { // Check if the transaction is retried
+				return errHandled // Fail the request
+			}
+			handled = true                               // Run the handlers once
+			c.Request = c.Request.WithContext(txCtx)     // Let the handlers write in the transaction
+			c.Next()                                     // Handle the request
+			entry = newEntry(c, writer.Status(), logger) // Describe the request
+			return log.Record(txCtx, entry)              // Record the entry with the changes
+		})
+		c.Request = c.Request.WithContext(ctx) // Leave the transaction
+		c.Writer = writer.ResponseWriter       // Write to the client again
+
+		if err != nil { // Check if the changes were rolled back
+			if !handled || entry.Status < http.StatusBadRequest { // Check if the handlers did not run or reported a change that was rolled back
+				discardResponse(c)                                                                               // Forget the response of the handlers
+				WriteProblem(c, logger, fmt.Errorf("failed to commit the changes of %s: %w", c.FullPath(), err)) // Fail the request
+				entry = newEntry(c, c.Writer.Status(), logger)                                                   // Describe the failed request
+			}
+			if err := log.Record(context.WithoutCancel(ctx), entry); err != nil { // Record the entry on its own, even if the client went away
+				logger.ErrorContext(ctx, "Failed to record the audit entry of a rolled back request", slog.String("action", entry.Action), slog.Any("error", err)) // Log the error message
+				if c.Writer.Written() {                                                                                                                            // Check if the failure was already answered
+					return // Return from the function
+				}
+				discardResponse(c)                                                                                   // Forget the response of the handlers
+				WriteProblem(c, logger, fmt.Errorf("failed to record the audit entry of %s: %w", entry.Action, err)) // Fail the request
+				return                                                                                               // Return from the function
+			}
+			if c.Writer.Written() { // Check if the failure was answered
+				return // Return from the function
+			}
+		}
+		writer.flush() // Send the response
+	}
+}
+
+// newEntry describes a request answered with the status, with the change the handlers described
+func newEntry(c *gin.Context, status int, logger *slog.Logger) models.AuditEntry { // Describe a request
+	route := c.FullPath() // Get the route pattern of the request
+	entry := models.AuditEntry{
+		Action:     c.Request.Method + " " + route,                            // Set the method and route
+		TargetType: strings.SplitN(strings.TrimPrefix(route, "/"), "/", 2)[0], // Default to the first segment of the route
+		Status:     status,                                                    // Set the response status
+		IP:         c.ClientIP(),                                              // Set the address of the client
+		RequestID:  logging.RequestID(c.Request.Context()),                    // Link the entry to the logs
+		OccurredAt: time.Now(),                                                // Set the occurred_at timestamp
+	}
+	if len(c.Params) > 0 { // Check if the route names a document
+		entry.TargetID = c.Params[0].Value // Default to the first parameter
+	}
+	if actorID, err := primitive.ObjectIDFromHex(c.GetString("userID")); err == nil { // Check if the caller was identified
+		entry.ActorID = &actorID // Set the actor
+	}
+
+	if value, ok := c.Get(audit.ContextKey); ok { // Check if the handler described its change
+		change := value.(audit.Change)       // Get the change
+		entry.TargetType = change.TargetType // Set the kind of the document
+		entry.TargetID = change.TargetID     // Set the document
+		changes, err := change.Diff()        // Compare the states, without the personal values
+		if err != nil {                      // Check if there is an error
+			logger.ErrorContext(c.Request.Context(), "Failed to compare the audited states", slog.Any("error", err)) // Log the error message
+		}
+		entry.Changes = changes // Set the changed fields
+	}
+	return entry // Return the entry
+}
+
+// discardResponse forgets the headers of the held back response before a problem replaces it
+func discardResponse(c *gin.Context) { // Forget the response
+	for _, header := range []string{"ETag", "Location", "Content-Length", "Content-Disposition"} { // Iterate over the headers of the held back response
+		c.Writer.Header().Del(header) // Forget the header
+	}
+}
+
+// bufferedWriter holds the status and body written by the handlers back until flush sends them
+type bufferedWriter struct {
+	gin.ResponseWriter              // Writer of the response
+	status             int          // Status set by the handlers
+	written            bool         // Whether the handlers wrote the status
+	body               bytes.Buffer // Body written by the handlers
+}
+
+// WriteHeader keeps the status
+func (w *bufferedWriter) WriteHeader(code int) { // Set the status
+	if code > 0 && !w.written { // Check if the status can still change
+		w.status = code // Keep the status
+	}
+}
+
+// WriteHeaderNow marks the status as written
+func (w *bufferedWriter) WriteHeaderNow() { // Write the status
+	w.written = true // Mark the status as written
+}
+
+// Write keeps the data
+func (w *bufferedWriter) Write(data []byte) (int, error) { // Write the body
+	w.written = true          // Mark the status as written
+	return w.body.Write(data) // Keep the data
+}
+
+// WriteString keeps the string
+func (w *bufferedWriter) WriteString(s string) (int, error) { // Write the body
+	w.written = true             // Mark the status as written
+	return w.body.WriteString(s) // Keep the string
+}
+
+// Status returns the status set by the handlers
+func (w *bufferedWriter) Status() int { // Get the status
+	return w.status // Return the status
+}
+
+// Size returns the size of the body written by the handlers, -1 before anything is written
+func (w *bufferedWriter) Size() int { // Get the size of the body
+	if !w.written { // Check if nothing was written
+		return -1 // Return no size
+	}
+	return w.body.Len() // Return the size
+}
+
+// Written reports whether the handlers wrote the status
+func (w *bufferedWriter) Written() bool { // Check the status
+	return w.written // Return whether the status was written
+}
+
+// Flush is a no-op, the response is sent by flush once the request is audited
+func (w *bufferedWriter) Flush() {} // Keep the response
+
+// flush sends the held back status and body
+func (w *bufferedWriter) flush() { // Send the response
+	w.ResponseWriter.WriteHeader(w.status) // Set the status
+	if !w.written {                        // Check if the handlers wrote nothing
+		return // Let gin write the status
+	}
+	w.ResponseWriter.WriteHeaderNow()             // Write the status
+	_, _ = w.ResponseWriter.Write(w.body.Bytes()) // Send the body
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"training_session/pkg/apperr"
+	"training_session/pkg/audit"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+	"training_session/pkg/repository/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingOutbox refuses the audit entries while failing is set
+type failingOutbox struct {
+	repository.OutboxRepository      // Outbox the other events are written to
+	failing                     bool // Whether the audit entries are refused
+}
+
+// Insert refuses the audit entries while failing is set
+func (o *failingOutbox) Insert(ctx context.Context, event *models.Event) error {
+	if o.failing && event.Type == models.EventAuditRecorded {
+		return errors.New("outbox unavailable")
+	}
+	return o.OutboxRepository.Insert(ctx, event)
+}
+
+func TestAuditCommitsChangesWithTheirEntry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name         string
+		handler      func(repos *repository.Repositories) gin.HandlerFunc
+		failOutbox   bool
+		status       int
+		stored       bool // Whether the change of the handler is committed
+		recorded     bool // Whether the entry is in the outbox
+		recordStatus int  // Status of the recorded entry
+	}{
+		{
+			name:    "committed change",
+			handler: createSession(nil),
+			status:  http.StatusCreated, stored: true, recorded: true, recordStatus: http.StatusCreated,
+		},
+		{
+			name:       "entry not recorded",
+			handler:    createSession(nil),
+			failOutbox: true,
+			status:     http.StatusInternalServerError,
+		},
+		{
+			name:    "rejected in a failed transaction",
+			handler: createSession(apperr.Conflict("session_conflict", "Conflict")),
+			status:  http.StatusConflict, recorded: true, recordStatus: http.StatusConflict,
+		},
+		{
+			name: "success reported for a failed transaction",
+			handler: func(repos *repository.Repositories) gin.HandlerFunc {
+				inner := createSession(errors.New("write failed"))(repos)
+				return func(c *gin.Context) {
+					inner(c)
+					c.Errors = nil                      // Swallow the failure
+					c.Writer.WriteHeader(http.StatusOK) // Report a success
+					c.Writer.WriteHeaderNow()
+				}
+			},
+			status: http.StatusInternalServerError, recorded: true, recordStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := memory.NewRepositories()
+			outbox := &failingOutbox{OutboxRepository: repos.Outbox, failing: tt.failOutbox}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			router := gin.New()
+			router.Use(Audit(audit.NewLog(repos.Audit, outbox), repos.Transactions, logger), Problems(logger))
+			router.POST("/sessions", tt.handler(repos))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sessions", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+
+			sessions, _ := repos.Sessions.FindAll(context.Background())
+			if stored := len(sessions) == 1; stored != tt.stored {
+				t.Errorf("session stored: %v, want %v", stored, tt.stored)
+			}
+			events, _ := repos.Outbox.FindPending(context.Background(), 10)
+			var entries []models.AuditEntry
+			for _, event := range events {
+				if event.Type == models.EventAuditRecorded {
+					var entry models.AuditEntry
+					if err := json.Unmarshal(event.Data, &entry); err != nil {
+						t.Fatalf("decode entry: %v", err)
+					}
+					entries = append(entries, entry)
+				}
+			}
+			if recorded := len(entries) == 1; recorded != tt.recorded {
+				t.Fatalf("entries recorded: %d, want one: %v", len(entries), tt.recorded)
+			}
+			if tt.recorded && (entries[0].Status != tt.recordStatus || entries[0].Action != "POST /sessions") {
+				t.Errorf("recorded %+v, want status %d", entries[0], tt.recordStatus)
+			}
+		})
+	}
+}
+
+// createSession returns a handler inserting a session in a transaction that fails with err, when not nil
+func createSession(err error) func(repos *repository.Repositories) gin.HandlerFunc {
+	return func(repos *repository.Repositories) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			txErr := repos.Transactions.WithTransaction(c.Request.Context(), func(ctx context.Context) error {
+				if err := repos.Sessions.Insert(ctx, &models.Session{Title: "Drills"}); err != nil {
+					return err
+				}
+				return err
+			})
+			if txErr != nil {
+				c.Error(txErr)
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"created": true})
+		}
+	}
+}
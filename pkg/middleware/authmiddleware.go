@@ -1,45 +1,72 @@
 package middleware
 
 import (
-	"log"
-	"net/http"
+	"errors"
+	"log/slog"
 	"strings"
-
-	"training_session/config"
+	"training_session/pkg/apperr"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
-var cfg *config.Config // Config variable to store the configuration
-
-func init() { // Initialize the configuration
-	cfg = config.LoadConfig() // Load the configuration
-}
-
-func AuthMiddleware() gin.HandlerFunc { // AuthMiddleware function to authenticate requests
+// AuthMiddleware authenticates requests with JWT tokens signed with the given secret key
+func AuthMiddleware(jwtSecretKey string) gin.HandlerFunc { // AuthMiddleware function to authenticate requests
 	return func(c *gin.Context) { // Return a Gin handler function
 		tokenString := c.GetHeader("Authorization") // Get the Authorization header from the request
 		if tokenString == "" { // Check if the token is missing
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing token"}) // Return an error if the token is missing
-			c.Abort() // Abort the request
+			WriteProblem(c, slog.Default(), apperr.Unauthorized("missing_token", "Missing token")) // Return an error if the token is missing
 			return // Return from the function
 		}
 
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ") // Remove the "Bearer " prefix from the token
-		log.Printf("Token after prefix removal: %s", tokenString) // Log the token after removing the prefix
-
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) { // Parse the JWT token
-			return []byte(cfg.JwtSecretKey), nil // Return the JWT secret key
-		})
 
-		if err != nil || !token.Valid { // Check if there is an error or the token is invalid
-			log.Printf("Error parsing token: %v", err) // Log the error message
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"}) // Return an error response
-			c.Abort() // Abort the request
+		userID, err := userIDFromToken(tokenString, jwtSecretKey) // Parse the JWT token
+		if err != nil { // Check if there is an error or the token is invalid
+			slog.WarnContext(c.Request.Context(), "Invalid auth token", slog.Any("error", err)) // Log the error message
+			WriteProblem(c, slog.Default(), apperr.Unauthorized("invalid_token", "Invalid token")) // Return an error response
 			return   // Return from the function
 		}
 
+		if userID != "" { // Check if the token carries a user ID
+			c.Set("userID", userID) // Store the user ID for the next handlers
+		}
+
 		c.Next() // Call the next handler
 	}
 }
+
+// OptionalAuth identifies the caller of public routes: the user ID of a valid JWT is stored for the
+// next handlers like AuthMiddleware does, while requests without a valid token go on anonymously
+func OptionalAuth(jwtSecretKey string) gin.HandlerFunc { // OptionalAuth function to identify the caller
+	return func(c *gin.Context) { // Return a Gin handler function
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ") // Get the token from the Authorization header
+		if tokenString != "" { // Check if a token was sent
+			if userID, err := userIDFromToken(tokenString, jwtSecretKey); err == nil && userID != "" { // Parse the JWT token
+				c.Set("userID", userID) // Store the user ID for the next handlers
+			}
+		}
+
+		c.Next() // Call the next handler
+	}
+}
+
+// userIDFromToken parses a JWT signed with the secret key and returns the user ID of its claims
+func userIDFromToken(tokenString, jwtSecretKey string) (string, error) { // Parse a JWT token
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) { // Parse the JWT token
+		return []byte(jwtSecretKey), nil // Return the JWT secret key
+	})
+	if err != nil { // Check if there is an error
+		return "", err // Return the error
+	}
+	if !token.Valid { // Check if the token is invalid
+		return "", errors.New("invalid token") // Return an error
+	}
+
+	if claims, ok := token.Claims.(jwt.MapClaims); ok { // Get the claims from the token
+		if userID, ok := claims["id"].(string); ok { // Get the user ID from the claims
+			return userID, nil // Return the user ID
+		}
+	}
+	return "", nil // The token carries no user ID
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"training_session/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exposedHeaders are the response headers of the API that cross-origin scripts may read
+const exposedHeaders = "ETag, " + IdempotentReplayedHeader
+
+// CORS answers preflight requests and sets the CORS headers for the allowed origins
+func CORS(cfg config.CORSConfig) gin.HandlerFunc { // CORS function to allow cross-origin requests
+	anyOrigin := false                          // Whether any origin is allowed
+	origins := map[string]bool{}                // Index of the allowed origins
+	for _, origin := range cfg.AllowedOrigins { // Iterate over the allowed origins
+		if origin == "*" { // Check if any origin is allowed
+			anyOrigin = true // Allow any origin
+		}
+		origins[origin] = true // Add the origin to the index
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ") // Format the allowed methods
+	headers := strings.Join(cfg.AllowedHeaders, ", ") // Format the allowed headers
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds())) // Format the preflight cache duration
+
+	return func(c *gin.Context) { // Return a Gin handler function
+		origin := c.GetHeader("Origin")                      // Get the origin of the request
+		if origin == "" || !(anyOrigin || origins[origin]) { // Check if the request is not an allowed cross-origin request
+			c.Next() // Call the next handler
+			return   // Return from the function
+		}
+
+		header := c.Writer.Header()                                 // Get the response headers
+		header.Add("Vary", "Origin")                                // The response depends on the origin
+		header.Set("Access-Control-Allow-Origin", origin)           // Allow the origin
+		header.Set("Access-Control-Expose-Headers", exposedHeaders) // Let scripts read the ETag and replay headers
+		if cfg.AllowCredentials {                                   // Check if credentials are allowed
+			header.Set("Access-Control-Allow-Credentials", "true") // Allow credentials
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" { // Check if this is a preflight request
+			header.Set("Access-Control-Allow-Methods", methods) // Set the allowed methods
+			header.Set("Access-Control-Allow-Headers", headers) // Set the allowed headers
+			header.Set("Access-Control-Max-Age", maxAge)        // Set the preflight cache duration
+			c.AbortWithStatus(http.StatusNoContent)             // Answer the preflight request
+			return                                              // Return from the function
+		}
+
+		c.Next() // Call the next handler
+	}
+}
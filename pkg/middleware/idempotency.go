@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+	"training_session/config"
+	"training_session/pkg/apperr"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"     // Header carrying the idempotency key of a request
+	IdempotentReplayedHeader  = "Idempotent-Replayed" // Header set on the responses replayed from a previous request
+	maxIdempotencyKeyLength   = 255                   // Longest idempotency key accepted
+	idempotencyCredentialsSep = "\x00"                // Separator of the credentials and the key in the scoped key
+)
+
+// idempotentMethods are the methods whose requests may carry an idempotency key
+var idempotentMethods = map[string]bool{http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true}
+
+// replayedHeaders are the response headers stored and replayed with the body, the clients of a create or an
+// update rely on them. Set-Cookie is never stored.
+var replayedHeaders = []string{"ETag", "Location"}
+
+// Idempotency makes the mutating requests sent with an Idempotency-Key safe to retry: the response of the
+// first request is stored and replayed to the retries with the same key, method, path and body for cfg.TTL.
+// A retry sent while the first request is still running is answered with 409, and a key reused for another
+// request with 422. Server errors and rate limited requests are not stored so they can be retried. The
+// requests to skipPaths, which issue credentials, ignore the key: their responses are never stored.
+func Idempotency(keys repository.IdempotencyRepository, cfg config.IdempotencyConfig, logger *slog.Logger, skipPaths []string) gin.HandlerFunc { // Create the idempotency middleware
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)                                                                 // Get the idempotency key
+		if key == "" || !idempotentMethods[c.Request.Method] || slices.Contains(skipPaths, c.Request.URL.Path) { // Check if the request is not idempotent
+			c.Next() // Handle the request
+			return   // Return from the function
+		}
+		if !validIdempotencyKey(key) { // Check if the key is malformed
+			WriteProblem(c, logger, apperr.Validation("invalid_idempotency_key", "Invalid idempotency key", apperr.Field(IdempotencyKeyHeader, fmt.Sprintf("must be 1 to %d printable ASCII characters", maxIdempotencyKeyLength)))) // Return a bad request response
+			return                                                                                                                                                                                                                   // Return from the function
+		}
+
+		body, err := io.ReadAll(c.Request.Body) // Read the body to fingerprint the request
+		if err != nil {                         // Check if there is an error
+			WriteProblem(c, logger, apperr.Validation("invalid_body", "The request body could not be read").Wrap(err)) // Return a bad request response
+			return                                                                                                     // Return from the function
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body)) // Give the body back to the handler
+
+		now := time.Now()                   // Get the current time
+		record := models.IdempotencyRecord{ // Define the record of the key
+			ID:          scopedIdempotencyKey(c, key), // Scope the key to the credentials of the client
+			Fingerprint: fingerprint(c, body),         // Identify the request
+			Status:      models.IdempotencyStatusPending,
+			LockedAt:    now,              // Hold the key from now on
+			CreatedAt:   now,              // Set the created_at timestamp
+			ExpiresAt:   now.Add(cfg.TTL), // Forget the key after the configured window
+		}
+
+		current, err := keys.Reserve(c.Request.Context(), record, now.Add(-cfg.LockTimeout)) // Reserve the key
+		switch {                                                                             // Check if the request was already sent
+		case errors.Is(err, repository.ErrConflict): // The key is used
+			replay(c, logger, record, current) // Answer from the first request
+			return                             // Return from the function
+		case err != nil: // The key could not be reserved
+			WriteProblem(c, logger, fmt.Errorf("failed to reserve idempotency key: %w", err)) // Return an error response
+			return                                                                            // Return from the function
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer} // Record the response of the handler
+		c.Writer = recorder                                     // Write the response through the recorder
+		c.Next()                                                // Handle the request
+
+		ctx := context.WithoutCancel(c.Request.Context())                                     // Store the outcome even if the client went away
+		status := recorder.Status()                                                           // Get the status of the response
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests { // Check if the request may succeed when retried
+			if err := keys.Release(ctx, record.ID); err != nil { // Free the key
+				logger.ErrorContext(ctx, "Failed to release idempotency key", slog.Any("error", err)) // Log the error message
+			}
+			return // Return from the function
+		}
+
+		response := models.IdempotentResponse{StatusCode: status, ContentType: recorder.Header().Get("Content-Type"), Body: recorder.body.Bytes()} // Define the stored response
+		for _, name := range replayedHeaders {                                                                                                     // Iterate over the replayed headers
+			if value := recorder.Header().Get(name); value != "" { // Check if the handler set the header
+				if response.Headers == nil { // Check if no header was stored yet
+					response.Headers = map[string]string{} // Define the stored headers
+				}
+				response.Headers[name] = value // Store the header
+			}
+		}
+		if err := keys.Complete(ctx, record.ID, response); err != nil { // Store the response
+			logger.ErrorContext(ctx, "Failed to store idempotent response", slog.Any("error", err)) // Log the error message
+		}
+	}
+}
+
+// replay answers a request whose key is already used: with the stored response when the key was used
+// for the same request and that request completed, or with a problem response otherwise
+func replay(c *gin.Context, logger *slog.Logger, record, current models.IdempotencyRecord) { // Answer a retried request
+	switch { // Check the state of the key
+	case current.Fingerprint != "" && current.Fingerprint != record.Fingerprint: // Key reused for another request
+		WriteProblem(c, logger, apperr.Unprocessable("idempotency_key_reused", "The idempotency key was already used for another request")) // Return an unprocessable response
+	case current.Status != models.IdempotencyStatusCompleted || current.Response == nil: // First request still running
+		WriteProblem(c, logger, apperr.Conflict("request_in_progress", "A request with this idempotency key is still being processed")) // Return a conflict response
+	default: // First request completed
+		c.Header(IdempotentReplayedHeader, "true")          // Tell the client the response is replayed
+		for name, value := range current.Response.Headers { // Iterate over the stored headers
+			c.Header(name, value) // Replay the header
+		}
+		c.Data(current.Response.StatusCode, current.Response.ContentType, current.Response.Body) // Replay the response
+		c.Abort()                                                                                // Skip the handler
+	}
+}
+
+// validIdempotencyKey checks that the key is short and only made of printable ASCII characters
+func validIdempotencyKey(key string) bool { // Check an idempotency key
+	if len(key) > maxIdempotencyKeyLength { // Check if the key is too long
+		return false // The key is invalid
+	}
+	for i := 0; i < len(key); i++ { // Iterate over the bytes of the key
+		if key[i] < 0x20 || key[i] > 0x7e { // Check if the byte is not printable ASCII
+			return false // The key is invalid
+		}
+	}
+	return true // The key is valid
+}
+
+// scopedIdempotencyKey hashes the key with the credentials of the client, so clients cannot see each
+// other's responses by guessing their keys
+func scopedIdempotencyKey(c *gin.Context, key string) string { // Scope a key to the client
+	credentials := c.GetHeader("Authorization") // Get the bearer token
+	if credentials == "" {                      // Check if the client authenticates with the cookie
+		credentials, _ = c.Cookie("auth_token") // Get the auth cookie
+	}
+	sum := sha256.Sum256([]byte(credentials + idempotencyCredentialsSep + key)) // Hash the credentials and the key
+	return hex.EncodeToString(sum[:])                                           // Return the scoped key
+}
+
+// fingerprint hashes the method, path and body of the request
+func fingerprint(c *gin.Context, body []byte) string { // Fingerprint a request
+	hash := sha256.New()                                                           // Define the hash
+	hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n")) // Hash the method and path
+	hash.Write(body)                                                               // Hash the body
+	return hex.EncodeToString(hash.Sum(nil))                                       // Return the fingerprint
+}
+
+// responseRecorder copies the body written by the handlers so it can be stored
+type responseRecorder struct {
+	gin.ResponseWriter              // Writer of the response
+	body               bytes.Buffer // Copy of the body
+}
+
+// Write writes the data to the response and to the copy
+func (w *responseRecorder) Write(data []byte) (int, error) { // Write the body
+	w.body.Write(data)                  // Copy the data
+	return w.ResponseWriter.Write(data) // Write the data
+}
+
+// WriteString writes the string to the response and to the copy
+func (w *responseRecorder) WriteString(s string) (int, error) { // Write the body
+	w.body.WriteString(s)                  // Copy the string
+	return w.ResponseWriter.WriteString(s) // Write the string
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"training_session/pkg/apperr"
+	"training_session/pkg/logging"
+	"training_session/pkg/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of the error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of every error response
+type Problem struct {
+	Type      string              `json:"type"`                 // URI identifying the kind of problem
+	Title     string              `json:"title"`                // Short summary of the HTTP status
+	Status    int                 `json:"status"`               // HTTP status
+	Detail    string              `json:"detail,omitempty"`     // Human-readable explanation of this occurrence
+	Instance  string              `json:"instance,omitempty"`   // Path of the request that failed
+	Code      string              `json:"code"`                 // Stable machine-readable code
+	Errors    []apperr.FieldError `json:"errors,omitempty"`     // Invalid fields of a validation error
+	RequestID string              `json:"request_id,omitempty"` // ID of the request, to find its logs
+}
+
+// statuses maps the kinds of domain errors to their HTTP status
+var statuses = map[apperr.Kind]int{
+	apperr.KindInternal:             http.StatusInternalServerError,  // Unexpected failure
+	apperr.KindValidation:           http.StatusBadRequest,           // Invalid request
+	apperr.KindUnauthorized:         http.StatusUnauthorized,         // Not authenticated
+	apperr.KindForbidden:            http.StatusForbidden,            // Not allowed
+	apperr.KindNotFound:             http.StatusNotFound,             // Missing resource
+	apperr.KindConflict:             http.StatusConflict,             // Conflicting state
+	apperr.KindUnprocessable:        http.StatusUnprocessableEntity,  // Request that cannot be processed
+	apperr.KindPreconditionFailed:   http.StatusPreconditionFailed,   // Stale precondition
+	apperr.KindPreconditionRequired: http.StatusPreconditionRequired, // Missing precondition
+	apperr.KindUnsupportedMediaType: http.StatusUnsupportedMediaType, // Unsupported body format
+	apperr.KindRateLimited:          http.StatusTooManyRequests,      // Too many requests
+}
+
+// Problems renders the last error recorded by the handlers with c.Error as a problem response,
+// unless the handler already wrote a response
+func Problems(logger *slog.Logger) gin.HandlerFunc { // Create the error rendering middleware
+	return func(c *gin.Context) {
+		c.Next() // Handle the request
+
+		if len(c.Errors) == 0 || c.Writer.Written() { // Check if there is an error left to render
+			return // Nothing to render
+		}
+		WriteProblem(c, logger, c.Errors.Last().Err) // Render the error
+	}
+}
+
+// WriteProblem maps the error to its domain error, writes it as a problem response and aborts the
+// request. Errors that are not domain errors are reported as internal errors: they are logged and
+// their message is never sent to the client.
+func WriteProblem(c *gin.Context, logger *slog.Logger, err error) { // Render an error
+	domainErr := toDomainError(err)    // Classify the error
+	status := statuses[domainErr.Kind] // Get the HTTP status
+
+	if domainErr.Kind == apperr.KindInternal { // Check if the failure was unexpected
+		logger.ErrorContext(c.Request.Context(), "Request failed", slog.String("code", domainErr.Code), slog.Any("error", err)) // Log the cause
+	}
+
+	c.Header("Content-Type", ProblemContentType) // Set the problem media type
+	c.AbortWithStatusJSON(status, Problem{
+		Type:      "urn:training-session:problem:" + domainErr.Code, // Identify the problem by its code
+		Title:     http.StatusText(status),                          // Summarize the status
+		Status:    status,                                           // Set the status
+		Detail:    domainErr.Message,                                // Explain the problem
+		Instance:  c.Request.URL.Path,                               // Point to the failed request
+		Code:      domainErr.Code,                                   // Set the code
+		Errors:    domainErr.Fields,                                 // List the invalid fields
+		RequestID: logging.RequestID(c.Request.Context()),           // Link the response to the logs
+	}) // Write the problem
+}
+
+// RouteNotFound answers the requests matching no route with a problem response
+func RouteNotFound(logger *slog.Logger) gin.HandlerFunc { // Create the not found handler
+	return func(c *gin.Context) {
+		WriteProblem(c, logger, apperr.NotFound("route_not_found", "No route matches "+c.Request.Method+" "+c.Request.URL.Path)) // Render the error
+	}
+}
+
+// toDomainError returns the domain error of err, mapping the repository errors and hiding anything else
+func toDomainError(err error) *apperr.Error { // Classify an error
+	if domainErr := apperr.As(err); domainErr != nil { // Check if the error is already a domain error
+		return domainErr // Return the domain error
+	}
+	switch { // Map the repository errors
+	case errors.Is(err, repository.ErrNotFound): // Missing document
+		return apperr.NotFound("not_found", "Resource not found").Wrap(err) // Return a not found error
+	case errors.Is(err, repository.ErrConflict): // Conflicting write
+		return apperr.Conflict("conflict", "The request conflicts with the current state of the resource").Wrap(err) // Return a conflict error
+	case errors.Is(err, repository.ErrVersionMismatch): // Stale conditional write
+		return apperr.PreconditionFailed("version_mismatch", "The resource was modified since it was read").Wrap(err) // Return a precondition failed error
+	}
+	return apperr.Internal(err) // Hide the unexpected error
+}
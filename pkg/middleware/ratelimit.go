@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"training_session/config"
+	"training_session/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdleBuckets is the number of clients tracked before full buckets are swept
+const maxIdleBuckets = 10000
+
+// bucket holds the tokens left to a client
+type bucket struct {
+	tokens float64   // Requests the client may still send
+	last   time.Time // Last time the tokens were refilled
+}
+
+// RateLimit limits the requests of each client IP with a token bucket
+func RateLimit(cfg config.RateLimitConfig) gin.HandlerFunc { // RateLimit function to limit the request rate
+	var mu sync.Mutex                                                             // Mutex guarding the buckets
+	buckets := map[string]*bucket{}                                               // Buckets of the clients
+	burst := float64(cfg.Burst)                                                   // Size of the buckets
+	refill := time.Duration(burst / cfg.RequestsPerSecond * float64(time.Second)) // Time after which an idle bucket is full again
+
+	return func(c *gin.Context) { // Return a Gin handler function
+		now := time.Now()      // Get the current time
+		client := c.ClientIP() // Identify the client
+
+		mu.Lock()                           // Lock the buckets
+		if len(buckets) >= maxIdleBuckets { // Check if too many clients are tracked
+			for key, b := range buckets { // Iterate over the buckets
+				if now.Sub(b.last) >= refill { // Check if the bucket is full again
+					delete(buckets, key) // Forget the client, a new bucket starts full
+				}
+			}
+		}
+		b, ok := buckets[client] // Find the bucket of the client
+		if !ok {                 // Check if the client is new
+			b = &bucket{tokens: burst, last: now} // Start with a full bucket
+			buckets[client] = b                   // Track the client
+		}
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*cfg.RequestsPerSecond) // Refill the bucket
+		b.last = now                                                                         // Remember the refill
+		allowed := b.tokens >= 1                                                             // Check if a token is left
+		if allowed {                                                                         // Check if the request is allowed
+			b.tokens-- // Take a token
+		}
+		wait := (1 - b.tokens) / cfg.RequestsPerSecond // Seconds until the next token
+		mu.Unlock()                                    // Unlock the buckets
+
+		if !allowed { // Check if the request is rejected
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait))))                              // Tell the client when to retry
+			WriteProblem(c, slog.Default(), apperr.RateLimited("rate_limited", "Too many requests")) // Return a too many requests response
+			return                                                                                   // Return from the function
+		}
+
+		c.Next() // Call the next handler
+	}
+}
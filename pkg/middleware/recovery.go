@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"training_session/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery answers 500 to the requests whose handler panicked and logs the panic with its stack.
+// Unlike gin.Recovery it does not dump the request headers, which carry cookies and tokens.
+func Recovery(logger *slog.Logger) gin.HandlerFunc { // Create the recovery middleware
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) { // Recover from panics
+		logger.ErrorContext(c.Request.Context(), "panic recovered",
+			slog.String("error", fmt.Sprint(recovered)), // Value the handler panicked with
+			slog.String("stack", string(debug.Stack())), // Stack of the panic
+		) // Log the panic
+		WriteProblem(c, logger, apperr.Internal(fmt.Errorf("panic: %v", recovered))) // Return an error response without the details
+	})
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"training_session/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header carrying the request ID, in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request IDs accepted from clients, others are replaced so they cannot forge log lines
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID reuses the X-Request-ID of the request or generates one, echoes it in the response and
+// attaches it to the request context so every log line of the request carries it
+func RequestID() gin.HandlerFunc { // Create the request ID middleware
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)   // Get the request ID of the caller
+		if !validRequestID.MatchString(requestID) { // Check if the ID is missing or unsafe
+			requestID = newRequestID() // Generate a request ID
+		}
+
+		c.Set("requestID", requestID)                                                            // Store the request ID for the next handlers
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID)) // Attach the request ID to the logs
+		c.Header(RequestIDHeader, requestID)                                                     // Echo the request ID
+		c.Next()                                                                                 // Call the next handler
+	}
+}
+
+// newRequestID returns a random 128-bit request ID
+func newRequestID() string { // Generate a request ID
+	id := make([]byte, 16)        // Define the random bytes
+	_, _ = rand.Read(id)          // Fill the bytes, crypto/rand never fails
+	return hex.EncodeToString(id) // Return the hexadecimal ID
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry represents a state-changing request recorded in the audit log. Entries are never updated or
+// deleted: each one holds the hash of the previous entry, so altering or removing one breaks the chain.
+type AuditEntry struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`                    // Unique identifier for the entry
+	Sequence   int64                  `bson:"sequence" json:"sequence"`                   // Position of the entry in the chain, from 1
+	ActorID    *primitive.ObjectID    `bson:"actor_id,omitempty" json:"actor_id"`         // User who sent the request, null when anonymous
+	Action     string                 `bson:"action" json:"action"`                       // Method and route of the request (e.g. "POST /sessions/:sessionId/cancel")
+	TargetType string                 `bson:"target_type" json:"target_type"`             // Kind of the document changed (e.g. "sessions", "users")
+	TargetID   string                 `bson:"target_id,omitempty" json:"target_id"`       // Document changed, empty when unknown
+	Changes    map[string]FieldChange `bson:"changes,omitempty" json:"changes,omitempty"` // Fields changed by the request, when the handler described them
+	Status     int                    `bson:"status" json:"status"`                       // Status code of the response
+	IP         string                 `bson:"ip" json:"ip"`                               // Address of the client
+	RequestID  string                 `bson:"request_id" json:"request_id"`               // ID of the request, to find its logs
+	OccurredAt time.Time              `bson:"occurred_at" json:"occurred_at"`             // Timestamp when the request was answered
+	PrevHash   string                 `bson:"prev_hash" json:"prev_hash"`                 // Hash of the previous entry, empty for the first one
+	Hash       string                 `bson:"hash" json:"hash"`                           // Hash of this entry and of the previous hash
+}
+
+// FieldChange represents the values of a field before and after a request, as JSON
+type FieldChange struct {
+	Before   json.RawMessage `bson:"before,omitempty" json:"before,omitempty"`     // Value before the request, missing when the field was added
+	After    json.RawMessage `bson:"after,omitempty" json:"after,omitempty"`       // Value after the request, missing when the field was removed
+	Redacted bool            `bson:"redacted,omitempty" json:"redacted,omitempty"` // Set when the field holds personal data, whose values are not recorded
+}
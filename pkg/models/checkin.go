@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CheckIn represents the attendance of a participant verified with the QR code of a session, one per user and session.
+type CheckIn struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`            // Unique identifier for the check-in
+	SessionID   primitive.ObjectID `bson:"session_id" json:"session_id"`       // Session attended
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`             // Participant who checked in
+	CheckedInAt time.Time          `bson:"checked_in_at" json:"checked_in_at"` // Timestamp when the QR code was validated
+}
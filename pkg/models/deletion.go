@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Deletion records the soft deletion of a document, empty while the document is live. Soft deleted documents
+// are hidden from the queries until an admin restores them, and removed for good after the retention period.
+type Deletion struct {
+	DeletedAt *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`           // Timestamp when the document was deleted
+	DeletedBy *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`           // User who deleted the document, nil when anonymous
+	Reason    string              `bson:"deletion_reason,omitempty" json:"deletion_reason,omitempty"` // Why the document was deleted
+	Final     bool                `bson:"deletion_final,omitempty" json:"deletion_final,omitempty"`   // Set when the changes made with the deletion cannot be undone, the document is then never restored
+}
+
+// IsDeleted reports whether the document was soft deleted
+func (d Deletion) IsDeleted() bool { // Check the deletion
+	return d.DeletedAt != nil // A deleted document has a deletion time
+}
+
+// SoftDeletion returns the deletion itself, so the documents embedding it expose where it is stored
+func (d *Deletion) SoftDeletion() *Deletion { // Get the deletion
+	return d // Return the deletion
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErasureRequest represents the request of a user to have their personal data erased, reviewed by an admin.
+type ErasureRequest struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`                            // Unique identifier for the request
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`                             // User whose personal data is erased
+	Reason      string              `bson:"reason,omitempty" json:"reason,omitempty"`           // Reason given by the user
+	Status      string              `bson:"status" json:"status"`                               // Review status (e.g., "pending", "approved")
+	RequestedAt time.Time           `bson:"requested_at" json:"requested_at"`                   // Timestamp when the request was made
+	ReviewedBy  *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"` // Admin who approved or rejected the request
+	ReviewedAt  *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"` // Timestamp when the request was approved or rejected
+	ReviewNote  string              `bson:"review_note,omitempty" json:"review_note,omitempty"` // Note of the admin, shown to the user
+}
+
+// Statuses of an erasure request
+const (
+	ErasureStatusPending  = "pending"  // Waiting in the approval queue, a user has at most one
+	ErasureStatusApproved = "approved" // Approved, the personal data was erased
+	ErasureStatusRejected = "rejected" // Rejected, the personal data is kept
+)
+
+// ErasedUserName replaces the name of a user whose personal data was erased
+const ErasedUserName = "Erased user"
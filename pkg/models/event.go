@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Types of the domain events
+const (
+	EventSessionCreated      = "SessionCreated"      // A session was scheduled
+	EventSessionRescheduled  = "SessionRescheduled"  // The start or end time of a session changed
+	EventParticipantEnrolled = "ParticipantEnrolled" // A user enrolled in a session
+	EventCheckInRecorded     = "CheckInRecorded"     // A participation was verified with the QR code of a session
+	EventPitchBooked         = "PitchBooked"         // A pitch was booked
+	EventFeedbackSubmitted   = "FeedbackSubmitted"   // Feedback was submitted for a session or a coach
+)
+
+// EventTypes lists the types of the domain events, the values a webhook can subscribe to
+var EventTypes = []string{EventSessionCreated, EventSessionRescheduled, EventParticipantEnrolled, EventCheckInRecorded, EventPitchBooked, EventFeedbackSubmitted}
+
+// EventNotificationRequested is written to the outbox with a change that notifies a user, its data is the
+// notification. It is internal: the relay delivers it to the notifier, never to the broker or the webhooks.
+const EventNotificationRequested = "NotificationRequested"
+
+// EventAuditRecorded is written to the outbox in the transaction of a state-changing request, its data is the
+// audit entry of the request. It is internal: the relay hands it to the audit log, which appends it to the chain.
+const EventAuditRecorded = "AuditRecorded"
+
+// Types of the aggregates the domain events belong to
+const (
+	AggregateSession  = "session"  // Training session
+	AggregatePitch    = "pitch"    // Pitch booking
+	AggregateFeedback = "feedback" // Feedback
+	AggregateErasure  = "erasure"  // Erasure request
+	AggregateAudit    = "audit"    // Audit log, its entries are appended in the order they were recorded
+)
+
+// Event represents a domain event written to the outbox with the change it describes, until it is published.
+type Event struct {
+	ID            primitive.ObjectID `bson:"_id" json:"id"`                        // Unique identifier for the event, consumers deduplicate with it
+	Type          string             `bson:"type" json:"type"`                     // Type of the event (e.g., "SessionCreated")
+	AggregateType string             `bson:"aggregate_type" json:"aggregate_type"` // Type of the aggregate the event belongs to (e.g., "session")
+	AggregateID   primitive.ObjectID `bson:"aggregate_id" json:"aggregate_id"`     // ID of the aggregate, events of an aggregate are published in order
+	Data          json.RawMessage    `bson:"data" json:"data"`                     // JSON payload of the event
+	OccurredAt    time.Time          `bson:"occurred_at" json:"occurred_at"`       // Timestamp when the change happened
+	PublishedAt   *time.Time         `bson:"published_at" json:"-"`                // Timestamp when the broker accepted the event, nil while pending
+	Attempts      int                `bson:"attempts" json:"-"`                    // Number of failed publications
+	LastError     string             `bson:"last_error,omitempty" json:"-"`        // Error of the last failed publication
+}
@@ -8,12 +8,50 @@ import (
 
 // Feedback model
 type Feedback struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"` // Unique identifier for the feedback
-	SessionID primitive.ObjectID `bson:"session_id" json:"session_id"` // Session for which the feedback was provided
-	CoachID   primitive.ObjectID `bson:"coach_id" json:"coach_id"` 		// Coach who received the feedback
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"` // User who provided the feedback
-	Content   string             `bson:"content" json:"content"` // Feedback content
-	Rating    int                `bson:"rating" json:"rating"` // Example: Rating between 1 to 5
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"` // Timestamp when the feedback was created
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"` // Timestamp when the feedback was last updated
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`          // Unique identifier for the feedback
+	SessionID primitive.ObjectID `bson:"session_id" json:"session_id"`               // Session for which the feedback was provided
+	CoachID   primitive.ObjectID `bson:"coach_id" json:"coach_id"`                   // Coach who received the feedback
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`                     // User who provided the feedback
+	Content   string             `bson:"content" json:"content"`                     // Feedback content
+	Rating    int                `bson:"rating" json:"rating"`                       // Example: Rating between 1 to 5
+	FormID    primitive.ObjectID `bson:"form_id,omitempty" json:"form_id,omitempty"` // Feedback form the answers were submitted against
+	Answers   []FeedbackAnswer   `bson:"answers,omitempty" json:"answers,omitempty"` // Answers to the criteria of the form
+	Status    string             `bson:"status" json:"status"`                       // Moderation status (e.g., "pending", "approved", "hidden")
+	Anonymous bool               `bson:"anonymous" json:"anonymous"`                 // Whether the author is hidden in public views
+	Flags     []FeedbackFlag     `bson:"flags,omitempty" json:"flags,omitempty"`     // Reports raised by users against the feedback
+	Reply     *CoachReply        `bson:"reply,omitempty" json:"reply,omitempty"`     // Public reply of the coach
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`               // Timestamp when the feedback was created
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`               // Timestamp when the feedback was last updated
+	Version   int64              `bson:"version" json:"version"`                     // Incremented on every write, served as the ETag
+}
+
+// Moderation statuses of a feedback
+const (
+	FeedbackStatusPending  = "pending"  // Waiting in the moderation queue
+	FeedbackStatusApproved = "approved" // Publicly visible
+	FeedbackStatusHidden   = "hidden"   // Hidden by a moderator
+)
+
+// FeedbackFlag represents a report raised by a user against a feedback
+type FeedbackFlag struct {
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`       // User who flagged the feedback
+	Reason    string             `bson:"reason" json:"reason"`         // Reason given for the flag
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"` // Timestamp when the feedback was flagged
+}
+
+// CoachReply represents the single public reply a coach can post on a feedback
+type CoachReply struct {
+	CoachID   primitive.ObjectID `bson:"coach_id" json:"coach_id"`     // Coach who replied
+	Content   string             `bson:"content" json:"content"`       // Reply content
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"` // Timestamp when the reply was posted
+}
+
+// ModerationAction represents an audited moderation action on a feedback
+type ModerationAction struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`                  // Unique identifier for the action
+	FeedbackID primitive.ObjectID `bson:"feedback_id" json:"feedback_id"`           // Feedback the action applies to
+	ActorID    primitive.ObjectID `bson:"actor_id,omitempty" json:"actor_id"`       // User who performed the action (empty for the automatic filter)
+	Action     string             `bson:"action" json:"action"`                     // Action performed (e.g., "filtered", "flagged", "approved", "hidden", "replied")
+	Reason     string             `bson:"reason,omitempty" json:"reason,omitempty"` // Reason given for the action
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`             // Timestamp when the action was performed
 }
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Question types supported by a feedback form criterion
+const (
+	QuestionTypeScale  = "scale"  // Numeric rating between Min and Max
+	QuestionTypeChoice = "choice" // One of the predefined Options
+	QuestionTypeText   = "text"   // Free text answer
+)
+
+// FeedbackForm represents a feedback form template defined by a business owner for a training type.
+type FeedbackForm struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`            // Unique identifier for the form
+	OwnerID      primitive.ObjectID  `bson:"owner_id" json:"owner_id"`           // Business owner who defined the form
+	TrainingType string              `bson:"training_type" json:"training_type"` // Training type the form applies to
+	Title        string              `bson:"title" json:"title"`                 // Title of the form
+	Criteria     []FeedbackCriterion `bson:"criteria" json:"criteria"`           // Criteria evaluated by the form
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`       // Timestamp when the form was created
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`       // Timestamp when the form was last updated
+	Version      int64               `bson:"version" json:"version"`             // Incremented on every write, served as the ETag
+}
+
+// FeedbackCriterion represents a single question of a feedback form (e.g. coaching quality, facilities).
+type FeedbackCriterion struct {
+	Key      string   `bson:"key" json:"key"`                             // Machine-readable key of the criterion (e.g. "punctuality")
+	Label    string   `bson:"label" json:"label"`                         // Question shown to the user
+	Type     string   `bson:"type" json:"type"`                           // Question type ("scale", "choice", "text")
+	Required bool     `bson:"required" json:"required"`                   // Whether an answer is mandatory
+	Options  []string `bson:"options,omitempty" json:"options,omitempty"` // Allowed answers for choice questions
+	Min      int      `bson:"min,omitempty" json:"min,omitempty"`         // Lowest score for scale questions
+	Max      int      `bson:"max,omitempty" json:"max,omitempty"`         // Highest score for scale questions
+}
+
+// FeedbackAnswer represents the answer given to one criterion of a feedback form.
+type FeedbackAnswer struct {
+	Criterion string `bson:"criterion" json:"criterion"`               // Key of the answered criterion
+	Score     *int   `bson:"score,omitempty" json:"score,omitempty"`   // Score for scale questions
+	Choice    string `bson:"choice,omitempty" json:"choice,omitempty"` // Selected option for choice questions
+	Text      string `bson:"text,omitempty" json:"text,omitempty"`     // Answer for free text questions
+}
+
+// CriterionReport represents the aggregated answers of a single criterion.
+type CriterionReport struct {
+	Criterion    string         `json:"criterion"`              // Key of the criterion
+	Label        string         `json:"label"`                  // Question shown to the user
+	Type         string         `json:"type"`                   // Question type
+	Responses    int            `json:"responses"`              // Number of answers received
+	Average      float64        `json:"average,omitempty"`      // Average score for scale questions
+	Min          int            `json:"min,omitempty"`          // Lowest score received for scale questions
+	Max          int            `json:"max,omitempty"`          // Highest score received for scale questions
+	Distribution map[string]int `json:"distribution,omitempty"` // Count per score or option
+}
+
+// FeedbackReport represents the rating breakdown of a set of feedback.
+type FeedbackReport struct {
+	Feedbacks     int               `json:"feedbacks"`      // Number of feedback included in the report
+	AverageRating float64           `json:"average_rating"` // Average overall rating
+	Criteria      []CriterionReport `json:"criteria"`       // Breakdown by criterion
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// Statuses of an idempotency key
+const (
+	IdempotencyStatusPending   = "pending"   // The first request with the key is being processed
+	IdempotencyStatusCompleted = "completed" // The response of the first request is stored for replay
+)
+
+// IdempotencyRecord represents an Idempotency-Key sent by a client and the response of its first request.
+type IdempotencyRecord struct {
+	ID          string              `bson:"_id" json:"id"`                                // Key scoped to the credentials of the client
+	Fingerprint string              `bson:"fingerprint" json:"fingerprint"`               // Hash of the method, path and body of the first request
+	Status      string              `bson:"status" json:"status"`                         // Status of the key ("pending", "completed")
+	Response    *IdempotentResponse `bson:"response,omitempty" json:"response,omitempty"` // Response replayed to the retries
+	LockedAt    time.Time           `bson:"locked_at" json:"locked_at"`                   // Timestamp when the request holding the key started
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`                 // Timestamp when the key was first used
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`                 // Timestamp after which the key is forgotten
+}
+
+// IdempotentResponse represents the stored response of a request sent with an Idempotency-Key.
+type IdempotentResponse struct {
+	StatusCode  int               `bson:"status_code" json:"status_code"`             // HTTP status of the response
+	ContentType string            `bson:"content_type" json:"content_type"`           // Content type of the body
+	Body        []byte            `bson:"body" json:"body"`                           // Body of the response
+	Headers     map[string]string `bson:"headers,omitempty" json:"headers,omitempty"` // Headers replayed with the body (ETag, Location)
+}
@@ -9,10 +9,19 @@ import (
 // Invitation represents the structure of an invitation document in MongoDB.
 type Invitation struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`                // Unique identifier for the invitation
-	SessionID      string             `bson:"session_id" json:"session_id"`           // ID of the associated training session
-	UserID         string             `bson:"user_id" json:"user_id"`                 // ID of the user who receives the invitation
+	SessionID      primitive.ObjectID `bson:"session_id" json:"session_id"`           // ID of the associated training session
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`                 // ID of the user who receives the invitation
 	InvitationDate string             `bson:"invitation_date" json:"invitation_date"` // Date when the invitation was sent
 	Status         string             `bson:"status" json:"status"`                   // Status of the invitation (e.g., "pending", "accepted", "declined")
-	CreatedAt      time.Time          `bson:"createdAt" json:"created_at"`            // Timestamp when the session was created
-	UpdatedAt      time.Time          `bson:"updatedAt" json:"updated_at"`            // Timestamp when the session was last updated
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`           // Timestamp when the session was created
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`           // Timestamp when the session was last updated
+
+	Deletion `bson:",inline"` // Soft deletion of the invitation, empty while it is live
 }
+
+// Statuses of an invitation
+const (
+	InvitationStatusPending  = "pending"  // Waiting for an answer
+	InvitationStatusAccepted = "accepted" // Accepted by the invited user
+	InvitationStatusDeclined = "declined" // Declined by the invited user
+)
@@ -13,6 +13,9 @@ type Pitch struct {
 	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`         // ID of the user who created the pitch
 	Title       string             `bson:"title" json:"title"`             // Title of the pitch
 	Description string             `bson:"description" json:"description"` // Description of the pitch
-	CreatedAt   time.Time          `bson:"createdAt" json:"created_at"`    // Timestamp when the pitch was created
-	UpdatedAt   time.Time          `bson:"updatedAt" json:"updated_at"`    // Timestamp when the pitch was last updated
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`   // Timestamp when the pitch was created
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`   // Timestamp when the pitch was last updated
+	Version     int64              `bson:"version" json:"version"`         // Incremented on every write, served as the ETag
+
+	Deletion `bson:",inline"` // Soft deletion of the pitch booking, empty while it is live
 }
@@ -8,20 +8,45 @@ import (
 
 // Session represents the structure of a session document in MongoDB.
 type Session struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`           // Unique identifier for the session
-	Title        string             `bson:"title" json:"title"`                // Title of the session
-	Description  string             `bson:"description" json:"description"`    // Description of the session
-	StartTime    time.Time          `bson:"startTime" json:"start_time"`       // Start time of the session
-	EndTime      time.Time          `bson:"endTime" json:"end_time"`           // End time of the session
-	Location     string             `bson:"location" json:"location"`          // Location of the session
-	TrainingType string             `bson:"trainingType" json:"training_type"` // Type of training
-	Duration     int                `bson:"duration" json:"duration"`          // Duration of the session in minutes
-	Recurrence   string             `bson:"recurrence" json:"recurrence"`      // Recurrence pattern of the session
-	Coach        string             `bson:"coach" json:"coach"`                // Coach for the session
-	CoachAssists []string           `bson:"coachAssists" json:"coach_assists"` // List of assistants for the coach
-	Participants []string           `bson:"participants" json:"participants"`  // List of participants
-	Status       string             `bson:"status" json:"status"`              // Status of the session (e.g., "active", "cancelled")
-	QRCode       string             `bson:"qrCode" json:"qr_code"`             // QR code associated with the session
-	CreatedAt    time.Time          `bson:"createdAt" json:"created_at"`      // Timestamp when the session was created
-	UpdatedAt    time.Time          `bson:"updatedAt" json:"updated_at"`       // Timestamp when the session was last updated
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`            // Unique identifier for the session
+	Title        string               `bson:"title" json:"title"`                 // Title of the session
+	Description  string               `bson:"description" json:"description"`     // Description of the session
+	StartTime    time.Time            `bson:"start_time" json:"start_time"`       // Start time of the session
+	EndTime      time.Time            `bson:"end_time" json:"end_time"`           // End time of the session
+	Location     string               `bson:"location" json:"location"`           // Location of the session
+	TrainingType string               `bson:"training_type" json:"training_type"` // Type of training
+	Duration     int                  `bson:"duration" json:"duration"`           // Duration of the session in minutes
+	Recurrence   string               `bson:"recurrence" json:"recurrence"`       // Recurrence pattern of the session
+	OwnerID      primitive.ObjectID   `bson:"owner_id" json:"owner_id"`           // ID of the coach or business owner who created the session, whose webhooks receive its events
+	Coach        primitive.ObjectID   `bson:"coach" json:"coach"`                 // ID of the coach of the session
+	CoachAssists []primitive.ObjectID `bson:"coach_assists" json:"coach_assists"` // IDs of the assistants of the coach
+	Participants []primitive.ObjectID `bson:"participants" json:"participants"`   // IDs of the participants
+	Capacity     int                  `bson:"capacity" json:"capacity"`           // Maximum number of participants, 0 for no limit
+	Waitlist     []primitive.ObjectID `bson:"waitlist" json:"waitlist"`           // IDs of the users waiting for a place, first come first served
+	Status       string               `bson:"status" json:"status"`               // Status of the session (e.g., "active", "cancelled")
+	QRCode       string               `bson:"qr_code" json:"qr_code"`             // QR code associated with the session
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`       // Timestamp when the session was created
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`       // Timestamp when the session was last updated
+	Version      int64                `bson:"version" json:"version"`             // Incremented on every write, served as the ETag
+
+	Deletion `bson:",inline"` // Soft deletion of the session, empty while it is live
+}
+
+// Statuses of a session
+const (
+	SessionStatusActive   = "active"   // Open for enrollment and check-in
+	SessionStatusArchived = "archived" // Kept for history, no longer open
+)
+
+// Open reports whether users can enroll in the session at the given time: it is active and has not ended yet
+func (s Session) Open(now time.Time) bool { // Check if the session is open
+	if s.Status != "" && s.Status != SessionStatusActive { // Check if the session is archived
+		return false // Closed
+	}
+	return s.EndTime.IsZero() || s.EndTime.After(now) // Open until it ends
+}
+
+// Full reports whether the session has as many participants as its capacity
+func (s Session) Full() bool { // Check if the session is full
+	return s.Capacity > 0 && len(s.Participants) >= s.Capacity // Compare the participants with the capacity
 }
@@ -7,13 +7,26 @@ import (
 )
 
 // User represents the structure of a user document in MongoDB.
+// Users are never serialized as is: responses use one of the views of pkg/dto.
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`            // Unique identifier for the user
-	Name      string             `json:"name" bson:"name,omitempty"`         // Name of the user
-	Email     string             `json:"email" bson:"email,omitempty"`       // Email address of the user
-	Role      string             `json:"role" bson:"role,omitempty"`         // Role of the user (e.g., "admin", "user")
-	Cin       string             `json:"cin" bson:"cin,omitempty"`           // National ID or CIN of the user
-	Password  string             `json:"password" bson:"password,omitempty"` // Encrypted password of the user
-	CreatedAt time.Time          `bson:"createdAt" json:"created_at"`        // Timestamp when the user was created
-	UpdatedAt time.Time          `bson:"updatedAt" json:"updated_at"`        // Timestamp when the user was last updated
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`        // Unique identifier for the user
+	Name      string             `json:"name" bson:"name,omitempty"`     // Name of the user
+	Email     string             `json:"email" bson:"email,omitempty"`   // Email address of the user
+	Role      string             `json:"role" bson:"role,omitempty"`     // Role of the user (e.g., "admin", "user")
+	Avatar    string             `json:"avatar" bson:"avatar,omitempty"` // URL of the profile picture of the user
+	Cin       string             `json:"-" bson:"cin,omitempty"`         // National ID or CIN of the user, only shown in the admin view
+	Password  string             `json:"-" bson:"password,omitempty"`    // Encrypted password of the user, never serialized
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`   // Timestamp when the user was created
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`   // Timestamp when the user was last updated
+	Version   int64              `bson:"version" json:"version"`         // Incremented on every write, served as the ETag
+
+	Deletion `bson:",inline"` // Soft deletion of the user, empty while it is live
 }
+
+// Roles of a user
+const (
+	RoleUser          = "user"           // Member booking sessions and pitches
+	RoleCoach         = "coach"          // Coach running sessions
+	RoleBusinessOwner = "business owner" // Business owner running sessions and defining feedback forms
+	RoleAdmin         = "admin"          // Administrator moderating the platform
+)
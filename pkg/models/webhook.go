@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statuses of a webhook delivery
+const (
+	DeliveryStatusPending   = "pending"   // Waiting for its next attempt
+	DeliveryStatusSucceeded = "succeeded" // Accepted by the receiver
+	DeliveryStatusDead      = "dead"      // Given up after the last attempt, listed in the dead letters until redelivered
+)
+
+// Webhook represents the subscription of a partner application to the domain events, registered by a business owner.
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`                            // Unique identifier for the webhook
+	OwnerID     primitive.ObjectID `bson:"owner_id" json:"owner_id"`                           // Business owner who registered the webhook
+	URL         string             `bson:"url" json:"url"`                                     // Endpoint the events are posted to
+	Description string             `bson:"description,omitempty" json:"description,omitempty"` // Description of the receiver (e.g. "Club website")
+	EventTypes  []string           `bson:"event_types" json:"event_types"`                     // Event types delivered, every type when empty
+	Secret      string             `bson:"secret" json:"-"`                                    // Key of the HMAC signature of the payloads, only shown on creation
+	Active      bool               `bson:"active" json:"active"`                               // Whether new events are delivered
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`                       // Timestamp when the webhook was registered
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`                       // Timestamp when the webhook was last updated
+	Version     int64              `bson:"version" json:"version"`                             // Incremented on every write, served as the ETag
+}
+
+// Subscribes reports whether the webhook receives the events of the type
+func (w Webhook) Subscribes(eventType string) bool { // Check the event type filter
+	if len(w.EventTypes) == 0 { // Check if every type is delivered
+		return true // Every type is delivered
+	}
+	for _, t := range w.EventTypes { // Iterate over the subscribed types
+		if t == eventType { // Check if the type matches
+			return true // The type is delivered
+		}
+	}
+	return false // The type is filtered out
+}
+
+// WebhookDelivery represents the delivery of one event to one webhook, with the log of its attempts.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`                              // Unique identifier for the delivery
+	WebhookID     primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`                         // Webhook the event is delivered to
+	EventID       primitive.ObjectID `bson:"event_id" json:"event_id"`                             // Event delivered, a webhook receives each event once
+	EventType     string             `bson:"event_type" json:"event_type"`                         // Type of the event
+	Payload       json.RawMessage    `bson:"payload" json:"payload"`                               // Body posted to the webhook
+	Status        string             `bson:"status" json:"status"`                                 // "pending", "succeeded" or "dead"
+	Failures      int                `bson:"failures" json:"failures"`                             // Failed attempts since the delivery was created or redelivered, drives the backoff
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`               // Earliest time of the next attempt of a pending delivery
+	Attempts      []DeliveryAttempt  `bson:"attempts" json:"attempts"`                             // Log of the attempts, oldest first
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`                         // Timestamp when the delivery was created
+	DeliveredAt   *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"` // Timestamp when the receiver accepted the event
+}
+
+// DeliveryAttempt represents one request posting an event to a webhook.
+type DeliveryAttempt struct {
+	At         time.Time `bson:"at" json:"at"`                                       // Timestamp when the request was sent
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"` // Status code of the response, 0 when none was received
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`             // Why the attempt failed
+	Duration   int64     `bson:"duration_ms" json:"duration_ms"`                     // Duration of the request in milliseconds
+}
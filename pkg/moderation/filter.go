@@ -0,0 +1,49 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultBlocklist contains the words masked in feedback when no blocklist is configured
+var DefaultBlocklist = []string{"idiot", "stupid", "moron", "dumb", "shit", "fuck", "bastard", "crap"}
+
+// Filter masks blocklisted words in user submitted text
+type Filter struct {
+	pattern *regexp.Regexp // Pattern matching any blocklisted word
+}
+
+// NewFilter creates a filter for the given blocklist, falling back to DefaultBlocklist when it is empty
+func NewFilter(blocklist []string) *Filter { // Create a new filter
+	if len(blocklist) == 0 { // Check if the blocklist is empty
+		blocklist = DefaultBlocklist // Use the default blocklist
+	}
+
+	words := make([]string, 0, len(blocklist)) // Define a slice to hold the escaped words
+	for _, word := range blocklist {           // Iterate over the blocklist
+		word = strings.TrimSpace(word) // Remove the surrounding spaces
+		if word != "" {                // Skip empty entries
+			words = append(words, regexp.QuoteMeta(word)) // Escape the word
+		}
+	}
+	if len(words) == 0 { // Check if no word is left
+		return &Filter{} // Return a filter that never matches
+	}
+
+	pattern := regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\b`) // Match the whole words only, so "dumb" leaves "dumbbell" alone, ignoring case
+	return &Filter{pattern: pattern}                                            // Return the filter
+}
+
+// Clean masks the blocklisted words of the text and reports whether any was found
+func (f *Filter) Clean(text string) (string, bool) { // Clean a text
+	if f == nil || f.pattern == nil { // Check if the filter has no pattern
+		return text, false // Return the text unchanged
+	}
+
+	found := false                                                             // Assume no word was found
+	cleaned := f.pattern.ReplaceAllStringFunc(text, func(word string) string { // Replace each match
+		found = true                                  // Remember that a word was found
+		return strings.Repeat("*", len([]rune(word))) // Mask the word
+	})
+	return cleaned, found // Return the cleaned text
+}
@@ -0,0 +1,30 @@
+package moderation
+
+import "testing"
+
+func TestFilterClean(t *testing.T) {
+	tests := []struct {
+		name      string
+		blocklist []string
+		text      string
+		want      string
+		found     bool
+	}{
+		{name: "clean text", text: "Great session", want: "Great session"},
+		{name: "blocklisted word", text: "The drill was dumb", want: "The drill was ****", found: true},
+		{name: "ignores case", text: "STUPID warm-up", want: "****** warm-up", found: true},
+		{name: "word inside another word", text: "We used every dumbbell", want: "We used every dumbbell"},
+		{name: "word as a prefix", text: "Crappie fishing after training", want: "Crappie fishing after training"},
+		{name: "word followed by punctuation", text: "Dumb, really.", want: "****, really.", found: true},
+		{name: "configured blocklist", blocklist: []string{" lazy ", ""}, text: "lazy coach, dumb drill", want: "**** coach, dumb drill", found: true},
+		{name: "empty blocklist entries only", blocklist: []string{" "}, text: "dumb", want: "dumb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := NewFilter(tt.blocklist).Clean(tt.text)
+			if got != tt.want || found != tt.found {
+				t.Errorf("Clean(%q) = %q, %v; want %q, %v", tt.text, got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
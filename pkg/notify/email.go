@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"training_session/config"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+)
+
+// errNoAddress reports that the user has no email address, which is not a failed delivery
+var errNoAddress = errors.New("user has no email address")
+
+// EmailNotifier delivers notifications with another notifier, then emails a copy to the user.
+// Emails are best effort: a failed delivery is logged and does not fail the notification.
+type EmailNotifier struct {
+	next     Notifier                  // Notifier the notification is delivered with first
+	cfg      config.SMTPConfig         // Mail server settings
+	users    repository.UserRepository // Repository the email addresses are read from
+	observer Observer                  // Observer of the deliveries
+}
+
+// NewEmailNotifier creates a notifier emailing the notifications delivered by next, reporting each email to the observer
+func NewEmailNotifier(next Notifier, cfg config.SMTPConfig, users repository.UserRepository, observer Observer) *EmailNotifier { // Create an email notifier
+	return &EmailNotifier{next: next, cfg: cfg, users: users, observer: observer} // Return the notifier
+}
+
+// Notify delivers the notification, then emails it to the user
+func (n *EmailNotifier) Notify(ctx context.Context, notification models.Notification) error { // Deliver and email a notification
+	if err := n.next.Notify(ctx, notification); err != nil { // Deliver the notification
+		return err // Return the error
+	}
+
+	spanCtx, span := startSpan(ctx, "email", notification) // Trace the email
+	err := n.email(spanCtx, notification)                  // Email the notification
+	if errors.Is(err, errNoAddress) {                      // Check if the user cannot be emailed
+		span.End() // End the span
+		return nil // Nothing was sent
+	}
+	endSpan(span, err)                       // End the span
+	n.observer.ObserveDelivery("email", err) // Record the delivery
+	if err != nil {                          // Check if the email failed
+		slog.WarnContext(ctx, "Failed to email notification", slog.String("user_id", notification.UserID.Hex()), slog.Any("error", err)) // Log the error message
+	}
+	return nil // Return nil
+}
+
+// email sends the notification to the address of the user
+func (n *EmailNotifier) email(ctx context.Context, notification models.Notification) error { // Email a notification
+	user, err := n.users.FindByID(ctx, notification.UserID) // Find the user to email
+	if err != nil {                                         // Check if there is an error
+		return err // Return the error
+	}
+	if user.Email == "" { // Check if the user has no email address
+		return errNoAddress // Nothing to send
+	}
+
+	to, err := mail.ParseAddress(user.Email) // Parse the address of the user
+	if err != nil {                          // Check if the address is invalid
+		return fmt.Errorf("invalid email address: %w", err) // Return the error
+	}
+	from, err := mail.ParseAddress(n.cfg.From) // Parse the sender address
+	if err != nil {                            // Check if the address is invalid
+		return fmt.Errorf("invalid sender address: %w", err) // Return the error
+	}
+
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(notification.Type) // Keep the subject on a single header line
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		from.String(), to.String(), subject, notification.Message) // Build the message
+
+	var auth smtp.Auth        // Define the authentication, none by default
+	if n.cfg.Username != "" { // Check if the mail server needs authentication
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host) // Authenticate with the username and password
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port)) // Build the address of the mail server
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout) // Bound the delivery time
+	defer cancel()                                         // Release the timer
+
+	done := make(chan error, 1) // Define a channel to receive the result of the delivery
+	go func() {                 // Deliver in the background so the timeout can be enforced
+		done <- smtp.SendMail(addr, auth, from.Address, []string{to.Address}, []byte(message)) // Send the email
+	}()
+
+	select { // Wait for the delivery or the timeout
+	case err := <-done: // The delivery finished
+		return err // Return the result
+	case <-ctx.Done(): // The timeout expired
+		return ctx.Err() // Return the timeout error
+	}
+}
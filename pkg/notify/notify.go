@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("training_session/pkg/notify") // Tracer of the notification deliveries
+
+// startSpan starts the span of a delivery on the channel
+func startSpan(ctx context.Context, channel string, notification models.Notification) (context.Context, trace.Span) { // Trace a delivery
+	return tracer.Start(ctx, "notify "+channel, trace.WithAttributes(
+		attribute.String("notification.type", notification.Type),            // Type of the notification
+		attribute.String("notification.user_id", notification.UserID.Hex()), // Recipient of the notification
+	)) // Start the span
+}
+
+// endSpan ends the span of a delivery, recording the error of a failed delivery
+func endSpan(span trace.Span, err error) { // End the span of a delivery
+	if err != nil { // Check if the delivery failed
+		span.RecordError(err)                    // Record the error
+		span.SetStatus(codes.Error, err.Error()) // Mark the delivery as failed
+	}
+	span.End() // End the span
+}
+
+// Notifier delivers notifications to users
+type Notifier interface {
+	Notify(ctx context.Context, notification models.Notification) error // Deliver a notification
+}
+
+// Observer is told the outcome of every delivery, err is nil when the delivery succeeded
+type Observer interface {
+	ObserveDelivery(channel string, err error) // Record a delivery on the channel
+}
+
+// StoreNotifier delivers notifications by storing them in the notification repository, where users read them
+type StoreNotifier struct {
+	notifications repository.NotificationRepository // Repository the notifications are stored in
+	observer      Observer                          // Observer of the deliveries
+}
+
+// NewStoreNotifier creates a notifier storing notifications in the given repository, reporting each delivery to the observer
+func NewStoreNotifier(notifications repository.NotificationRepository, observer Observer) *StoreNotifier { // Create a store notifier
+	return &StoreNotifier{notifications: notifications, observer: observer} // Return the notifier
+}
+
+// Notify stores the notification
+func (n *StoreNotifier) Notify(ctx context.Context, notification models.Notification) error { // Store a notification
+	ctx, span := startSpan(ctx, "store", notification) // Trace the delivery
+	err := n.notifications.Insert(ctx, &notification)  // Insert the notification
+	endSpan(span, err)                                 // End the span
+	n.observer.ObserveDelivery("store", err)           // Record the delivery
+	if err != nil {                                    // Check if there is an error
+		slog.ErrorContext(ctx, "Failed to insert notification", slog.Any("error", err)) // Log the error message
+		return err                                                                      // Return the error
+	}
+
+	slog.DebugContext(ctx, "Notification stored", slog.String("type", notification.Type)) // Log the success message
+	return nil                                                                            // Return nil
+}
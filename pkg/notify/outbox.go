@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+)
+
+// OutboxNotifier delivers the notifications written to the outbox with the change they announce: the relay
+// publishes the NotificationRequested events to it and it hands their notification to the notifier. A failed
+// delivery fails the publication, so the relay delivers the notification again on its next poll.
+type OutboxNotifier struct {
+	next Notifier // Notifier the notifications are delivered with
+}
+
+// NewOutboxNotifier creates a broker delivering the notifications of the outbox with the notifier
+func NewOutboxNotifier(next Notifier) *OutboxNotifier { // Create an outbox notifier
+	return &OutboxNotifier{next: next} // Return the notifier
+}
+
+// Publish delivers the notification of a NotificationRequested event, ignoring the other events. A notification
+// already stored by an earlier publication of the event is not delivered again.
+func (n *OutboxNotifier) Publish(ctx context.Context, event models.Event) error { // Deliver a notification
+	if event.Type != models.EventNotificationRequested { // Check if the event announces no notification
+		return nil // Nothing to deliver
+	}
+	var notification models.Notification                              // Define the notification
+	if err := json.Unmarshal(event.Data, &notification); err != nil { // Decode the notification
+		return fmt.Errorf("invalid notification in event %s: %w", event.ID.Hex(), err) // Return the error
+	}
+	err := n.next.Notify(ctx, notification)     // Deliver the notification
+	if errors.Is(err, repository.ErrConflict) { // Check if the notification was already delivered
+		return nil // Nothing left to deliver
+	}
+	return err // Return the error
+}
+
+// Close releases nothing, the notifier has no connection of its own
+func (n *OutboxNotifier) Close() error { // Close the notifier
+	return nil // Return nil
+}
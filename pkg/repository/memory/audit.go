@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditRepository stores the audit log in memory
+type AuditRepository struct {
+	store *store[models.AuditEntry] // Audit entry documents
+}
+
+// auditKey is the key no two audit entries may share, each position of the chain is taken once
+func auditKey(e *models.AuditEntry) string { // Get the unique key of an entry
+	return strconv.FormatInt(e.Sequence, 10) // Use the sequence
+}
+
+// Last finds the entry with the highest sequence, returning repository.ErrNotFound when the log is empty
+func (r *AuditRepository) Last(ctx context.Context) (models.AuditEntry, error) {
+	entries := r.store.find(nil) // Find every entry
+	if len(entries) == 0 {       // Check if the log is empty
+		return models.AuditEntry{}, repository.ErrNotFound // Return a not found error
+	}
+	last := entries[0]              // Start with the first entry
+	for _, entry := range entries { // Iterate over the entries
+		if entry.Sequence > last.Sequence { // Check if the entry comes later
+			last = entry // Keep the entry
+		}
+	}
+	return last, nil // Return the last entry
+}
+
+// FindByID finds an entry by ID
+func (r *AuditRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.AuditEntry, error) {
+	return r.store.get(id) // Return the entry
+}
+
+// Append inserts an entry, returning repository.ErrConflict if its sequence or ID is taken
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	return r.store.insert(entry) // Insert the entry
+}
+
+// Find finds the entries matching the filter, newest first
+func (r *AuditRepository) Find(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, error) {
+	entries := r.store.find(func(e *models.AuditEntry) bool { // Find the matching entries
+		return (filter.ActorID == nil || (e.ActorID != nil && *e.ActorID == *filter.ActorID)) && // Match the actor
+			(filter.Action == "" || e.Action == filter.Action) && // Match the action
+			(filter.TargetType == "" || e.TargetType == filter.TargetType) && // Match the kind of target
+			(filter.TargetID == "" || e.TargetID == filter.TargetID) && // Match the target
+			(filter.From.IsZero() || !e.OccurredAt.Before(filter.From)) && // Match the start of the period
+			(filter.To.IsZero() || e.OccurredAt.Before(filter.To)) && // Match the end of the period
+			(filter.Before == 0 || e.Sequence < filter.Before) // Match the page
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence > entries[j].Sequence }) // Put the newest first
+	if filter.Limit > 0 && len(entries) > filter.Limit {                                          // Check if there are too many entries
+		entries = entries[:filter.Limit] // Keep the newest entries
+	}
+	return entries, nil // Return the entries
+}
+
+// FindRange finds the entries following a sequence, in sequence order
+func (r *AuditRepository) FindRange(ctx context.Context, after int64, limit int) ([]models.AuditEntry, error) {
+	entries := r.store.find(func(e *models.AuditEntry) bool { return e.Sequence > after })        // Find the following entries
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Sequence < entries[j].Sequence }) // Sort by sequence
+	if len(entries) > limit {                                                                     // Check if there are too many entries
+		entries = entries[:limit] // Keep the first entries
+	}
+	return entries, nil // Return the entries
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CheckInRepository stores the check-ins in memory
+type CheckInRepository struct {
+	store *store[models.CheckIn] // Check-in documents
+}
+
+// checkInKey is the key no two check-ins may share, a user checks in to a session once
+func checkInKey(c *models.CheckIn) string { // Get the unique key of a check-in
+	return c.SessionID.Hex() + "/" + c.UserID.Hex() // Use the session and the user
+}
+
+// Find finds the check-in of a user in a session
+func (r *CheckInRepository) Find(ctx context.Context, sessionID, userID primitive.ObjectID) (models.CheckIn, error) {
+	return r.store.findOne(func(c *models.CheckIn) bool { return c.SessionID == sessionID && c.UserID == userID }) // Find the check-in
+}
+
+// FindByUser finds the check-ins of a user, oldest first
+func (r *CheckInRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CheckIn, error) {
+	checkIns := r.store.find(func(c *models.CheckIn) bool { return c.UserID == userID }) // Find the check-ins of the user
+	sortByTime(checkIns, func(c *models.CheckIn) time.Time { return c.CheckedInAt })     // Sort the check-ins by time
+	return checkIns, nil                                                                 // Return the check-ins
+}
+
+// Insert inserts a new check-in, returning repository.ErrConflict if the user already checked in to the session
+func (r *CheckInRepository) Insert(ctx context.Context, checkIn *models.CheckIn) error {
+	return r.store.insert(checkIn) // Insert the check-in
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErasureRepository stores the erasure requests in memory
+type ErasureRepository struct {
+	store *store[models.ErasureRequest] // Erasure request documents
+}
+
+// erasureKey is the key no two erasure requests may share, a user has at most one pending request
+func erasureKey(r *models.ErasureRequest) string { // Get the unique key of a request
+	if r.Status != models.ErasureStatusPending { // Check if the request was reviewed
+		return "" // Reviewed requests are not checked
+	}
+	return r.UserID.Hex() // Use the user
+}
+
+// Find finds the requests, filtered by status when not empty, oldest first
+func (r *ErasureRepository) Find(ctx context.Context, status string) ([]models.ErasureRequest, error) {
+	requests := r.store.find(func(e *models.ErasureRequest) bool { return status == "" || e.Status == status }) // Find the matching requests
+	sortByTime(requests, func(e *models.ErasureRequest) time.Time { return e.RequestedAt })                     // Sort the requests by request date
+	return requests, nil                                                                                        // Return the requests
+}
+
+// FindByUser finds the requests of a user, newest first
+func (r *ErasureRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.ErasureRequest, error) {
+	requests := r.store.find(func(e *models.ErasureRequest) bool { return e.UserID == userID }) // Find the requests of the user
+	sortByTime(requests, func(e *models.ErasureRequest) time.Time { return e.RequestedAt })     // Sort the requests by request date
+	slices.Reverse(requests)                                                                    // Put the newest first
+	return requests, nil                                                                        // Return the requests
+}
+
+// FindByID finds a request by ID
+func (r *ErasureRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.ErasureRequest, error) {
+	return r.store.get(id) // Return the request
+}
+
+// Insert inserts a new request, returning repository.ErrConflict if the user already has a pending one
+func (r *ErasureRepository) Insert(ctx context.Context, request *models.ErasureRequest) error {
+	return r.store.insert(request) // Insert the request
+}
+
+// Review stores the outcome of a pending request, returning repository.ErrNotFound if it is no longer pending
+func (r *ErasureRepository) Review(ctx context.Context, request models.ErasureRequest) error {
+	return r.store.update(request.ID, func(e *models.ErasureRequest) error { // Update the request
+		if e.Status != models.ErasureStatusPending { // Check if the request was already reviewed
+			return repository.ErrNotFound // Return a not found error
+		}
+		e.Status = request.Status         // Set the status
+		e.ReviewedBy = request.ReviewedBy // Set the reviewer
+		e.ReviewedAt = request.ReviewedAt // Set the review time
+		e.ReviewNote = request.ReviewNote // Set the note
+		return nil                        // Return nil
+	})
+}
@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedbackRepository stores feedback in memory
+type FeedbackRepository struct {
+	store *store[models.Feedback] // Feedback documents
+}
+
+// Find finds feedback, oldest first
+func (r *FeedbackRepository) Find(ctx context.Context, filter repository.FeedbackFilter) ([]models.Feedback, error) {
+	feedbacks := r.store.find(func(f *models.Feedback) bool { // Find the matching feedback
+		switch { // Check each criterion of the filter
+		case !filter.UserID.IsZero() && f.UserID != filter.UserID: // Check the author
+			return false // The feedback does not match
+		case !filter.CoachID.IsZero() && f.CoachID != filter.CoachID: // Check the coach
+			return false // The feedback does not match
+		case !filter.SessionID.IsZero() && f.SessionID != filter.SessionID: // Check the session
+			return false // The feedback does not match
+		case filter.Status != "" && f.Status != filter.Status: // Check the status
+			return false // The feedback does not match
+		case filter.Status == "" && filter.ExcludeStatus != "" && f.Status == filter.ExcludeStatus: // Check the excluded status
+			return false // The feedback does not match
+		}
+		return true // The feedback matches
+	})
+	sortByTime(feedbacks, func(f *models.Feedback) time.Time { return f.CreatedAt }) // Sort the feedback by creation date
+	return feedbacks, nil                                                            // Return the feedback
+}
+
+// FindByID finds a feedback by ID
+func (r *FeedbackRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Feedback, error) {
+	return r.store.get(id) // Return the feedback
+}
+
+// Insert inserts a new feedback at version 1
+func (r *FeedbackRepository) Insert(ctx context.Context, feedback *models.Feedback) error {
+	return r.store.insert(feedback) // Insert the feedback
+}
+
+// Edit edits a feedback still at edit.Version and increments the version
+func (r *FeedbackRepository) Edit(ctx context.Context, id primitive.ObjectID, edit repository.FeedbackEdit) error {
+	return r.store.updateVersion(id, edit.Version, func(f *models.Feedback) error { // Update the feedback
+		f.Content = edit.Content     // Set the content
+		f.Rating = edit.Rating       // Set the rating
+		f.Anonymous = edit.Anonymous // Set the anonymous display choice
+		if edit.Answers != nil {     // Check if the answers are being changed
+			f.Answers = edit.Answers // Set the answers
+		}
+		if edit.Status != "" { // Check if the status is being changed
+			f.Status = edit.Status // Set the status
+		}
+		f.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// AddFlag adds a flag to a feedback, changing its status when not empty
+func (r *FeedbackRepository) AddFlag(ctx context.Context, id primitive.ObjectID, flag models.FeedbackFlag, status string) error {
+	return r.store.update(id, func(f *models.Feedback) error { // Update the feedback
+		f.Flags = append(f.Flags, flag) // Add the flag
+		if status != "" {               // Check if the status is being changed
+			f.Status = status // Set the status
+		}
+		f.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// SetStatus changes the moderation status of a feedback
+func (r *FeedbackRepository) SetStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	return r.store.update(id, func(f *models.Feedback) error { // Update the feedback
+		f.Status = status        // Set the status
+		f.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// SetReply sets the reply of a feedback, returning repository.ErrConflict if it already has one
+func (r *FeedbackRepository) SetReply(ctx context.Context, id primitive.ObjectID, reply models.CoachReply) error {
+	return r.store.update(id, func(f *models.Feedback) error { // Update the feedback
+		if f.Reply != nil { // Check if the feedback already has a reply
+			return repository.ErrConflict // Return a conflict error
+		}
+		f.Reply = &reply         // Set the reply
+		f.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// Delete deletes a feedback
+func (r *FeedbackRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.store.remove(id) // Delete the feedback
+}
+
+// AnonymizeAuthor detaches the feedback of a user from them and marks it anonymous
+func (r *FeedbackRepository) AnonymizeAuthor(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.store.updateAll(func(f *models.Feedback) bool { return f.UserID == userID }, func(f *models.Feedback) error { // Update the feedback of the user
+		f.UserID = primitive.NilObjectID // Forget the author
+		f.Anonymous = true               // Hide the author in public views
+		return nil                       // Return nil
+	})
+}
+
+// AnonymizeFlags detaches the flags a user raised from them
+func (r *FeedbackRepository) AnonymizeFlags(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.store.updateAll(func(f *models.Feedback) bool { return flaggedBy(f, userID) }, func(f *models.Feedback) error { // Update the feedback the user flagged
+		for i := range f.Flags { // Iterate over the flags
+			if f.Flags[i].UserID == userID { // Check if the user raised the flag
+				f.Flags[i].UserID = primitive.NilObjectID // Forget who raised the flag
+			}
+		}
+		return nil // Return nil
+	})
+}
+
+// flaggedBy reports whether a user raised a flag against a feedback
+func flaggedBy(feedback *models.Feedback, userID primitive.ObjectID) bool { // Check the flags of a feedback
+	for _, flag := range feedback.Flags { // Iterate over the flags
+		if flag.UserID == userID { // Check if the user raised the flag
+			return true // Return true
+		}
+	}
+	return false // Return false
+}
+
+// AnonymizeReplies detaches the replies a coach posted from them
+func (r *FeedbackRepository) AnonymizeReplies(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.store.updateAll(func(f *models.Feedback) bool { return f.Reply != nil && f.Reply.CoachID == userID }, func(f *models.Feedback) error { // Update the feedback the coach replied to
+		f.Reply.CoachID = primitive.NilObjectID // Forget who replied
+		return nil                              // Return nil
+	})
+}
+
+// FeedbackFormRepository stores feedback form templates in memory
+type FeedbackFormRepository struct {
+	store *store[models.FeedbackForm] // Feedback form documents
+}
+
+// feedbackFormKey is the key no two feedback forms may share, an owner has at most one form per training type
+func feedbackFormKey(f *models.FeedbackForm) string { // Get the unique key of a form
+	return f.OwnerID.Hex() + "/" + f.TrainingType // Key the form by owner and training type
+}
+
+// FindAll finds the forms, filtered by training type when not empty
+func (r *FeedbackFormRepository) FindAll(ctx context.Context, trainingType string) ([]models.FeedbackForm, error) {
+	return r.store.find(func(f *models.FeedbackForm) bool { // Return the matching forms
+		return trainingType == "" || f.TrainingType == trainingType // Match the training type
+	}), nil
+}
+
+// FindByID finds a form by ID
+func (r *FeedbackFormRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.FeedbackForm, error) {
+	return r.store.get(id) // Return the form
+}
+
+// FindByIDs finds the forms with the given IDs
+func (r *FeedbackFormRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.FeedbackForm, error) {
+	wanted := map[primitive.ObjectID]bool{} // Index the wanted IDs
+	for _, id := range ids {                // Iterate over the IDs
+		wanted[id] = true // Add the ID to the index
+	}
+	return r.store.find(func(f *models.FeedbackForm) bool { return wanted[f.ID] }), nil // Return the wanted forms
+}
+
+// FindByOwner finds the form an owner defined for a training type
+func (r *FeedbackFormRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID, trainingType string) (models.FeedbackForm, error) {
+	forms := r.store.find(func(f *models.FeedbackForm) bool { return f.OwnerID == ownerID && f.TrainingType == trainingType }) // Find the form of the owner
+	if len(forms) == 0 {                                                                                                       // Check if no form matched
+		return models.FeedbackForm{}, repository.ErrNotFound // Return a not found error
+	}
+	return forms[0], nil // Return the form, an owner has one per training type
+}
+
+// Insert inserts a new form, returning repository.ErrConflict when the owner already has a form for the training type
+func (r *FeedbackFormRepository) Insert(ctx context.Context, form *models.FeedbackForm) error {
+	return r.store.insert(form) // Insert the form
+}
+
+// Update updates the definition of a form, returning repository.ErrConflict when the owner already has a form for the training type
+func (r *FeedbackFormRepository) Update(ctx context.Context, form models.FeedbackForm) error {
+	return r.store.updateVersion(form.ID, form.Version, func(f *models.FeedbackForm) error { // Update the form if nobody changed it in the meantime
+		f.TrainingType = form.TrainingType // Set the training type
+		f.Title = form.Title               // Set the title
+		f.Criteria = form.Criteria         // Set the criteria
+		f.UpdatedAt = form.UpdatedAt       // Set the updated_at timestamp
+		return nil                         // Return nil
+	})
+}
+
+// Delete deletes a form
+func (r *FeedbackFormRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.store.remove(id) // Delete the form
+}
+
+// ModerationLogRepository stores the audit log of feedback moderation in memory
+type ModerationLogRepository struct {
+	store *store[models.ModerationAction] // Moderation action documents
+}
+
+// FindByFeedback finds the actions performed on a feedback, oldest first
+func (r *ModerationLogRepository) FindByFeedback(ctx context.Context, feedbackID primitive.ObjectID) ([]models.ModerationAction, error) {
+	actions := r.store.find(func(a *models.ModerationAction) bool { return a.FeedbackID == feedbackID }) // Find the actions on the feedback
+	sortByTime(actions, func(a *models.ModerationAction) time.Time { return a.CreatedAt })               // Sort the actions chronologically
+	return actions, nil                                                                                  // Return the actions
+}
+
+// Insert appends an action
+func (r *ModerationLogRepository) Insert(ctx context.Context, action *models.ModerationAction) error {
+	return r.store.insert(action) // Insert the action
+}
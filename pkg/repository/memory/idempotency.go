@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+)
+
+// IdempotencyRepository stores idempotency keys in memory, expired keys are taken over when reused
+type IdempotencyRepository struct {
+	mu      sync.Mutex                          // Protects the records
+	records map[string]models.IdempotencyRecord // Records by key
+}
+
+// Reserve stores the record of a new key, taking over the keys that expired or whose request was abandoned
+// before staleBefore; a key already held or completed is returned with repository.ErrConflict
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, error) {
+	r.mu.Lock()         // Lock the records
+	defer r.mu.Unlock() // Unlock the records
+
+	current, found := r.records[record.ID] // Find the record holding the key
+	if found {                             // Check if the key is used
+		expired := !current.ExpiresAt.After(record.CreatedAt)                                                  // Check if the key expired
+		abandoned := current.Status == models.IdempotencyStatusPending && current.LockedAt.Before(staleBefore) // Check if the request holding the key never finished
+		if !expired && !abandoned {                                                                            // Check if the key is still held
+			return clone(current), repository.ErrConflict // Report the key as held
+		}
+	}
+
+	r.records[record.ID] = clone(record) // Store the record
+	return record, nil                   // Return the reserved record
+}
+
+// Complete stores the response of the request holding a key
+func (r *IdempotencyRepository) Complete(ctx context.Context, id string, response models.IdempotentResponse) error {
+	r.mu.Lock()         // Lock the records
+	defer r.mu.Unlock() // Unlock the records
+
+	record, found := r.records[id] // Find the record
+	if !found {                    // Check if the key is unknown
+		return repository.ErrNotFound // Return a not found error
+	}
+	record.Status = models.IdempotencyStatusCompleted // Mark the key as completed
+	record.Response = &response                       // Store the response
+	r.records[id] = clone(record)                     // Store the record
+	return nil                                        // Return nil
+}
+
+// Release deletes a key
+func (r *IdempotencyRepository) Release(ctx context.Context, id string) error {
+	r.mu.Lock()         // Lock the records
+	defer r.mu.Unlock() // Unlock the records
+
+	if _, found := r.records[id]; !found { // Check if the key is unknown
+		return repository.ErrNotFound // Return a not found error
+	}
+	delete(r.records, id) // Delete the record
+	return nil            // Return nil
+}
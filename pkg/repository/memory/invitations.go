@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvitationRepository stores session invitations in memory
+type InvitationRepository struct {
+	store *store[models.Invitation] // Invitation documents
+}
+
+// FindAll finds all invitations
+func (r *InvitationRepository) FindAll(ctx context.Context) ([]models.Invitation, error) {
+	return r.store.find(live[models.Invitation](nil)), nil // Return all invitations
+}
+
+// FindByID finds an invitation by ID
+func (r *InvitationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Invitation, error) {
+	return r.store.getLive(id) // Return the invitation
+}
+
+// Insert inserts a new invitation
+func (r *InvitationRepository) Insert(ctx context.Context, invitation *models.Invitation) error {
+	return r.store.insert(invitation) // Insert the invitation
+}
+
+// SetStatus changes the status of an invitation
+func (r *InvitationRepository) SetStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	return r.store.updateLive(id, func(i *models.Invitation) error { // Update the live invitation
+		i.Status = status        // Set the status
+		i.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// FindDeleted finds the soft deleted invitations, last deleted first
+func (r *InvitationRepository) FindDeleted(ctx context.Context) ([]models.Invitation, error) {
+	return r.store.findDeleted(), nil // Return the deleted invitations
+}
+
+// SoftDelete hides an invitation, returning repository.ErrNotFound if it is missing or already deleted
+func (r *InvitationRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return r.store.softDelete(id, deletion) // Soft delete the invitation
+}
+
+// Restore shows a soft deleted invitation again and returns the undone deletion
+func (r *InvitationRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return r.store.restore(id) // Restore the invitation
+}
+
+// Purge removes the invitations soft deleted before the given time
+func (r *InvitationRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return r.store.purge(before), nil // Remove the expired invitations
+}
+
+// SoftDeleteBySession hides the live invitations to a session
+func (r *InvitationRepository) SoftDeleteBySession(ctx context.Context, sessionID primitive.ObjectID, deletion models.Deletion) error {
+	for _, invitation := range r.store.find(live(func(i *models.Invitation) bool { return i.SessionID == sessionID })) { // Iterate over the live invitations
+		if err := r.store.softDelete(invitation.ID, deletion); err != nil { // Soft delete the invitation
+			return err // Return the error
+		}
+	}
+	return nil // Return nil
+}
+
+// RestoreBySession shows again the invitations to a session soft deleted at the given time
+func (r *InvitationRepository) RestoreBySession(ctx context.Context, sessionID primitive.ObjectID, deletedAt time.Time) error {
+	for _, invitation := range r.store.findDeleted() { // Iterate over the deleted invitations
+		if invitation.SessionID == sessionID && invitation.DeletedAt.Equal(deletedAt) { // Check if it was deleted with the session
+			if _, err := r.store.restore(invitation.ID); err != nil { // Restore the invitation
+				return err // Return the error
+			}
+		}
+	}
+	return nil // Return nil
+}
+
+// SoftDeleteByUser hides the live invitations of a user
+func (r *InvitationRepository) SoftDeleteByUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion) (int64, error) {
+	return r.store.updateAll(live(func(i *models.Invitation) bool { return i.UserID == userID }), func(i *models.Invitation) error { // Update the live invitations of the user
+		i.Deletion = deletion // Record the deletion
+		return nil            // Return nil
+	})
+}
+
+// RestoreByUser shows again the invitations of a user soft deleted at the given time
+func (r *InvitationRepository) RestoreByUser(ctx context.Context, userID primitive.ObjectID, deletedAt time.Time) error {
+	_, err := r.store.updateAll(func(i *models.Invitation) bool { // Update the invitations deleted with the user
+		return i.UserID == userID && i.IsDeleted() && i.DeletedAt.Equal(deletedAt) // Match the user and the deletion time
+	}, func(i *models.Invitation) error {
+		i.Deletion = models.Deletion{} // Forget the deletion
+		return nil                     // Return nil
+	})
+	return err // Return the error
+}
+
+// FindByUser finds the invitations of a user
+func (r *InvitationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Invitation, error) {
+	return r.store.find(live(func(i *models.Invitation) bool { return i.UserID == userID })), nil // Return the invitations of the user
+}
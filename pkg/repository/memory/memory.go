@@ -0,0 +1,275 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewRepositories creates in-memory repositories of every aggregate, useful for tests and local tools
+func NewRepositories() *repository.Repositories { // Create the in-memory repositories
+	sessions := newVersionedStore(func(s *models.Session) *primitive.ObjectID { return &s.ID }, func(s *models.Session) *int64 { return &s.Version })                                                      // Store the sessions
+	users := newVersionedStore(func(u *models.User) *primitive.ObjectID { return &u.ID }, func(u *models.User) *int64 { return &u.Version }).withUniqueKey(func(u *models.User) string { return u.Email }) // Store the users
+	invitations := newStore(func(i *models.Invitation) *primitive.ObjectID { return &i.ID })                                                                                                               // Store the invitations
+	notifications := newStore(func(n *models.Notification) *primitive.ObjectID { return &n.ID })                                                                                                           // Store the notifications
+	feedback := newVersionedStore(func(f *models.Feedback) *primitive.ObjectID { return &f.ID }, func(f *models.Feedback) *int64 { return &f.Version })                                                    // Store the feedback
+	forms := newVersionedStore(func(f *models.FeedbackForm) *primitive.ObjectID { return &f.ID }, func(f *models.FeedbackForm) *int64 { return &f.Version }).withUniqueKey(feedbackFormKey)                // Store the feedback forms
+	moderation := newStore(func(a *models.ModerationAction) *primitive.ObjectID { return &a.ID })                                                                                                          // Store the moderation log
+	pitches := newVersionedStore(func(p *models.Pitch) *primitive.ObjectID { return &p.ID }, func(p *models.Pitch) *int64 { return &p.Version })                                                           // Store the pitch bookings
+	outbox := newStore(func(e *models.Event) *primitive.ObjectID { return &e.ID })                                                                                                                         // Store the outbox
+	webhooks := newVersionedStore(func(w *models.Webhook) *primitive.ObjectID { return &w.ID }, func(w *models.Webhook) *int64 { return &w.Version })                                                      // Store the webhooks
+	deliveries := newStore(func(d *models.WebhookDelivery) *primitive.ObjectID { return &d.ID }).withUniqueKey(deliveryKey)                                                                                // Store the webhook deliveries
+	audit := newStore(func(e *models.AuditEntry) *primitive.ObjectID { return &e.ID }).withUniqueKey(auditKey)                                                                                             // Store the audit log
+	erasures := newStore(func(e *models.ErasureRequest) *primitive.ObjectID { return &e.ID }).withUniqueKey(erasureKey)                                                                                    // Store the erasure requests
+	checkIns := newStore(func(c *models.CheckIn) *primitive.ObjectID { return &c.ID }).withUniqueKey(checkInKey)                                                                                           // Store the check-ins
+
+	return &repository.Repositories{
+		Sessions:      &SessionRepository{store: sessions},                                    // Set the session repository
+		Users:         &UserRepository{store: users},                                          // Set the user repository
+		Invitations:   &InvitationRepository{store: invitations},                              // Set the invitation repository
+		Notifications: &NotificationRepository{store: notifications},                          // Set the notification repository
+		Feedback:      &FeedbackRepository{store: feedback},                                   // Set the feedback repository
+		FeedbackForms: &FeedbackFormRepository{store: forms},                                  // Set the feedback form repository
+		ModerationLog: &ModerationLogRepository{store: moderation},                            // Set the moderation log repository
+		Pitches:       &PitchRepository{store: pitches},                                       // Set the pitch booking repository
+		Idempotency:   &IdempotencyRepository{records: map[string]models.IdempotencyRecord{}}, // Set the idempotency key repository
+		Outbox:        &OutboxRepository{store: outbox},                                       // Set the outbox repository
+		Webhooks:      &WebhookRepository{store: webhooks},                                    // Set the webhook repository
+		Deliveries:    &DeliveryRepository{store: deliveries},                                 // Set the webhook delivery repository
+		Audit:         &AuditRepository{store: audit},                                         // Set the audit log repository
+		Erasures:      &ErasureRepository{store: erasures},                                    // Set the erasure request repository
+		CheckIns:      &CheckInRepository{store: checkIns},                                    // Set the check-in repository
+		Transactions: &Transactor{stores: []snapshotter{ // Set the transactor, rolling back every store
+			sessions, users, invitations, notifications, feedback, forms, moderation, pitches, outbox, webhooks, deliveries, audit, erasures, checkIns,
+		}},
+	}
+}
+
+// store keeps documents in insertion order, copying them in and out like a database would
+type store[T any] struct {
+	mu        sync.RWMutex                 // Protects the documents
+	docs      []T                          // Documents in insertion order
+	idOf      func(*T) *primitive.ObjectID // Returns a pointer to the ID of a document
+	versionOf func(*T) *int64              // Returns a pointer to the version of a document, nil when the documents are not versioned
+	uniqueKey func(*T) string              // Returns the key no two documents may share, empty keys are not checked; nil when nothing is unique
+}
+
+// newStore creates an empty store
+func newStore[T any](idOf func(*T) *primitive.ObjectID) *store[T] { // Create a store
+	return &store[T]{idOf: idOf} // Return the store
+}
+
+// newVersionedStore creates an empty store whose documents start at version 1 and move to the next version on every update
+func newVersionedStore[T any](idOf func(*T) *primitive.ObjectID, versionOf func(*T) *int64) *store[T] { // Create a versioned store
+	return &store[T]{idOf: idOf, versionOf: versionOf} // Return the store
+}
+
+// withUniqueKey makes the store reject the writes giving two documents the same non-empty key with
+// repository.ErrConflict, like a unique index would
+func (s *store[T]) withUniqueKey(key func(*T) string) *store[T] { // Add a unique key
+	s.uniqueKey = key // Set the unique key
+	return s          // Return the store
+}
+
+// snapshot copies the documents and returns the function putting them back
+func (s *store[T]) snapshot() func() { // Copy the documents
+	s.mu.RLock()                 // Lock the store for reading
+	docs := slices.Clone(s.docs) // Copy the documents, a write replaces a document instead of changing it
+	s.mu.RUnlock()               // Unlock the store
+
+	return func() { // Put the documents back
+		s.mu.Lock()         // Lock the store for writing
+		defer s.mu.Unlock() // Unlock the store
+		s.docs = docs       // Restore the documents
+	}
+}
+
+// clone deep copies a document through its BSON representation, so callers never share memory with the store
+func clone[T any](document T) T { // Copy a document
+	var copied T                        // Define the copy
+	data, err := bson.Marshal(document) // Encode the document
+	if err != nil {                     // Check if there is an error
+		panic(fmt.Sprintf("memory: cannot encode document: %v", err)) // Documents are always encodable
+	}
+	if err := bson.Unmarshal(data, &copied); err != nil { // Decode the copy
+		panic(fmt.Sprintf("memory: cannot decode document: %v", err)) // Documents are always decodable
+	}
+	return copied // Return the copy
+}
+
+// index returns the position of the document with the given ID, or -1
+func (s *store[T]) index(id primitive.ObjectID) int { // Find the position of a document
+	for i := range s.docs { // Iterate over the documents
+		if *s.idOf(&s.docs[i]) == id { // Check if the ID matches
+			return i // Return the position
+		}
+	}
+	return -1 // The document was not found
+}
+
+// taken reports whether a document other than the one at position skip has the key of the document
+func (s *store[T]) taken(document *T, skip int) bool { // Check the unique key
+	if s.uniqueKey == nil || s.uniqueKey(document) == "" { // Check if the key is not checked
+		return false // The key is free
+	}
+	for i := range s.docs { // Iterate over the documents
+		if i != skip && s.uniqueKey(&s.docs[i]) == s.uniqueKey(document) { // Check if another document has the key
+			return true // The key is taken
+		}
+	}
+	return false // The key is free
+}
+
+// find returns copies of the documents matching the predicate
+func (s *store[T]) find(match func(*T) bool) []T { // Find documents
+	s.mu.RLock()         // Lock the store for reading
+	defer s.mu.RUnlock() // Unlock the store
+
+	documents := []T{}      // Define a slice to hold the documents
+	for i := range s.docs { // Iterate over the documents
+		if match == nil || match(&s.docs[i]) { // Check if the document matches
+			documents = append(documents, clone(s.docs[i])) // Add a copy of the document
+		}
+	}
+	return documents // Return the documents
+}
+
+// findOne returns a copy of the first document matching the predicate
+func (s *store[T]) findOne(match func(*T) bool) (T, error) { // Find a document
+	documents := s.find(match) // Find the matching documents
+	if len(documents) == 0 {   // Check if no document matched
+		var zero T                          // Define an empty document
+		return zero, repository.ErrNotFound // Return a not found error
+	}
+	return documents[0], nil // Return the first document
+}
+
+// get returns a copy of the document with the given ID
+func (s *store[T]) get(id primitive.ObjectID) (T, error) { // Get a document by ID
+	return s.findOne(func(document *T) bool { return *s.idOf(document) == id }) // Find the document by ID
+}
+
+// insert stores a copy of the document, generating its ID when empty
+func (s *store[T]) insert(document *T) error { // Insert a document
+	s.mu.Lock()         // Lock the store for writing
+	defer s.mu.Unlock() // Unlock the store
+
+	id := s.idOf(document) // Get the ID of the document
+	if id.IsZero() {       // Check if the document has no ID
+		*id = primitive.NewObjectID() // Generate a new ID
+	}
+	if s.index(*id) >= 0 { // Check if the ID is already used
+		return repository.ErrConflict // Return a conflict error, like the unique index on the ID would
+	}
+	if s.taken(document, -1) { // Check if another document has the unique key
+		return repository.ErrConflict // Return a conflict error
+	}
+	if s.versionOf != nil { // Check if the documents are versioned
+		*s.versionOf(document) = 1 // Start at the first version
+	}
+
+	s.docs = append(s.docs, clone(*document)) // Store a copy of the document
+	return nil                                // Return nil
+}
+
+// update applies a change to the document with the given ID
+func (s *store[T]) update(id primitive.ObjectID, change func(*T) error) error { // Update a document
+	return s.apply(id, nil, false, change) // Update the document at any version
+}
+
+// updateVersion applies a change to the live document with the given ID if it is still at the given version,
+// returning repository.ErrVersionMismatch otherwise and repository.ErrNotFound if it is missing or soft deleted
+func (s *store[T]) updateVersion(id primitive.ObjectID, version int64, change func(*T) error) error { // Update a document at a version
+	return s.apply(id, &version, true, change) // Update the live document at the version
+}
+
+// apply applies a change to the document with the given ID, skipping a soft deleted document when live is set,
+// checking its version when expected is not nil and moving it to the next version when the documents are versioned
+func (s *store[T]) apply(id primitive.ObjectID, expected *int64, live bool, change func(*T) error) error { // Change a document
+	s.mu.Lock()         // Lock the store for writing
+	defer s.mu.Unlock() // Unlock the store
+
+	i := s.index(id) // Find the document
+	if i < 0 {       // Check if the document was not found
+		return repository.ErrNotFound // Return a not found error
+	}
+	if d := deletionOf(&s.docs[i]); live && d != nil && d.IsDeleted() { // Check if the document was soft deleted
+		return repository.ErrNotFound // Return a not found error
+	}
+
+	var version int64       // Define the current version
+	if s.versionOf != nil { // Check if the documents are versioned
+		version = *s.versionOf(&s.docs[i]) // Get the current version
+	}
+	if expected != nil && *expected != version { // Check if the document changed since it was read
+		return repository.ErrVersionMismatch // Return a version mismatch error
+	}
+
+	document := clone(s.docs[i])              // Change a copy so a failed change leaves the document untouched
+	if err := change(&document); err != nil { // Apply the change
+		return err // Return the error
+	}
+	if s.taken(&document, i) { // Check if another document has the unique key
+		return repository.ErrConflict // Return a conflict error
+	}
+	if s.versionOf != nil { // Check if the documents are versioned
+		*s.versionOf(&document) = version + 1 // Move to the next version
+	}
+	s.docs[i] = clone(document) // Store the changed document
+	return nil                  // Return nil
+}
+
+// updateAll applies a change to the documents matching the predicate and returns their count
+func (s *store[T]) updateAll(match func(*T) bool, change func(*T) error) (int64, error) { // Update documents
+	var updated int64                        // Define the number of updated documents
+	for _, document := range s.find(match) { // Iterate over the matching documents
+		if err := s.update(*s.idOf(&document), change); err != nil { // Update the document
+			return updated, err // Return the error
+		}
+		updated++ // Count the document
+	}
+	return updated, nil // Return the number of updated documents
+}
+
+// remove deletes the document with the given ID
+func (s *store[T]) remove(id primitive.ObjectID) error { // Delete a document
+	s.mu.Lock()         // Lock the store for writing
+	defer s.mu.Unlock() // Unlock the store
+
+	i := s.index(id) // Find the document
+	if i < 0 {       // Check if the document was not found
+		return repository.ErrNotFound // Return a not found error
+	}
+	s.docs = append(s.docs[:i], s.docs[i+1:]...) // Remove the document
+	return nil                                   // Return nil
+}
+
+// removeAll deletes the documents matching the predicate and returns their count
+func (s *store[T]) removeAll(match func(*T) bool) int64 { // Delete documents
+	s.mu.Lock()         // Lock the store for writing
+	defer s.mu.Unlock() // Unlock the store
+
+	kept := s.docs[:0]      // Reuse the slice for the kept documents
+	for i := range s.docs { // Iterate over the documents
+		if !match(&s.docs[i]) { // Check if the document is kept
+			kept = append(kept, s.docs[i]) // Keep the document
+		}
+	}
+	removed := int64(len(s.docs) - len(kept)) // Count the removed documents
+	s.docs = kept                             // Remove the matching documents
+	return removed                            // Return the number of removed documents
+}
+
+// sortByTime sorts documents from the oldest to the newest, keeping insertion order for equal timestamps
+func sortByTime[T any](documents []T, at func(*T) time.Time) { // Sort documents by timestamp
+	sort.SliceStable(documents, func(i, j int) bool { // Sort the documents
+		return at(&documents[i]).Before(at(&documents[j])) // Compare the timestamps
+	})
+}
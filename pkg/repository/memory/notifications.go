@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationRepository stores user notifications in memory
+type NotificationRepository struct {
+	store *store[models.Notification] // Notification documents
+}
+
+// FindByUser finds the notifications of a user
+func (r *NotificationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error) {
+	notifications := r.store.find(func(n *models.Notification) bool { return n.UserID == userID }) // Find the notifications of the user
+	sortByTime(notifications, func(n *models.Notification) time.Time { return n.CreatedAt })       // Sort the notifications by creation date
+	return notifications, nil                                                                      // Return the notifications
+}
+
+// Insert inserts a new notification
+func (r *NotificationRepository) Insert(ctx context.Context, notification *models.Notification) error {
+	return r.store.insert(notification) // Insert the notification
+}
+
+// Delete deletes a notification
+func (r *NotificationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.store.remove(id) // Delete the notification
+}
+
+// DeleteByUser deletes the notifications of a user
+func (r *NotificationRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.store.removeAll(func(n *models.Notification) bool { return n.UserID == userID }), nil // Remove the notifications of the user
+}
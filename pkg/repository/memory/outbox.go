@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxRepository stores the domain events in memory
+type OutboxRepository struct {
+	store *store[models.Event] // Event documents
+
+	mu           sync.Mutex // Protects the lease
+	leaseOwner   string     // Owner of the relay lease
+	leaseExpires time.Time  // Expiry of the relay lease
+}
+
+// Insert appends an event
+func (r *OutboxRepository) Insert(ctx context.Context, event *models.Event) error {
+	return r.store.insert(event) // Insert the event
+}
+
+// FindPending finds the events not published yet, oldest first
+func (r *OutboxRepository) FindPending(ctx context.Context, limit int) ([]models.Event, error) {
+	events := r.store.find(func(e *models.Event) bool { return e.PublishedAt == nil }) // Find the pending events
+	sortByTime(events, func(e *models.Event) time.Time { return e.OccurredAt })        // Sort by occurrence
+	if len(events) > limit {                                                           // Check if there are more events than requested
+		events = events[:limit] // Keep the oldest events
+	}
+	return events, nil // Return the events
+}
+
+// MarkPublished records that the broker accepted an event
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return r.store.update(id, func(e *models.Event) error { // Update the event
+		e.PublishedAt = &at // Set the published_at timestamp
+		return nil          // Return nil
+	})
+}
+
+// MarkFailed records a failed publication of an event
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error {
+	return r.store.update(id, func(e *models.Event) error { // Update the event
+		e.Attempts++         // Count the attempt
+		e.LastError = reason // Store the error
+		return nil           // Return nil
+	})
+}
+
+// Lease holds the right to publish until the given time, taking over a lease that expired by now;
+// repository.ErrConflict is returned while another owner holds it
+func (r *OutboxRepository) Lease(ctx context.Context, owner string, now, until time.Time) error {
+	r.mu.Lock()         // Lock the lease
+	defer r.mu.Unlock() // Unlock the lease
+
+	if r.leaseOwner != owner && r.leaseExpires.After(now) { // Check if another owner holds the lease
+		return repository.ErrConflict // Return a conflict error
+	}
+	r.leaseOwner, r.leaseExpires = owner, until // Hold the lease
+	return nil                                  // Return nil
+}
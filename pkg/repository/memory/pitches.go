@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PitchRepository stores pitch bookings in memory
+type PitchRepository struct {
+	store *store[models.Pitch] // Pitch booking documents
+}
+
+// FindAll finds all pitch bookings
+func (r *PitchRepository) FindAll(ctx context.Context) ([]models.Pitch, error) {
+	return r.store.find(live[models.Pitch](nil)), nil // Return all pitch bookings
+}
+
+// FindByID finds a pitch booking by ID
+func (r *PitchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Pitch, error) {
+	return r.store.getLive(id) // Return the pitch booking
+}
+
+// FindByUser finds the pitch bookings of a user
+func (r *PitchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Pitch, error) {
+	return r.store.find(live(func(p *models.Pitch) bool { return p.UserID == userID })), nil // Return the pitch bookings of the user
+}
+
+// Insert inserts a new pitch booking at version 1
+func (r *PitchRepository) Insert(ctx context.Context, pitch *models.Pitch) error {
+	return r.store.insert(pitch) // Insert the pitch booking
+}
+
+// Replace replaces a pitch booking still at pitch.Version and increments the version
+func (r *PitchRepository) Replace(ctx context.Context, pitch *models.Pitch) error {
+	err := r.store.updateVersion(pitch.ID, pitch.Version, func(p *models.Pitch) error { // Replace the pitch booking
+		*p = *pitch // Overwrite the document
+		return nil  // Return nil
+	})
+	if err == nil { // Check if the pitch booking was replaced
+		pitch.Version++ // Report the new version
+	}
+	return err // Return the error
+}
+
+// FindDeleted finds the soft deleted pitch bookings, last deleted first
+func (r *PitchRepository) FindDeleted(ctx context.Context) ([]models.Pitch, error) {
+	return r.store.findDeleted(), nil // Return the deleted pitch bookings
+}
+
+// SoftDelete hides a pitch booking, returning repository.ErrNotFound if it is missing or already deleted
+func (r *PitchRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return r.store.softDelete(id, deletion) // Soft delete the pitch booking
+}
+
+// Restore shows a soft deleted pitch booking again and returns the undone deletion
+func (r *PitchRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return r.store.restore(id) // Restore the pitch booking
+}
+
+// Purge removes the pitch bookings soft deleted before the given time
+func (r *PitchRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return r.store.purge(before), nil // Remove the expired pitch bookings
+}
+
+// SoftDeleteByUser hides the live pitch bookings of a user
+func (r *PitchRepository) SoftDeleteByUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion) (int64, error) {
+	return r.store.updateAll(live(func(p *models.Pitch) bool { return p.UserID == userID }), func(p *models.Pitch) error { // Update the live pitch bookings of the user
+		p.Deletion = deletion // Record the deletion
+		return nil            // Return nil
+	})
+}
+
+// RestoreByUser shows again the pitch bookings of a user soft deleted at the given time
+func (r *PitchRepository) RestoreByUser(ctx context.Context, userID primitive.ObjectID, deletedAt time.Time) error {
+	_, err := r.store.updateAll(func(p *models.Pitch) bool { // Update the pitch bookings deleted with the user
+		return p.UserID == userID && p.IsDeleted() && p.DeletedAt.Equal(deletedAt) // Match the user and the deletion time
+	}, func(p *models.Pitch) error {
+		p.Deletion = models.Deletion{} // Forget the deletion
+		return nil                     // Return nil
+	})
+	return err // Return the error
+}
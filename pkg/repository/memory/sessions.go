@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SessionRepository stores training sessions in memory
+type SessionRepository struct {
+	store *store[models.Session] // Session documents
+}
+
+// FindAll finds all sessions
+func (r *SessionRepository) FindAll(ctx context.Context) ([]models.Session, error) {
+	return r.store.find(live[models.Session](nil)), nil // Return all sessions
+}
+
+// FindByStatus finds the sessions with the given status
+func (r *SessionRepository) FindByStatus(ctx context.Context, status string) ([]models.Session, error) {
+	return r.store.find(live(func(s *models.Session) bool { return s.Status == status })), nil // Return the sessions with the status
+}
+
+// FindByUser finds the sessions coached or attended by a user
+func (r *SessionRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	return r.store.find(live(func(s *models.Session) bool { // Return the sessions of the user
+		return s.Coach == userID || slices.Contains(s.Participants, userID) // Match the coach or a participant
+	})), nil
+}
+
+// FindByID finds a session by ID
+func (r *SessionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Session, error) {
+	return r.store.getLive(id) // Return the session
+}
+
+// Insert inserts a new session at version 1
+func (r *SessionRepository) Insert(ctx context.Context, session *models.Session) error {
+	return r.store.insert(session) // Insert the session
+}
+
+// Replace replaces a session still at session.Version and increments the version
+func (r *SessionRepository) Replace(ctx context.Context, session *models.Session) error {
+	err := r.store.updateVersion(session.ID, session.Version, func(s *models.Session) error { // Replace the session
+		*s = *session // Overwrite the document
+		return nil    // Return nil
+	})
+	if err == nil { // Check if the session was replaced
+		session.Version++ // Report the new version
+	}
+	return err // Return the error
+}
+
+// FindDeleted finds the soft deleted sessions, last deleted first
+func (r *SessionRepository) FindDeleted(ctx context.Context) ([]models.Session, error) {
+	return r.store.findDeleted(), nil // Return the deleted sessions
+}
+
+// SoftDelete hides a session, returning repository.ErrNotFound if it is missing or already deleted
+func (r *SessionRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return r.store.softDelete(id, deletion) // Soft delete the session
+}
+
+// Restore shows a soft deleted session again and returns the undone deletion
+func (r *SessionRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return r.store.restore(id) // Restore the session
+}
+
+// Purge removes the sessions soft deleted before the given time
+func (r *SessionRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return r.store.purge(before), nil // Remove the expired sessions
+}
+
+// AddParticipant adds a participant to a session, returning repository.ErrConflict if it is full
+func (r *SessionRepository) AddParticipant(ctx context.Context, id, userID primitive.ObjectID) error {
+	return r.store.updateLive(id, func(s *models.Session) error { // Update the live session
+		if s.Full() { // Check if there is no place left
+			return repository.ErrConflict // Return a conflict error
+		}
+		if !slices.Contains(s.Participants, userID) { // Check if the user is not a participant yet
+			s.Participants = append(s.Participants, userID) // Add the user to the participants
+		}
+		return nil // Return nil
+	})
+}
+
+// RemoveParticipant removes a user from the participants and the waitlist of a session
+func (r *SessionRepository) RemoveParticipant(ctx context.Context, id, userID primitive.ObjectID) error {
+	return r.store.updateLive(id, func(s *models.Session) error { // Update the live session
+		s.Participants = slices.DeleteFunc(s.Participants, func(id primitive.ObjectID) bool { return id == userID }) // Remove the user from the participants
+		s.Waitlist = slices.DeleteFunc(s.Waitlist, func(id primitive.ObjectID) bool { return id == userID })         // Remove the user from the waitlist
+		return nil                                                                                                   // Return nil
+	})
+}
+
+// AddToWaitlist adds a user to the end of the waitlist of a session
+func (r *SessionRepository) AddToWaitlist(ctx context.Context, id, userID primitive.ObjectID) error {
+	return r.store.updateLive(id, func(s *models.Session) error { // Update the live session
+		if !slices.Contains(s.Waitlist, userID) { // Check if the user is not waiting yet
+			s.Waitlist = append(s.Waitlist, userID) // Add the user to the waitlist
+		}
+		return nil // Return nil
+	})
+}
+
+// PromoteWaitlisted moves a user from the waitlist to the participants of a session
+func (r *SessionRepository) PromoteWaitlisted(ctx context.Context, id, userID primitive.ObjectID) error {
+	return r.store.updateLive(id, func(s *models.Session) error { // Update the live session
+		s.Waitlist = slices.DeleteFunc(s.Waitlist, func(id primitive.ObjectID) bool { return id == userID }) // Remove the user from the waitlist
+		if !slices.Contains(s.Participants, userID) {                                                        // Check if the user is not a participant yet
+			s.Participants = append(s.Participants, userID) // Add the user to the participants
+		}
+		return nil // Return nil
+	})
+}
+
+// RemoveUser removes a user from the participants, waitlist and assistants of every session, soft deleted ones included
+func (r *SessionRepository) RemoveUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.store.updateAll(func(s *models.Session) bool { // Update the sessions of the user
+		return slices.Contains(s.Participants, userID) || slices.Contains(s.Waitlist, userID) || slices.Contains(s.CoachAssists, userID) // Match a participant, a waitlisted user or an assistant
+	}, func(s *models.Session) error {
+		s.Participants = slices.DeleteFunc(s.Participants, func(id primitive.ObjectID) bool { return id == userID }) // Remove the user from the participants
+		s.Waitlist = slices.DeleteFunc(s.Waitlist, func(id primitive.ObjectID) bool { return id == userID })         // Remove the user from the waitlist
+		s.CoachAssists = slices.DeleteFunc(s.CoachAssists, func(id primitive.ObjectID) bool { return id == userID }) // Remove the user from the assistants
+		return nil                                                                                                   // Return nil
+	})
+}
+
+// ReassignCoach gives the live sessions of a coach ending after the given time to another coach, who also
+// becomes the owner of the ones the coach created
+func (r *SessionRepository) ReassignCoach(ctx context.Context, from, to primitive.ObjectID, endingAfter time.Time) (int64, error) {
+	return r.store.updateAll(live(func(s *models.Session) bool { // Update the remaining sessions of the coach
+		return s.Coach == from && s.EndTime.After(endingAfter) // Match the coach and the end time
+	}), func(s *models.Session) error {
+		if s.OwnerID == from { // Check if the coach created the session
+			s.OwnerID = to // Set the new owner
+		}
+		s.Coach = to             // Set the new coach
+		s.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// SetStatus changes the status of a session
+func (r *SessionRepository) SetStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	return r.store.updateLive(id, func(s *models.Session) error { // Update the live session
+		s.Status = status // Set the status
+		return nil        // Return nil
+	})
+}
+
+// SetQRCode stores the QR code content of a session
+func (r *SessionRepository) SetQRCode(ctx context.Context, id primitive.ObjectID, qrCode string) error {
+	return r.store.updateLive(id, func(s *models.Session) error { // Update the live session
+		s.QRCode = qrCode // Set the QR code
+		return nil        // Return nil
+	})
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"sort"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// softDeletable is implemented by the documents embedding a models.Deletion
+type softDeletable interface {
+	SoftDeletion() *models.Deletion // Get the soft deletion of the document
+}
+
+// deletionOf returns the soft deletion of a document, nil when the document cannot be soft deleted
+func deletionOf[T any](document *T) *models.Deletion { // Get the soft deletion of a document
+	if d, ok := any(document).(softDeletable); ok { // Check if the document embeds a deletion
+		return d.SoftDeletion() // Return the deletion
+	}
+	return nil // The document cannot be soft deleted
+}
+
+// live restricts a predicate to the documents that are not soft deleted, nil matching every live document
+func live[T any](match func(*T) bool) func(*T) bool { // Exclude the soft deleted documents
+	return func(document *T) bool { // Return the predicate
+		if d := deletionOf(document); d != nil && d.IsDeleted() { // Check if the document was soft deleted
+			return false // Hide the document
+		}
+		return match == nil || match(document) // Apply the predicate
+	}
+}
+
+// getLive returns a copy of the live document with the given ID
+func (s *store[T]) getLive(id primitive.ObjectID) (T, error) { // Get a live document by ID
+	return s.findOne(live(func(document *T) bool { return *s.idOf(document) == id })) // Find the live document by ID
+}
+
+// updateLive applies a change to the live document with the given ID, returning repository.ErrNotFound if it is
+// missing or soft deleted
+func (s *store[T]) updateLive(id primitive.ObjectID, change func(*T) error) error { // Update a live document
+	return s.apply(id, nil, true, change) // Update the live document at any version
+}
+
+// findDeleted returns copies of the soft deleted documents, last deleted first
+func (s *store[T]) findDeleted() []T { // Find the soft deleted documents
+	documents := s.find(func(document *T) bool { // Find the deleted documents
+		d := deletionOf(document)        // Get the deletion
+		return d != nil && d.IsDeleted() // Match the deleted documents
+	})
+	sort.SliceStable(documents, func(i, j int) bool { // Sort the documents
+		return deletionOf(&documents[i]).DeletedAt.After(*deletionOf(&documents[j]).DeletedAt) // Put the last deleted first
+	})
+	return documents // Return the documents
+}
+
+// softDelete hides the live document with the given ID, returning repository.ErrNotFound if it is missing or
+// already deleted
+func (s *store[T]) softDelete(id primitive.ObjectID, deletion models.Deletion) error { // Soft delete a document
+	return s.update(id, func(document *T) error { // Update the document
+		d := deletionOf(document) // Get the deletion
+		if d.IsDeleted() {        // Check if the document is already deleted
+			return repository.ErrNotFound // Return a not found error
+		}
+		*d = deletion // Record the deletion
+		return nil    // Return nil
+	})
+}
+
+// restore shows the soft deleted document with the given ID again and returns the undone deletion, returning
+// repository.ErrNotFound if it is missing or not deleted and repository.ErrFinalDeletion if its deletion is final
+func (s *store[T]) restore(id primitive.ObjectID) (models.Deletion, error) { // Restore a document
+	var undone models.Deletion                    // Define the undone deletion
+	err := s.update(id, func(document *T) error { // Update the document
+		d := deletionOf(document) // Get the deletion
+		if !d.IsDeleted() {       // Check if the document is live
+			return repository.ErrNotFound // Return a not found error
+		}
+		if d.Final { // Check if the deletion cannot be undone
+			return repository.ErrFinalDeletion // Return a final deletion error
+		}
+		undone, *d = *d, models.Deletion{} // Forget the deletion
+		return nil                         // Return nil
+	})
+	return undone, err // Return the undone deletion
+}
+
+// purge removes the documents soft deleted before the given time and returns their count
+func (s *store[T]) purge(before time.Time) int64 { // Remove the expired deleted documents
+	return s.removeAll(func(document *T) bool { // Remove the expired deleted documents
+		d := deletionOf(document)                                      // Get the deletion
+		return d != nil && d.IsDeleted() && d.DeletedAt.Before(before) // Check if the retention is over
+	})
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"training_session/pkg/repository"
+)
+
+// snapshotter is a store whose documents can be copied and put back, to roll a transaction back
+type snapshotter interface {
+	snapshot() func() // Copy the documents and return the function putting them back
+}
+
+// Transactor runs functions one at a time, so they do not interleave with each other, and rolls the stores
+// back when a function fails. The writes made outside of transactions while a function runs are rolled back
+// with it. A function given the context of the running transaction joins it.
+type Transactor struct {
+	mu     sync.Mutex    // Serializes the transactions
+	stores []snapshotter // Stores rolled back when a function fails
+}
+
+// WithTransaction runs fn once no other transaction is running, putting every store back as it was when fn
+// returns an error
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error { // Run a transaction
+	if joined, err := repository.JoinTransaction(ctx, fn); joined { // Check if fn joined the running transaction
+		return err // Return the error
+	}
+	t.mu.Lock()         // Wait for the running transaction
+	defer t.mu.Unlock() // Let the next transaction run
+
+	rollback := make([]func(), len(t.stores)) // Define the functions putting the stores back
+	for i, store := range t.stores {          // Iterate over the stores
+		rollback[i] = store.snapshot() // Copy the documents
+	}
+	txCtx, failed := repository.BeginTransaction(ctx) // Mark the context of the transaction
+	err := fn(txCtx)                                  // Run the function
+	if err == nil && failed() {                       // Check if a function joining the transaction failed
+		err = repository.ErrRolledBack // Roll the transaction back
+	}
+	if err != nil { // Check if the function failed
+		for _, restore := range rollback { // Iterate over the stores
+			restore() // Put the documents back
+		}
+	}
+	return err // Return the error
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository stores users in memory
+type UserRepository struct {
+	store *store[models.User] // User documents
+}
+
+// FindAll finds all users
+func (r *UserRepository) FindAll(ctx context.Context) ([]models.User, error) {
+	return r.store.find(live[models.User](nil)), nil // Return all users
+}
+
+// FindByRole finds the users with the given role
+func (r *UserRepository) FindByRole(ctx context.Context, role string) ([]models.User, error) {
+	return r.store.find(live(func(u *models.User) bool { return u.Role == role })), nil // Return the users with the role
+}
+
+// FindByID finds a user by ID
+func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	return r.store.getLive(id) // Return the user
+}
+
+// FindByEmail finds a user by email
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.store.findOne(live(func(u *models.User) bool { return u.Email == email })) // Return the user with the email
+}
+
+// Insert inserts a new user at version 1, returning repository.ErrConflict if the email is taken
+func (r *UserRepository) Insert(ctx context.Context, user *models.User) error {
+	return r.store.insert(user) // Insert the user
+}
+
+// Update updates the non-empty fields of a user still at user.Version and increments the version,
+// returning repository.ErrConflict if the email is taken
+func (r *UserRepository) Update(ctx context.Context, user models.User) error {
+	return r.store.updateVersion(user.ID, user.Version, func(u *models.User) error { // Update the user
+		if user.Name != "" { // Check if the name is being updated
+			u.Name = user.Name // Set the name
+		}
+		if user.Email != "" { // Check if the email is being updated
+			u.Email = user.Email // Set the email
+		}
+		if user.Role != "" { // Check if the role is being updated
+			u.Role = user.Role // Set the role
+		}
+		if user.Avatar != "" { // Check if the avatar is being updated
+			u.Avatar = user.Avatar // Set the avatar
+		}
+		if user.Cin != "" { // Check if the CIN is being updated
+			u.Cin = user.Cin // Set the CIN
+		}
+		if user.Password != "" { // Check if the password is being updated
+			u.Password = user.Password // Set the password
+		}
+		u.UpdatedAt = user.UpdatedAt // Set the updated_at timestamp
+		return nil                   // Return nil
+	})
+}
+
+// Replace replaces a user still at user.Version and increments the version, returning repository.ErrConflict
+// if the email is taken
+func (r *UserRepository) Replace(ctx context.Context, user *models.User) error {
+	err := r.store.updateVersion(user.ID, user.Version, func(u *models.User) error { // Replace the user
+		*u = *user // Overwrite the document
+		return nil // Return nil
+	})
+	if err == nil { // Check if the user was replaced
+		user.Version++ // Report the new version
+	}
+	return err // Return the error
+}
+
+// FindDeleted finds the soft deleted users, last deleted first
+func (r *UserRepository) FindDeleted(ctx context.Context) ([]models.User, error) {
+	return r.store.findDeleted(), nil // Return the deleted users
+}
+
+// FindByIDWithDeleted finds a user by ID, live or soft deleted
+func (r *UserRepository) FindByIDWithDeleted(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	return r.store.get(id) // Return the user
+}
+
+// Erase replaces a user, live or soft deleted, still at user.Version and increments the version
+func (r *UserRepository) Erase(ctx context.Context, user *models.User) error {
+	err := r.store.apply(user.ID, &user.Version, false, func(u *models.User) error { // Replace the user, even soft deleted
+		*u = *user // Overwrite the document
+		return nil // Return nil
+	})
+	if err == nil { // Check if the user was replaced
+		user.Version++ // Report the new version
+	}
+	return err // Return the error
+}
+
+// SoftDelete hides a user, returning repository.ErrNotFound if it is missing or already deleted
+func (r *UserRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return r.store.softDelete(id, deletion) // Soft delete the user
+}
+
+// Restore shows a soft deleted user again and returns the undone deletion
+func (r *UserRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return r.store.restore(id) // Restore the user
+}
+
+// Purge removes the users soft deleted before the given time
+func (r *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return r.store.purge(before), nil // Remove the expired users
+}
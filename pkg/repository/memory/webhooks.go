@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookRepository stores the webhook subscriptions in memory
+type WebhookRepository struct {
+	store *store[models.Webhook] // Webhook documents
+}
+
+// FindByOwner finds the webhooks of a business owner, oldest first
+func (r *WebhookRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Webhook, error) {
+	return r.store.find(func(w *models.Webhook) bool { return w.OwnerID == ownerID }), nil // Find the webhooks of the owner
+}
+
+// FindByID finds a webhook by ID
+func (r *WebhookRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Webhook, error) {
+	return r.store.get(id) // Get the webhook
+}
+
+// FindSubscribed finds the active webhooks of the owners receiving the events of the type
+func (r *WebhookRepository) FindSubscribed(ctx context.Context, eventType string, ownerIDs []primitive.ObjectID) ([]models.Webhook, error) {
+	return r.store.find(func(w *models.Webhook) bool { // Find the subscribed webhooks
+		return w.Active && w.Subscribes(eventType) && slices.Contains(ownerIDs, w.OwnerID) // Match the state, the type and the owner
+	}), nil
+}
+
+// Insert inserts a new webhook
+func (r *WebhookRepository) Insert(ctx context.Context, webhook *models.Webhook) error {
+	return r.store.insert(webhook) // Insert the webhook
+}
+
+// Update updates the URL, description, event types and state of a webhook
+func (r *WebhookRepository) Update(ctx context.Context, webhook models.Webhook) error {
+	return r.store.updateVersion(webhook.ID, webhook.Version, func(w *models.Webhook) error { // Update the webhook if nobody changed it in the meantime
+		w.URL = webhook.URL                 // Set the URL
+		w.Description = webhook.Description // Set the description
+		w.EventTypes = webhook.EventTypes   // Set the event types
+		w.Active = webhook.Active           // Set the state
+		w.UpdatedAt = webhook.UpdatedAt     // Set the updated_at timestamp
+		return nil                          // Return nil
+	})
+}
+
+// Delete deletes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return r.store.remove(id) // Remove the webhook
+}
+
+// DisableByOwner stops delivering to the active webhooks of a business owner
+func (r *WebhookRepository) DisableByOwner(ctx context.Context, ownerID primitive.ObjectID) (int64, error) {
+	return r.store.updateAll(func(w *models.Webhook) bool { return w.OwnerID == ownerID && w.Active }, func(w *models.Webhook) error { // Update the active webhooks of the owner
+		w.Active = false         // Disable the webhook
+		w.UpdatedAt = time.Now() // Set the updated_at timestamp
+		return nil               // Return nil
+	})
+}
+
+// DeliveryRepository stores the webhook deliveries in memory
+type DeliveryRepository struct {
+	store *store[models.WebhookDelivery] // Delivery documents
+}
+
+// deliveryKey is the key no two deliveries may share, a webhook receives each event once
+func deliveryKey(d *models.WebhookDelivery) string { // Get the unique key of a delivery
+	return d.WebhookID.Hex() + "/" + d.EventID.Hex() // Combine the webhook and the event
+}
+
+// FindByWebhook finds the deliveries of a webhook, filtered by status when not empty, newest first
+func (r *DeliveryRepository) FindByWebhook(ctx context.Context, webhookID primitive.ObjectID, status string) ([]models.WebhookDelivery, error) {
+	deliveries := r.store.find(func(d *models.WebhookDelivery) bool { // Find the deliveries of the webhook
+		return d.WebhookID == webhookID && (status == "" || d.Status == status) // Match the webhook and the status
+	})
+	sortByTime(deliveries, func(d *models.WebhookDelivery) time.Time { return d.CreatedAt }) // Sort by creation
+	slices.Reverse(deliveries)                                                               // Put the newest first
+	return deliveries, nil                                                                   // Return the deliveries
+}
+
+// FindByID finds a delivery by ID
+func (r *DeliveryRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.WebhookDelivery, error) {
+	return r.store.get(id) // Get the delivery
+}
+
+// FindDue finds the pending deliveries due by now, oldest first
+func (r *DeliveryRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := r.store.find(func(d *models.WebhookDelivery) bool { // Find the due deliveries
+		return d.Status == models.DeliveryStatusPending && !d.NextAttemptAt.After(now) // Match the pending deliveries due by now
+	})
+	sortByTime(deliveries, func(d *models.WebhookDelivery) time.Time { return d.NextAttemptAt }) // Sort by due time
+	if len(deliveries) > limit {                                                                 // Check if there are more deliveries than requested
+		deliveries = deliveries[:limit] // Keep the oldest deliveries
+	}
+	return deliveries, nil // Return the deliveries
+}
+
+// Insert inserts a new delivery, repository.ErrConflict is returned if the webhook already has one for the event
+func (r *DeliveryRepository) Insert(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.store.insert(delivery) // Insert the delivery
+}
+
+// Claim postpones a pending delivery due by now until the given time, repository.ErrNotFound is returned
+// if another worker claimed it
+func (r *DeliveryRepository) Claim(ctx context.Context, id primitive.ObjectID, now, until time.Time) error {
+	return r.store.update(id, func(d *models.WebhookDelivery) error { // Update the delivery
+		if d.Status != models.DeliveryStatusPending || d.NextAttemptAt.After(now) { // Check if the delivery is not due
+			return repository.ErrNotFound // Return a not found error
+		}
+		d.NextAttemptAt = until // Postpone the delivery
+		return nil              // Return nil
+	})
+}
+
+// RecordAttempt appends an attempt and stores the status, failures, next attempt and delivery time of the delivery
+func (r *DeliveryRepository) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.DeliveryAttempt) error {
+	return r.store.update(delivery.ID, func(d *models.WebhookDelivery) error { // Update the delivery
+		d.Attempts = append(d.Attempts, attempt) // Append the attempt
+		d.Status = delivery.Status               // Set the status
+		d.Failures = delivery.Failures           // Set the failures
+		d.NextAttemptAt = delivery.NextAttemptAt // Set the next attempt
+		d.DeliveredAt = delivery.DeliveredAt     // Set the delivery time
+		return nil                               // Return nil
+	})
+}
+
+// Redeliver makes a delivery pending again from now with no failures
+func (r *DeliveryRepository) Redeliver(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	return r.store.update(id, func(d *models.WebhookDelivery) error { // Update the delivery
+		d.Status = models.DeliveryStatusPending // Make the delivery pending
+		d.Failures = 0                          // Reset the backoff
+		d.NextAttemptAt = now                   // Deliver it on the next poll
+		d.DeliveredAt = nil                     // Forget the previous delivery
+		return nil                              // Return nil
+	})
+}
+
+// DeleteByWebhook deletes the deliveries of a webhook
+func (r *DeliveryRepository) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	r.store.removeAll(func(d *models.WebhookDelivery) bool { return d.WebhookID == webhookID }) // Remove the deliveries of the webhook
+	return nil                                                                                  // Return nil
+}
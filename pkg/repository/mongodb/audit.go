@@ -0,0 +1,82 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository stores the audit log in the "audit_log" collection
+type AuditRepository struct {
+	collection *mongo.Collection // Audit entry collection
+}
+
+// Last finds the entry with the highest sequence, returning repository.ErrNotFound when the log is empty
+func (r *AuditRepository) Last(ctx context.Context) (models.AuditEntry, error) {
+	var entry models.AuditEntry                                             // Define an entry variable
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}}) // Take the highest sequence
+	err := r.collection.FindOne(ctx, bson.D{}, opts).Decode(&entry)         // Find the last entry
+	if errors.Is(err, mongo.ErrNoDocuments) {                               // Check if the log is empty
+		return entry, repository.ErrNotFound // Return a not found error
+	}
+	return entry, err // Return the entry
+}
+
+// FindByID finds an entry by ID
+func (r *AuditRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.AuditEntry, error) {
+	return findOne[models.AuditEntry](ctx, r.collection, bson.M{"_id": id}) // Find the entry
+}
+
+// Append inserts an entry, returning repository.ErrConflict if its sequence or ID is taken
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	_, err := r.collection.InsertOne(ctx, entry) // Insert the entry
+	return conflictError(err)                    // Report a taken sequence as a conflict
+}
+
+// Find finds the entries matching the filter, newest first
+func (r *AuditRepository) Find(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEntry, error) {
+	query := bson.M{}          // Define the query
+	if filter.ActorID != nil { // Check if the actor is filtered
+		query["actor_id"] = *filter.ActorID // Match the actor
+	}
+	if filter.Action != "" { // Check if the action is filtered
+		query["action"] = filter.Action // Match the action
+	}
+	if filter.TargetType != "" { // Check if the kind of target is filtered
+		query["target_type"] = filter.TargetType // Match the kind of target
+	}
+	if filter.TargetID != "" { // Check if the target is filtered
+		query["target_id"] = filter.TargetID // Match the target
+	}
+	occurred := bson.M{}       // Define the period
+	if !filter.From.IsZero() { // Check if the period has a start
+		occurred["$gte"] = filter.From // Match the start of the period
+	}
+	if !filter.To.IsZero() { // Check if the period has an end
+		occurred["$lt"] = filter.To // Match the end of the period
+	}
+	if len(occurred) > 0 { // Check if the period is filtered
+		query["occurred_at"] = occurred // Match the period
+	}
+	if filter.Before > 0 { // Check if the entries are paged
+		query["sequence"] = bson.M{"$lt": filter.Before} // Match the page
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: -1}}) // Put the newest first
+	if filter.Limit > 0 {                                                // Check if the entries are limited
+		opts.SetLimit(int64(filter.Limit)) // Limit the entries
+	}
+	return findAll[models.AuditEntry](ctx, r.collection, query, opts) // Find the entries
+}
+
+// FindRange finds the entries following a sequence, in sequence order
+func (r *AuditRepository) FindRange(ctx context.Context, after int64, limit int) ([]models.AuditEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}}).SetLimit(int64(limit))           // Sort by sequence
+	return findAll[models.AuditEntry](ctx, r.collection, bson.M{"sequence": bson.M{"$gt": after}}, opts) // Find the following entries
+}
@@ -0,0 +1,32 @@
+package mongodb
+
+import (
+	"context"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CheckInRepository stores the check-ins in the "check_ins" collection, a unique index on session_id and user_id
+// keeps a user from checking in to a session twice
+type CheckInRepository struct {
+	collection *mongo.Collection // Check-in collection
+}
+
+// Find finds the check-in of a user in a session
+func (r *CheckInRepository) Find(ctx context.Context, sessionID, userID primitive.ObjectID) (models.CheckIn, error) {
+	return findOne[models.CheckIn](ctx, r.collection, bson.M{"session_id": sessionID, "user_id": userID}) // Find the check-in
+}
+
+// FindByUser finds the check-ins of a user, oldest first
+func (r *CheckInRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CheckIn, error) {
+	return findAll[models.CheckIn](ctx, r.collection, bson.M{"user_id": userID}, byCreation("checked_in_at")) // Find the check-ins of the user
+}
+
+// Insert inserts a new check-in, returning repository.ErrConflict if the user already checked in to the session
+func (r *CheckInRepository) Insert(ctx context.Context, checkIn *models.CheckIn) error {
+	_, err := r.collection.InsertOne(ctx, checkIn) // Insert the check-in
+	return conflictError(err)                      // Report a second check-in as a conflict
+}
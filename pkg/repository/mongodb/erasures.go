@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErasureRepository stores the erasure requests in the "erasure_requests" collection, a partial unique index on
+// user_id keeps a user from having two pending requests
+type ErasureRepository struct {
+	collection *mongo.Collection // Erasure request collection
+}
+
+// Find finds the requests, filtered by status when not empty, oldest first
+func (r *ErasureRepository) Find(ctx context.Context, status string) ([]models.ErasureRequest, error) {
+	filter := bson.M{} // Match every request
+	if status != "" {  // Check if the status is filtered
+		filter["status"] = status // Match the status
+	}
+	return findAll[models.ErasureRequest](ctx, r.collection, filter, byCreation("requested_at")) // Find the requests
+}
+
+// FindByUser finds the requests of a user, newest first
+func (r *ErasureRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.ErasureRequest, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "requested_at", Value: -1}, {Key: "_id", Value: -1}}) // Sort by request date, newest first
+	return findAll[models.ErasureRequest](ctx, r.collection, bson.M{"user_id": userID}, opts)         // Find the requests of the user
+}
+
+// FindByID finds a request by ID
+func (r *ErasureRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.ErasureRequest, error) {
+	return findOne[models.ErasureRequest](ctx, r.collection, bson.M{"_id": id}) // Find the request by ID
+}
+
+// Insert inserts a new request, returning repository.ErrConflict if the user already has a pending one
+func (r *ErasureRepository) Insert(ctx context.Context, request *models.ErasureRequest) error {
+	_, err := r.collection.InsertOne(ctx, request) // Insert the request
+	return conflictError(err)                      // Report a second pending request as a conflict
+}
+
+// Review stores the outcome of a pending request, returning repository.ErrNotFound if it is no longer pending
+func (r *ErasureRepository) Review(ctx context.Context, request models.ErasureRequest) error {
+	update := bson.M{"$set": bson.M{ // Set the outcome of the review
+		"status":      request.Status,     // Set the status
+		"reviewed_by": request.ReviewedBy, // Set the reviewer
+		"reviewed_at": request.ReviewedAt, // Set the review time
+		"review_note": request.ReviewNote, // Set the note
+	}}
+	filter := bson.M{"_id": request.ID, "status": models.ErasureStatusPending} // Match the pending request
+	return updateOne(ctx, r.collection, filter, update)                        // Update the request
+}
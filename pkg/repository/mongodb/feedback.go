@@ -0,0 +1,200 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FeedbackRepository stores feedback in the "feedbacks" collection
+type FeedbackRepository struct {
+	collection *mongo.Collection // Feedback collection
+}
+
+// Find finds feedback, oldest first
+func (r *FeedbackRepository) Find(ctx context.Context, filter repository.FeedbackFilter) ([]models.Feedback, error) {
+	query := bson.M{}            // Define the query
+	if !filter.UserID.IsZero() { // Check if the author is given
+		query["user_id"] = filter.UserID // Filter by author
+	}
+	if !filter.CoachID.IsZero() { // Check if the coach is given
+		query["coach_id"] = filter.CoachID // Filter by coach
+	}
+	if !filter.SessionID.IsZero() { // Check if the session is given
+		query["session_id"] = filter.SessionID // Filter by session
+	}
+	if filter.Status != "" { // Check if the status is given
+		query["status"] = filter.Status // Filter by status
+	} else if filter.ExcludeStatus != "" { // Check if a status is excluded
+		query["status"] = bson.M{"$ne": filter.ExcludeStatus} // Exclude the status
+	}
+
+	return findAll[models.Feedback](ctx, r.collection, query, byCreation("created_at")) // Find the feedback
+}
+
+// FindByID finds a feedback by ID
+func (r *FeedbackRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Feedback, error) {
+	return findOne[models.Feedback](ctx, r.collection, bson.M{"_id": id}) // Find the feedback by ID
+}
+
+// Insert inserts a new feedback at version 1
+func (r *FeedbackRepository) Insert(ctx context.Context, feedback *models.Feedback) error {
+	feedback.Version = 1                            // Start at the first version
+	_, err := r.collection.InsertOne(ctx, feedback) // Insert the feedback
+	return err                                      // Return the error
+}
+
+// Edit edits a feedback still at edit.Version and increments the version
+func (r *FeedbackRepository) Edit(ctx context.Context, id primitive.ObjectID, edit repository.FeedbackEdit) error {
+	set := bson.M{ // Define the fields to set
+		"content":    edit.Content,   // Set the content
+		"rating":     edit.Rating,    // Set the rating
+		"anonymous":  edit.Anonymous, // Set the anonymous display choice
+		"updated_at": time.Now(),     // Set the updated_at timestamp
+	}
+	if edit.Answers != nil { // Check if the answers are being changed
+		set["answers"] = edit.Answers // Set the answers
+	}
+	if edit.Status != "" { // Check if the status is being changed
+		set["status"] = edit.Status // Set the status
+	}
+
+	return updateVersioned(ctx, r.collection, id, edit.Version, bson.M{"$set": set}) // Update the feedback
+}
+
+// AddFlag adds a flag to a feedback, changing its status when not empty
+func (r *FeedbackRepository) AddFlag(ctx context.Context, id primitive.ObjectID, flag models.FeedbackFlag, status string) error {
+	set := bson.M{"updated_at": time.Now()} // Define the fields to set
+	if status != "" {                       // Check if the status is being changed
+		set["status"] = status // Set the status
+	}
+
+	update := bumpVersion(bson.M{"$push": bson.M{"flags": flag}, "$set": set}) // Define the update
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)             // Update the feedback
+}
+
+// SetStatus changes the moderation status of a feedback
+func (r *FeedbackRepository) SetStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	update := bumpVersion(bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}}) // Define the update
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)                            // Update the feedback
+}
+
+// SetReply sets the reply of a feedback, returning repository.ErrConflict if it already has one
+func (r *FeedbackRepository) SetReply(ctx context.Context, id primitive.ObjectID, reply models.CoachReply) error {
+	filter := bson.M{"_id": id, "reply": bson.M{"$exists": false}}                          // Only set the reply if there is none yet
+	update := bumpVersion(bson.M{"$set": bson.M{"reply": reply, "updated_at": time.Now()}}) // Define the update
+
+	err := updateOne(ctx, r.collection, filter, update) // Update the feedback
+	if !errors.Is(err, repository.ErrNotFound) {        // Check if the feedback was found
+		return err // Return the error
+	}
+
+	if _, err := r.FindByID(ctx, id); err != nil { // Check if the feedback exists
+		return err // Return the error
+	}
+	return repository.ErrConflict // The feedback already has a reply
+}
+
+// Delete deletes a feedback
+func (r *FeedbackRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id}) // Delete the feedback
+}
+
+// AnonymizeAuthor detaches the feedback of a user from them and marks it anonymous
+func (r *FeedbackRepository) AnonymizeAuthor(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	update := bumpVersion(bson.M{"$set": bson.M{"user_id": primitive.NilObjectID, "anonymous": true}}) // Forget the author and hide them
+	return updateMany(ctx, r.collection, bson.M{"user_id": userID}, update)                            // Update the feedback of the user
+}
+
+// AnonymizeFlags detaches the flags a user raised from them
+func (r *FeedbackRepository) AnonymizeFlags(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	update := bumpVersion(bson.M{"$set": bson.M{"flags.$[flag].user_id": primitive.NilObjectID}})                          // Forget who raised the flags
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"flag.user_id": userID}}}) // Match the flags of the user
+	result, err := r.collection.UpdateMany(ctx, bson.M{"flags.user_id": userID}, update, opts)                             // Update the feedback the user flagged
+	if err != nil {                                                                                                        // Check if there is an error
+		return 0, err // Return the error
+	}
+	return result.ModifiedCount, nil // Return the number of changed feedback
+}
+
+// AnonymizeReplies detaches the replies a coach posted from them
+func (r *FeedbackRepository) AnonymizeReplies(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	update := bumpVersion(bson.M{"$set": bson.M{"reply.coach_id": primitive.NilObjectID}}) // Forget who replied
+	return updateMany(ctx, r.collection, bson.M{"reply.coach_id": userID}, update)         // Update the feedback the coach replied to
+}
+
+// FeedbackFormRepository stores feedback form templates in the "feedback_forms" collection
+type FeedbackFormRepository struct {
+	collection *mongo.Collection // Feedback form collection
+}
+
+// FindAll finds the forms, filtered by training type when not empty
+func (r *FeedbackFormRepository) FindAll(ctx context.Context, trainingType string) ([]models.FeedbackForm, error) {
+	filter := bson.M{}      // Define the filter
+	if trainingType != "" { // Check if a training type was given
+		filter["training_type"] = trainingType // Filter by training type
+	}
+	return findAll[models.FeedbackForm](ctx, r.collection, filter) // Find the forms
+}
+
+// FindByID finds a form by ID
+func (r *FeedbackFormRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.FeedbackForm, error) {
+	return findOne[models.FeedbackForm](ctx, r.collection, bson.M{"_id": id}) // Find the form by ID
+}
+
+// FindByIDs finds the forms with the given IDs
+func (r *FeedbackFormRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.FeedbackForm, error) {
+	return findAll[models.FeedbackForm](ctx, r.collection, bson.M{"_id": bson.M{"$in": ids}}) // Find the forms by ID
+}
+
+// FindByOwner finds the form an owner defined for a training type
+func (r *FeedbackFormRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID, trainingType string) (models.FeedbackForm, error) {
+	return findOne[models.FeedbackForm](ctx, r.collection, bson.M{"owner_id": ownerID, "training_type": trainingType}) // Find the form by owner and training type
+}
+
+// Insert inserts a new form, returning repository.ErrConflict when the owner already has a form for the training type
+func (r *FeedbackFormRepository) Insert(ctx context.Context, form *models.FeedbackForm) error {
+	form.Version = 1                            // Start at the first version
+	_, err := r.collection.InsertOne(ctx, form) // Insert the form
+	return conflictError(err)                   // Translate a duplicate owner and training type
+}
+
+// Update updates the definition of a form still at form.Version and increments the version, returning
+// repository.ErrConflict when the owner already has a form for the training type
+func (r *FeedbackFormRepository) Update(ctx context.Context, form models.FeedbackForm) error {
+	update := bson.M{"$set": bson.M{ // Define the update
+		"training_type": form.TrainingType, // Update the training type
+		"title":         form.Title,        // Update the title
+		"criteria":      form.Criteria,     // Update the criteria
+		"updated_at":    form.UpdatedAt,    // Update the updated_at timestamp
+	}}
+	return conflictError(updateVersioned(ctx, r.collection, form.ID, form.Version, update)) // Update the form if nobody changed it in the meantime
+}
+
+// Delete deletes a form
+func (r *FeedbackFormRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id}) // Delete the form
+}
+
+// ModerationLogRepository stores the audit log of feedback moderation in the "feedback_moderation_log" collection
+type ModerationLogRepository struct {
+	collection *mongo.Collection // Moderation log collection
+}
+
+// FindByFeedback finds the actions performed on a feedback, oldest first
+func (r *ModerationLogRepository) FindByFeedback(ctx context.Context, feedbackID primitive.ObjectID) ([]models.ModerationAction, error) {
+	return findAll[models.ModerationAction](ctx, r.collection, bson.M{"feedback_id": feedbackID}, byCreation("created_at")) // Find the actions
+}
+
+// Insert appends an action
+func (r *ModerationLogRepository) Insert(ctx context.Context, action *models.ModerationAction) error {
+	_, err := r.collection.InsertOne(ctx, action) // Insert the action
+	return err                                    // Return the error
+}
@@ -0,0 +1,59 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// IdempotencyRepository stores idempotency keys in the "idempotency_keys" collection, expired keys are
+// removed by the TTL index on expires_at
+type IdempotencyRepository struct {
+	collection *mongo.Collection // Idempotency key collection
+}
+
+// Reserve inserts the record of a new key, taking over the keys that expired or whose request was abandoned
+// before staleBefore; a key already held or completed is returned with repository.ErrConflict
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, error) {
+	_, err := r.collection.InsertOne(ctx, record) // Insert the record
+	if err == nil {                               // Check if the key was free
+		return record, nil // Return the reserved record
+	}
+	if !mongo.IsDuplicateKeyError(err) { // Check if the insert failed for another reason
+		return models.IdempotencyRecord{}, err // Return the error
+	}
+
+	filter := bson.M{"_id": record.ID, "$or": bson.A{ // Match a key that can be taken over
+		bson.M{"expires_at": bson.M{"$lte": record.CreatedAt}},                                     // Expired, not yet removed by the TTL monitor
+		bson.M{"status": models.IdempotencyStatusPending, "locked_at": bson.M{"$lt": staleBefore}}, // Abandoned by a request that never finished
+	}}
+	result, err := r.collection.ReplaceOne(ctx, filter, record) // Take the key over
+	if err != nil {                                             // Check if there is an error
+		return models.IdempotencyRecord{}, err // Return the error
+	}
+	if result.MatchedCount > 0 { // Check if the key was taken over
+		return record, nil // Return the reserved record
+	}
+
+	current, err := findOne[models.IdempotencyRecord](ctx, r.collection, bson.M{"_id": record.ID}) // Find the record holding the key
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {                                     // Check if there is an error
+		return models.IdempotencyRecord{}, err // Return the error
+	}
+	return current, repository.ErrConflict // Report the key as held
+}
+
+// Complete stores the response of the request holding a key
+func (r *IdempotencyRepository) Complete(ctx context.Context, id string, response models.IdempotentResponse) error {
+	update := bson.M{"$set": bson.M{"status": models.IdempotencyStatusCompleted, "response": response}} // Define the update
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)                                      // Store the response
+}
+
+// Release deletes a key
+func (r *IdempotencyRepository) Release(ctx context.Context, id string) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id}) // Delete the key
+}
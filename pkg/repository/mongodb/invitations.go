@@ -0,0 +1,88 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// InvitationRepository stores session invitations in the "invitations" collection
+type InvitationRepository struct {
+	collection *mongo.Collection // Invitation collection
+}
+
+// FindAll finds all invitations
+func (r *InvitationRepository) FindAll(ctx context.Context) ([]models.Invitation, error) {
+	return findAll[models.Invitation](ctx, r.collection, live(bson.M{})) // Find all invitations
+}
+
+// FindByID finds an invitation by ID
+func (r *InvitationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Invitation, error) {
+	return findOne[models.Invitation](ctx, r.collection, live(bson.M{"_id": id})) // Find the invitation by ID
+}
+
+// Insert inserts a new invitation
+func (r *InvitationRepository) Insert(ctx context.Context, invitation *models.Invitation) error {
+	_, err := r.collection.InsertOne(ctx, invitation) // Insert the invitation
+	return err                                        // Return the error
+}
+
+// SetStatus changes the status of an invitation
+func (r *InvitationRepository) SetStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	update := bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}} // Set the status and the updated_at timestamp
+	return updateOne(ctx, r.collection, live(bson.M{"_id": id}), update)         // Update the live invitation
+}
+
+// FindDeleted finds the soft deleted invitations, last deleted first
+func (r *InvitationRepository) FindDeleted(ctx context.Context) ([]models.Invitation, error) {
+	return findDeleted[models.Invitation](ctx, r.collection) // Find the deleted invitations
+}
+
+// SoftDelete hides an invitation, returning repository.ErrNotFound if it is missing or already deleted
+func (r *InvitationRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return softDelete(ctx, r.collection, id, deletion, false) // Soft delete the invitation
+}
+
+// Restore shows a soft deleted invitation again and returns the undone deletion
+func (r *InvitationRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return restore(ctx, r.collection, id, false) // Restore the invitation
+}
+
+// Purge removes the invitations soft deleted before the given time
+func (r *InvitationRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, r.collection, before) // Remove the expired invitations
+}
+
+// SoftDeleteBySession hides the live invitations to a session
+func (r *InvitationRepository) SoftDeleteBySession(ctx context.Context, sessionID primitive.ObjectID, deletion models.Deletion) error {
+	_, err := r.collection.UpdateMany(ctx, live(bson.M{"session_id": sessionID}), bson.M{"$set": deletion}) // Soft delete the invitations
+	return err                                                                                              // Return the error
+}
+
+// RestoreBySession shows again the invitations to a session soft deleted at the given time
+func (r *InvitationRepository) RestoreBySession(ctx context.Context, sessionID primitive.ObjectID, deletedAt time.Time) error {
+	update := bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": "", "deletion_reason": ""}}            // Forget the deletion
+	_, err := r.collection.UpdateMany(ctx, bson.M{"session_id": sessionID, "deleted_at": deletedAt}, update) // Restore the invitations
+	return err                                                                                               // Return the error
+}
+
+// SoftDeleteByUser hides the live invitations of a user
+func (r *InvitationRepository) SoftDeleteByUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion) (int64, error) {
+	return updateMany(ctx, r.collection, live(bson.M{"user_id": userID}), bson.M{"$set": deletion}) // Soft delete the invitations
+}
+
+// RestoreByUser shows again the invitations of a user soft deleted at the given time
+func (r *InvitationRepository) RestoreByUser(ctx context.Context, userID primitive.ObjectID, deletedAt time.Time) error {
+	update := bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": "", "deletion_reason": ""}}       // Forget the deletion
+	_, err := updateMany(ctx, r.collection, bson.M{"user_id": userID, "deleted_at": deletedAt}, update) // Restore the invitations
+	return err                                                                                          // Return the error
+}
+
+// FindByUser finds the invitations of a user
+func (r *InvitationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Invitation, error) {
+	return findAll[models.Invitation](ctx, r.collection, live(bson.M{"user_id": userID})) // Find the invitations by user ID
+}
@@ -0,0 +1,171 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	migrationsCollection  = "migrations"    // Collection recording the applied migrations and holding the lock
+	migrationLockID       = "lock"          // ID of the lock document of the migration runners
+	migrationLockInterval = 2 * time.Second // How often a runner waiting for the lock tries again
+)
+
+// Migration is a change of the indexes, validators or data of the database. Migrations are applied once, in
+// version order, and recorded in the "migrations" collection; an applied migration must never be edited.
+type Migration struct {
+	Version     int                                                       // Version of the migration, unique and increasing
+	Description string                                                    // What the migration changes
+	Up          func(ctx context.Context, database *mongo.Database) error // Apply the migration, safe to run again after a failure
+}
+
+// migrationRecord is the document recording an applied migration
+type migrationRecord struct {
+	Version     int       `bson:"_id"`         // Version of the migration
+	Description string    `bson:"description"` // What the migration changed
+	AppliedAt   time.Time `bson:"applied_at"`  // Timestamp when the migration was applied
+	DurationMS  int64     `bson:"duration_ms"` // Time the migration took, in milliseconds
+}
+
+// Migrator applies the pending migrations of a database. Runners exclude each other with a lock document,
+// so every instance of the service may run them on startup: the first one applies them, the others wait.
+type Migrator struct {
+	database    *mongo.Database   // Database to migrate
+	collection  *mongo.Collection // Collection of the applied migrations
+	migrations  []Migration       // Known migrations in version order
+	lockTimeout time.Duration     // How long a runner that stopped without releasing the lock holds it
+	logger      *slog.Logger      // Logger of the applied migrations
+}
+
+// NewMigrator creates the migrator of the database with the migrations of this package
+func NewMigrator(database *mongo.Database, lockTimeout time.Duration, logger *slog.Logger) *Migrator { // Create the migrator
+	return &Migrator{
+		database:    database,                                  // Set the database
+		collection:  database.Collection(migrationsCollection), // Set the collection of the applied migrations
+		migrations:  migrations,                                // Set the migrations
+		lockTimeout: lockTimeout,                               // Set the lock timeout
+		logger:      logger,                                    // Set the logger
+	}
+}
+
+// Pending returns the migrations not applied yet, in version order
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) { // List the pending migrations
+	records, err := findAll[migrationRecord](ctx, m.collection, bson.M{"_id": bson.M{"$type": "number"}}) // Find the applied migrations, not the lock
+	if err != nil {                                                                                       // Check if there is an error
+		return nil, fmt.Errorf("failed to list the applied migrations: %w", err) // Return the error
+	}
+	applied := make(map[int]bool, len(records)) // Define the applied versions
+	for _, record := range records {            // Iterate over the applied migrations
+		applied[record.Version] = true // Mark the version as applied
+	}
+
+	var pending []Migration                  // Define a slice to hold the pending migrations
+	for _, migration := range m.migrations { // Iterate over the migrations
+		if !applied[migration.Version] { // Check if the migration was not applied
+			pending = append(pending, migration) // Add the migration
+		}
+	}
+	return pending, nil // Return the pending migrations
+}
+
+// Up applies the pending migrations and returns how many were applied. It waits for the lock while another
+// runner holds it, until ctx is done, and stops at the first migration that fails.
+func (m *Migrator) Up(ctx context.Context) (int, error) { // Apply the pending migrations
+	if err := checkMigrations(m.migrations); err != nil { // Check the order of the migrations
+		return 0, err // Return the error
+	}
+
+	owner := lockOwner()                       // Identify this runner
+	if err := m.lock(ctx, owner); err != nil { // Wait for the lock
+		return 0, err // Return the error
+	}
+	defer m.unlock(context.WithoutCancel(ctx), owner) // Release the lock, even if ctx is done
+
+	pending, err := m.Pending(ctx) // List the migrations still pending once the lock is held
+	if err != nil {                // Check if there is an error
+		return 0, err // Return the error
+	}
+	for i, migration := range pending { // Iterate over the pending migrations
+		m.logger.InfoContext(ctx, "Applying migration", slog.Int("version", migration.Version), slog.String("description", migration.Description)) // Log the migration
+		start := time.Now()                                                                                                                        // Time the migration
+		if err := migration.Up(ctx, m.database); err != nil {                                                                                      // Apply the migration
+			return i, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err) // Return the error
+		}
+
+		record := migrationRecord{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now(), DurationMS: time.Since(start).Milliseconds()} // Define the record
+		if _, err := m.collection.InsertOne(ctx, record); err != nil {                                                                                                 // Record the migration
+			return i, fmt.Errorf("failed to record migration %d: %w", migration.Version, err) // Return the error
+		}
+		if err := m.refreshLock(ctx, owner); err != nil { // Keep the lock for the next migration
+			return i + 1, err // Return the error
+		}
+	}
+	return len(pending), nil // Return the number of applied migrations
+}
+
+// lock waits until this runner holds the lock. The lock is taken over when its holder did not refresh it for
+// the lock timeout, so a runner that crashed does not block the deployments forever.
+func (m *Migrator) lock(ctx context.Context, owner string) error { // Take the lock
+	for { // Try until the lock is taken or ctx is done
+		now := time.Now()                                                                             // Get the current time
+		filter := bson.M{"_id": migrationLockID, "locked_at": bson.M{"$lt": now.Add(-m.lockTimeout)}} // Match a free or abandoned lock
+		update := bson.M{"$set": bson.M{"owner": owner, "locked_at": now}}                            // Hold the lock from now on
+		_, err := m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))       // Take the lock, inserting it when missing
+		if err == nil {                                                                               // Check if the lock was taken
+			return nil // The lock is held
+		}
+		if !mongo.IsDuplicateKeyError(err) { // Check if the lock is not held by another runner
+			return fmt.Errorf("failed to take the migration lock: %w", err) // Return the error
+		}
+
+		m.logger.InfoContext(ctx, "Waiting for another instance to finish the migrations") // Log the wait
+		select {
+		case <-ctx.Done(): // Stop waiting
+			return fmt.Errorf("gave up waiting for the migration lock: %w", ctx.Err()) // Return the error
+		case <-time.After(migrationLockInterval): // Try again
+		}
+	}
+}
+
+// refreshLock tells the other runners this one is still alive
+func (m *Migrator) refreshLock(ctx context.Context, owner string) error { // Refresh the lock
+	err := updateOne(ctx, m.collection, bson.M{"_id": migrationLockID, "owner": owner}, bson.M{"$set": bson.M{"locked_at": time.Now()}}) // Move the lock timestamp
+	if err != nil {                                                                                                                      // Check if there is an error
+		return fmt.Errorf("lost the migration lock: %w", err) // Another runner took the lock over
+	}
+	return nil // The lock is still held
+}
+
+// unlock releases the lock if this runner still holds it
+func (m *Migrator) unlock(ctx context.Context, owner string) { // Release the lock
+	if _, err := m.collection.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": owner}); err != nil { // Delete the lock
+		m.logger.ErrorContext(ctx, "Failed to release the migration lock", slog.Any("error", err)) // Log the error message
+	}
+}
+
+// lockOwner identifies a runner in the lock document, with the host name to find it when it is stuck
+func lockOwner() string { // Identify this runner
+	host, _ := os.Hostname()                          // Get the host name
+	return host + "/" + primitive.NewObjectID().Hex() // Return a unique owner
+}
+
+// checkMigrations checks that the versions of the migrations are positive, unique and increasing
+func checkMigrations(list []Migration) error { // Check the migrations
+	versions := make([]int, len(list)) // Define a slice to hold the versions
+	for i, migration := range list {   // Iterate over the migrations
+		versions[i] = migration.Version // Add the version
+	}
+	if !slices.IsSorted(versions) || len(slices.Compact(slices.Clone(versions))) != len(versions) || (len(versions) > 0 && versions[0] < 1) { // Check the versions
+		return fmt.Errorf("migrations must have positive, unique and increasing versions, got %v", versions) // Return the error
+	}
+	return nil // The migrations are valid
+}
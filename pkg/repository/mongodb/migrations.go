@@ -0,0 +1,518 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations lists the migrations of the database in version order. Append new migrations at the end and
+// never edit or remove one that was released, the databases that applied it would not apply it again.
+var migrations = []Migration{
+	{Version: 1, Description: "Expire the idempotency keys with a TTL index", Up: createIdempotencyTTLIndex},
+	{Version: 2, Description: "Lowercase the user emails", Up: lowercaseUserEmails},
+	{Version: 3, Description: "Make the user emails unique", Up: createUniqueEmailIndex},
+	{Version: 4, Description: "Index the lookups of sessions, pitch bookings, feedback and notifications", Up: createLookupIndexes},
+	{Version: 5, Description: "Validate the users, sessions, pitch bookings and feedback with JSON schemas", Up: createValidators},
+	{Version: 6, Description: "Rename the camelCase fields to snake_case", Up: renameCamelCaseFields},
+	{Version: 7, Description: "Store the session and invitation references as ObjectIDs", Up: convertStringReferences},
+	{Version: 8, Description: "Index the pending events of the outbox and expire the published ones", Up: createOutboxIndexes},
+	{Version: 9, Description: "Index the webhooks and their deliveries and expire the succeeded deliveries", Up: createWebhookIndexes},
+	{Version: 10, Description: "Index the soft deleted sessions, users, invitations and pitch bookings", Up: createDeletionIndexes},
+	{Version: 11, Description: "Make the audit log sequence unique and index the audit log filters", Up: createAuditIndexes},
+	{Version: 12, Description: "Index the user and session references cleaned up when a user or session is deleted", Up: createCascadeIndexes},
+	{Version: 13, Description: "Allow one pending erasure request per user and index the approval queue", Up: createErasureIndexes},
+	{Version: 14, Description: "Allow one feedback form per owner and training type", Up: createFeedbackFormIndexes},
+	{Version: 15, Description: "Approve the feedback submitted before moderation", Up: approveLegacyFeedback},
+	{Version: 16, Description: "Allow one check-in per user and session and index the check-ins of a user", Up: createCheckInIndexes},
+	{Version: 17, Description: "Give the sessions created before they had an owner to their coach", Up: setSessionOwners},
+}
+
+// createIdempotencyTTLIndex removes the idempotency keys once they expire
+func createIdempotencyTTLIndex(ctx context.Context, database *mongo.Database) error { // Create the TTL index
+	idempotencyTTL := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},                              // Index the expiry date
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0), // Delete the documents at their expiry date
+	}
+	_, err := database.Collection("idempotency_keys").Indexes().CreateOne(ctx, idempotencyTTL) // Create the TTL index
+	return err                                                                                 // Return the error
+}
+
+// lowercaseUserEmails trims and lowercases the emails registered before they were normalized, so the same
+// address written differently is detected as a duplicate by the unique index
+func lowercaseUserEmails(ctx context.Context, database *mongo.Database) error { // Normalize the emails
+	normalized := bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$email"}}}                               // Define the normalized email
+	filter := bson.M{"email": bson.M{"$type": "string"}, "$expr": bson.M{"$ne": bson.A{"$email", normalized}}} // Match the emails to normalize
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"email": normalized}}}}                              // Replace the email with its normalized form
+	_, err := database.Collection("users").UpdateMany(ctx, filter, update)                                     // Normalize the emails
+	return err                                                                                                 // Return the error
+}
+
+// createUniqueEmailIndex prevents the registration of several accounts with the same email. The accounts
+// already sharing an email must be merged by hand first, the migration fails listing them.
+func createUniqueEmailIndex(ctx context.Context, database *mongo.Database) error { // Create the unique index
+	users := database.Collection("users") // Get the user collection
+
+	pipeline := mongo.Pipeline{ // Find the emails used more than once
+		{{Key: "$match", Value: bson.M{"email": bson.M{"$type": "string"}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$email", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+	cursor, err := users.Aggregate(ctx, pipeline) // Run the aggregation
+	if err != nil {                               // Check if there is an error
+		return err // Return the error
+	}
+	var duplicates []struct {
+		Email string `bson:"_id"` // Email used more than once
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil { // Decode the duplicates
+		return err // Return the error
+	}
+	if len(duplicates) > 0 { // Check if some accounts share an email
+		emails := make([]string, len(duplicates)) // Define a slice to hold the emails
+		for i, duplicate := range duplicates {    // Iterate over the duplicates
+			emails[i] = duplicate.Email // Add the email
+		}
+		slices.Sort(emails)                                                                                                                 // Report the emails in a stable order
+		return fmt.Errorf("%d emails are used by several users, merge or delete the accounts: %s", len(emails), strings.Join(emails, ", ")) // Return the error
+	}
+
+	partial := bson.M{"email": bson.M{"$type": "string"}}                                               // Ignore the users without an email
+	opts := options.Index().SetName("email_unique").SetUnique(true).SetPartialFilterExpression(partial) // Reject a second user with the same email
+	index := mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}, Options: opts}                    // Index the email
+	_, err = users.Indexes().CreateOne(ctx, index)                                                      // Create the unique index
+	return err                                                                                          // Return the error
+}
+
+// createLookupIndexes indexes the fields the repositories filter on
+func createLookupIndexes(ctx context.Context, database *mongo.Database) error { // Create the lookup indexes
+	indexes := map[string][]mongo.IndexModel{ // Define the indexes of each collection
+		"sessions": {
+			{Keys: bson.D{{Key: "coach", Value: 1}}},        // Sessions of a coach
+			{Keys: bson.D{{Key: "participants", Value: 1}}}, // Sessions of a participant
+			{Keys: bson.D{{Key: "status", Value: 1}}},       // Sessions by status
+		},
+		"pitch_bookings": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}}}, // Pitch bookings of a user
+		},
+		"feedbacks": {
+			{Keys: bson.D{{Key: "coach_id", Value: 1}, {Key: "created_at", Value: 1}}},   // Feedback of a coach, oldest first
+			{Keys: bson.D{{Key: "session_id", Value: 1}, {Key: "created_at", Value: 1}}}, // Feedback of a session, oldest first
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}},    // Feedback of an author, oldest first
+			{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},     // Moderation queue, oldest first
+		},
+		"feedback_moderation_log": {
+			{Keys: bson.D{{Key: "feedback_id", Value: 1}, {Key: "created_at", Value: 1}}}, // Moderation log of a feedback
+		},
+		"notifications": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "createdAt", Value: 1}}}, // Notifications of a user, oldest first
+		},
+	}
+	for _, name := range sortedKeys(indexes) { // Iterate over the collections in a stable order
+		if _, err := database.Collection(name).Indexes().CreateMany(ctx, indexes[name]); err != nil { // Create the indexes
+			return fmt.Errorf("failed to index %s: %w", name, err) // Return the error
+		}
+	}
+	return nil // Return nil
+}
+
+// createValidators makes the server reject the writes of malformed users, sessions, pitch bookings and
+// feedback. The "moderate" level leaves the documents that were already invalid writable.
+func createValidators(ctx context.Context, database *mongo.Database) error { // Create the validators
+	for _, name := range sortedKeys(initialSchemas) { // Iterate over the collections in a stable order
+		if err := setValidator(ctx, database, name, initialSchemas[name]); err != nil { // Set the validator
+			return fmt.Errorf("failed to set the validator of %s: %w", name, err) // Return the error
+		}
+	}
+	return nil // Return nil
+}
+
+// setValidator sets the JSON schema of a collection, creating the collection when it does not exist yet
+func setValidator(ctx context.Context, database *mongo.Database, name string, schema bson.M) error { // Set the validator of a collection
+	validator := bson.M{"$jsonSchema": schema}                            // Define the validator
+	names, err := database.ListCollectionNames(ctx, bson.M{"name": name}) // Check if the collection exists
+	if err != nil {                                                       // Check if there is an error
+		return err // Return the error
+	}
+	if len(names) == 0 { // Check if the collection must be created
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate").SetValidationAction("error") // Define the collection options
+		return database.CreateCollection(ctx, name, opts)                                                                      // Create the collection
+	}
+	command := bson.D{{Key: "collMod", Value: name}, {Key: "validator", Value: validator}, {Key: "validationLevel", Value: "moderate"}, {Key: "validationAction", Value: "error"}} // Define the command
+	return database.RunCommand(ctx, command).Err()                                                                                                                                 // Set the validator
+}
+
+// camelCaseFields maps the camelCase fields of each collection to their snake_case names
+var camelCaseFields = map[string]bson.M{
+	"sessions": {
+		"startTime":    "start_time",
+		"endTime":      "end_time",
+		"trainingType": "training_type",
+		"coachAssists": "coach_assists",
+		"qrCode":       "qr_code",
+		"createdAt":    "created_at",
+		"updatedAt":    "updated_at",
+	},
+	"users":          {"createdAt": "created_at", "updatedAt": "updated_at"},
+	"pitch_bookings": {"createdAt": "created_at", "updatedAt": "updated_at"},
+	"notifications":  {"createdAt": "created_at", "updatedAt": "updated_at"},
+	"invitations":    {"createdAt": "created_at", "updatedAt": "updated_at"},
+}
+
+// renameCamelCaseFields renames the fields written in camelCase so every collection uses the snake_case names
+// of the JSON views. The session validator moves to the new names first: the renamed documents would break
+// the old one, while the moderate level lets documents that break the new one be renamed.
+func renameCamelCaseFields(ctx context.Context, database *mongo.Database) error { // Rename the fields
+	if err := setValidator(ctx, database, "sessions", sessionSchema); err != nil { // Validate the new names
+		return fmt.Errorf("failed to set the validator of sessions: %w", err) // Return the error
+	}
+
+	for _, name := range sortedKeys(camelCaseFields) { // Iterate over the collections in a stable order
+		renames := camelCaseFields[name]          // Get the renamed fields
+		present := make(bson.A, 0, len(renames))  // Define the conditions matching the documents to rename
+		for _, old := range sortedKeys(renames) { // Iterate over the old names
+			present = append(present, bson.M{old: bson.M{"$exists": true}}) // Match the documents with the old name
+		}
+		filter := bson.M{"$or": present}                                                                         // Match the documents with an old name
+		if _, err := database.Collection(name).UpdateMany(ctx, filter, bson.M{"$rename": renames}); err != nil { // Rename the fields
+			return fmt.Errorf("failed to rename the fields of %s: %w", name, err) // Return the error
+		}
+	}
+
+	notifications := database.Collection("notifications")                          // Get the notification collection
+	if err := dropIndex(ctx, notifications, "user_id_1_createdAt_1"); err != nil { // Drop the index of the old name
+		return err // Return the error
+	}
+	index := mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: 1}}} // Notifications of a user, oldest first
+	_, err := notifications.Indexes().CreateOne(ctx, index)                                            // Index the new name
+	return err                                                                                         // Return the error
+}
+
+// convertStringReferences converts the session coach, assistants and participants and the invitation session
+// and user, stored as hex strings, to ObjectIDs like the other references, so they match the IDs they point
+// to. A string that is not a valid ID never referenced anything: it is dropped from the lists and set to null
+// otherwise.
+func convertStringReferences(ctx context.Context, database *mongo.Database) error { // Convert the references
+	sessions := bson.M{"$or": bson.A{ // Match the sessions with a string reference
+		bson.M{"coach": bson.M{"$type": "string"}},         // String coach
+		bson.M{"coach_assists": bson.M{"$type": "string"}}, // String assistant
+		bson.M{"participants": bson.M{"$type": "string"}},  // String participant
+	}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{ // Convert the references
+		"coach":         toObjectID("$coach"),          // Convert the coach
+		"coach_assists": toObjectIDs("$coach_assists"), // Convert the assistants
+		"participants":  toObjectIDs("$participants"),  // Convert the participants
+	}}}}
+	if _, err := database.Collection("sessions").UpdateMany(ctx, sessions, update); err != nil { // Convert the sessions
+		return fmt.Errorf("failed to convert the references of sessions: %w", err) // Return the error
+	}
+
+	invitations := bson.M{"$or": bson.A{ // Match the invitations with a string reference
+		bson.M{"session_id": bson.M{"$type": "string"}}, // String session
+		bson.M{"user_id": bson.M{"$type": "string"}},    // String user
+	}}
+	update = mongo.Pipeline{{{Key: "$set", Value: bson.M{ // Convert the references
+		"session_id": toObjectID("$session_id"), // Convert the session
+		"user_id":    toObjectID("$user_id"),    // Convert the user
+	}}}}
+	if _, err := database.Collection("invitations").UpdateMany(ctx, invitations, update); err != nil { // Convert the invitations
+		return fmt.Errorf("failed to convert the references of invitations: %w", err) // Return the error
+	}
+	return nil // Return nil
+}
+
+// outboxRetention is how long the published events are kept in the outbox, to investigate a consumer
+const outboxRetention = 7 * 24 * time.Hour
+
+// createOutboxIndexes indexes the pending events in the order the relay publishes them, and removes the
+// published events after the retention; pending events have no published_at and are never removed
+func createOutboxIndexes(ctx context.Context, database *mongo.Database) error { // Create the outbox indexes
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}}}, // Pending events, oldest first
+		{
+			Keys:    bson.D{{Key: "published_at", Value: 1}},                                                                 // Index the publication date
+			Options: options.Index().SetName("published_at_ttl").SetExpireAfterSeconds(int32(outboxRetention / time.Second)), // Delete the published events after the retention
+		},
+	}
+	_, err := database.Collection("outbox").Indexes().CreateMany(ctx, indexes) // Create the indexes
+	return err                                                                 // Return the error
+}
+
+// deliveryRetention is how long the succeeded webhook deliveries are kept, with the log of their attempts
+const deliveryRetention = 30 * 24 * time.Hour
+
+// createWebhookIndexes indexes the webhooks by owner and event type, makes an event delivered once to each webhook,
+// indexes the due deliveries and the delivery log of a webhook, and removes the succeeded deliveries after the
+// retention; pending and dead deliveries have no delivered_at and are never removed
+func createWebhookIndexes(ctx context.Context, database *mongo.Database) error { // Create the webhook indexes
+	webhooks := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: 1}}}, // Webhooks of a business owner
+		{Keys: bson.D{{Key: "active", Value: 1}, {Key: "event_types", Value: 1}}},  // Webhooks subscribed to an event type
+	}
+	if _, err := database.Collection("webhooks").Indexes().CreateMany(ctx, webhooks); err != nil { // Create the webhook indexes
+		return err // Return the error
+	}
+
+	deliveries := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "event_id", Value: 1}}, Options: options.Index().SetUnique(true)}, // One delivery per webhook and event
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},                                        // Due deliveries, oldest first
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},                                        // Delivery log of a webhook, newest first
+		{
+			Keys:    bson.D{{Key: "delivered_at", Value: 1}},                                                                   // Index the delivery date
+			Options: options.Index().SetName("delivered_at_ttl").SetExpireAfterSeconds(int32(deliveryRetention / time.Second)), // Delete the succeeded deliveries after the retention
+		},
+	}
+	_, err := database.Collection("webhook_deliveries").Indexes().CreateMany(ctx, deliveries) // Create the delivery indexes
+	return err                                                                                // Return the error
+}
+
+// createDeletionIndexes indexes the deletion date of the soft deleted documents, listed to the admins and purged
+// after the retention; the index is sparse as the live documents have no deletion date
+func createDeletionIndexes(ctx context.Context, database *mongo.Database) error { // Create the deletion indexes
+	for _, name := range []string{"sessions", "users", "invitations", "pitch_bookings"} { // Iterate over the soft deleted collections
+		deletedAt := mongo.IndexModel{
+			Keys:    bson.D{{Key: "deleted_at", Value: -1}},                // Index the deletion date, last deleted first
+			Options: options.Index().SetName("deleted_at").SetSparse(true), // Leave the live documents out
+		}
+		if _, err := database.Collection(name).Indexes().CreateOne(ctx, deletedAt); err != nil { // Create the deletion index
+			return fmt.Errorf("failed to index the deletions of %s: %w", name, err) // Return the error
+		}
+	}
+	return nil // Return nil
+}
+
+// createAuditIndexes makes each position of the audit log chain taken once, so concurrent appends cannot fork it,
+// and indexes the filters of the admins
+func createAuditIndexes(ctx context.Context, database *mongo.Database) error { // Create the audit log indexes
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sequence", Value: 1}}, Options: options.Index().SetUnique(true)},                      // One entry per position of the chain
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "sequence", Value: -1}}},                                  // Entries of a user, newest first
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "sequence", Value: -1}}}, // Entries of a document, newest first
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "sequence", Value: -1}}},                                    // Entries of a route, newest first
+		{Keys: bson.D{{Key: "occurred_at", Value: 1}}},                                                             // Entries of a period
+	}
+	_, err := database.Collection("audit_log").Indexes().CreateMany(ctx, indexes) // Create the indexes
+	return err                                                                    // Return the error
+}
+
+// createCascadeIndexes indexes the references looked up when a user or a session is deleted that no query indexed
+// yet: the invitations of a user or a session and the sessions a user assists
+func createCascadeIndexes(ctx context.Context, database *mongo.Database) error { // Create the cascade indexes
+	indexes := map[string][]mongo.IndexModel{
+		"invitations": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},    // Invitations of a user
+			{Keys: bson.D{{Key: "session_id", Value: 1}}}, // Invitations to a session
+		},
+		"sessions": {
+			{Keys: bson.D{{Key: "coach_assists", Value: 1}}}, // Sessions assisted by a user
+		},
+	}
+	for _, name := range sortedKeys(indexes) { // Iterate over the collections in a stable order
+		if _, err := database.Collection(name).Indexes().CreateMany(ctx, indexes[name]); err != nil { // Create the indexes
+			return fmt.Errorf("failed to index %s: %w", name, err) // Return the error
+		}
+	}
+	return nil // Return nil
+}
+
+// createErasureIndexes keeps a user from having two pending erasure requests and indexes the approval queue and
+// the requests of a user
+func createErasureIndexes(ctx context.Context, database *mongo.Database) error { // Create the erasure request indexes
+	pending := bson.M{"status": models.ErasureStatusPending} // Match the pending requests
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetName("pending_user_id").SetUnique(true).SetPartialFilterExpression(pending)}, // One pending request per user
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "requested_at", Value: 1}}},                                                                          // Approval queue, oldest first
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "requested_at", Value: -1}}},                                                                        // Requests of a user, newest first
+	}
+	_, err := database.Collection("erasure_requests").Indexes().CreateMany(ctx, indexes) // Create the indexes
+	return err                                                                           // Return the error
+}
+
+// createFeedbackFormIndexes keeps a business owner from defining two forms for a training type and indexes the
+// lookup of the oldest form of a training type. The duplicated forms must be merged or deleted by hand first,
+// the migration fails listing them.
+func createFeedbackFormIndexes(ctx context.Context, database *mongo.Database) error { // Create the feedback form indexes
+	forms := database.Collection("feedback_forms") // Get the feedback form collection
+
+	pipeline := mongo.Pipeline{ // Find the training types an owner defined several forms for
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"owner_id": "$owner_id", "training_type": "$training_type"}, "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+	cursor, err := forms.Aggregate(ctx, pipeline) // Run the aggregation
+	if err != nil {                               // Check if there is an error
+		return err // Return the error
+	}
+	var duplicates []struct {
+		Key struct {
+			OwnerID      interface{} `bson:"owner_id"`      // Owner of the forms
+			TrainingType string      `bson:"training_type"` // Training type of the forms
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil { // Decode the duplicates
+		return err // Return the error
+	}
+	if len(duplicates) > 0 { // Check if some owners defined several forms for a training type
+		keys := make([]string, len(duplicates)) // Define a slice to hold the owners and training types
+		for i, duplicate := range duplicates {  // Iterate over the duplicates
+			keys[i] = fmt.Sprintf("%v/%s", duplicate.Key.OwnerID, duplicate.Key.TrainingType) // Describe the duplicate
+		}
+		slices.Sort(keys)                                                                                                                            // Report the duplicates in a stable order
+		return fmt.Errorf("%d owners defined several forms for a training type, merge or delete the forms: %s", len(keys), strings.Join(keys, ", ")) // Return the error
+	}
+
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "training_type", Value: 1}}, Options: options.Index().SetName("owner_training_type_unique").SetUnique(true)}, // One form per owner and training type
+		{Keys: bson.D{{Key: "training_type", Value: 1}, {Key: "created_at", Value: 1}}},                                                                               // Forms of a training type, oldest first
+	}
+	_, err = forms.Indexes().CreateMany(ctx, indexes) // Create the indexes
+	return err                                        // Return the error
+}
+
+// approveLegacyFeedback publishes the feedback submitted before moderation existed, which has no status and would
+// otherwise never be shown on the public views that list the approved feedback
+func approveLegacyFeedback(ctx context.Context, database *mongo.Database) error { // Approve the legacy feedback
+	filter := bson.M{"status": bson.M{"$in": bson.A{nil, ""}}}                 // Match the feedback without a status
+	update := bson.M{"$set": bson.M{"status": models.FeedbackStatusApproved}}  // Publish the feedback
+	_, err := database.Collection("feedbacks").UpdateMany(ctx, filter, update) // Approve the feedback
+	return err                                                                 // Return the error
+}
+
+// toObjectID is the aggregation expression converting a field to an ObjectID, null when it is not a valid ID
+func toObjectID(field interface{}) bson.M { // Convert a reference
+	return bson.M{"$convert": bson.M{"input": field, "to": "objectId", "onError": nil, "onNull": nil}} // Convert the field
+}
+
+// toObjectIDs is the aggregation expression converting the items of a list to ObjectIDs, dropping the invalid IDs
+func toObjectIDs(field string) bson.M { // Convert a list of references
+	converted := bson.M{"$map": bson.M{"input": field, "as": "id", "in": toObjectID("$$id")}}                      // Convert the items
+	valid := bson.M{"$filter": bson.M{"input": converted, "as": "id", "cond": bson.M{"$ne": bson.A{"$$id", nil}}}} // Drop the invalid IDs
+	return bson.M{"$cond": bson.A{bson.M{"$isArray": field}, valid, field}}                                        // Leave the missing and null lists
+}
+
+// dropIndex drops an index, doing nothing when the index or the collection does not exist
+func dropIndex(ctx context.Context, collection *mongo.Collection, name string) error { // Drop an index
+	_, err := collection.Indexes().DropOne(ctx, name)                                    // Drop the index
+	var commandErr mongo.CommandError                                                    // Define the error of the server
+	if errors.As(err, &commandErr) && (commandErr.Code == 26 || commandErr.Code == 27) { // Check if the collection (26) or the index (27) does not exist
+		return nil // Nothing to drop
+	}
+	if err != nil { // Check if there is an error
+		return fmt.Errorf("failed to drop the index %s of %s: %w", name, collection.Name(), err) // Return the error
+	}
+	return nil // Return nil
+}
+
+var (
+	schemaObjectID     = bson.M{"bsonType": "objectId"}                                       // Schema of an ID
+	schemaString       = bson.M{"bsonType": "string"}                                         // Schema of a string
+	schemaDate         = bson.M{"bsonType": "date"}                                           // Schema of a timestamp
+	schemaInt          = bson.M{"bsonType": bson.A{"int", "long"}}                            // Schema of an integer
+	schemaStringList   = bson.M{"bsonType": bson.A{"array", "null"}, "items": schemaString}   // Schema of a list of IDs stored as strings
+	schemaObjectIDList = bson.M{"bsonType": bson.A{"array", "null"}, "items": schemaObjectID} // Schema of a list of IDs
+)
+
+// initialSchemas holds the JSON schema of each validated collection set by migration 5; fields written before
+// a schema existed may be missing, so only the fields every write sets are required
+var initialSchemas = map[string]bson.M{
+	"users": {
+		"bsonType": "object",
+		"required": bson.A{"email", "password"}, // Users sign in with their email and password
+		"properties": bson.M{
+			"email":    schemaString,                                                                                          // Email of the user
+			"password": schemaString,                                                                                          // Hash of the password
+			"role":     bson.M{"enum": bson.A{models.RoleUser, models.RoleCoach, models.RoleBusinessOwner, models.RoleAdmin}}, // Role of the user
+			"version":  schemaInt,                                                                                             // Version of the user
+		},
+	},
+	"sessions": {
+		"bsonType": "object",
+		"required": bson.A{"title", "startTime", "endTime", "status"}, // Sessions are scheduled and have a status
+		"properties": bson.M{
+			"title":        schemaString,                                                                     // Title of the session
+			"startTime":    schemaDate,                                                                       // Start time of the session
+			"endTime":      schemaDate,                                                                       // End time of the session
+			"duration":     schemaInt,                                                                        // Duration in minutes
+			"coach":        schemaString,                                                                     // Coach of the session
+			"coachAssists": schemaStringList,                                                                 // Assistants of the coach
+			"participants": schemaStringList,                                                                 // Participants of the session
+			"status":       bson.M{"enum": bson.A{models.SessionStatusActive, models.SessionStatusArchived}}, // Status of the session
+			"version":      schemaInt,                                                                        // Version of the session
+		},
+	},
+	"pitch_bookings": {
+		"bsonType": "object",
+		"required": bson.A{"session_id", "user_id", "title"}, // Pitch bookings belong to a session and a user
+		"properties": bson.M{
+			"session_id": schemaObjectID, // Session of the pitch booking
+			"user_id":    schemaObjectID, // User who booked the pitch
+			"title":      schemaString,   // Title of the pitch booking
+			"version":    schemaInt,      // Version of the pitch booking
+		},
+	},
+	"feedbacks": {
+		"bsonType": "object",
+		"required": bson.A{"session_id", "coach_id", "user_id", "rating", "status"}, // Feedback rates a coach for a session
+		"properties": bson.M{
+			"session_id": schemaObjectID,                                                                                                   // Session of the feedback
+			"coach_id":   schemaObjectID,                                                                                                   // Coach who received the feedback
+			"user_id":    schemaObjectID,                                                                                                   // Author of the feedback
+			"rating":     bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1, "maximum": 5},                                            // Rating between 1 and 5
+			"status":     bson.M{"enum": bson.A{models.FeedbackStatusPending, models.FeedbackStatusApproved, models.FeedbackStatusHidden}}, // Moderation status
+			"version":    schemaInt,                                                                                                        // Version of the feedback
+		},
+	},
+}
+
+// sessionSchema is the JSON schema of the sessions once their fields are snake_case and their references ObjectIDs
+var sessionSchema = bson.M{
+	"bsonType": "object",
+	"required": bson.A{"title", "start_time", "end_time", "status"}, // Sessions are scheduled and have a status
+	"properties": bson.M{
+		"title":         schemaString,                                                                     // Title of the session
+		"start_time":    schemaDate,                                                                       // Start time of the session
+		"end_time":      schemaDate,                                                                       // End time of the session
+		"duration":      schemaInt,                                                                        // Duration in minutes
+		"coach":         bson.M{"bsonType": bson.A{"objectId", "null"}},                                   // Coach of the session
+		"coach_assists": schemaObjectIDList,                                                               // Assistants of the coach
+		"participants":  schemaObjectIDList,                                                               // Participants of the session
+		"status":        bson.M{"enum": bson.A{models.SessionStatusActive, models.SessionStatusArchived}}, // Status of the session
+		"version":       schemaInt,                                                                        // Version of the session
+	},
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys[V any](m map[string]V) []string { // Sort the keys of a map
+	keys := make([]string, 0, len(m)) // Define a slice to hold the keys
+	for key := range m {              // Iterate over the map
+		keys = append(keys, key) // Add the key
+	}
+	slices.Sort(keys) // Sort the keys
+	return keys       // Return the keys
+}
+
+// createCheckInIndexes keeps a user from checking in to a session twice and indexes the check-ins of a user
+func createCheckInIndexes(ctx context.Context, database *mongo.Database) error { // Create the check-in indexes
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "session_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)}, // One check-in per user and session
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "checked_in_at", Value: 1}}},                                        // Check-ins of a user, oldest first
+	}
+	_, err := database.Collection("check_ins").Indexes().CreateMany(ctx, indexes) // Create the indexes
+	return err                                                                    // Return the error
+}
+
+// setSessionOwners makes the coach the owner of the sessions created before the creator was recorded, the
+// closest known owner, so their events keep reaching the webhooks of the coach
+func setSessionOwners(ctx context.Context, database *mongo.Database) error { // Set the session owners
+	filter := bson.M{"owner_id": bson.M{"$exists": false}}                         // Match the sessions without owner
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"owner_id": "$coach"}}}} // Copy the coach to the owner
+	_, err := database.Collection("sessions").UpdateMany(ctx, filter, update)      // Set the owners
+	return err                                                                     // Return the error
+}
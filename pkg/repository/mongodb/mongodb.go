@@ -0,0 +1,162 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewRepositories creates the MongoDB repositories of every aggregate on the given database
+func NewRepositories(database *mongo.Database) *repository.Repositories { // Create the MongoDB repositories
+	return &repository.Repositories{
+		Sessions:      &SessionRepository{collection: database.Collection("sessions")},                      // Set the session repository
+		Users:         &UserRepository{collection: database.Collection("users")},                            // Set the user repository
+		Invitations:   &InvitationRepository{collection: database.Collection("invitations")},                // Set the invitation repository
+		Notifications: &NotificationRepository{collection: database.Collection("notifications")},            // Set the notification repository
+		Feedback:      &FeedbackRepository{collection: database.Collection("feedbacks")},                    // Set the feedback repository
+		FeedbackForms: &FeedbackFormRepository{collection: database.Collection("feedback_forms")},           // Set the feedback form repository
+		ModerationLog: &ModerationLogRepository{collection: database.Collection("feedback_moderation_log")}, // Set the moderation log repository
+		Pitches:       &PitchRepository{collection: database.Collection("pitch_bookings")},                  // Set the pitch booking repository
+		Idempotency:   &IdempotencyRepository{collection: database.Collection("idempotency_keys")},          // Set the idempotency key repository
+		Outbox:        &OutboxRepository{collection: database.Collection("outbox")},                         // Set the outbox repository
+		Webhooks:      &WebhookRepository{collection: database.Collection("webhooks")},                      // Set the webhook repository
+		Deliveries:    &DeliveryRepository{collection: database.Collection("webhook_deliveries")},           // Set the webhook delivery repository
+		Audit:         &AuditRepository{collection: database.Collection("audit_log")},                       // Set the audit log repository
+		Erasures:      &ErasureRepository{collection: database.Collection("erasure_requests")},              // Set the erasure request repository
+		CheckIns:      &CheckInRepository{collection: database.Collection("check_ins")},                     // Set the check-in repository
+		Transactions:  &Transactor{client: database.Client()},                                               // Set the transactor
+	}
+}
+
+// findAll runs a query and decodes every matching document
+func findAll[T any](ctx context.Context, collection *mongo.Collection, filter interface{}, opts ...*options.FindOptions) ([]T, error) { // Find and decode documents
+	cursor, err := collection.Find(ctx, filter, opts...) // Find the documents
+	if err != nil {                                      // Check if there is an error
+		return nil, err // Return the error
+	}
+	defer cursor.Close(ctx) // Close the cursor
+
+	documents := []T{}                                  // Define a slice to hold the documents
+	if err := cursor.All(ctx, &documents); err != nil { // Decode the documents
+		return nil, err // Return the error
+	}
+	return documents, nil // Return the documents
+}
+
+// findOne finds a single document, translating a missing document into repository.ErrNotFound
+func findOne[T any](ctx context.Context, collection *mongo.Collection, filter interface{}, opts ...*options.FindOneOptions) (T, error) { // Find and decode a document
+	var document T                                                    // Define a document variable
+	err := collection.FindOne(ctx, filter, opts...).Decode(&document) // Find the document
+	if errors.Is(err, mongo.ErrNoDocuments) {                         // Check if the document was not found
+		return document, repository.ErrNotFound // Return a not found error
+	}
+	return document, err // Return the document
+}
+
+// updateOne updates a single document, translating an unmatched filter into repository.ErrNotFound
+func updateOne(ctx context.Context, collection *mongo.Collection, filter, update interface{}) error { // Update a document
+	result, err := collection.UpdateOne(ctx, filter, update) // Update the document
+	if err != nil {                                          // Check if there is an error
+		return err // Return the error
+	}
+	if result.MatchedCount == 0 { // Check if no document matched
+		return repository.ErrNotFound // Return a not found error
+	}
+	return nil // Return nil
+}
+
+// replaceOne replaces a single document, translating an unmatched filter into repository.ErrNotFound
+func replaceOne(ctx context.Context, collection *mongo.Collection, filter, replacement interface{}) error { // Replace a document
+	result, err := collection.ReplaceOne(ctx, filter, replacement) // Replace the document
+	if err != nil {                                                // Check if there is an error
+		return err // Return the error
+	}
+	if result.MatchedCount == 0 { // Check if no document matched
+		return repository.ErrNotFound // Return a not found error
+	}
+	return nil // Return nil
+}
+
+// deleteOne deletes a single document, translating an unmatched filter into repository.ErrNotFound
+func deleteOne(ctx context.Context, collection *mongo.Collection, filter interface{}) error { // Delete a document
+	result, err := collection.DeleteOne(ctx, filter) // Delete the document
+	if err != nil {                                  // Check if there is an error
+		return err // Return the error
+	}
+	if result.DeletedCount == 0 { // Check if no document was deleted
+		return repository.ErrNotFound // Return a not found error
+	}
+	return nil // Return nil
+}
+
+// updateMany updates the documents matching the filter and returns the number of changed documents
+func updateMany(ctx context.Context, collection *mongo.Collection, filter, update interface{}) (int64, error) { // Update documents
+	result, err := collection.UpdateMany(ctx, filter, update) // Update the documents
+	if err != nil {                                           // Check if there is an error
+		return 0, err // Return the error
+	}
+	return result.ModifiedCount, nil // Return the number of changed documents
+}
+
+// conflictError translates the violation of a unique index into repository.ErrConflict
+func conflictError(err error) error { // Describe a duplicate key
+	if mongo.IsDuplicateKeyError(err) { // Check if a unique index rejected the write
+		return repository.ErrConflict // Return a conflict error
+	}
+	return err // Return the error
+}
+
+// versionFilter matches the live document with the given ID at the given version; documents written before
+// they were versioned have no version field and are at version 0
+func versionFilter(id primitive.ObjectID, version int64) bson.M { // Match a document at a version
+	if version == 0 { // Check if the document may predate versioning
+		return live(bson.M{"_id": id, "version": bson.M{"$in": bson.A{0, nil}}}) // Match a zero or missing version
+	}
+	return live(bson.M{"_id": id, "version": version}) // Match the version
+}
+
+// bumpVersion adds the increment of the version to an update, every write of a versioned document changes its ETag
+func bumpVersion(update bson.M) bson.M { // Increment the version
+	update["$inc"] = bson.M{"version": 1} // Increment the version
+	return update                         // Return the update
+}
+
+// updateVersioned updates the document with the given ID if it is still at the given version and increments it,
+// translating an unmatched filter into repository.ErrVersionMismatch or repository.ErrNotFound
+func updateVersioned(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, version int64, update bson.M) error { // Update a document at a version
+	err := updateOne(ctx, collection, versionFilter(id, version), bumpVersion(update)) // Update the document
+	return versionError(ctx, collection, id, err)                                      // Tell a stale version from a missing document
+}
+
+// replaceVersioned replaces the document with the given ID if it is still at the given version, the replacement
+// holding the next version, translating an unmatched filter into repository.ErrVersionMismatch or repository.ErrNotFound
+func replaceVersioned(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, version int64, replacement interface{}) error { // Replace a document at a version
+	err := replaceOne(ctx, collection, versionFilter(id, version), replacement) // Replace the document
+	return versionError(ctx, collection, id, err)                               // Tell a stale version from a missing document
+}
+
+// versionError tells whether a conditional write matched nothing because the document changed or because it is
+// missing or soft deleted
+func versionError(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, err error) error { // Describe an unmatched conditional write
+	if !errors.Is(err, repository.ErrNotFound) { // Check if the write matched or failed otherwise
+		return err // Return the error
+	}
+	count, err := collection.CountDocuments(ctx, live(bson.M{"_id": id}), options.Count().SetLimit(1)) // Check if the live document exists
+	if err != nil {                                                                                    // Check if there is an error
+		return err // Return the error
+	}
+	if count > 0 { // Check if the document exists at another version
+		return repository.ErrVersionMismatch // Return a version mismatch error
+	}
+	return repository.ErrNotFound // Return a not found error
+}
+
+// byCreation sorts documents from the oldest to the newest
+func byCreation(field string) *options.FindOptions { // Sort by creation date
+	return options.Find().SetSort(bson.D{{Key: field, Value: 1}, {Key: "_id", Value: 1}}) // Sort by creation date, then by ID
+}
@@ -0,0 +1,40 @@
+package mongodb
+
+import (
+	"context"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NotificationRepository stores user notifications in the "notifications" collection
+type NotificationRepository struct {
+	collection *mongo.Collection // Notification collection
+}
+
+// FindByUser finds the notifications of a user
+func (r *NotificationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error) {
+	return findAll[models.Notification](ctx, r.collection, bson.M{"user_id": userID}, byCreation("created_at")) // Find the notifications by user ID
+}
+
+// Insert inserts a new notification
+func (r *NotificationRepository) Insert(ctx context.Context, notification *models.Notification) error {
+	_, err := r.collection.InsertOne(ctx, notification) // Insert the notification
+	return conflictError(err)                           // Return the error, a conflict when the notification was already inserted
+}
+
+// Delete deletes a notification
+func (r *NotificationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id}) // Delete the notification
+}
+
+// DeleteByUser deletes the notifications of a user
+func (r *NotificationRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}) // Delete the notifications of the user
+	if err != nil {                                                        // Check if there is an error
+		return 0, err // Return the error
+	}
+	return result.DeletedCount, nil // Return the number of deleted notifications
+}
@@ -0,0 +1,61 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// outboxLeaseID is the ID of the lease document of the relays
+const outboxLeaseID = "relay"
+
+// OutboxRepository stores the domain events in the "outbox" collection, the published events are removed
+// by the TTL index on published_at; the relay lease is kept in the "outbox_leases" collection
+type OutboxRepository struct {
+	collection *mongo.Collection // Outbox collection
+}
+
+// Insert appends an event
+func (r *OutboxRepository) Insert(ctx context.Context, event *models.Event) error {
+	_, err := r.collection.InsertOne(ctx, event) // Insert the event
+	return err                                   // Return the error
+}
+
+// FindPending finds the events not published yet, oldest first
+func (r *OutboxRepository) FindPending(ctx context.Context, limit int) ([]models.Event, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit)) // Sort by occurrence, then by ID
+	return findAll[models.Event](ctx, r.collection, bson.M{"published_at": nil}, opts)                                    // Find the pending events
+}
+
+// MarkPublished records that the broker accepted an event
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, bson.M{"$set": bson.M{"published_at": at}}) // Set the published_at timestamp
+}
+
+// MarkFailed records a failed publication of an event
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error {
+	update := bson.M{"$set": bson.M{"last_error": reason}, "$inc": bson.M{"attempts": 1}} // Store the error and count the attempt
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)                        // Update the event
+}
+
+// Lease holds the right to publish until the given time, taking over a lease that expired by now;
+// repository.ErrConflict is returned while another owner holds it
+func (r *OutboxRepository) Lease(ctx context.Context, owner string, now, until time.Time) error {
+	filter := bson.M{"_id": outboxLeaseID, "$or": bson.A{ // Match a lease this owner can hold
+		bson.M{"owner": owner},                    // Already held by this owner
+		bson.M{"expires_at": bson.M{"$lte": now}}, // Expired
+	}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": until}}             // Hold the lease until the given time
+	leases := r.collection.Database().Collection("outbox_leases")                     // Get the lease collection
+	_, err := leases.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)) // Take the lease, inserting it when missing
+	if mongo.IsDuplicateKeyError(err) {                                               // Check if another owner holds the lease
+		return repository.ErrConflict // Return a conflict error
+	}
+	return err // Return the error
+}
@@ -0,0 +1,81 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PitchRepository stores pitch bookings in the "pitch_bookings" collection
+type PitchRepository struct {
+	collection *mongo.Collection // Pitch booking collection
+}
+
+// FindAll finds all pitch bookings
+func (r *PitchRepository) FindAll(ctx context.Context) ([]models.Pitch, error) {
+	return findAll[models.Pitch](ctx, r.collection, live(bson.M{})) // Find all pitch bookings
+}
+
+// FindByID finds a pitch booking by ID
+func (r *PitchRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Pitch, error) {
+	return findOne[models.Pitch](ctx, r.collection, live(bson.M{"_id": id})) // Find the pitch booking by ID
+}
+
+// FindByUser finds the pitch bookings of a user
+func (r *PitchRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Pitch, error) {
+	return findAll[models.Pitch](ctx, r.collection, live(bson.M{"user_id": userID})) // Find the pitch bookings by user ID
+}
+
+// Insert inserts a new pitch booking at version 1
+func (r *PitchRepository) Insert(ctx context.Context, pitch *models.Pitch) error {
+	pitch.Version = 1                            // Start at the first version
+	_, err := r.collection.InsertOne(ctx, pitch) // Insert the pitch booking
+	return err                                   // Return the error
+}
+
+// Replace replaces a pitch booking still at pitch.Version and increments the version
+func (r *PitchRepository) Replace(ctx context.Context, pitch *models.Pitch) error {
+	replacement := *pitch                                                            // Copy the pitch booking
+	replacement.Version++                                                            // Move to the next version
+	err := replaceVersioned(ctx, r.collection, pitch.ID, pitch.Version, replacement) // Replace the pitch booking document
+	if err == nil {                                                                  // Check if the pitch booking was replaced
+		pitch.Version = replacement.Version // Report the new version
+	}
+	return err // Return the error
+}
+
+// FindDeleted finds the soft deleted pitch bookings, last deleted first
+func (r *PitchRepository) FindDeleted(ctx context.Context) ([]models.Pitch, error) {
+	return findDeleted[models.Pitch](ctx, r.collection) // Find the deleted pitch bookings
+}
+
+// SoftDelete hides a pitch booking, returning repository.ErrNotFound if it is missing or already deleted
+func (r *PitchRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return softDelete(ctx, r.collection, id, deletion, true) // Soft delete the pitch booking
+}
+
+// Restore shows a soft deleted pitch booking again and returns the undone deletion
+func (r *PitchRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return restore(ctx, r.collection, id, true) // Restore the pitch booking
+}
+
+// Purge removes the pitch bookings soft deleted before the given time
+func (r *PitchRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, r.collection, before) // Remove the expired pitch bookings
+}
+
+// SoftDeleteByUser hides the live pitch bookings of a user
+func (r *PitchRepository) SoftDeleteByUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion) (int64, error) {
+	return updateMany(ctx, r.collection, live(bson.M{"user_id": userID}), bumpVersion(bson.M{"$set": deletion})) // Soft delete the pitch bookings
+}
+
+// RestoreByUser shows again the pitch bookings of a user soft deleted at the given time
+func (r *PitchRepository) RestoreByUser(ctx context.Context, userID primitive.ObjectID, deletedAt time.Time) error {
+	update := bumpVersion(bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": "", "deletion_reason": ""}}) // Forget the deletion
+	_, err := updateMany(ctx, r.collection, bson.M{"user_id": userID, "deleted_at": deletedAt}, update)        // Restore the pitch bookings
+	return err                                                                                                 // Return the error
+}
@@ -0,0 +1,143 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SessionRepository stores training sessions in the "sessions" collection
+type SessionRepository struct {
+	collection *mongo.Collection // Session collection
+}
+
+// FindAll finds all sessions
+func (r *SessionRepository) FindAll(ctx context.Context) ([]models.Session, error) {
+	return findAll[models.Session](ctx, r.collection, live(bson.M{})) // Find all sessions
+}
+
+// FindByStatus finds the sessions with the given status
+func (r *SessionRepository) FindByStatus(ctx context.Context, status string) ([]models.Session, error) {
+	return findAll[models.Session](ctx, r.collection, live(bson.M{"status": status})) // Find the sessions by status
+}
+
+// FindByUser finds the sessions coached or attended by a user
+func (r *SessionRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error) {
+	filter := live(bson.M{"$or": bson.A{bson.M{"coach": userID}, bson.M{"participants": userID}}}) // Match the coach or a participant
+	return findAll[models.Session](ctx, r.collection, filter)                                      // Find the sessions of the user
+}
+
+// FindByID finds a session by ID
+func (r *SessionRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Session, error) {
+	return findOne[models.Session](ctx, r.collection, live(bson.M{"_id": id})) // Find the session by ID
+}
+
+// Insert inserts a new session at version 1
+func (r *SessionRepository) Insert(ctx context.Context, session *models.Session) error {
+	session.Version = 1                            // Start at the first version
+	_, err := r.collection.InsertOne(ctx, session) // Insert the session
+	return err                                     // Return the error
+}
+
+// Replace replaces a session still at session.Version and increments the version
+func (r *SessionRepository) Replace(ctx context.Context, session *models.Session) error {
+	replacement := *session                                                              // Copy the session
+	replacement.Version++                                                                // Move to the next version
+	err := replaceVersioned(ctx, r.collection, session.ID, session.Version, replacement) // Replace the session document
+	if err == nil {                                                                      // Check if the session was replaced
+		session.Version = replacement.Version // Report the new version
+	}
+	return err // Return the error
+}
+
+// FindDeleted finds the soft deleted sessions, last deleted first
+func (r *SessionRepository) FindDeleted(ctx context.Context) ([]models.Session, error) {
+	return findDeleted[models.Session](ctx, r.collection) // Find the deleted sessions
+}
+
+// SoftDelete hides a session, returning repository.ErrNotFound if it is missing or already deleted
+func (r *SessionRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return softDelete(ctx, r.collection, id, deletion, true) // Soft delete the session
+}
+
+// Restore shows a soft deleted session again and returns the undone deletion
+func (r *SessionRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return restore(ctx, r.collection, id, true) // Restore the session
+}
+
+// Purge removes the sessions soft deleted before the given time
+func (r *SessionRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, r.collection, before) // Remove the expired sessions
+}
+
+// hasRoom matches the sessions with no capacity or fewer participants than their capacity
+var hasRoom = bson.M{"$or": bson.A{
+	bson.M{"$lte": bson.A{"$capacity", 0}}, // Match a session with no limit
+	bson.M{"$lt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$participants", bson.A{}}}}, "$capacity"}}, // Match a session with a place left
+}}
+
+// AddParticipant adds a participant to a session, returning repository.ErrConflict if it is full. The capacity is
+// checked by the update itself, so two enrollments cannot both take the last place, even without a transaction
+func (r *SessionRepository) AddParticipant(ctx context.Context, id, userID primitive.ObjectID) error {
+	filter := live(bson.M{"_id": id, "$expr": hasRoom})                                                           // Match the live session while it has room
+	err := updateOne(ctx, r.collection, filter, bumpVersion(bson.M{"$addToSet": bson.M{"participants": userID}})) // Add the user to the participants
+	if !errors.Is(err, repository.ErrNotFound) {                                                                  // Check if the session was found
+		return err // Return the error
+	}
+
+	if _, err := r.FindByID(ctx, id); err != nil { // Check if the session exists
+		return err // Return the error
+	}
+	return repository.ErrConflict // The session is full
+}
+
+// RemoveParticipant removes a user from the participants and the waitlist of a session
+func (r *SessionRepository) RemoveParticipant(ctx context.Context, id, userID primitive.ObjectID) error {
+	return updateOne(ctx, r.collection, live(bson.M{"_id": id}), bumpVersion(bson.M{"$pull": bson.M{"participants": userID, "waitlist": userID}})) // Remove the user from both lists
+}
+
+// AddToWaitlist adds a user to the end of the waitlist of a session
+func (r *SessionRepository) AddToWaitlist(ctx context.Context, id, userID primitive.ObjectID) error {
+	return updateOne(ctx, r.collection, live(bson.M{"_id": id}), bumpVersion(bson.M{"$addToSet": bson.M{"waitlist": userID}})) // Add the user to the waitlist
+}
+
+// PromoteWaitlisted moves a user from the waitlist to the participants of a session
+func (r *SessionRepository) PromoteWaitlisted(ctx context.Context, id, userID primitive.ObjectID) error {
+	update := bumpVersion(bson.M{"$pull": bson.M{"waitlist": userID}, "$addToSet": bson.M{"participants": userID}}) // Move the user to the participants
+	return updateOne(ctx, r.collection, live(bson.M{"_id": id}), update)                                            // Update the session
+}
+
+// RemoveUser removes a user from the participants, waitlist and assistants of every session, soft deleted ones included
+func (r *SessionRepository) RemoveUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	filter := bson.M{"$or": bson.A{bson.M{"participants": userID}, bson.M{"waitlist": userID}, bson.M{"coach_assists": userID}}} // Match a participant, a waitlisted user or an assistant
+	update := bumpVersion(bson.M{"$pull": bson.M{"participants": userID, "waitlist": userID, "coach_assists": userID}})          // Remove the user from the three lists
+	return updateMany(ctx, r.collection, filter, update)                                                                         // Update the sessions of the user
+}
+
+// ReassignCoach gives the live sessions of a coach ending after the given time to another coach, who also
+// becomes the owner of the ones the coach created
+func (r *SessionRepository) ReassignCoach(ctx context.Context, from, to primitive.ObjectID, endingAfter time.Time) (int64, error) {
+	owned := live(bson.M{"coach": from, "owner_id": from, "end_time": bson.M{"$gt": endingAfter}})          // Match the remaining sessions the coach created
+	if _, err := updateMany(ctx, r.collection, owned, bson.M{"$set": bson.M{"owner_id": to}}); err != nil { // Set the new owner, the version moves with the coach below
+		return 0, err // Return the error
+	}
+	filter := live(bson.M{"coach": from, "end_time": bson.M{"$gt": endingAfter}})        // Match the remaining sessions of the coach
+	update := bumpVersion(bson.M{"$set": bson.M{"coach": to, "updated_at": time.Now()}}) // Set the new coach and the updated_at timestamp
+	return updateMany(ctx, r.collection, filter, update)                                 // Update the sessions
+}
+
+// SetStatus changes the status of a session
+func (r *SessionRepository) SetStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	return updateOne(ctx, r.collection, live(bson.M{"_id": id}), bumpVersion(bson.M{"$set": bson.M{"status": status}})) // Set the status
+}
+
+// SetQRCode stores the QR code content of a session
+func (r *SessionRepository) SetQRCode(ctx context.Context, id primitive.ObjectID, qrCode string) error {
+	return updateOne(ctx, r.collection, live(bson.M{"_id": id}), bumpVersion(bson.M{"$set": bson.M{"qr_code": qrCode}})) // Set the QR code
+}
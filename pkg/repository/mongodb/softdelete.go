@@ -0,0 +1,75 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deletedFilter matches the soft deleted documents
+var deletedFilter = bson.M{"deleted_at": bson.M{"$ne": nil}}
+
+// live restricts a filter to the documents that are not soft deleted
+func live(filter bson.M) bson.M { // Exclude the soft deleted documents
+	filter["deleted_at"] = nil // Match a missing deletion
+	return filter              // Return the filter
+}
+
+// findDeleted finds the soft deleted documents of a collection, last deleted first
+func findDeleted[T any](ctx context.Context, collection *mongo.Collection) ([]T, error) { // Find the soft deleted documents
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}}) // Sort by deletion date, last first
+	return findAll[T](ctx, collection, deletedFilter, opts)                                         // Find the deleted documents
+}
+
+// softDelete records the deletion of the live document with the given ID, moving a versioned document to its
+// next version, and returns repository.ErrNotFound if it is missing or already deleted
+func softDelete(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, deletion models.Deletion, versioned bool) error { // Soft delete a document
+	update := bson.M{"$set": deletion} // Record the deletion
+	if versioned {                     // Check if the document is versioned
+		update = bumpVersion(update) // Move to the next version
+	}
+	return updateOne(ctx, collection, live(bson.M{"_id": id}), update) // Update the live document
+}
+
+// restore removes the deletion of the soft deleted document with the given ID, moving a versioned document to its
+// next version, and returns the undone deletion, repository.ErrNotFound if it is missing or not deleted or
+// repository.ErrFinalDeletion if its deletion is final
+func restore(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, versioned bool) (models.Deletion, error) { // Restore a document
+	update := bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": "", "deletion_reason": "", "deletion_final": ""}} // Forget the deletion
+	if versioned {                                                                                                      // Check if the document is versioned
+		update = bumpVersion(update) // Move to the next version
+	}
+
+	var previous struct {
+		models.Deletion `bson:",inline"` // Deletion before the update
+	}
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}, "deletion_final": bson.M{"$ne": true}} // Match the deleted document whose deletion can be undone
+	err := collection.FindOneAndUpdate(ctx, filter, update).Decode(&previous)                            // Restore the document, returning it as it was
+	if errors.Is(err, mongo.ErrNoDocuments) {                                                            // Check if the document was not found
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": id, "deletion_final": true}, options.Count().SetLimit(1)) // Check if the deletion is final
+		if err != nil {                                                                                                      // Check if there is an error
+			return models.Deletion{}, err // Return the error
+		}
+		if count > 0 { // Check if the document cannot be restored
+			return models.Deletion{}, repository.ErrFinalDeletion // Return a final deletion error
+		}
+		return models.Deletion{}, repository.ErrNotFound // Return a not found error
+	}
+	return previous.Deletion, err // Return the undone deletion
+}
+
+// purge removes the documents of a collection soft deleted before the given time and returns their count
+func purge(ctx context.Context, collection *mongo.Collection, before time.Time) (int64, error) { // Remove the expired deleted documents
+	result, err := collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}) // Delete the expired documents
+	if err != nil {                                                                        // Check if there is an error
+		return 0, err // Return the error
+	}
+	return result.DeletedCount, nil // Return the number of removed documents
+}
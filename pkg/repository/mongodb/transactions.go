@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs functions in MongoDB transactions. Transactions need a replica set or a sharded cluster:
+// on a standalone server, as often used in local development, the functions run without a transaction.
+type Transactor struct {
+	client    *mongo.Client // Client the sessions are started on
+	mu        sync.Mutex    // Protects supported
+	supported *bool         // Whether the deployment supports transactions, nil until known
+}
+
+// helloReply is the part of the reply of the hello command telling the topology of the deployment
+type helloReply struct {
+	SetName string `bson:"setName"` // Name of the replica set, empty outside of a replica set
+	Msg     string `bson:"msg"`     // "isdbgrid" when connected to a mongos router
+}
+
+// WithTransaction runs fn in a transaction, committing its writes when it returns nil and aborting them when it
+// returns an error. The transaction is retried on transient errors and unknown commit results, so fn may run
+// more than once and must not have effects outside of the database. A function given the context of a running
+// transaction joins it.
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error { // Run a transaction
+	if joined, err := repository.JoinTransaction(ctx, fn); joined { // Check if fn joined the running transaction
+		return err // Return the error
+	}
+	supported, err := t.supports(ctx) // Check if the deployment supports transactions
+	if err != nil {                   // Check if there is an error
+		return err // Return the error
+	}
+	if !supported { // Check if the server is standalone
+		return fn(ctx) // Run without a transaction
+	}
+
+	session, err := t.client.StartSession() // Start a session
+	if err != nil {                         // Check if there is an error
+		return fmt.Errorf("failed to start a session: %w", err) // Return the error
+	}
+	defer session.EndSession(context.WithoutCancel(ctx)) // End the session, even if ctx is done
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) { // Run fn in a transaction, retrying the transient errors
+		txCtx, failed := repository.BeginTransaction(sessionCtx) // Mark the context of the transaction, the session stays in it
+		if err := fn(txCtx); err != nil {                        // Run fn, the writes made with the session context join the transaction
+			return nil, err // Abort the transaction
+		}
+		if failed() { // Check if a function joining the transaction failed
+			return nil, repository.ErrRolledBack // Abort the transaction
+		}
+		return nil, nil // Commit the transaction
+	})
+	return err // Return the error
+}
+
+// supports tells whether the deployment supports transactions, asking the server once
+func (t *Transactor) supports(ctx context.Context) (bool, error) { // Check the topology
+	t.mu.Lock()         // Lock the topology
+	defer t.mu.Unlock() // Unlock the topology
+
+	if t.supported != nil { // Check if the topology is known
+		return *t.supported, nil // Return the cached answer
+	}
+	var reply helloReply                                                                               // Define the reply
+	err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&reply) // Ask the server for its topology
+	if err != nil {                                                                                    // Check if there is an error
+		return false, fmt.Errorf("failed to check if transactions are supported: %w", err) // Return the error, asking again next time
+	}
+
+	supported := reply.SetName != "" || reply.Msg == "isdbgrid" // Transactions need a replica set or a sharded cluster
+	if !supported {                                             // Check if the server is standalone
+		slog.WarnContext(ctx, "MongoDB is a standalone server, multi-document writes run without transactions") // Log the fallback
+	}
+	t.supported = &supported // Cache the answer
+	return supported, nil    // Return the answer
+}
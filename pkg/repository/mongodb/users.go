@@ -0,0 +1,131 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserRepository stores users in the "users" collection
+type UserRepository struct {
+	collection *mongo.Collection // User collection
+}
+
+// FindAll finds all users
+func (r *UserRepository) FindAll(ctx context.Context) ([]models.User, error) {
+	return findAll[models.User](ctx, r.collection, live(bson.M{})) // Find all users
+}
+
+// FindByRole finds the users with the given role
+func (r *UserRepository) FindByRole(ctx context.Context, role string) ([]models.User, error) {
+	return findAll[models.User](ctx, r.collection, live(bson.M{"role": role})) // Find the users with the role
+}
+
+// FindByID finds a user by ID
+func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	return findOne[models.User](ctx, r.collection, live(bson.M{"_id": id})) // Find the user by ID
+}
+
+// FindByEmail finds a user by email
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	return findOne[models.User](ctx, r.collection, live(bson.M{"email": email})) // Find the user by email
+}
+
+// Insert inserts a new user at version 1, returning repository.ErrConflict if the email is taken
+func (r *UserRepository) Insert(ctx context.Context, user *models.User) error {
+	user.Version = 1                            // Start at the first version
+	_, err := r.collection.InsertOne(ctx, user) // Insert the user
+	return conflictError(err)                   // Report a taken email as a conflict
+}
+
+// Update updates the non-empty fields of a user still at user.Version and increments the version,
+// returning repository.ErrConflict if the email is taken
+func (r *UserRepository) Update(ctx context.Context, user models.User) error {
+	set := bson.M{"updated_at": user.UpdatedAt} // Always update the updated_at timestamp
+	if user.Name != "" {                        // Check if the name is being updated
+		set["name"] = user.Name // Set the name
+	}
+	if user.Email != "" { // Check if the email is being updated
+		set["email"] = user.Email // Set the email
+	}
+	if user.Role != "" { // Check if the role is being updated
+		set["role"] = user.Role // Set the role
+	}
+	if user.Avatar != "" { // Check if the avatar is being updated
+		set["avatar"] = user.Avatar // Set the avatar
+	}
+	if user.Cin != "" { // Check if the CIN is being updated
+		set["cin"] = user.Cin // Set the CIN
+	}
+	if user.Password != "" { // Check if the password is being updated
+		set["password"] = user.Password // Set the password
+	}
+
+	return conflictError(updateVersioned(ctx, r.collection, user.ID, user.Version, bson.M{"$set": set})) // Update the user, reporting a taken email as a conflict
+}
+
+// Replace replaces a user still at user.Version and increments the version, returning repository.ErrConflict
+// if the email is taken
+func (r *UserRepository) Replace(ctx context.Context, user *models.User) error {
+	replacement := *user                                                           // Copy the user
+	replacement.Version++                                                          // Move to the next version
+	err := replaceVersioned(ctx, r.collection, user.ID, user.Version, replacement) // Replace the user document
+	if err == nil {                                                                // Check if the user was replaced
+		user.Version = replacement.Version // Report the new version
+	}
+	return conflictError(err) // Report a taken email as a conflict
+}
+
+// FindDeleted finds the soft deleted users, last deleted first
+func (r *UserRepository) FindDeleted(ctx context.Context) ([]models.User, error) {
+	return findDeleted[models.User](ctx, r.collection) // Find the deleted users
+}
+
+// FindByIDWithDeleted finds a user by ID, live or soft deleted
+func (r *UserRepository) FindByIDWithDeleted(ctx context.Context, id primitive.ObjectID) (models.User, error) {
+	return findOne[models.User](ctx, r.collection, bson.M{"_id": id}) // Find the user by ID, even soft deleted
+}
+
+// Erase replaces a user, live or soft deleted, still at user.Version and increments the version
+func (r *UserRepository) Erase(ctx context.Context, user *models.User) error {
+	replacement := *user                                      // Copy the user
+	replacement.Version++                                     // Move to the next version
+	filter := versionFilter(user.ID, user.Version)            // Match the user at its version
+	delete(filter, "deleted_at")                              // Match the user even soft deleted
+	err := replaceOne(ctx, r.collection, filter, replacement) // Replace the user document
+	if errors.Is(err, repository.ErrNotFound) {               // Check if the user was not replaced
+		count, countErr := r.collection.CountDocuments(ctx, bson.M{"_id": user.ID}, options.Count().SetLimit(1)) // Check if the user exists at another version
+		if countErr != nil {                                                                                     // Check if there is an error
+			return countErr // Return the error
+		}
+		if count > 0 { // Check if the user changed since it was read
+			return repository.ErrVersionMismatch // Return a version mismatch error
+		}
+	}
+	if err == nil { // Check if the user was replaced
+		user.Version = replacement.Version // Report the new version
+	}
+	return conflictError(err) // Report a taken email as a conflict
+}
+
+// SoftDelete hides an user, returning repository.ErrNotFound if it is missing or already deleted
+func (r *UserRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error {
+	return softDelete(ctx, r.collection, id, deletion, true) // Soft delete the user
+}
+
+// Restore shows a soft deleted user again and returns the undone deletion
+func (r *UserRepository) Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error) {
+	return restore(ctx, r.collection, id, true) // Restore the user
+}
+
+// Purge removes the users soft deleted before the given time
+func (r *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purge(ctx, r.collection, before) // Remove the expired users
+}
@@ -0,0 +1,136 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookRepository stores the webhook subscriptions in the "webhooks" collection
+type WebhookRepository struct {
+	collection *mongo.Collection // Webhook collection
+}
+
+// FindByOwner finds the webhooks of a business owner, oldest first
+func (r *WebhookRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Webhook, error) {
+	return findAll[models.Webhook](ctx, r.collection, bson.M{"owner_id": ownerID}, byCreation("created_at")) // Find the webhooks of the owner
+}
+
+// FindByID finds a webhook by ID
+func (r *WebhookRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.Webhook, error) {
+	return findOne[models.Webhook](ctx, r.collection, bson.M{"_id": id}) // Find the webhook
+}
+
+// FindSubscribed finds the active webhooks of the owners receiving the events of the type
+func (r *WebhookRepository) FindSubscribed(ctx context.Context, eventType string, ownerIDs []primitive.ObjectID) ([]models.Webhook, error) {
+	filter := bson.M{"active": true, "owner_id": bson.M{"$in": ownerIDs}, "$or": bson.A{ // Match the active webhooks of the owners subscribed to the type
+		bson.M{"event_types": eventType},          // Subscribed to the type
+		bson.M{"event_types": bson.M{"$size": 0}}, // Subscribed to every type
+	}}
+	return findAll[models.Webhook](ctx, r.collection, filter, byCreation("created_at")) // Find the webhooks
+}
+
+// Insert inserts a new webhook
+func (r *WebhookRepository) Insert(ctx context.Context, webhook *models.Webhook) error {
+	webhook.Version = 1                            // Start at the first version
+	_, err := r.collection.InsertOne(ctx, webhook) // Insert the webhook
+	return err                                     // Return the error
+}
+
+// Update updates the URL, description, event types and state of a webhook still at webhook.Version and increments the version
+func (r *WebhookRepository) Update(ctx context.Context, webhook models.Webhook) error {
+	update := bson.M{"$set": bson.M{ // Set the fields a business owner can change
+		"url":         webhook.URL,         // Set the URL
+		"description": webhook.Description, // Set the description
+		"event_types": webhook.EventTypes,  // Set the event types
+		"active":      webhook.Active,      // Set the state
+		"updated_at":  webhook.UpdatedAt,   // Set the updated_at timestamp
+	}}
+	return updateVersioned(ctx, r.collection, webhook.ID, webhook.Version, update) // Update the webhook if nobody changed it in the meantime
+}
+
+// Delete deletes a webhook
+func (r *WebhookRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id}) // Delete the webhook
+}
+
+// DisableByOwner stops delivering to the active webhooks of a business owner
+func (r *WebhookRepository) DisableByOwner(ctx context.Context, ownerID primitive.ObjectID) (int64, error) {
+	update := bumpVersion(bson.M{"$set": bson.M{"active": false, "updated_at": time.Now()}})  // Disable the webhooks
+	return updateMany(ctx, r.collection, bson.M{"owner_id": ownerID, "active": true}, update) // Update the active webhooks of the owner
+}
+
+// DeliveryRepository stores the webhook deliveries in the "webhook_deliveries" collection, a unique index
+// on webhook_id and event_id keeps an event published twice from being delivered twice
+type DeliveryRepository struct {
+	collection *mongo.Collection // Delivery collection
+}
+
+// FindByWebhook finds the deliveries of a webhook, filtered by status when not empty, newest first
+func (r *DeliveryRepository) FindByWebhook(ctx context.Context, webhookID primitive.ObjectID, status string) ([]models.WebhookDelivery, error) {
+	filter := bson.M{"webhook_id": webhookID} // Match the deliveries of the webhook
+	if status != "" {                         // Check if the status is filtered
+		filter["status"] = status // Match the status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}) // Sort by creation, newest first
+	return findAll[models.WebhookDelivery](ctx, r.collection, filter, opts)                         // Find the deliveries
+}
+
+// FindByID finds a delivery by ID
+func (r *DeliveryRepository) FindByID(ctx context.Context, id primitive.ObjectID) (models.WebhookDelivery, error) {
+	return findOne[models.WebhookDelivery](ctx, r.collection, bson.M{"_id": id}) // Find the delivery
+}
+
+// FindDue finds the pending deliveries due by now, oldest first
+func (r *DeliveryRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	filter := bson.M{"status": models.DeliveryStatusPending, "next_attempt_at": bson.M{"$lte": now}}                          // Match the pending deliveries due by now
+	opts := options.Find().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(int64(limit)) // Sort by due time, then by ID
+	return findAll[models.WebhookDelivery](ctx, r.collection, filter, opts)                                                   // Find the deliveries
+}
+
+// Insert inserts a new delivery, repository.ErrConflict is returned if the webhook already has one for the event
+func (r *DeliveryRepository) Insert(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := r.collection.InsertOne(ctx, delivery) // Insert the delivery
+	return conflictError(err)                       // Translate a delivery of the event already created
+}
+
+// Claim postpones a pending delivery due by now until the given time, repository.ErrNotFound is returned
+// if another worker claimed it
+func (r *DeliveryRepository) Claim(ctx context.Context, id primitive.ObjectID, now, until time.Time) error {
+	filter := bson.M{"_id": id, "status": models.DeliveryStatusPending, "next_attempt_at": bson.M{"$lte": now}} // Match the delivery while it is due
+	return updateOne(ctx, r.collection, filter, bson.M{"$set": bson.M{"next_attempt_at": until}})               // Postpone the delivery
+}
+
+// RecordAttempt appends an attempt and stores the status, failures, next attempt and delivery time of the delivery
+func (r *DeliveryRepository) RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.DeliveryAttempt) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":          delivery.Status,        // Set the status
+			"failures":        delivery.Failures,      // Set the failures
+			"next_attempt_at": delivery.NextAttemptAt, // Set the next attempt
+			"delivered_at":    delivery.DeliveredAt,   // Set the delivery time
+		},
+		"$push": bson.M{"attempts": attempt}, // Append the attempt
+	}
+	return updateOne(ctx, r.collection, bson.M{"_id": delivery.ID}, update) // Update the delivery
+}
+
+// Redeliver makes a delivery pending again from now with no failures
+func (r *DeliveryRepository) Redeliver(ctx context.Context, id primitive.ObjectID, now time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.DeliveryStatusPending, "failures": 0, "next_attempt_at": now}, // Deliver it on the next poll
+		"$unset": bson.M{"delivered_at": ""},                                                            // Keep the TTL index from removing it
+	}
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update) // Update the delivery
+}
+
+// DeleteByWebhook deletes the deliveries of a webhook
+func (r *DeliveryRepository) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"webhook_id": webhookID}) // Delete the deliveries of the webhook
+	return err                                                              // Return the error
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"training_session/pkg/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNotFound        = errors.New("document not found")         // Returned when no document matches the request
+	ErrConflict        = errors.New("document conflict")          // Returned when a write conflicts with the current state of the document
+	ErrVersionMismatch = errors.New("document version mismatch")  // Returned when a conditional write finds the document at another version
+	ErrFinalDeletion   = errors.New("document deletion is final") // Returned when restoring a document whose deletion cannot be undone
+	ErrRolledBack      = errors.New("transaction rolled back")    // Returned when a function joining a transaction failed and every write of the transaction was discarded
+)
+
+// Repositories groups the repositories of every aggregate handled by the service
+type Repositories struct {
+	Sessions      SessionRepository       // Training sessions
+	Users         UserRepository          // Users
+	Invitations   InvitationRepository    // Session invitations
+	Notifications NotificationRepository  // User notifications
+	Feedback      FeedbackRepository      // Feedback submitted for sessions and coaches
+	FeedbackForms FeedbackFormRepository  // Feedback form templates
+	ModerationLog ModerationLogRepository // Audit log of feedback moderation
+	Pitches       PitchRepository         // Pitch bookings
+	Idempotency   IdempotencyRepository   // Idempotency keys of the mutating requests
+	Outbox        OutboxRepository        // Domain events waiting to be published
+	Webhooks      WebhookRepository       // Webhook subscriptions of the partner applications
+	Deliveries    DeliveryRepository      // Deliveries of the domain events to the webhooks
+	Audit         AuditRepository         // Hash-chained log of the state-changing requests
+	Erasures      ErasureRepository       // Requests of the users to have their personal data erased
+	CheckIns      CheckInRepository       // Attendance verified with the QR codes of the sessions
+	Transactions  Transactor              // Runs the writes to several documents atomically
+}
+
+// Transactor runs functions in transactions: the writes a function makes with the context it is given are
+// committed together when it returns nil and discarded when it returns an error. The function may run more
+// than once, so it must not have effects outside of the repositories. A function given the context of a running
+// transaction joins it: transactions do not nest, so when it fails the whole transaction is rolled back.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error // Run fn in a transaction
+}
+
+// transactionKey is the key of the context value marking the context of a running transaction
+type transactionKey struct{}
+
+// transaction is the state of a running transaction shared by the functions joining it
+type transaction struct {
+	failed bool // Whether a function joining the transaction failed
+}
+
+// BeginTransaction returns the context of a new transaction for the Transactor implementations, and the function
+// reporting whether a function joining it failed: the transaction must then be rolled back with ErrRolledBack.
+func BeginTransaction(ctx context.Context) (context.Context, func() bool) { // Mark the context of a transaction
+	tx := &transaction{}                                                                  // Define the state of the transaction
+	return context.WithValue(ctx, transactionKey{}, tx), func() bool { return tx.failed } // Return the context and the failure check
+}
+
+// JoinTransaction runs fn in the transaction ctx belongs to and reports true, or reports false without running
+// fn when ctx belongs to no transaction. A failing fn makes the whole transaction roll back.
+func JoinTransaction(ctx context.Context, fn func(ctx context.Context) error) (bool, error) { // Join a transaction
+	tx, ok := ctx.Value(transactionKey{}).(*transaction) // Get the running transaction
+	if !ok {                                             // Check if no transaction is running
+		return false, nil // Let the caller start one
+	}
+	err := fn(ctx)  // Run the function in the transaction
+	if err != nil { // Check if the function failed
+		tx.failed = true // Roll the whole transaction back
+	}
+	return true, err // Return the error
+}
+
+// SoftDeleter soft deletes the documents of a collection: a deleted document is hidden from the other queries
+// of its repository until it is restored, and removed for good by Purge once the retention period is over
+type SoftDeleter interface {
+	SoftDelete(ctx context.Context, id primitive.ObjectID, deletion models.Deletion) error // Hide a document, ErrNotFound if it is missing or already deleted
+	Restore(ctx context.Context, id primitive.ObjectID) (models.Deletion, error)           // Show a deleted document again and return the undone deletion, ErrNotFound if it is missing or not deleted, ErrFinalDeletion if its deletion is final
+	Purge(ctx context.Context, before time.Time) (int64, error)                            // Remove the documents deleted before the given time and return their count
+}
+
+// SessionRepository stores training sessions, the soft deleted ones are only found by FindDeleted
+// and the writes by ID leave them untouched, returning ErrNotFound
+type SessionRepository interface {
+	SoftDeleter
+	FindAll(ctx context.Context) ([]models.Session, error)                                                // Find all sessions
+	FindByStatus(ctx context.Context, status string) ([]models.Session, error)                            // Find the sessions with the given status
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Session, error)                  // Find the sessions coached or attended by a user
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Session, error)                          // Find a session by ID
+	Insert(ctx context.Context, session *models.Session) error                                            // Insert a new session at version 1
+	Replace(ctx context.Context, session *models.Session) error                                           // Replace a session still at session.Version and increment it, ErrVersionMismatch if it changed
+	FindDeleted(ctx context.Context) ([]models.Session, error)                                            // Find the soft deleted sessions, last deleted first
+	AddParticipant(ctx context.Context, id, userID primitive.ObjectID) error                              // Add a participant to a session, ErrConflict if it is full
+	RemoveParticipant(ctx context.Context, id, userID primitive.ObjectID) error                           // Remove a user from the participants and the waitlist of a session
+	AddToWaitlist(ctx context.Context, id, userID primitive.ObjectID) error                               // Add a user to the end of the waitlist of a session
+	PromoteWaitlisted(ctx context.Context, id, userID primitive.ObjectID) error                           // Move a user from the waitlist to the participants of a session
+	SetStatus(ctx context.Context, id primitive.ObjectID, status string) error                            // Change the status of a session
+	SetQRCode(ctx context.Context, id primitive.ObjectID, qrCode string) error                            // Store the QR code content of a session
+	RemoveUser(ctx context.Context, userID primitive.ObjectID) (int64, error)                             // Remove a user from the participants, waitlist and assistants of every session and return the number of changed sessions
+	ReassignCoach(ctx context.Context, from, to primitive.ObjectID, endingAfter time.Time) (int64, error) // Give the live sessions of a coach ending after the given time to another coach, also their owner when the coach owned them, and return their count
+}
+
+// UserRepository stores users, the soft deleted ones are only found by FindDeleted and keep their email;
+// the writes by ID leave them untouched, returning ErrNotFound
+type UserRepository interface {
+	SoftDeleter
+	FindAll(ctx context.Context) ([]models.User, error)                                  // Find all users
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.User, error)            // Find a user by ID
+	FindByEmail(ctx context.Context, email string) (models.User, error)                  // Find a user by email
+	FindByRole(ctx context.Context, role string) ([]models.User, error)                  // Find the users with the given role
+	Insert(ctx context.Context, user *models.User) error                                 // Insert a new user at version 1, ErrConflict if the email is taken
+	Update(ctx context.Context, user models.User) error                                  // Update the non-empty fields of a user still at user.Version, ErrVersionMismatch if it changed, ErrConflict if the email is taken
+	Replace(ctx context.Context, user *models.User) error                                // Replace a user still at user.Version and increment it, ErrVersionMismatch if it changed, ErrConflict if the email is taken
+	FindDeleted(ctx context.Context) ([]models.User, error)                              // Find the soft deleted users, last deleted first
+	FindByIDWithDeleted(ctx context.Context, id primitive.ObjectID) (models.User, error) // Find a user by ID, live or soft deleted
+	Erase(ctx context.Context, user *models.User) error                                  // Replace a user, live or soft deleted, still at user.Version and increment it, ErrVersionMismatch if it changed
+}
+
+// InvitationRepository stores session invitations, the soft deleted ones are only found by FindDeleted
+// and the writes by ID leave them untouched, returning ErrNotFound
+type InvitationRepository interface {
+	SoftDeleter
+	FindAll(ctx context.Context) ([]models.Invitation, error)                                                 // Find all invitations
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Invitation, error)                           // Find an invitation by ID
+	Insert(ctx context.Context, invitation *models.Invitation) error                                          // Insert a new invitation
+	SetStatus(ctx context.Context, id primitive.ObjectID, status string) error                                // Change the status of an invitation
+	FindDeleted(ctx context.Context) ([]models.Invitation, error)                                             // Find the soft deleted invitations, last deleted first
+	SoftDeleteBySession(ctx context.Context, sessionID primitive.ObjectID, deletion models.Deletion) error    // Soft delete the live invitations to a session
+	RestoreBySession(ctx context.Context, sessionID primitive.ObjectID, deletedAt time.Time) error            // Restore the invitations to a session deleted at the given time
+	SoftDeleteByUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion) (int64, error) // Soft delete the live invitations of a user and return their count
+	RestoreByUser(ctx context.Context, userID primitive.ObjectID, deletedAt time.Time) error                  // Restore the invitations of a user deleted at the given time
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Invitation, error)                   // Find the invitations of a user
+}
+
+// NotificationRepository stores user notifications
+type NotificationRepository interface {
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error) // Find the notifications of a user
+	Insert(ctx context.Context, notification *models.Notification) error                      // Insert a new notification, ErrConflict if one with its ID was already inserted
+	Delete(ctx context.Context, id primitive.ObjectID) error                                  // Delete a notification
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)               // Delete the notifications of a user and return their count
+}
+
+// FeedbackFilter selects feedback, zero fields are ignored
+type FeedbackFilter struct {
+	UserID        primitive.ObjectID // Author of the feedback
+	CoachID       primitive.ObjectID // Coach who received the feedback
+	SessionID     primitive.ObjectID // Session the feedback was provided for
+	Status        string             // Only feedback with this status
+	ExcludeStatus string             // Only feedback without this status
+}
+
+// FeedbackEdit holds the fields an author can change on a feedback
+type FeedbackEdit struct {
+	Version   int64                   // Version the feedback must still be at
+	Content   string                  // New content
+	Rating    int                     // New rating
+	Anonymous bool                    // New anonymous display choice
+	Answers   []models.FeedbackAnswer // New answers, unchanged when nil
+	Status    string                  // New moderation status, unchanged when empty
+}
+
+// FeedbackRepository stores feedback
+type FeedbackRepository interface {
+	Find(ctx context.Context, filter FeedbackFilter) ([]models.Feedback, error)                        // Find feedback, oldest first
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Feedback, error)                      // Find a feedback by ID
+	Insert(ctx context.Context, feedback *models.Feedback) error                                       // Insert a new feedback at version 1
+	Edit(ctx context.Context, id primitive.ObjectID, edit FeedbackEdit) error                          // Edit a feedback still at edit.Version, ErrVersionMismatch if it changed
+	AddFlag(ctx context.Context, id primitive.ObjectID, flag models.FeedbackFlag, status string) error // Add a flag, changing the status when not empty
+	SetStatus(ctx context.Context, id primitive.ObjectID, status string) error                         // Change the moderation status of a feedback
+	SetReply(ctx context.Context, id primitive.ObjectID, reply models.CoachReply) error                // Set the reply of a feedback, ErrConflict if it already has one
+	Delete(ctx context.Context, id primitive.ObjectID) error                                           // Delete a feedback
+	AnonymizeAuthor(ctx context.Context, userID primitive.ObjectID) (int64, error)                     // Detach the feedback of a user from them, marked anonymous, and return its count
+	AnonymizeFlags(ctx context.Context, userID primitive.ObjectID) (int64, error)                      // Detach the flags a user raised from them and return the count of the flagged feedback
+	AnonymizeReplies(ctx context.Context, userID primitive.ObjectID) (int64, error)                    // Detach the replies a coach posted from them and return their count
+}
+
+// FeedbackFormRepository stores feedback form templates
+type FeedbackFormRepository interface {
+	FindAll(ctx context.Context, trainingType string) ([]models.FeedbackForm, error)                               // Find the forms, filtered by training type when not empty
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.FeedbackForm, error)                              // Find a form by ID
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.FeedbackForm, error)                        // Find the forms with the given IDs
+	FindByOwner(ctx context.Context, ownerID primitive.ObjectID, trainingType string) (models.FeedbackForm, error) // Find the form an owner defined for a training type
+	Insert(ctx context.Context, form *models.FeedbackForm) error                                                   // Insert a new form, ErrConflict when the owner has a form for the training type
+	Update(ctx context.Context, form models.FeedbackForm) error                                                    // Update the definition of a form still at form.Version, ErrVersionMismatch otherwise and ErrConflict when the owner has a form for the training type
+	Delete(ctx context.Context, id primitive.ObjectID) error                                                       // Delete a form
+}
+
+// ModerationLogRepository stores the audit log of feedback moderation
+type ModerationLogRepository interface {
+	FindByFeedback(ctx context.Context, feedbackID primitive.ObjectID) ([]models.ModerationAction, error) // Find the actions performed on a feedback, oldest first
+	Insert(ctx context.Context, action *models.ModerationAction) error                                    // Append an action
+}
+
+// PitchRepository stores pitch bookings, the soft deleted ones are only found by FindDeleted
+// and the writes by ID leave them untouched, returning ErrNotFound
+type PitchRepository interface {
+	SoftDeleter
+	FindAll(ctx context.Context) ([]models.Pitch, error)                                                      // Find all pitch bookings
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Pitch, error)                                // Find a pitch booking by ID
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Pitch, error)                        // Find the pitch bookings of a user
+	Insert(ctx context.Context, pitch *models.Pitch) error                                                    // Insert a new pitch booking at version 1
+	Replace(ctx context.Context, pitch *models.Pitch) error                                                   // Replace a pitch booking still at pitch.Version and increment it, ErrVersionMismatch if it changed
+	FindDeleted(ctx context.Context) ([]models.Pitch, error)                                                  // Find the soft deleted pitch bookings, last deleted first
+	SoftDeleteByUser(ctx context.Context, userID primitive.ObjectID, deletion models.Deletion) (int64, error) // Soft delete the live pitch bookings of a user and return their count
+	RestoreByUser(ctx context.Context, userID primitive.ObjectID, deletedAt time.Time) error                  // Restore the pitch bookings of a user deleted at the given time
+}
+
+// IdempotencyRepository stores the idempotency keys of the mutating requests and their responses
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (models.IdempotencyRecord, error) // Reserve a key, ErrConflict and the current record if it is held or completed
+	Complete(ctx context.Context, id string, response models.IdempotentResponse) error                                     // Store the response of the request holding a key
+	Release(ctx context.Context, id string) error                                                                          // Forget a key so the request can be retried
+}
+
+// OutboxRepository stores the domain events written with the changes they describe until they are published
+type OutboxRepository interface {
+	Insert(ctx context.Context, event *models.Event) error                        // Append an event
+	FindPending(ctx context.Context, limit int) ([]models.Event, error)           // Find the events not published yet, oldest first
+	MarkPublished(ctx context.Context, id primitive.ObjectID, at time.Time) error // Record that the broker accepted an event
+	MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error   // Record a failed publication of an event
+	Lease(ctx context.Context, owner string, now, until time.Time) error          // Hold the right to publish until the given time, ErrConflict while another owner holds it
+}
+
+// WebhookRepository stores the webhook subscriptions
+type WebhookRepository interface {
+	FindByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Webhook, error)                         // Find the webhooks of a business owner, oldest first
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.Webhook, error)                                   // Find a webhook by ID
+	FindSubscribed(ctx context.Context, eventType string, ownerIDs []primitive.ObjectID) ([]models.Webhook, error) // Find the active webhooks of the owners receiving the events of the type
+	Insert(ctx context.Context, webhook *models.Webhook) error                                                     // Insert a new webhook
+	Update(ctx context.Context, webhook models.Webhook) error                                                      // Update the URL, description, event types and state of a webhook still at webhook.Version, ErrVersionMismatch otherwise
+	Delete(ctx context.Context, id primitive.ObjectID) error                                                       // Delete a webhook
+	DisableByOwner(ctx context.Context, ownerID primitive.ObjectID) (int64, error)                                 // Stop delivering to the active webhooks of a business owner and return their count
+}
+
+// DeliveryRepository stores the deliveries of the domain events to the webhooks
+type DeliveryRepository interface {
+	FindByWebhook(ctx context.Context, webhookID primitive.ObjectID, status string) ([]models.WebhookDelivery, error) // Find the deliveries of a webhook, filtered by status when not empty, newest first
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.WebhookDelivery, error)                              // Find a delivery by ID
+	FindDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)                          // Find the pending deliveries due by now, oldest first
+	Insert(ctx context.Context, delivery *models.WebhookDelivery) error                                               // Insert a new delivery, ErrConflict if the webhook already has one for the event
+	Claim(ctx context.Context, id primitive.ObjectID, now, until time.Time) error                                     // Postpone a pending delivery due by now until the given time, ErrNotFound if another worker claimed it
+	RecordAttempt(ctx context.Context, delivery models.WebhookDelivery, attempt models.DeliveryAttempt) error         // Append an attempt and store the status, failures, next attempt and delivery time of the delivery
+	Redeliver(ctx context.Context, id primitive.ObjectID, now time.Time) error                                        // Make a delivery pending again from now with no failures
+	DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error                                          // Delete the deliveries of a webhook
+}
+
+// AuditFilter selects audit entries, every field left empty matching every entry
+type AuditFilter struct {
+	ActorID    *primitive.ObjectID // Entries of the user
+	Action     string              // Entries of the method and route
+	TargetType string              // Entries changing a kind of document
+	TargetID   string              // Entries changing the document
+	From       time.Time           // Entries that occurred at or after this time
+	To         time.Time           // Entries that occurred before this time
+	Before     int64               // Entries with a lower sequence, to page through the log
+	Limit      int                 // Maximum number of entries, every entry when 0
+}
+
+// AuditRepository stores the audit log, an append-only chain of entries ordered by sequence
+type AuditRepository interface {
+	Last(ctx context.Context) (models.AuditEntry, error)                                // Find the entry with the highest sequence, ErrNotFound when the log is empty
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.AuditEntry, error)     // Find an entry by ID
+	Append(ctx context.Context, entry *models.AuditEntry) error                         // Insert an entry, ErrConflict if its sequence or ID is taken
+	Find(ctx context.Context, filter AuditFilter) ([]models.AuditEntry, error)          // Find the entries matching the filter, newest first
+	FindRange(ctx context.Context, after int64, limit int) ([]models.AuditEntry, error) // Find the entries following a sequence, in sequence order
+}
+
+// ErasureRepository stores the erasure requests, a user has at most one pending request
+type ErasureRepository interface {
+	Find(ctx context.Context, status string) ([]models.ErasureRequest, error)                   // Find the requests, filtered by status when not empty, oldest first
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.ErasureRequest, error) // Find the requests of a user, newest first
+	FindByID(ctx context.Context, id primitive.ObjectID) (models.ErasureRequest, error)         // Find a request by ID
+	Insert(ctx context.Context, request *models.ErasureRequest) error                           // Insert a new request, ErrConflict if the user already has a pending one
+	Review(ctx context.Context, request models.ErasureRequest) error                            // Store the status, reviewer, review time and note of a pending request, ErrNotFound if it is no longer pending
+}
+
+// CheckInRepository stores the check-ins, a user checks in to a session at most once
+type CheckInRepository interface {
+	Find(ctx context.Context, sessionID, userID primitive.ObjectID) (models.CheckIn, error) // Find the check-in of a user in a session, ErrNotFound if they have not checked in
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.CheckIn, error)    // Find the check-ins of a user, oldest first
+	Insert(ctx context.Context, checkIn *models.CheckIn) error                              // Insert a new check-in, ErrConflict if the user already checked in to the session
+}
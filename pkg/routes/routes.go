@@ -2,65 +2,132 @@ package routes
 
 import ( // Import the required packages
 	"training_session/pkg/controllers"
-	"training_session/pkg/middleware"
+	"training_session/pkg/health"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine) { // SetupRoutes function to define the routes
+// ProbePaths are the paths of the probe routes, left out of the request log
+var ProbePaths = []string{"/healthz", "/readyz", "/version", "/metrics"}
+
+// CredentialPaths are the paths of the routes issuing or revoking tokens, whose responses are never stored for
+// idempotent replay
+var CredentialPaths = []string{"/users/login", "/users/logout"}
+
+// SetupProbeRoutes registers the liveness, readiness, version and metrics routes, which never require authentication
+func SetupProbeRoutes(r *gin.Engine, probes *health.Probes, metrics gin.HandlerFunc) { // SetupProbeRoutes function to define the probe routes
+	r.GET("/healthz", probes.Healthz) // Define a route to check that the process is alive
+	r.GET("/readyz", probes.Readyz)   // Define a route to check that the server can take traffic
+	r.GET("/version", probes.Version) // Define a route to get the build information
+	r.GET("/metrics", metrics)        // Define a route to scrape the Prometheus metrics
+}
+
+// SetupRoutes registers the handlers of the controller, protecting the private routes with the auth middleware
+// and identifying the caller of the public user routes, whose response depends on who asks, with identify
+func SetupRoutes(r *gin.Engine, ctrl *controllers.Controller, auth, identify gin.HandlerFunc) { // SetupRoutes function to define the routes
 	// Add routes for users
-	r.GET("/users", controllers.GetUsers)                     // Define a route to get all users
-	r.GET("/users/:userId", controllers.GetUserByID)          // Define a route to get a user by ID
-	r.POST("/users/register", controllers.RegisterUser)       // Define a route to register a new user
-	r.POST("/users/login", controllers.LoginUser)             // Define a route to login a user
-	r.POST("/users/logout", controllers.LogoutUser)           // Define a route to logout a user
-	r.PUT("/users/update/:userId", controllers.UpdateUser)    // Define a route to update a user
-	r.DELETE("/users/delete/:userId", controllers.DeleteUser) // Define a route to delete a user
+	r.GET("/users", identify, ctrl.GetUsers)            // Define a route to get all users
+	r.GET("/users/:userId", identify, ctrl.GetUserByID) // Define a route to get a user by ID
+	r.POST("/users/register", ctrl.RegisterUser)        // Define a route to register a new user
+	r.POST("/users/login", ctrl.LoginUser)              // Define a route to login a user
+	r.POST("/users/logout", ctrl.LogoutUser)            // Define a route to logout a user
 
 	// Protected routes with authentication middleware
 	protected := r.Group("/")
-	protected.Use(middleware.AuthMiddleware()) // Use the AuthMiddleware to authenticate requests
+	protected.Use(auth) // Use the auth middleware to authenticate requests
+
+	// Add protected routes for users
+	protected.PUT("/users/update/:userId", ctrl.UpdateUser)    // Define a route to update a user
+	protected.PATCH("/users/:userId", ctrl.PatchUser)          // Define a route to patch a user
+	protected.DELETE("/users/delete/:userId", ctrl.DeleteUser) // Define a route to delete a user, recording who deleted them
 
 	// Add routes for sessions
-	protected.POST("/sessions/create", controllers.CreateSession)                                              // Define a route to create a new session
-	protected.PUT("/sessions/:sessionId/update", controllers.UpdateSession)                                    // Define a route to update a session
-	protected.GET("/sessions", controllers.GetSessions)                                                 // Define a route to get all sessions
-	protected.GET("/sessions/active", controllers.GetActiveSessions)                                    // Define a route to get all active sessions
-	protected.GET("/sessions/:sessionId", controllers.GetSessionByID)                                   // Define a route to get a session by ID
-	protected.GET("/sessions/user/:userId", controllers.GetSessionsByUserID)                            // Define a route to get all sessions by a user ID
-	protected.POST("/sessions/:sessionId/user/:userId/enroll", controllers.EnrollInSession)             // Define a route to enroll in a session
-	protected.POST("/sessions/:sessionId/user/:userId/cancel-enrollment", controllers.CancelEnrollment) // Define a route to cancel enrollment in a session
-	protected.POST("/sessions/:sessionId/cancel", controllers.CancelSession)                            // Define a route to cancel a session
-	protected.POST("/sessions/:sessionId/archive", controllers.ArchiveSession)                          // Define a route to archive a session
+	protected.POST("/sessions/create", ctrl.CreateSession)                                                     // Define a route to create a new session
+	protected.PUT("/sessions/:sessionId/update", ctrl.UpdateSession)                                           // Define a route to update a session
+	protected.PATCH("/sessions/:sessionId", ctrl.PatchSession)                                                 // Define a route to patch a session
+	protected.GET("/sessions", ctrl.GetSessions)                                                        // Define a route to get all sessions
+	protected.GET("/sessions/active", ctrl.GetActiveSessions)                                           // Define a route to get all active sessions
+	protected.GET("/sessions/:sessionId", ctrl.GetSessionByID)                                          // Define a route to get a session by ID
+	protected.GET("/sessions/user/:userId", ctrl.GetSessionsByUserID)                                   // Define a route to get all sessions by a user ID
+	protected.POST("/sessions/:sessionId/user/:userId/enroll", ctrl.EnrollInSession)                    // Define a route to enroll in a session
+	protected.POST("/sessions/:sessionId/user/:userId/cancel-enrollment", ctrl.CancelEnrollment)        // Define a route to cancel enrollment in a session
+	protected.POST("/sessions/:sessionId/cancel", ctrl.CancelSession)                                   // Define a route to cancel a session
+	protected.POST("/sessions/:sessionId/archive", ctrl.ArchiveSession)                                 // Define a route to archive a session
 
 	// Add routes for invitations
-	r.POST("/invitations", controllers.SendInvitation)                          // Define a route to send an invitation
-	r.POST("/invitations/:invitationId/accept", controllers.AcceptInvitation)   // Define a route to accept an invitation
-	r.POST("/invitations/:invitationId/decline", controllers.DeclineInvitation) // Define a route to decline an invitation
-	r.GET("/invitations", controllers.GetInvitations)                           // Define a route to get all invitations
-	r.GET("/invitations/:invitationId", controllers.GetInvitationByID)          // Define a route to get an invitation by ID
-	r.DELETE("/invitations/:invitationId", controllers.DeleteInvitation)        // Define a route to delete an invitation
+	r.POST("/invitations", ctrl.SendInvitation)                                 // Define a route to send an invitation
+	r.POST("/invitations/:invitationId/accept", ctrl.AcceptInvitation)          // Define a route to accept an invitation
+	r.POST("/invitations/:invitationId/decline", ctrl.DeclineInvitation)        // Define a route to decline an invitation
+	r.GET("/invitations", ctrl.GetInvitations)                                  // Define a route to get all invitations
+	r.GET("/invitations/:invitationId", ctrl.GetInvitationByID)                 // Define a route to get an invitation by ID
+	r.DELETE("/invitations/:invitationId", identify, ctrl.DeleteInvitation)     // Define a route to delete an invitation, recording who deleted it
 
 	// Add routes for QR codes
-	r.GET("/sessions/:sessionId/qrcode", controllers.GenerateQRCode)   // Define a route to generate a QR code
-	r.GET("/sessions/:sessionId/validate", controllers.ValidateQRCode) // Define a route to validate a QR code
+	r.GET("/sessions/:sessionId/qrcode", ctrl.GenerateQRCode)            // Define a route to generate a QR code
+	protected.POST("/sessions/:sessionId/validate", ctrl.ValidateQRCode) // Define a route to check in with a QR code
 
 	// Add routes for Feedback
-	r.POST("/feedback", controllers.SubmitFeedback)               // Define a route to submit feedback
-	r.GET("/feedback/user/:userId", controllers.ViewFeedback)     // Define a route to view feedback
-	r.PUT("/feedback/:feedbackId", controllers.EditFeedback)      // Define a route to edit feedback
-	r.DELETE("/feedback/:feedbackId", controllers.DeleteFeedback) // Define a route to delete feedback
+	r.POST("/feedback", ctrl.SubmitFeedback)                     // Define a route to submit feedback
+	r.GET("/feedback/user/:userId", identify, ctrl.ViewFeedback) // Define a route to view feedback, in full for its author and admins
+	protected.PUT("/feedback/:feedbackId", ctrl.EditFeedback)    // Define a route to edit feedback
+	r.DELETE("/feedback/:feedbackId", ctrl.DeleteFeedback)       // Define a route to delete feedback
+	protected.PATCH("/feedback/:feedbackId", ctrl.PatchFeedback) // Define a route to patch feedback
+
+	// Add routes for Feedback forms and reports
+	protected.POST("/feedback/forms", ctrl.CreateFeedbackForm)                                  // Define a route to create a feedback form
+	protected.PUT("/feedback/forms/:formId", ctrl.UpdateFeedbackForm)                           // Define a route to update a feedback form
+	protected.DELETE("/feedback/forms/:formId", ctrl.DeleteFeedbackForm)                        // Define a route to delete a feedback form
+	r.GET("/feedback/forms", ctrl.GetFeedbackForms)                                             // Define a route to get all feedback forms
+	r.GET("/feedback/forms/:formId", ctrl.GetFeedbackFormByID)                                  // Define a route to get a feedback form by ID
+	r.GET("/feedback/reports/coach/:coachId", ctrl.GetCoachFeedbackReport)                      // Define a route to get the feedback report of a coach
+	r.GET("/feedback/reports/session/:sessionId", ctrl.GetSessionFeedbackReport)                // Define a route to get the feedback report of a session
+
+	// Add routes for Feedback moderation and coach replies
+	r.GET("/feedback/coach/:coachId", ctrl.GetCoachFeedback)                                   // Define a route to get the public feedback of a coach
+	protected.POST("/feedback/:feedbackId/flag", ctrl.FlagFeedback)                             // Define a route to flag a feedback
+	protected.POST("/feedback/:feedbackId/reply", ctrl.ReplyToFeedback)                         // Define a route to reply to a feedback
+	protected.GET("/feedback/moderation/queue", ctrl.GetModerationQueue)                        // Define a route to get the moderation queue
+	protected.POST("/feedback/:feedbackId/approve", ctrl.ApproveFeedback)                       // Define a route to approve a feedback
+	protected.POST("/feedback/:feedbackId/hide", ctrl.HideFeedback)                             // Define a route to hide a feedback
+	protected.GET("/feedback/:feedbackId/moderation-log", ctrl.GetModerationLog)                // Define a route to get the moderation log of a feedback
+
+	// Add routes for Webhooks
+	protected.POST("/webhooks", ctrl.CreateWebhook)                                                        // Define a route to register a webhook
+	protected.GET("/webhooks", ctrl.GetWebhooks)                                                           // Define a route to get the webhooks of the user
+	protected.GET("/webhooks/:webhookId", ctrl.GetWebhookByID)                                             // Define a route to get a webhook by ID
+	protected.PUT("/webhooks/:webhookId", ctrl.UpdateWebhook)                                              // Define a route to update a webhook
+	protected.DELETE("/webhooks/:webhookId", ctrl.DeleteWebhook)                                           // Define a route to delete a webhook
+	protected.GET("/webhooks/:webhookId/deliveries", ctrl.GetWebhookDeliveries)                            // Define a route to get the delivery log of a webhook
+	protected.GET("/webhooks/:webhookId/dead-letters", ctrl.GetWebhookDeadLetters)                         // Define a route to get the dead letters of a webhook
+	protected.POST("/webhooks/:webhookId/deliveries/:deliveryId/redeliver", ctrl.RedeliverWebhookDelivery) // Define a route to redeliver a webhook delivery
+
+	// Add routes for personal data
+	protected.GET("/privacy/export", ctrl.ExportPersonalData)    // Define a route to export the personal data of the user
+	protected.POST("/privacy/erasure", ctrl.RequestErasure)      // Define a route to request the erasure of the personal data of the user
+	protected.GET("/privacy/erasure", ctrl.GetMyErasureRequests) // Define a route to get the erasure requests of the user
+
+	// Add routes for administration
+	protected.GET("/admin/config", ctrl.GetConfig)                                    // Define a route to get the redacted configuration
+	protected.GET("/admin/deleted/:kind", ctrl.GetDeleted)                            // Define a route to get the soft deleted documents of a kind
+	protected.POST("/admin/deleted/:kind/:id/restore", ctrl.RestoreDeleted)           // Define a route to restore a soft deleted document
+	protected.GET("/admin/audit", ctrl.GetAuditLog)                                   // Define a route to get the audit log
+	protected.GET("/admin/audit/export", ctrl.ExportAuditLog)                         // Define a route to export the audit log
+	protected.GET("/admin/audit/verify", ctrl.VerifyAuditLog)                         // Define a route to verify the hash chain of the audit log
+	protected.GET("/admin/erasure-requests", ctrl.GetErasureRequests)                 // Define a route to get the erasure requests waiting for review
+	protected.POST("/admin/erasure-requests/:requestId/approve", ctrl.ApproveErasure) // Define a route to approve an erasure request
+	protected.POST("/admin/erasure-requests/:requestId/reject", ctrl.RejectErasure)   // Define a route to reject an erasure request
 
 	// Add routes for Notifications
-	r.POST("/notifications/user", controllers.SendUserNotification)            // Define a route to send a user notification
-	r.GET("/notifications/:userId", controllers.GetNotifications)              // Define a route to get notifications for a user
-	r.DELETE("/notifications/:notificationId", controllers.DeleteNotification) // Define a route to delete a notification
+	r.POST("/notifications/user", ctrl.SendUserNotification)                   // Define a route to send a user notification
+	r.GET("/notifications/:userId", ctrl.GetNotifications)                     // Define a route to get notifications for a user
+	r.DELETE("/notifications/:notificationId", ctrl.DeleteNotification)        // Define a route to delete a notification
 
 	// Add routes for Pitch bookings
-	r.POST("/pitches", controllers.BookPitch)                            // Define a route to book a pitch
-	r.GET("/pitches", controllers.GetPitchBookings)                      // Define a route to get all pitch bookings
-	r.GET("/pitches/:pitchId", controllers.GetPitchBookingsByPitchID)    // Define a route to get pitch bookings by pitch ID
-	r.GET("/pitches/user/:userId", controllers.GetPitchBookingsByUserID) // Define a route to get pitch bookings by user ID
-	r.PUT("/pitches/:pitchId", controllers.UpdatePitchBooking)           // Define a route to update a pitch booking
-	r.DELETE("/pitches/:pitchId", controllers.DeletePitchBooking)        // Define a route to delete a pitch booking
+	r.POST("/pitches", ctrl.BookPitch)                                   // Define a route to book a pitch
+	r.GET("/pitches", ctrl.GetPitchBookings)                             // Define a route to get all pitch bookings
+	r.GET("/pitches/:pitchId", ctrl.GetPitchBookingByID)                // Define a route to get a pitch booking by ID
+	r.GET("/pitches/user/:userId", ctrl.GetPitchBookingsByUserID)        // Define a route to get pitch bookings by user ID
+	protected.PUT("/pitches/:pitchId", ctrl.UpdatePitchBooking)          // Define a route to update a pitch booking
+	protected.PATCH("/pitches/:pitchId", ctrl.PatchPitchBooking)         // Define a route to patch a pitch booking
+	r.DELETE("/pitches/:pitchId", identify, ctrl.DeletePitchBooking)     // Define a route to delete a pitch booking, recording who deleted it
 } // End of SetupRoutes function
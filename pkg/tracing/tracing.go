@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"training_session/config"
+	"training_session/pkg/buildinfo"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Setup installs the W3C trace-context propagator and, unless the exporter is "none", a tracer
+// provider exporting the spans. The returned provider must be shut down to flush the last spans,
+// it is nil when tracing is disabled.
+func Setup(ctx context.Context, cfg config.TracingConfig) (*sdktrace.TracerProvider, error) { // Set up tracing
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})) // Propagate the trace context
+
+	var exporter sdktrace.SpanExporter // Define the span exporter
+	switch cfg.Exporter {              // Create the configured exporter
+	case "none": // Tracing is disabled
+		return nil, nil // Keep the no-op provider
+	case "stdout": // Print the spans
+		var err error                                                                                     // Define the error
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint()) // Create the stdout exporter
+		if err != nil {                                                                                   // Check if there is an error
+			return nil, fmt.Errorf("failed to create the stdout exporter: %w", err) // Return the error
+		}
+	case "otlp": // Send the spans to a collector
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)} // Set the collector
+		if cfg.Insecure {                                                        // Check if TLS must be disabled
+			opts = append(opts, otlptracehttp.WithInsecure()) // Use plain HTTP
+		}
+		var err error                                   // Define the error
+		exporter, err = otlptracehttp.New(ctx, opts...) // Create the OTLP exporter
+		if err != nil {                                 // Check if there is an error
+			return nil, fmt.Errorf("failed to create the OTLP exporter: %w", err) // Return the error
+		}
+	default: // Unknown exporter
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter) // Return the error
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),            // Name the service
+		semconv.ServiceVersion(buildinfo.Get().Version), // Version the service
+	)) // Describe the service
+	if err != nil { // Check if there is an error
+		return nil, fmt.Errorf("failed to describe the service: %w", err) // Return the error
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter), // Export the spans in batches
+		sdktrace.WithResource(res),     // Attach the service
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))), // Follow the caller, sample the rest
+	) // Create the tracer provider
+	otel.SetTracerProvider(provider) // Install the provider
+	return provider, nil             // Return the provider
+}
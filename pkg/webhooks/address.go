@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"training_session/config"
+)
+
+// ErrPrivateAddress is returned when a webhook connects to an address that is not reachable on the internet
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// reservedPrefixes are the ranges netip does not report as private although the internet cannot reach them:
+// "this network", shared carrier-grade NAT, IETF protocol assignments, benchmarking, reserved, and NAT64, which
+// maps any IPv4 address and so the private ones too
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // This network
+	netip.MustParsePrefix("100.64.0.0/10"), // Shared address space
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64
+}
+
+// internalSuffixes end the host names of internal networks, which public DNS never resolves
+var internalSuffixes = []string{".localhost", ".local", ".localdomain", ".internal", ".intranet", ".lan", ".home.arpa"}
+
+// PublicIP reports whether an address can be reached on the internet: not a loopback, private, link-local,
+// multicast, unspecified or reserved address
+func PublicIP(addr netip.Addr) bool { // Check an address
+	addr = addr.Unmap()                              // Check an IPv4-mapped IPv6 address as IPv4
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() { // Check if the address is loopback, link-local, multicast, unspecified or private
+		return false // Not reachable on the internet
+	}
+	return !slices.ContainsFunc(reservedPrefixes, func(prefix netip.Prefix) bool { return prefix.Contains(addr) }) // Check the reserved ranges
+}
+
+// PublicHost reports whether a webhook may be registered with the host of its URL: an IP address must be public,
+// and a name must have several labels and not end with the suffix of an internal network. A public name can still
+// resolve to a private address, so the dispatcher checks the resolved address again on every connection.
+func PublicHost(host string) bool { // Check a host
+	if addr, err := netip.ParseAddr(host); err == nil { // Check if the host is an IP address
+		return PublicIP(addr) // Check the address
+	}
+	name := strings.TrimSuffix(strings.ToLower(host), ".")   // Normalize the name
+	if name == "localhost" || !strings.Contains(name, ".") { // Check if the name is local or a single label, resolved by the search domains
+		return false // Internal name
+	}
+	return !slices.ContainsFunc(internalSuffixes, func(suffix string) bool { return strings.HasSuffix(name, suffix) }) // Check the internal suffixes
+}
+
+// dialControl refuses to connect to an address PublicIP rejects. It runs once the host is resolved, right before
+// connecting, so a name resolving to a private address is refused even when it changed after the registration.
+func dialControl(network, address string, _ syscall.RawConn) error { // Check the resolved address
+	addrPort, err := netip.ParseAddrPort(address) // Parse the resolved address
+	if err != nil {                               // Check if there is an error
+		return fmt.Errorf("invalid webhook address %q: %w", address, err) // Return the error
+	}
+	if !PublicIP(addrPort.Addr()) { // Check if the address is not public
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addrPort.Addr()) // Refuse the connection
+	}
+	return nil // Connect
+}
+
+// newClient creates the client posting the events. It never follows redirects nor uses a proxy, and unless
+// private networks are allowed it only connects to public addresses.
+func newClient(cfg config.WebhooksConfig) *http.Client { // Create the client
+	dialer := &net.Dialer{Timeout: cfg.Timeout, KeepAlive: 30 * time.Second} // Define the dialer
+	if !cfg.AllowPrivateNetworks {                                           // Check if the private networks are off limits
+		dialer.Control = dialControl // Check every resolved address
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,                // Connect directly, a proxy would hide the address from the check
+		DialContext:           dialer.DialContext, // Connect with the checking dialer
+		ForceAttemptHTTP2:     true,               // Use HTTP/2 when the receiver supports it
+		MaxIdleConns:          100,                // Keep connections to the receivers
+		IdleConnTimeout:       90 * time.Second,   // Close the unused connections
+		TLSHandshakeTimeout:   cfg.Timeout,        // Give up on a silent TLS handshake
+		ExpectContinueTimeout: time.Second,        // Wait briefly for a 100 Continue
+	}
+	return &http.Client{
+		Transport: transport,   // Post with the checking transport
+		Timeout:   cfg.Timeout, // Give up on a silent receiver
+		CheckRedirect: func(*http.Request, []*http.Request) error { // Never follow redirects
+			return http.ErrUseLastResponse // A redirect is a failed attempt
+		},
+	}
+}
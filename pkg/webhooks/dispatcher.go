@@ -0,0 +1,279 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"training_session/config"
+	"training_session/pkg/events"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outcomes of a delivery attempt
+const (
+	OutcomeDelivered = "delivered" // The receiver accepted the event
+	OutcomeRetrying  = "retrying"  // The attempt failed, the delivery is retried after the backoff
+	OutcomeDead      = "dead"      // The last attempt failed, the delivery is moved to the dead letters
+)
+
+// Observer is told the outcome of every delivery attempt
+type Observer interface {
+	ObserveWebhookDelivery(eventType, outcome string) // Record an attempt to deliver an event of the type
+}
+
+// Dispatcher delivers the domain events to the webhooks subscribed to them. As a broker of the outbox relay it
+// creates a pending delivery per subscribed webhook of the owner of the event and of the admins, and DeliverDue
+// posts the due deliveries, retrying the failed ones with an exponential backoff until they succeed or run out
+// of attempts.
+type Dispatcher struct {
+	webhooks   repository.WebhookRepository  // Subscriptions the events are matched against
+	deliveries repository.DeliveryRepository // Deliveries and their attempts
+	sessions   repository.SessionRepository  // Sessions owning the events
+	users      repository.UserRepository     // Admins receiving every event
+	cfg        config.WebhooksConfig         // Timeout, attempts and backoff
+	client     *http.Client                  // Client posting the events
+	observer   Observer                      // Observer of the attempts
+	logger     *slog.Logger                  // Logger of the failed deliveries
+}
+
+// NewDispatcher creates a dispatcher delivering the events with the repositories
+func NewDispatcher(repos *repository.Repositories, cfg config.WebhooksConfig, observer Observer, logger *slog.Logger) *Dispatcher { // Create a dispatcher
+	return &Dispatcher{
+		webhooks:   repos.Webhooks,   // Find the subscriptions
+		deliveries: repos.Deliveries, // Store the deliveries
+		sessions:   repos.Sessions,   // Find the owners of the sessions
+		users:      repos.Users,      // Find the admins
+		cfg:        cfg,              // Set the configuration
+		client:     newClient(cfg),   // Post to the public addresses only
+		observer:   observer,         // Set the observer
+		logger:     logger,           // Set the logger
+	}
+}
+
+// Publish creates a pending delivery of the event for every active webhook subscribed to its type, owned by the
+// owner of the event or by an admin. An event published again by the relay is not delivered twice to the same
+// webhook.
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error { // Queue the deliveries of an event
+	owners, err := d.recipients(ctx, event) // Find the owners receiving the event
+	if err != nil {                         // Check if there is an error
+		return err // Return the error
+	}
+	webhooks, err := d.webhooks.FindSubscribed(ctx, event.Type, owners) // Find the subscribed webhooks
+	if err != nil {                                                     // Check if there is an error
+		return fmt.Errorf("failed to find the webhooks: %w", err) // Return the error
+	}
+	if len(webhooks) == 0 { // Check if nobody subscribed to the event
+		return nil // Nothing to deliver
+	}
+
+	payload, err := events.Encode(event) // Encode the event as the brokers do
+	if err != nil {                      // Check if there is an error
+		return fmt.Errorf("failed to encode the event: %w", err) // Return the error
+	}
+	now := time.Now()                  // Get the current time
+	for _, webhook := range webhooks { // Iterate over the webhooks
+		delivery := models.WebhookDelivery{
+			ID:            primitive.NewObjectID(),      // Generate a new ObjectID for the delivery
+			WebhookID:     webhook.ID,                   // Set the webhook
+			EventID:       event.ID,                     // Set the event
+			EventType:     event.Type,                   // Set the event type
+			Payload:       payload,                      // Set the payload
+			Status:        models.DeliveryStatusPending, // Deliver it on the next poll
+			NextAttemptAt: now,                          // Deliver it now
+			Attempts:      []models.DeliveryAttempt{},   // Start without attempts
+			CreatedAt:     now,                          // Set the created_at timestamp
+		}
+		err := d.deliveries.Insert(ctx, &delivery)                 // Insert the delivery
+		if err != nil && !errors.Is(err, repository.ErrConflict) { // Check if there is an error other than an event already queued
+			return fmt.Errorf("failed to queue the delivery to webhook %s: %w", webhook.ID.Hex(), err) // Return the error
+		}
+	}
+	return nil // Every delivery is queued
+}
+
+// recipients returns the users whose webhooks receive an event: the admins, and the owner of the event when
+// there is one
+func (d *Dispatcher) recipients(ctx context.Context, event models.Event) ([]primitive.ObjectID, error) { // Find the recipients
+	admins, err := d.users.FindByRole(ctx, models.RoleAdmin) // Find the admins
+	if err != nil {                                          // Check if there is an error
+		return nil, fmt.Errorf("failed to find the admins: %w", err) // Return the error
+	}
+	owners := make([]primitive.ObjectID, 0, len(admins)+1) // Define a slice to hold the recipients
+	for _, admin := range admins {                         // Iterate over the admins
+		owners = append(owners, admin.ID) // Add the admin
+	}
+
+	owner, err := d.owner(ctx, event) // Find the owner of the event
+	if err != nil {                   // Check if there is an error
+		return nil, err // Return the error
+	}
+	if !owner.IsZero() { // Check if the event has an owner
+		owners = append(owners, owner) // Add the owner
+	}
+	return owners, nil // Return the recipients
+}
+
+// owner returns the owner of the session an event belongs to, the session of its aggregate or the session_id of
+// its data. An event without a session belongs to the coach_id of its data, like the feedback about a coach, and
+// the owner is zero when the event has neither or its session was deleted.
+func (d *Dispatcher) owner(ctx context.Context, event models.Event) (primitive.ObjectID, error) { // Find the owner of an event
+	var data struct {
+		SessionID primitive.ObjectID `json:"session_id"` // Session the event is about
+		CoachID   primitive.ObjectID `json:"coach_id"`   // Coach the event is about
+	}
+	if event.AggregateType == models.AggregateSession { // Check if the event belongs to a session
+		data.SessionID = event.AggregateID // The aggregate is the session
+	} else if err := json.Unmarshal(event.Data, &data); err != nil { // Read the session from the data
+		return primitive.NilObjectID, fmt.Errorf("invalid data in event %s: %w", event.ID.Hex(), err) // Return the error
+	}
+	if data.SessionID.IsZero() { // Check if the event has no session
+		return data.CoachID, nil // Return the coach, if any
+	}
+
+	session, err := d.sessions.FindByID(ctx, data.SessionID) // Find the session
+	if errors.Is(err, repository.ErrNotFound) {              // Check if the session was deleted
+		return primitive.NilObjectID, nil // Only the admins receive the event
+	}
+	if err != nil { // Check if there is an error
+		return primitive.NilObjectID, fmt.Errorf("failed to find session %s: %w", data.SessionID.Hex(), err) // Return the error
+	}
+	return session.OwnerID, nil // Return the owner of the session
+}
+
+// Close does nothing, the deliveries in flight are finished by DeliverDue
+func (d *Dispatcher) Close() error { // Close the dispatcher
+	return nil // Return nil
+}
+
+// DeliverDue attempts a batch of the deliveries due by now, in parallel. Each delivery is claimed first, so
+// several instances never post it at the same time.
+func (d *Dispatcher) DeliverDue(ctx context.Context) error { // Attempt the due deliveries
+	now := time.Now()                                           // Get the current time
+	due, err := d.deliveries.FindDue(ctx, now, d.cfg.BatchSize) // Find the due deliveries
+	if err != nil {                                             // Check if there is an error
+		return fmt.Errorf("failed to find the due deliveries: %w", err) // Return the error
+	}
+
+	var wg sync.WaitGroup           // Define a wait group for the attempts
+	errs := make([]error, len(due)) // Define a slice to hold the errors of the attempts
+	for i, delivery := range due {  // Iterate over the deliveries
+		wg.Add(1)                                         // Count the attempt
+		go func(i int, delivery models.WebhookDelivery) { // Attempt the delivery in the background
+			defer wg.Done()                         // Report the attempt done
+			errs[i] = d.deliver(ctx, delivery, now) // Attempt the delivery
+		}(i, delivery)
+	}
+	wg.Wait()                   // Wait for the attempts
+	return errors.Join(errs...) // Return the errors met
+}
+
+// deliver claims a delivery, posts it to its webhook and records the attempt
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery, now time.Time) error { // Attempt a delivery
+	err := d.deliveries.Claim(ctx, delivery.ID, now, now.Add(2*d.cfg.Timeout)) // Hold the delivery while it is attempted
+	if errors.Is(err, repository.ErrNotFound) {                                // Check if another instance claimed it
+		return nil // The other instance attempts it
+	}
+	if err != nil { // Check if there is an error
+		return fmt.Errorf("failed to claim delivery %s: %w", delivery.ID.Hex(), err) // Return the error
+	}
+
+	webhook, err := d.webhooks.FindByID(ctx, delivery.WebhookID) // Find the webhook
+	if errors.Is(err, repository.ErrNotFound) {                  // Check if the webhook was deleted meanwhile
+		return nil // Its deliveries are deleted with it
+	}
+	if err != nil { // Check if there is an error
+		return fmt.Errorf("failed to find webhook %s: %w", delivery.WebhookID.Hex(), err) // Return the error
+	}
+
+	attempt := models.DeliveryAttempt{At: time.Now(), Error: "webhook is disabled"} // Fail without a request when the webhook is disabled
+	if webhook.Active {                                                             // Check if the webhook still receives events
+		statusCode, err := d.post(ctx, webhook, delivery, attempt.At) // Post the event
+		attempt.StatusCode, attempt.Error = statusCode, ""            // Store the status code
+		if err != nil {                                               // Check if the attempt failed
+			attempt.Error = err.Error() // Store the error
+		}
+		attempt.Duration = time.Since(attempt.At).Milliseconds() // Time the attempt
+	}
+
+	outcome := d.settle(&delivery, attempt, webhook.Active)        // Decide what happens next
+	d.observer.ObserveWebhookDelivery(delivery.EventType, outcome) // Record the attempt
+	if outcome == OutcomeDead {                                    // Check if the delivery gave up
+		d.logger.WarnContext(ctx, "Webhook delivery moved to the dead letters", slog.String("delivery_id", delivery.ID.Hex()), slog.String("webhook_id", webhook.ID.Hex()), slog.String("error", attempt.Error)) // Log the error message
+	}
+
+	if err := d.deliveries.RecordAttempt(ctx, delivery, attempt); err != nil { // Store the attempt
+		return fmt.Errorf("failed to record the attempt of delivery %s: %w", delivery.ID.Hex(), err) // Return the error
+	}
+	return nil // Return nil
+}
+
+// post sends the signed payload of a delivery to its webhook and returns the status code of the response,
+// anything but a 2xx response is an error
+func (d *Dispatcher) post(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery, at time.Time) (int, error) { // Post an event
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload)) // Create the request
+	if err != nil {                                                                                                  // Check if there is an error
+		return 0, err // Return the error
+	}
+	request.Header.Set("Content-Type", "application/json")                          // Send JSON
+	request.Header.Set("User-Agent", "training_session-webhooks")                   // Identify the sender
+	request.Header.Set(HeaderWebhookID, webhook.ID.Hex())                           // Identify the webhook
+	request.Header.Set(HeaderDelivery, delivery.ID.Hex())                           // Identify the delivery
+	request.Header.Set(HeaderEvent, delivery.EventType)                             // Tell the event type
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))           // Tell the signing time
+	request.Header.Set(HeaderSignature, Sign(webhook.Secret, at, delivery.Payload)) // Sign the payload
+
+	response, err := d.client.Do(request) // Send the request
+	if err != nil {                       // Check if there is an error
+		return 0, err // Return the error
+	}
+	defer response.Body.Close()                                // Close the body
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10)) // Drain the body so the connection is reused
+
+	if response.StatusCode < 200 || response.StatusCode > 299 { // Check if the receiver rejected the event
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode) // Return the error
+	}
+	return response.StatusCode, nil // The receiver accepted the event
+}
+
+// settle updates the delivery after an attempt and returns the outcome; the deliveries of a disabled webhook go
+// straight to the dead letters, to be redelivered once it is enabled again
+func (d *Dispatcher) settle(delivery *models.WebhookDelivery, attempt models.DeliveryAttempt, active bool) string { // Decide what happens next
+	if attempt.Error == "" { // Check if the receiver accepted the event
+		delivered := attempt.At                          // Copy the attempt time
+		delivery.Status = models.DeliveryStatusSucceeded // The delivery is done
+		delivery.DeliveredAt = &delivered                // Set the delivery time
+		return OutcomeDelivered                          // Return the outcome
+	}
+
+	delivery.Failures++                                    // Count the failure
+	if !active || delivery.Failures >= d.cfg.MaxAttempts { // Check if the delivery must give up
+		delivery.Status = models.DeliveryStatusDead // Move it to the dead letters
+		return OutcomeDead                          // Return the outcome
+	}
+	delivery.NextAttemptAt = attempt.At.Add(d.backoff(delivery.Failures)) // Retry after the backoff
+	return OutcomeRetrying                                                // Return the outcome
+}
+
+// backoff returns the delay before the retry following the given number of failures: the configured backoff
+// doubled after every failure but the first, up to the configured maximum
+func (d *Dispatcher) backoff(failures int) time.Duration { // Compute the retry delay
+	delay := d.cfg.Backoff          // Start with the first delay
+	for i := 1; i < failures; i++ { // Double the delay for every further failure
+		delay *= 2                     // Double the delay
+		if delay >= d.cfg.MaxBackoff { // Check if the maximum is reached
+			return d.cfg.MaxBackoff // Return the maximum
+		}
+	}
+	return min(delay, d.cfg.MaxBackoff) // Return the delay
+}
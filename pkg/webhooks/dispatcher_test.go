@@ -0,0 +1,305 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"training_session/config"
+	"training_session/pkg/models"
+	"training_session/pkg/repository"
+	"training_session/pkg/repository/memory"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// outcomes records the outcomes the dispatcher observes
+type outcomes struct {
+	mu   sync.Mutex // Guards the list
+	list []string   // Outcomes in the order of the attempts
+}
+
+// ObserveWebhookDelivery records the outcome of an attempt
+func (o *outcomes) ObserveWebhookDelivery(eventType, outcome string) {
+	o.mu.Lock()                      // Lock the list
+	defer o.mu.Unlock()              // Unlock the list
+	o.list = append(o.list, outcome) // Record the outcome
+}
+
+// fixture holds a dispatcher over the in-memory repositories and the users owning the webhooks
+type fixture struct {
+	repos      *repository.Repositories // In-memory repositories
+	dispatcher *Dispatcher              // Dispatcher under test
+	observed   *outcomes                // Outcomes of the attempts
+	owner      models.User              // Business owner of the session
+	other      models.User              // Business owner of nothing
+	admin      models.User              // Admin receiving every event
+	session    models.Session           // Session owned by owner
+}
+
+// newFixture creates the users and the session and a dispatcher retrying right away up to three attempts
+func newFixture(t *testing.T, allowPrivate bool) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	cfg := config.WebhooksConfig{BatchSize: 10, Timeout: 5 * time.Second, MaxAttempts: 3, Backoff: time.Nanosecond, MaxBackoff: time.Nanosecond, AllowPrivateNetworks: allowPrivate}
+	f := &fixture{repos: memory.NewRepositories(), observed: &outcomes{}}
+	f.dispatcher = NewDispatcher(f.repos, cfg, f.observed, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, user := range []*models.User{&f.owner, &f.other, &f.admin} {
+		user.ID = primitive.NewObjectID()
+		user.Email = user.ID.Hex() + "@example.com"
+		user.Role = models.RoleBusinessOwner
+		if user == &f.admin {
+			user.Role = models.RoleAdmin
+		}
+		if err := f.repos.Users.Insert(ctx, user); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+	f.session = models.Session{ID: primitive.NewObjectID(), OwnerID: f.owner.ID, Coach: primitive.NewObjectID()}
+	if err := f.repos.Sessions.Insert(ctx, &f.session); err != nil {
+		t.Fatalf("insert session: %v", err)
+	}
+	return f
+}
+
+// register registers an active webhook of the owner posting to the URL
+func (f *fixture) register(t *testing.T, ownerID primitive.ObjectID, url string) models.Webhook {
+	t.Helper()
+	webhook := models.Webhook{ID: primitive.NewObjectID(), OwnerID: ownerID, URL: url, Secret: "whsec_test", Active: true}
+	if err := f.repos.Webhooks.Insert(context.Background(), &webhook); err != nil {
+		t.Fatalf("insert webhook: %v", err)
+	}
+	return webhook
+}
+
+// deliveries returns the deliveries of a webhook
+func (f *fixture) deliveries(t *testing.T, webhookID primitive.ObjectID) []models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := f.repos.Deliveries.FindByWebhook(context.Background(), webhookID, "")
+	if err != nil {
+		t.Fatalf("find deliveries: %v", err)
+	}
+	return deliveries
+}
+
+// newEvent creates an event of the aggregate with the data
+func newEvent(t *testing.T, eventType, aggregateType string, aggregateID primitive.ObjectID, data interface{}) models.Event {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("marshal data: %v", err)
+	}
+	return models.Event{ID: primitive.NewObjectID(), Type: eventType, AggregateType: aggregateType, AggregateID: aggregateID, Data: raw, OccurredAt: time.Now()}
+}
+
+func TestPublishScopesDeliveries(t *testing.T) {
+	tests := []struct {
+		name  string
+		event func(f *fixture) models.Event
+		owner int // Deliveries expected for the owner's webhook
+		other int // Deliveries expected for the other owner's webhook
+		admin int // Deliveries expected for the admin's webhook
+	}{
+		{
+			name: "session event reaches the owner and the admins",
+			event: func(f *fixture) models.Event {
+				return newEvent(t, models.EventSessionCreated, models.AggregateSession, f.session.ID, f.session)
+			},
+			owner: 1, admin: 1,
+		},
+		{
+			name: "pitch booking reaches the owner of its session",
+			event: func(f *fixture) models.Event {
+				return newEvent(t, models.EventPitchBooked, models.AggregatePitch, primitive.NewObjectID(), map[string]interface{}{"session_id": f.session.ID})
+			},
+			owner: 1, admin: 1,
+		},
+		{
+			name: "event of a missing session only reaches the admins",
+			event: func(f *fixture) models.Event {
+				return newEvent(t, models.EventParticipantEnrolled, models.AggregateSession, primitive.NewObjectID(), nil)
+			},
+			admin: 1,
+		},
+		{
+			name: "feedback without a session reaches the coach",
+			event: func(f *fixture) models.Event {
+				return newEvent(t, models.EventFeedbackSubmitted, models.AggregateFeedback, primitive.NewObjectID(), map[string]interface{}{"session_id": primitive.NilObjectID, "coach_id": f.other.ID})
+			},
+			other: 1, admin: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, true)
+			owner := f.register(t, f.owner.ID, "https://owner.example.com/hook")
+			other := f.register(t, f.other.ID, "https://other.example.com/hook")
+			admin := f.register(t, f.admin.ID, "https://admin.example.com/hook")
+
+			event := tt.event(f)
+			for i := 0; i < 2; i++ { // The relay may publish an event twice
+				if err := f.dispatcher.Publish(context.Background(), event); err != nil {
+					t.Fatalf("publish: %v", err)
+				}
+			}
+
+			for _, c := range []struct {
+				who     string
+				webhook models.Webhook
+				want    int
+			}{{"owner", owner, tt.owner}, {"other", other, tt.other}, {"admin", admin, tt.admin}} {
+				if got := len(f.deliveries(t, c.webhook.ID)); got != c.want {
+					t.Errorf("%s webhook has %d deliveries, want %d", c.who, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverDueSignsRetriesAndRedelivers(t *testing.T) {
+	var fail atomic.Bool
+	var requests atomic.Int32
+	var mu sync.Mutex
+	var verifyErrs []error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		err := Verify("whsec_test", r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body, time.Minute, time.Now())
+		mu.Lock()
+		verifyErrs = append(verifyErrs, err)
+		mu.Unlock()
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	f := newFixture(t, true)
+	webhook := f.register(t, f.owner.ID, server.URL)
+	if err := f.dispatcher.Publish(ctx, newEvent(t, models.EventSessionCreated, models.AggregateSession, f.session.ID, f.session)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	delivery := f.deliveries(t, webhook.ID)[0]
+
+	// A failing receiver is retried until the attempts run out
+	fail.Store(true)
+	wantStatuses := []string{models.DeliveryStatusPending, models.DeliveryStatusPending, models.DeliveryStatusDead}
+	for i, want := range wantStatuses {
+		time.Sleep(time.Millisecond) // Let the backoff elapse
+		if err := f.dispatcher.DeliverDue(ctx); err != nil {
+			t.Fatalf("deliver %d: %v", i+1, err)
+		}
+		got, err := f.repos.Deliveries.FindByID(ctx, delivery.ID)
+		if err != nil {
+			t.Fatalf("find delivery: %v", err)
+		}
+		if got.Status != want || got.Failures != i+1 || len(got.Attempts) != i+1 {
+			t.Fatalf("after attempt %d: status %q, failures %d, attempts %d, want %q, %d, %d", i+1, got.Status, got.Failures, len(got.Attempts), want, i+1, i+1)
+		}
+		if got.Attempts[i].StatusCode != http.StatusInternalServerError {
+			t.Errorf("attempt %d: status code %d, want 500", i+1, got.Attempts[i].StatusCode)
+		}
+	}
+	if err := f.dispatcher.DeliverDue(ctx); err != nil { // A dead delivery is not attempted again
+		t.Fatalf("deliver after dead: %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("receiver got %d requests, want 3", n)
+	}
+
+	// A redelivered delivery gets a fresh set of attempts
+	fail.Store(false)
+	if err := f.repos.Deliveries.Redeliver(ctx, delivery.ID, time.Now()); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if err := f.dispatcher.DeliverDue(ctx); err != nil {
+		t.Fatalf("deliver redelivery: %v", err)
+	}
+	got, err := f.repos.Deliveries.FindByID(ctx, delivery.ID)
+	if err != nil {
+		t.Fatalf("find delivery: %v", err)
+	}
+	if got.Status != models.DeliveryStatusSucceeded || got.DeliveredAt == nil || got.Failures != 0 || len(got.Attempts) != 4 {
+		t.Fatalf("after redelivery: status %q, delivered at %v, failures %d, attempts %d", got.Status, got.DeliveredAt, got.Failures, len(got.Attempts))
+	}
+
+	want := []string{OutcomeRetrying, OutcomeRetrying, OutcomeDead, OutcomeDelivered}
+	if strings.Join(f.observed.list, ",") != strings.Join(want, ",") {
+		t.Errorf("observed %v, want %v", f.observed.list, want)
+	}
+	for i, err := range verifyErrs {
+		if err != nil {
+			t.Errorf("request %d: signature: %v", i+1, err)
+		}
+	}
+}
+
+func TestDeliverDueRefusesPrivateAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	f := newFixture(t, false)
+	webhook := f.register(t, f.owner.ID, server.URL) // Registered before the checks, or resolving to a private address since
+	if err := f.dispatcher.Publish(ctx, newEvent(t, models.EventSessionCreated, models.AggregateSession, f.session.ID, f.session)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := f.dispatcher.DeliverDue(ctx); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+
+	delivery := f.deliveries(t, webhook.ID)[0]
+	if len(delivery.Attempts) != 1 || !strings.Contains(delivery.Attempts[0].Error, ErrPrivateAddress.Error()) {
+		t.Fatalf("attempts %+v, want one refused as private", delivery.Attempts)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("receiver got %d requests, want 0", n)
+	}
+}
+
+func TestPublicHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"localhost", false},
+		{"api.localhost", false},
+		{"intranet", false},
+		{"db.internal", false},
+		{"127.0.0.1", false},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicHost(tt.host); got != tt.want {
+			t.Errorf("PublicHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Headers of the requests posting an event to a webhook
+const (
+	HeaderWebhookID = "X-Webhook-Id"        // ID of the webhook
+	HeaderDelivery  = "X-Webhook-Delivery"  // ID of the delivery, the same for every attempt
+	HeaderEvent     = "X-Webhook-Event"     // Type of the event
+	HeaderTimestamp = "X-Webhook-Timestamp" // Unix time the request was signed at
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret
+)
+
+// secretPrefix starts every webhook secret, so a leaked one is easy to recognize
+const secretPrefix = "whsec_"
+
+// NewSecret generates the random key a webhook signs its payloads with
+func NewSecret() (string, error) { // Generate a secret
+	key := make([]byte, 32)                   // Define a 256-bit key
+	if _, err := rand.Read(key); err != nil { // Fill the key with random bytes
+		return "", fmt.Errorf("failed to generate the webhook secret: %w", err) // Return the error
+	}
+	return secretPrefix + hex.EncodeToString(key), nil // Return the secret
+}
+
+// Sign computes the signature header of a payload sent at the given time. The timestamp is signed with the
+// body so a captured request cannot be replayed later with another timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string { // Sign a payload
+	mac := hmac.New(sha256.New, []byte(secret))                // Key the HMAC with the secret
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10))) // Sign the timestamp
+	mac.Write([]byte("."))                                     // Separate the timestamp from the body
+	mac.Write(body)                                            // Sign the body
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))        // Return the signature
+}
+
+// Verify checks the signature and timestamp headers of a received payload, rejecting the payloads signed more
+// than tolerance ago; receivers written in Go can use it as is
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) error { // Verify a payload
+	seconds, err := strconv.ParseInt(timestamp, 10, 64) // Parse the timestamp
+	if err != nil {                                     // Check if the timestamp is invalid
+		return fmt.Errorf("invalid timestamp %q", timestamp) // Return the error
+	}
+	signedAt := time.Unix(seconds, 0)                                   // Get the signing time
+	if now.Sub(signedAt) > tolerance || signedAt.Sub(now) > tolerance { // Check if the payload is too old or from the future
+		return fmt.Errorf("timestamp %s is outside the tolerance of %s", signedAt.UTC().Format(time.RFC3339), tolerance) // Return the error
+	}
+	expected := Sign(secret, signedAt, body)              // Compute the expected signature
+	if !hmac.Equal([]byte(signature), []byte(expected)) { // Compare in constant time
+		return errors.New("signature mismatch") // Return the error
+	}
+	return nil // The payload is authentic
+}